@@ -31,6 +31,7 @@ type MockAutoscaling struct {
 	Groups            map[string]*autoscalingtypes.AutoScalingGroup
 	WarmPoolInstances map[string][]autoscalingtypes.Instance
 	LifecycleHooks    map[string]*autoscalingtypes.LifecycleHook
+	ScheduledActions  map[string]*autoscalingtypes.ScheduledUpdateGroupAction
 }
 
 var _ awsinterfaces.AutoScalingAPI = &MockAutoscaling{}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockautoscaling
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"k8s.io/klog/v2"
+)
+
+func (m *MockAutoscaling) AddScheduledAction(action *autoscalingtypes.ScheduledUpdateGroupAction) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.ScheduledActions == nil {
+		m.ScheduledActions = make(map[string]*autoscalingtypes.ScheduledUpdateGroupAction)
+	}
+	name := aws.ToString(action.AutoScalingGroupName) + "::" + aws.ToString(action.ScheduledActionName)
+	m.ScheduledActions[name] = action
+}
+
+func (m *MockAutoscaling) DescribeScheduledActions(ctx context.Context, input *autoscaling.DescribeScheduledActionsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeScheduledActionsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.V(2).Infof("Mock DescribeScheduledActions: %v", input)
+
+	if len(input.ScheduledActionNames) != 0 {
+		return nil, fmt.Errorf("ScheduledActionNames not implemented")
+	}
+
+	response := &autoscaling.DescribeScheduledActionsOutput{}
+	for _, action := range m.ScheduledActions {
+		if input.AutoScalingGroupName != nil && aws.ToString(action.AutoScalingGroupName) != aws.ToString(input.AutoScalingGroupName) {
+			continue
+		}
+		response.ScheduledUpdateGroupActions = append(response.ScheduledUpdateGroupActions, *action)
+	}
+	return response, nil
+}
+
+func (m *MockAutoscaling) DeleteScheduledAction(ctx context.Context, input *autoscaling.DeleteScheduledActionInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DeleteScheduledActionOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.V(2).Infof("Mock DeleteScheduledAction: %v", input)
+
+	name := aws.ToString(input.AutoScalingGroupName) + "::" + aws.ToString(input.ScheduledActionName)
+	if m.ScheduledActions[name] == nil {
+		return nil, fmt.Errorf("ScheduledAction %q not found", name)
+	}
+	delete(m.ScheduledActions, name)
+
+	return &autoscaling.DeleteScheduledActionOutput{}, nil
+}
@@ -18,8 +18,12 @@ package mockautoscaling
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"k8s.io/klog/v2"
 )
 
 func (m *MockAutoscaling) DescribeWarmPool(ctx context.Context, input *autoscaling.DescribeWarmPoolInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeWarmPoolOutput, error) {
@@ -33,6 +37,36 @@ func (m *MockAutoscaling) DescribeWarmPool(ctx context.Context, input *autoscali
 	return ret, nil
 }
 
+func (m *MockAutoscaling) PutWarmPool(ctx context.Context, input *autoscaling.PutWarmPoolInput, optFns ...func(*autoscaling.Options)) (*autoscaling.PutWarmPoolOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.V(2).Infof("Mock PutWarmPool: %v", input)
+
+	name := aws.ToString(input.AutoScalingGroupName)
+	group := m.Groups[name]
+	if group == nil {
+		return nil, fmt.Errorf("AutoScalingGroup %q not found", name)
+	}
+	group.WarmPoolConfiguration = &autoscalingtypes.WarmPoolConfiguration{
+		InstanceReusePolicy:      input.InstanceReusePolicy,
+		MaxGroupPreparedCapacity: input.MaxGroupPreparedCapacity,
+		MinSize:                  input.MinSize,
+		PoolState:                input.PoolState,
+	}
+	return &autoscaling.PutWarmPoolOutput{}, nil
+}
+
 func (m *MockAutoscaling) DeleteWarmPool(ctx context.Context, input *autoscaling.DeleteWarmPoolInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DeleteWarmPoolOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.V(2).Infof("Mock DeleteWarmPool: %v", input)
+
+	name := aws.ToString(input.AutoScalingGroupName)
+	if group := m.Groups[name]; group != nil {
+		group.WarmPoolConfiguration = nil
+	}
+	delete(m.WarmPoolInstances, name)
 	return &autoscaling.DeleteWarmPoolOutput{}, nil
 }
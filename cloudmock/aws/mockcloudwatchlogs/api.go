@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockcloudwatchlogs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwltypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"k8s.io/klog/v2"
+	"k8s.io/kops/util/pkg/awsinterfaces"
+)
+
+type MockCloudWatchLogs struct {
+	awsinterfaces.CloudWatchLogsAPI
+	mutex sync.Mutex
+
+	// LogGroups are the log groups by name
+	LogGroups map[string]*cwltypes.LogGroup
+	// TagsByName are the tags of the log groups, by name
+	TagsByName map[string]map[string]string
+}
+
+var _ awsinterfaces.CloudWatchLogsAPI = &MockCloudWatchLogs{}
+
+// MockCreateLogGroup adds a log group with the tags, with ARNs derived from its name
+func (m *MockCloudWatchLogs) MockCreateLogGroup(name string, tags map[string]string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.LogGroups == nil {
+		m.LogGroups = make(map[string]*cwltypes.LogGroup)
+	}
+	if m.TagsByName == nil {
+		m.TagsByName = make(map[string]map[string]string)
+	}
+
+	arn := "arn:aws-test:logs:us-east-1:012345678901:log-group:" + name
+	m.LogGroups[name] = &cwltypes.LogGroup{
+		LogGroupName: aws.String(name),
+		Arn:          aws.String(arn + ":*"),
+		LogGroupArn:  aws.String(arn),
+	}
+	m.TagsByName[name] = tags
+}
+
+func (m *MockCloudWatchLogs) DescribeLogGroups(ctx context.Context, input *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeLogGroups: %v", input)
+
+	prefix := aws.ToString(input.LogGroupNamePrefix)
+
+	var names []string
+	for name := range m.LogGroups {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	response := &cloudwatchlogs.DescribeLogGroupsOutput{}
+	for _, name := range names {
+		response.LogGroups = append(response.LogGroups, *m.LogGroups[name])
+	}
+	return response, nil
+}
+
+func (m *MockCloudWatchLogs) ListTagsForResource(ctx context.Context, input *cloudwatchlogs.ListTagsForResourceInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.ListTagsForResourceOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("ListTagsForResource: %v", input)
+
+	arn := aws.ToString(input.ResourceArn)
+	for name, logGroup := range m.LogGroups {
+		if aws.ToString(logGroup.LogGroupArn) == arn {
+			return &cloudwatchlogs.ListTagsForResourceOutput{Tags: m.TagsByName[name]}, nil
+		}
+	}
+	return nil, &cwltypes.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("resource %q not found", arn))}
+}
+
+func (m *MockCloudWatchLogs) DeleteLogGroup(ctx context.Context, input *cloudwatchlogs.DeleteLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteLogGroup: %v", input)
+
+	name := aws.ToString(input.LogGroupName)
+	if _, found := m.LogGroups[name]; !found {
+		return nil, &cwltypes.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("log group %q not found", name))}
+	}
+	delete(m.LogGroups, name)
+	delete(m.TagsByName, name)
+
+	return &cloudwatchlogs.DeleteLogGroupOutput{}, nil
+}
@@ -20,8 +20,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
@@ -145,7 +147,11 @@ func (m *MockEC2) DescribeAddresses(request *ec2.DescribeAddressesInput) (*ec2.D
 				}
 
 			default:
-				return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+				if strings.HasPrefix(*filter.Name, "tag:") || *filter.Name == "tag-key" {
+					match = m.hasTag(ec2.ResourceTypeElasticIp, *address.AllocationId, filter)
+				} else {
+					return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+				}
 			}
 
 			if !match {
@@ -187,7 +193,14 @@ func (m *MockEC2) ReleaseAddress(request *ec2.ReleaseAddressInput) (*ec2.Release
 	id := aws.StringValue(request.AllocationId)
 	o := m.Addresses[id]
 	if o == nil {
-		return nil, fmt.Errorf("Address %q not found", id)
+		return nil, awserr.New("InvalidAllocationID.NotFound", fmt.Sprintf("Address %q not found", id), nil)
+	}
+	for _, ngw := range m.NatGateways {
+		for _, address := range ngw.NatGatewayAddresses {
+			if aws.StringValue(address.AllocationId) == id {
+				return nil, awserr.New("InvalidIPAddress.InUse", fmt.Sprintf("Address %q is in use by NatGateway %q", id, aws.StringValue(ngw.NatGatewayId)), nil)
+			}
+		}
 	}
 	delete(m.Addresses, id)
 
@@ -40,6 +40,8 @@ type MockEC2 struct {
 
 	Images []*ec2.Image
 
+	Instances map[string]*ec2.Instance
+
 	securityGroupNumber int
 	SecurityGroups      map[string]*ec2.SecurityGroup
 	SecurityGroupRules  map[string]*ec2.SecurityGroupRule
@@ -62,6 +64,25 @@ type MockEC2 struct {
 
 	NatGateways map[string]*ec2.NatGateway
 
+	NetworkInterfaces map[string]*ec2.NetworkInterface
+
+	ManagedPrefixLists map[string]*ec2.ManagedPrefixList
+
+	VpcPeeringConnections map[string]*ec2.VpcPeeringConnection
+
+	VpcEndpoints map[string]*ec2.VpcEndpoint
+
+	TransitGateways              map[string]*ec2.TransitGateway
+	TransitGatewayVpcAttachments map[string]*ec2.TransitGatewayVpcAttachment
+
+	SpotInstanceRequests map[string]*ec2.SpotInstanceRequest
+
+	Snapshots map[string]*ec2.Snapshot
+
+	ClientVpnEndpoints          map[string]*ec2.ClientVpnEndpoint
+	ClientVpnTargetNetworks     map[string]*ec2.TargetNetwork
+	ClientVpnAuthorizationRules []*ec2.AuthorizationRule
+
 	idsMutex sync.Mutex
 	ids      map[string]*idAllocator
 }
@@ -83,6 +104,9 @@ func (m *MockEC2) All() map[string]interface{} {
 	for _, o := range m.Images {
 		all[aws.StringValue(o.ImageId)] = o
 	}
+	for id, o := range m.Instances {
+		all[id] = o
+	}
 	for id, o := range m.SecurityGroups {
 		all[id] = o
 	}
@@ -110,6 +134,33 @@ func (m *MockEC2) All() map[string]interface{} {
 	for id, o := range m.NatGateways {
 		all[id] = o
 	}
+	for id, o := range m.NetworkInterfaces {
+		all[id] = o
+	}
+	for id, o := range m.ManagedPrefixLists {
+		all[id] = o
+	}
+	for id, o := range m.VpcPeeringConnections {
+		all[id] = o
+	}
+	for id, o := range m.VpcEndpoints {
+		all[id] = o
+	}
+	for id, o := range m.TransitGateways {
+		all[id] = o
+	}
+	for id, o := range m.TransitGatewayVpcAttachments {
+		all[id] = o
+	}
+	for id, o := range m.SpotInstanceRequests {
+		all[id] = o
+	}
+	for id, o := range m.Snapshots {
+		all[id] = o
+	}
+	for id, o := range m.ClientVpnEndpoints {
+		all[id] = o
+	}
 
 	return all
 }
@@ -0,0 +1,289 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockec2
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+)
+
+func (m *MockEC2) AddClientVpnEndpoint(endpoint *ec2.ClientVpnEndpoint) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.ClientVpnEndpoints == nil {
+		m.ClientVpnEndpoints = make(map[string]*ec2.ClientVpnEndpoint)
+	}
+
+	m.addTags(*endpoint.ClientVpnEndpointId, endpoint.Tags...)
+
+	m.ClientVpnEndpoints[*endpoint.ClientVpnEndpointId] = endpoint
+}
+
+func (m *MockEC2) AddClientVpnTargetNetwork(targetNetwork *ec2.TargetNetwork) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.ClientVpnTargetNetworks == nil {
+		m.ClientVpnTargetNetworks = make(map[string]*ec2.TargetNetwork)
+	}
+
+	m.ClientVpnTargetNetworks[*targetNetwork.AssociationId] = targetNetwork
+}
+
+func (m *MockEC2) AddClientVpnAuthorizationRule(rule *ec2.AuthorizationRule) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.ClientVpnAuthorizationRules = append(m.ClientVpnAuthorizationRules, rule)
+}
+
+func (m *MockEC2) DescribeClientVpnEndpoints(request *ec2.DescribeClientVpnEndpointsInput) (*ec2.DescribeClientVpnEndpointsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeClientVpnEndpoints: %v", request)
+
+	if len(request.Filters) != 0 {
+		klog.Fatalf("MockEC2 DescribeClientVpnEndpoints Filters not implemented")
+	}
+
+	response := &ec2.DescribeClientVpnEndpointsOutput{}
+	for id, endpoint := range m.ClientVpnEndpoints {
+		if len(request.ClientVpnEndpointIds) != 0 {
+			match := false
+			for _, v := range request.ClientVpnEndpointIds {
+				if id == aws.StringValue(v) {
+					match = true
+				}
+			}
+			if !match {
+				continue
+			}
+		}
+
+		copy := *endpoint
+		copy.Tags = m.getTags(ec2.ResourceTypeClientVpnEndpoint, id)
+		response.ClientVpnEndpoints = append(response.ClientVpnEndpoints, &copy)
+	}
+
+	sort.Slice(response.ClientVpnEndpoints, func(i, j int) bool {
+		return aws.StringValue(response.ClientVpnEndpoints[i].ClientVpnEndpointId) < aws.StringValue(response.ClientVpnEndpoints[j].ClientVpnEndpointId)
+	})
+	start, end, nextToken, err := clientVpnPage(request.NextToken, len(response.ClientVpnEndpoints))
+	if err != nil {
+		return nil, err
+	}
+	response.ClientVpnEndpoints = response.ClientVpnEndpoints[start:end]
+	response.NextToken = nextToken
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeClientVpnEndpointsPages(request *ec2.DescribeClientVpnEndpointsInput, callback func(*ec2.DescribeClientVpnEndpointsOutput, bool) bool) error {
+	pageRequest := *request
+	for {
+		page, err := m.DescribeClientVpnEndpoints(&pageRequest)
+		if err != nil {
+			return err
+		}
+		lastPage := page.NextToken == nil
+		if !callback(page, lastPage) || lastPage {
+			return nil
+		}
+		pageRequest.NextToken = page.NextToken
+	}
+}
+
+func (m *MockEC2) DescribeClientVpnTargetNetworks(request *ec2.DescribeClientVpnTargetNetworksInput) (*ec2.DescribeClientVpnTargetNetworksOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeClientVpnTargetNetworks: %v", request)
+
+	endpointID := aws.StringValue(request.ClientVpnEndpointId)
+	if m.ClientVpnEndpoints[endpointID] == nil {
+		return nil, fmt.Errorf("ClientVpnEndpoint %q not found", endpointID)
+	}
+
+	response := &ec2.DescribeClientVpnTargetNetworksOutput{}
+	for _, targetNetwork := range m.ClientVpnTargetNetworks {
+		if aws.StringValue(targetNetwork.ClientVpnEndpointId) != endpointID {
+			continue
+		}
+		copy := *targetNetwork
+		response.ClientVpnTargetNetworks = append(response.ClientVpnTargetNetworks, &copy)
+	}
+
+	sort.Slice(response.ClientVpnTargetNetworks, func(i, j int) bool {
+		return aws.StringValue(response.ClientVpnTargetNetworks[i].AssociationId) < aws.StringValue(response.ClientVpnTargetNetworks[j].AssociationId)
+	})
+	start, end, nextToken, err := clientVpnPage(request.NextToken, len(response.ClientVpnTargetNetworks))
+	if err != nil {
+		return nil, err
+	}
+	response.ClientVpnTargetNetworks = response.ClientVpnTargetNetworks[start:end]
+	response.NextToken = nextToken
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeClientVpnTargetNetworksPages(request *ec2.DescribeClientVpnTargetNetworksInput, callback func(*ec2.DescribeClientVpnTargetNetworksOutput, bool) bool) error {
+	pageRequest := *request
+	for {
+		page, err := m.DescribeClientVpnTargetNetworks(&pageRequest)
+		if err != nil {
+			return err
+		}
+		lastPage := page.NextToken == nil
+		if !callback(page, lastPage) || lastPage {
+			return nil
+		}
+		pageRequest.NextToken = page.NextToken
+	}
+}
+
+func (m *MockEC2) DisassociateClientVpnTargetNetwork(request *ec2.DisassociateClientVpnTargetNetworkInput) (*ec2.DisassociateClientVpnTargetNetworkOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DisassociateClientVpnTargetNetwork: %v", request)
+
+	id := aws.StringValue(request.AssociationId)
+	targetNetwork := m.ClientVpnTargetNetworks[id]
+	if targetNetwork == nil || aws.StringValue(targetNetwork.ClientVpnEndpointId) != aws.StringValue(request.ClientVpnEndpointId) {
+		return nil, fmt.Errorf("ClientVpnTargetNetwork association %q not found", id)
+	}
+	delete(m.ClientVpnTargetNetworks, id)
+
+	return &ec2.DisassociateClientVpnTargetNetworkOutput{
+		AssociationId: request.AssociationId,
+	}, nil
+}
+
+func (m *MockEC2) DescribeClientVpnAuthorizationRules(request *ec2.DescribeClientVpnAuthorizationRulesInput) (*ec2.DescribeClientVpnAuthorizationRulesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeClientVpnAuthorizationRules: %v", request)
+
+	response := &ec2.DescribeClientVpnAuthorizationRulesOutput{}
+	for _, rule := range m.ClientVpnAuthorizationRules {
+		if aws.StringValue(rule.ClientVpnEndpointId) != aws.StringValue(request.ClientVpnEndpointId) {
+			continue
+		}
+		copy := *rule
+		response.AuthorizationRules = append(response.AuthorizationRules, &copy)
+	}
+
+	start, end, nextToken, err := clientVpnPage(request.NextToken, len(response.AuthorizationRules))
+	if err != nil {
+		return nil, err
+	}
+	response.AuthorizationRules = response.AuthorizationRules[start:end]
+	response.NextToken = nextToken
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeClientVpnAuthorizationRulesPages(request *ec2.DescribeClientVpnAuthorizationRulesInput, callback func(*ec2.DescribeClientVpnAuthorizationRulesOutput, bool) bool) error {
+	pageRequest := *request
+	for {
+		page, err := m.DescribeClientVpnAuthorizationRules(&pageRequest)
+		if err != nil {
+			return err
+		}
+		lastPage := page.NextToken == nil
+		if !callback(page, lastPage) || lastPage {
+			return nil
+		}
+		pageRequest.NextToken = page.NextToken
+	}
+}
+
+// describeClientVpnPageSize is the maximum number of results that the DescribeClientVpn* calls return in one page.
+// It is kept small so that tests exercise the pagination.
+const describeClientVpnPageSize = 10
+
+// clientVpnPage returns the bounds of the page of results that starts at nextToken, and the token of the following page.
+// As for route tables, the token is the index of the first result of the page.
+func clientVpnPage(nextToken *string, count int) (int, int, *string, error) {
+	start := 0
+	if nextToken != nil {
+		var err error
+		start, err = strconv.Atoi(*nextToken)
+		if err != nil || start < 0 || start > count {
+			return 0, 0, nil, fmt.Errorf("invalid NextToken %q", *nextToken)
+		}
+	}
+	end := start + describeClientVpnPageSize
+	if end >= count {
+		return start, count, nil, nil
+	}
+	return start, end, aws.String(strconv.Itoa(end)), nil
+}
+
+func (m *MockEC2) RevokeClientVpnIngress(request *ec2.RevokeClientVpnIngressInput) (*ec2.RevokeClientVpnIngressOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("RevokeClientVpnIngress: %v", request)
+
+	var rules []*ec2.AuthorizationRule
+	found := false
+	for _, rule := range m.ClientVpnAuthorizationRules {
+		if aws.StringValue(rule.ClientVpnEndpointId) == aws.StringValue(request.ClientVpnEndpointId) &&
+			aws.StringValue(rule.DestinationCidr) == aws.StringValue(request.TargetNetworkCidr) &&
+			(aws.BoolValue(request.RevokeAllGroups) || aws.StringValue(rule.GroupId) == aws.StringValue(request.AccessGroupId)) {
+			found = true
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	if !found {
+		return nil, fmt.Errorf("authorization rule for %q not found", aws.StringValue(request.TargetNetworkCidr))
+	}
+	m.ClientVpnAuthorizationRules = rules
+
+	return &ec2.RevokeClientVpnIngressOutput{}, nil
+}
+
+func (m *MockEC2) DeleteClientVpnEndpoint(request *ec2.DeleteClientVpnEndpointInput) (*ec2.DeleteClientVpnEndpointOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteClientVpnEndpoint: %v", request)
+
+	id := aws.StringValue(request.ClientVpnEndpointId)
+	if m.ClientVpnEndpoints[id] == nil {
+		return nil, awserr.New("InvalidClientVpnEndpointId.NotFound", fmt.Sprintf("ClientVpnEndpoint %q not found", id), nil)
+	}
+	for _, targetNetwork := range m.ClientVpnTargetNetworks {
+		if aws.StringValue(targetNetwork.ClientVpnEndpointId) == id {
+			return nil, fmt.Errorf("ClientVpnEndpoint %q still has associated target networks", id)
+		}
+	}
+	delete(m.ClientVpnEndpoints, id)
+
+	return &ec2.DeleteClientVpnEndpointOutput{}, nil
+}
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
@@ -51,7 +52,7 @@ func (m *MockEC2) DescribeDhcpOptions(request *ec2.DescribeDhcpOptionsInput) (*e
 			// 		match = true
 			// 	}
 			default:
-				if strings.HasPrefix(*filter.Name, "tag:") {
+				if strings.HasPrefix(*filter.Name, "tag:") || *filter.Name == "tag-key" {
 					match = m.hasTag(ec2.ResourceTypeDhcpOptions, *dhcpOptions.DhcpOptionsId, filter)
 				} else {
 					return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
@@ -175,7 +176,7 @@ func (m *MockEC2) DeleteDhcpOptions(request *ec2.DeleteDhcpOptionsInput) (*ec2.D
 	id := aws.StringValue(request.DhcpOptionsId)
 	o := m.DhcpOptions[id]
 	if o == nil {
-		return nil, fmt.Errorf("DhcpOptions %q not found", id)
+		return nil, awserr.New("InvalidDhcpOptionsID.NotFound", fmt.Sprintf("DhcpOptions %q not found", id), nil)
 	}
 	delete(m.DhcpOptions, id)
 
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
@@ -173,7 +174,7 @@ func (m *MockEC2) DeleteEgressOnlyInternetGateway(request *ec2.DeleteEgressOnlyI
 	id := aws.StringValue(request.EgressOnlyInternetGatewayId)
 	o := m.EgressOnlyInternetGateways[id]
 	if o == nil {
-		return nil, fmt.Errorf("EgressOnlyInternetGateway %q not found", id)
+		return nil, awserr.New("InvalidEgressOnlyInternetGatewayID.NotFound", fmt.Sprintf("EgressOnlyInternetGateway %q not found", id), nil)
 	}
 	delete(m.EgressOnlyInternetGateways, id)
 
@@ -16,13 +16,155 @@ limitations under the License.
 
 package mockec2
 
-import "github.com/aws/aws-sdk-go/service/ec2"
+import (
+	"fmt"
+	"strings"
 
-func (m *MockEC2) DescribeNetworkInterfaces(input *ec2.DescribeNetworkInterfacesInput) (*ec2.DescribeNetworkInterfacesOutput, error) {
-	output := &ec2.DescribeNetworkInterfacesOutput{}
-	return output, nil
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+)
+
+func (m *MockEC2) AddNetworkInterface(eni *ec2.NetworkInterface) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.NetworkInterfaces == nil {
+		m.NetworkInterfaces = make(map[string]*ec2.NetworkInterface)
+	}
+
+	m.addTags(*eni.NetworkInterfaceId, eni.TagSet...)
+
+	m.NetworkInterfaces[*eni.NetworkInterfaceId] = eni
+}
+
+func (m *MockEC2) FindNetworkInterface(id string) *ec2.NetworkInterface {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	eni := m.NetworkInterfaces[id]
+	if eni == nil {
+		return nil
+	}
+
+	copy := *eni
+	copy.TagSet = m.getTags(ec2.ResourceTypeNetworkInterface, id)
+	return &copy
 }
 
-func (m *MockEC2) DescribeNetworkInterfacesPages(*ec2.DescribeNetworkInterfacesInput, func(*ec2.DescribeNetworkInterfacesOutput, bool) bool) error {
+func (m *MockEC2) DescribeNetworkInterfaces(request *ec2.DescribeNetworkInterfacesInput) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeNetworkInterfaces: %v", request)
+
+	if len(request.NetworkInterfaceIds) != 0 {
+		request.Filters = append(request.Filters, &ec2.Filter{Name: s("network-interface-id"), Values: request.NetworkInterfaceIds})
+	}
+
+	var enis []*ec2.NetworkInterface
+	for id, eni := range m.NetworkInterfaces {
+		allFiltersMatch := true
+		for _, filter := range request.Filters {
+			match := false
+			switch *filter.Name {
+			case "network-interface-id":
+				for _, v := range filter.Values {
+					if id == *v {
+						match = true
+					}
+				}
+			case "vpc-id":
+				for _, v := range filter.Values {
+					if aws.StringValue(eni.VpcId) == *v {
+						match = true
+					}
+				}
+			case "status":
+				for _, v := range filter.Values {
+					if aws.StringValue(eni.Status) == *v {
+						match = true
+					}
+				}
+			default:
+				if strings.HasPrefix(*filter.Name, "tag:") || *filter.Name == "tag-key" {
+					match = m.hasTag(ec2.ResourceTypeNetworkInterface, id, filter)
+				} else {
+					return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+				}
+			}
+
+			if !match {
+				allFiltersMatch = false
+				break
+			}
+		}
+
+		if !allFiltersMatch {
+			continue
+		}
+
+		copy := *eni
+		copy.TagSet = m.getTags(ec2.ResourceTypeNetworkInterface, id)
+		enis = append(enis, &copy)
+	}
+
+	response := &ec2.DescribeNetworkInterfacesOutput{
+		NetworkInterfaces: enis,
+	}
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeNetworkInterfacesPages(request *ec2.DescribeNetworkInterfacesInput, callback func(*ec2.DescribeNetworkInterfacesOutput, bool) bool) error {
+	// For the mock, we just send everything in one page
+	page, err := m.DescribeNetworkInterfaces(request)
+	if err != nil {
+		return err
+	}
+
+	callback(page, false)
+
 	return nil
 }
+
+func (m *MockEC2) DetachNetworkInterface(request *ec2.DetachNetworkInterfaceInput) (*ec2.DetachNetworkInterfaceOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DetachNetworkInterface: %v", request)
+
+	attachmentID := aws.StringValue(request.AttachmentId)
+	for _, eni := range m.NetworkInterfaces {
+		if eni.Attachment == nil || aws.StringValue(eni.Attachment.AttachmentId) != attachmentID {
+			continue
+		}
+		// The mock detaches immediately, where AWS goes through "detaching"
+		eni.Attachment = nil
+		eni.Status = aws.String(ec2.NetworkInterfaceStatusAvailable)
+		return &ec2.DetachNetworkInterfaceOutput{}, nil
+	}
+
+	return nil, awserr.New("InvalidAttachmentID.NotFound", fmt.Sprintf("The attachment ID '%s' does not exist", attachmentID), nil)
+}
+
+func (m *MockEC2) DeleteNetworkInterface(request *ec2.DeleteNetworkInterfaceInput) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteNetworkInterface: %v", request)
+
+	id := aws.StringValue(request.NetworkInterfaceId)
+	eni := m.NetworkInterfaces[id]
+	if eni == nil {
+		return nil, awserr.New("InvalidNetworkInterfaceID.NotFound", fmt.Sprintf("The networkInterface ID '%s' does not exist", id), nil)
+	}
+	if eni.Attachment != nil {
+		return nil, awserr.New("InvalidNetworkInterface.InUse", fmt.Sprintf("The network interface '%s' is currently in use", id), nil)
+	}
+
+	delete(m.NetworkInterfaces, id)
+
+	return &ec2.DeleteNetworkInterfaceOutput{}, nil
+}
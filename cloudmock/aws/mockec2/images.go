@@ -102,6 +102,15 @@ func (m *MockEC2) imageMatchesFilter(image *ec2.Image, filters []*ec2.Filter) (b
 				}
 			}
 
+		case "block-device-mapping.snapshot-id":
+			for _, v := range filter.Values {
+				for _, mapping := range image.BlockDeviceMappings {
+					if mapping.Ebs != nil && aws.StringValue(mapping.Ebs.SnapshotId) == *v {
+						match = true
+					}
+				}
+			}
+
 		default:
 			if strings.HasPrefix(*filter.Name, "tag:") {
 				match = m.hasTag(ec2.ResourceTypeImage, *image.ImageId, filter)
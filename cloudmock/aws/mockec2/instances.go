@@ -17,15 +17,106 @@ limitations under the License.
 package mockec2
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
 )
 
-func (m *MockEC2) DescribeInstances(*ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
-	klog.Warningf("MockEc2::DescribeInstances is stub-implemented")
-	return &ec2.DescribeInstancesOutput{}, nil
+// AddInstance adds an instance, which the mock never starts or stops, for DescribeInstances to return
+func (m *MockEC2) AddInstance(instance *ec2.Instance) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.Instances == nil {
+		m.Instances = make(map[string]*ec2.Instance)
+	}
+
+	m.addTags(*instance.InstanceId, instance.Tags...)
+
+	m.Instances[*instance.InstanceId] = instance
+}
+
+func (m *MockEC2) DescribeInstances(request *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeInstances: %v", request)
+
+	filters := request.Filters
+	if len(request.InstanceIds) != 0 {
+		filters = append(filters, &ec2.Filter{Name: aws.String("instance-id"), Values: request.InstanceIds})
+	}
+
+	reservation := &ec2.Reservation{}
+	for id, instance := range m.Instances {
+		allFiltersMatch := true
+		for _, filter := range filters {
+			match := false
+			switch *filter.Name {
+			case "instance-id":
+				for _, v := range filter.Values {
+					if id == *v {
+						match = true
+					}
+				}
+			case "instance-state-name":
+				for _, v := range filter.Values {
+					if instance.State != nil && aws.StringValue(instance.State.Name) == *v {
+						match = true
+					}
+				}
+			case "subnet-id":
+				for _, v := range filter.Values {
+					if aws.StringValue(instance.SubnetId) == *v {
+						match = true
+					}
+				}
+			case "vpc-id":
+				for _, v := range filter.Values {
+					if aws.StringValue(instance.VpcId) == *v {
+						match = true
+					}
+				}
+			case "network-interface.subnet-id":
+				for _, eni := range instance.NetworkInterfaces {
+					for _, v := range filter.Values {
+						if aws.StringValue(eni.SubnetId) == *v {
+							match = true
+						}
+					}
+				}
+			default:
+				if strings.HasPrefix(*filter.Name, "tag:") {
+					match = m.hasTag(ec2.ResourceTypeInstance, id, filter)
+				} else {
+					return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+				}
+			}
+
+			if !match {
+				allFiltersMatch = false
+				break
+			}
+		}
+
+		if !allFiltersMatch {
+			continue
+		}
+
+		copy := *instance
+		copy.Tags = m.getTags(ec2.ResourceTypeInstance, id)
+		reservation.Instances = append(reservation.Instances, &copy)
+	}
+
+	response := &ec2.DescribeInstancesOutput{}
+	if len(reservation.Instances) != 0 {
+		response.Reservations = []*ec2.Reservation{reservation}
+	}
+	return response, nil
 }
 
 func (m *MockEC2) DescribeInstancesWithContext(aws.Context, *ec2.DescribeInstancesInput, ...request.Option) (*ec2.DescribeInstancesOutput, error) {
@@ -21,11 +21,25 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
 )
 
+func (m *MockEC2) AddInternetGateway(igw *ec2.InternetGateway) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.InternetGateways == nil {
+		m.InternetGateways = make(map[string]*ec2.InternetGateway)
+	}
+
+	m.addTags(*igw.InternetGatewayId, igw.Tags...)
+
+	m.InternetGateways[*igw.InternetGatewayId] = igw
+}
+
 func (m *MockEC2) FindInternetGateway(id string) *ec2.InternetGateway {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -229,7 +243,7 @@ func (m *MockEC2) DeleteInternetGateway(request *ec2.DeleteInternetGatewayInput)
 	id := aws.StringValue(request.InternetGatewayId)
 	o := m.InternetGateways[id]
 	if o == nil {
-		return nil, fmt.Errorf("InternetGateway %q not found", id)
+		return nil, awserr.New("InvalidInternetGatewayID.NotFound", fmt.Sprintf("InternetGateway %q not found", id), nil)
 	}
 	delete(m.InternetGateways, id)
 
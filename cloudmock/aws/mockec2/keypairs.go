@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
@@ -162,7 +163,7 @@ func (m *MockEC2) DeleteKeyPair(request *ec2.DeleteKeyPairInput) (*ec2.DeleteKey
 		}
 	}
 	if !found {
-		return nil, fmt.Errorf("KeyPairs %q not found", keyID)
+		return nil, awserr.New("InvalidKeyPair.NotFound", fmt.Sprintf("KeyPairs %q not found", keyID), nil)
 	}
 
 	return &ec2.DeleteKeyPairOutput{}, nil
@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
@@ -76,7 +77,7 @@ func (m *MockEC2) DescribeLaunchTemplates(request *ec2.DescribeLaunchTemplatesIn
 				filterMatches = true
 			}
 			if strings.HasPrefix(filterName, "tag:kubernetes.io/cluster/") {
-				filterMatches = true
+				filterMatches = m.hasTag(ec2.ResourceTypeLaunchTemplate, id, filter)
 			}
 
 			if !filterMatches {
@@ -198,14 +199,11 @@ func (m *MockEC2) DeleteLaunchTemplate(request *ec2.DeleteLaunchTemplateInput) (
 
 	o := &ec2.DeleteLaunchTemplateOutput{}
 
-	if m.LaunchTemplates == nil {
-		return o, nil
-	}
-	for id := range m.LaunchTemplates {
-		if id == aws.StringValue(request.LaunchTemplateId) {
-			delete(m.LaunchTemplates, id)
-		}
+	id := aws.StringValue(request.LaunchTemplateId)
+	if _, found := m.LaunchTemplates[id]; !found {
+		return nil, awserr.New("InvalidLaunchTemplateId.NotFound", fmt.Sprintf("The specified launch template, with template ID %s, does not exist", id), nil)
 	}
+	delete(m.LaunchTemplates, id)
 
 	return o, nil
 }
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
@@ -37,6 +38,9 @@ func (m *MockEC2) CreateNatGatewayWithId(request *ec2.CreateNatGatewayInput, id
 		SubnetId:     request.SubnetId,
 		Tags:         tags,
 	}
+	if subnet := m.subnets[aws.StringValue(request.SubnetId)]; subnet != nil {
+		ngw.VpcId = subnet.main.VpcId
+	}
 
 	if request.AllocationId != nil {
 		var eip *ec2.Address
@@ -133,6 +137,18 @@ func (m *MockEC2) DescribeNatGateways(request *ec2.DescribeNatGatewaysInput) (*e
 						match = true
 					}
 				}
+			case "subnet-id":
+				for _, v := range filter.Values {
+					if aws.StringValue(ngw.SubnetId) == *v {
+						match = true
+					}
+				}
+			case "vpc-id":
+				for _, v := range filter.Values {
+					if aws.StringValue(ngw.VpcId) == *v {
+						match = true
+					}
+				}
 			default:
 				if strings.HasPrefix(*filter.Name, "tag:") {
 					match = m.hasTag(ec2.ResourceTypeNatgateway, *ngw.NatGatewayId, filter)
@@ -171,8 +187,16 @@ func (m *MockEC2) DescribeNatGatewaysRequest(*ec2.DescribeNatGatewaysInput) (*re
 	panic("Not implemented")
 }
 
-func (m *MockEC2) DescribeNatGatewaysPages(*ec2.DescribeNatGatewaysInput, func(*ec2.DescribeNatGatewaysOutput, bool) bool) error {
-	panic("Not implemented")
+func (m *MockEC2) DescribeNatGatewaysPages(request *ec2.DescribeNatGatewaysInput, callback func(*ec2.DescribeNatGatewaysOutput, bool) bool) error {
+	// For the mock, we just send everything in one page
+	page, err := m.DescribeNatGateways(request)
+	if err != nil {
+		return err
+	}
+
+	callback(page, false)
+
+	return nil
 }
 
 func (m *MockEC2) DescribeNatGatewaysPagesWithContext(aws.Context, *ec2.DescribeNatGatewaysInput, func(*ec2.DescribeNatGatewaysOutput, bool) bool, ...request.Option) error {
@@ -188,7 +212,7 @@ func (m *MockEC2) DeleteNatGateway(request *ec2.DeleteNatGatewayInput) (*ec2.Del
 	id := aws.StringValue(request.NatGatewayId)
 	o := m.NatGateways[id]
 	if o == nil {
-		return nil, fmt.Errorf("NatGateway %q not found", id)
+		return nil, awserr.New("NatGatewayNotFound", fmt.Sprintf("NatGateway %q not found", id), nil)
 	}
 	delete(m.NatGateways, id)
 
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockec2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+)
+
+func (m *MockEC2) AddManagedPrefixList(prefixList *ec2.ManagedPrefixList) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.ManagedPrefixLists == nil {
+		m.ManagedPrefixLists = make(map[string]*ec2.ManagedPrefixList)
+	}
+
+	m.addTags(*prefixList.PrefixListId, prefixList.Tags...)
+
+	m.ManagedPrefixLists[*prefixList.PrefixListId] = prefixList
+}
+
+func (m *MockEC2) DescribeManagedPrefixLists(request *ec2.DescribeManagedPrefixListsInput) (*ec2.DescribeManagedPrefixListsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeManagedPrefixLists: %v", request)
+
+	if len(request.PrefixListIds) != 0 {
+		request.Filters = append(request.Filters, &ec2.Filter{Name: s("prefix-list-id"), Values: request.PrefixListIds})
+	}
+
+	var prefixLists []*ec2.ManagedPrefixList
+	for id, prefixList := range m.ManagedPrefixLists {
+		allFiltersMatch := true
+		for _, filter := range request.Filters {
+			match := false
+			switch *filter.Name {
+			case "prefix-list-id":
+				for _, v := range filter.Values {
+					if id == *v {
+						match = true
+					}
+				}
+			case "prefix-list-name":
+				for _, v := range filter.Values {
+					if aws.StringValue(prefixList.PrefixListName) == *v {
+						match = true
+					}
+				}
+			default:
+				if strings.HasPrefix(*filter.Name, "tag:") || *filter.Name == "tag-key" {
+					match = m.hasTag(ec2.ResourceTypePrefixList, id, filter)
+				} else {
+					return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+				}
+			}
+
+			if !match {
+				allFiltersMatch = false
+				break
+			}
+		}
+
+		if !allFiltersMatch {
+			continue
+		}
+
+		copy := *prefixList
+		copy.Tags = m.getTags(ec2.ResourceTypePrefixList, id)
+		prefixLists = append(prefixLists, &copy)
+	}
+
+	response := &ec2.DescribeManagedPrefixListsOutput{
+		PrefixLists: prefixLists,
+	}
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeManagedPrefixListsPages(request *ec2.DescribeManagedPrefixListsInput, callback func(*ec2.DescribeManagedPrefixListsOutput, bool) bool) error {
+	// For the mock, we just send everything in one page
+	page, err := m.DescribeManagedPrefixLists(request)
+	if err != nil {
+		return err
+	}
+
+	callback(page, false)
+
+	return nil
+}
+
+func (m *MockEC2) DeleteManagedPrefixList(request *ec2.DeleteManagedPrefixListInput) (*ec2.DeleteManagedPrefixListOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteManagedPrefixList: %v", request)
+
+	id := aws.StringValue(request.PrefixListId)
+	prefixList := m.ManagedPrefixLists[id]
+	if prefixList == nil {
+		return nil, awserr.New("InvalidPrefixListID.NotFound", fmt.Sprintf("The prefix list ID '%s' does not exist", id), nil)
+	}
+	for _, sg := range m.SecurityGroups {
+		if permissionsReferencePrefixList(sg.IpPermissions, id) || permissionsReferencePrefixList(sg.IpPermissionsEgress, id) {
+			return nil, awserr.New("DependencyViolation", fmt.Sprintf("The prefix list '%s' is referenced by '%s'", id, aws.StringValue(sg.GroupId)), nil)
+		}
+	}
+
+	delete(m.ManagedPrefixLists, id)
+
+	copy := *prefixList
+	copy.State = aws.String(ec2.PrefixListStateDeleteInProgress)
+	return &ec2.DeleteManagedPrefixListOutput{PrefixList: &copy}, nil
+}
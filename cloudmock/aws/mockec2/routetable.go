@@ -18,8 +18,11 @@ package mockec2
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
@@ -56,14 +59,15 @@ func (m *MockEC2) DescribeRouteTables(request *ec2.DescribeRouteTablesInput) (*e
 		klog.Fatalf("DryRun not implemented")
 	}
 
+	filters := request.Filters
 	if len(request.RouteTableIds) != 0 {
-		request.Filters = append(request.Filters, &ec2.Filter{Name: s("route-table-id"), Values: request.RouteTableIds})
+		filters = append(filters, &ec2.Filter{Name: s("route-table-id"), Values: request.RouteTableIds})
 	}
 
-	response := &ec2.DescribeRouteTablesOutput{}
+	var routeTables []*ec2.RouteTable
 	for _, rt := range m.RouteTables {
 		allFiltersMatch := true
-		for _, filter := range request.Filters {
+		for _, filter := range filters {
 			match := false
 			switch *filter.Name {
 			case "route-table-id":
@@ -72,6 +76,12 @@ func (m *MockEC2) DescribeRouteTables(request *ec2.DescribeRouteTablesInput) (*e
 						match = true
 					}
 				}
+			case "vpc-id":
+				for _, v := range filter.Values {
+					if aws.StringValue(rt.VpcId) == *v {
+						match = true
+					}
+				}
 			case "association.subnet-id":
 				for _, a := range rt.Associations {
 					for _, v := range filter.Values {
@@ -96,12 +106,63 @@ func (m *MockEC2) DescribeRouteTables(request *ec2.DescribeRouteTablesInput) (*e
 
 		copy := *rt
 		copy.Tags = m.getTags(ec2.ResourceTypeRouteTable, *rt.RouteTableId)
-		response.RouteTables = append(response.RouteTables, &copy)
+		routeTables = append(routeTables, &copy)
 	}
 
+	// As in AWS, results are returned in pages; the NextToken is the index of the first result of the next page
+	sort.Slice(routeTables, func(i, j int) bool {
+		return aws.StringValue(routeTables[i].RouteTableId) < aws.StringValue(routeTables[j].RouteTableId)
+	})
+	start := 0
+	if request.NextToken != nil {
+		var err error
+		start, err = strconv.Atoi(*request.NextToken)
+		if err != nil || start < 0 || start > len(routeTables) {
+			return nil, fmt.Errorf("invalid NextToken %q", *request.NextToken)
+		}
+	}
+	pageSize := describeRouteTablesPageSize
+	if request.MaxResults != nil && int(*request.MaxResults) < pageSize {
+		pageSize = int(*request.MaxResults)
+	}
+	end := start + pageSize
+	response := &ec2.DescribeRouteTablesOutput{}
+	if end < len(routeTables) {
+		response.NextToken = aws.String(strconv.Itoa(end))
+	} else {
+		end = len(routeTables)
+	}
+	response.RouteTables = routeTables[start:end]
+
 	return response, nil
 }
 
+// describeRouteTablesPageSize is the maximum number of route tables that DescribeRouteTables returns in one page
+const describeRouteTablesPageSize = 100
+
+func (m *MockEC2) DescribeRouteTablesPages(request *ec2.DescribeRouteTablesInput, callback func(*ec2.DescribeRouteTablesOutput, bool) bool) error {
+	return m.DescribeRouteTablesPagesWithContext(aws.BackgroundContext(), request, callback)
+}
+
+func (m *MockEC2) DescribeRouteTablesPagesWithContext(ctx aws.Context, request *ec2.DescribeRouteTablesInput, callback func(*ec2.DescribeRouteTablesOutput, bool) bool, options ...request.Option) error {
+	pageRequest := *request
+	for {
+		// As in AWS, no more pages are requested once the context is done
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := m.DescribeRouteTables(&pageRequest)
+		if err != nil {
+			return err
+		}
+		lastPage := page.NextToken == nil
+		if !callback(page, lastPage) || lastPage {
+			return nil
+		}
+		pageRequest.NextToken = page.NextToken
+	}
+}
+
 func (m *MockEC2) CreateRouteTable(request *ec2.CreateRouteTableInput) (*ec2.CreateRouteTableOutput, error) {
 	klog.Infof("CreateRouteTable: %v", request)
 
@@ -190,7 +251,22 @@ func (m *MockEC2) DeleteRouteTable(request *ec2.DeleteRouteTableInput) (*ec2.Del
 	id := aws.StringValue(request.RouteTableId)
 	o := m.RouteTables[id]
 	if o == nil {
-		return nil, fmt.Errorf("RouteTable %q not found", id)
+		return nil, awserr.New("InvalidRouteTableID.NotFound", fmt.Sprintf("RouteTable %q not found", id), nil)
+	}
+	for _, a := range o.Associations {
+		if !aws.BoolValue(a.Main) {
+			return nil, awserr.New("DependencyViolation", fmt.Sprintf("RouteTable %q has dependencies and cannot be deleted", id), nil)
+		}
+	}
+	if len(o.PropagatingVgws) != 0 {
+		return nil, awserr.New("DependencyViolation", fmt.Sprintf("RouteTable %q has route propagations and cannot be deleted", id), nil)
+	}
+	for _, endpoint := range m.VpcEndpoints {
+		for _, routeTableID := range endpoint.RouteTableIds {
+			if aws.StringValue(routeTableID) == id {
+				return nil, awserr.New("DependencyViolation", fmt.Sprintf("RouteTable %q is used by VpcEndpoint %q and cannot be deleted", id, aws.StringValue(endpoint.VpcEndpointId)), nil)
+			}
+		}
 	}
 	delete(m.RouteTables, id)
 
@@ -204,3 +280,74 @@ func (m *MockEC2) DeleteRouteTableWithContext(aws.Context, *ec2.DeleteRouteTable
 func (m *MockEC2) DeleteRouteTableRequest(*ec2.DeleteRouteTableInput) (*request.Request, *ec2.DeleteRouteTableOutput) {
 	panic("Not implemented")
 }
+
+func (m *MockEC2) DeleteRoute(request *ec2.DeleteRouteInput) (*ec2.DeleteRouteOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteRoute: %v", request)
+
+	id := aws.StringValue(request.RouteTableId)
+	rt := m.RouteTables[id]
+	if rt == nil {
+		return nil, awserr.New("InvalidRouteTableID.NotFound", fmt.Sprintf("RouteTable %q not found", id), nil)
+	}
+
+	var routes []*ec2.Route
+	found := false
+	for _, r := range rt.Routes {
+		if aws.StringValue(r.DestinationCidrBlock) == aws.StringValue(request.DestinationCidrBlock) &&
+			aws.StringValue(r.DestinationIpv6CidrBlock) == aws.StringValue(request.DestinationIpv6CidrBlock) &&
+			aws.StringValue(r.DestinationPrefixListId) == aws.StringValue(request.DestinationPrefixListId) {
+			found = true
+			continue
+		}
+		routes = append(routes, r)
+	}
+	if !found {
+		return nil, awserr.New("InvalidRoute.NotFound", fmt.Sprintf("no matching route in RouteTable %q", id), nil)
+	}
+	rt.Routes = routes
+
+	return &ec2.DeleteRouteOutput{}, nil
+}
+
+func (m *MockEC2) DeleteRouteWithContext(aws.Context, *ec2.DeleteRouteInput, ...request.Option) (*ec2.DeleteRouteOutput, error) {
+	panic("Not implemented")
+}
+
+func (m *MockEC2) DeleteRouteRequest(*ec2.DeleteRouteInput) (*request.Request, *ec2.DeleteRouteOutput) {
+	panic("Not implemented")
+}
+
+func (m *MockEC2) DisableVgwRoutePropagation(request *ec2.DisableVgwRoutePropagationInput) (*ec2.DisableVgwRoutePropagationOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DisableVgwRoutePropagation: %v", request)
+
+	id := aws.StringValue(request.RouteTableId)
+	rt := m.RouteTables[id]
+	if rt == nil {
+		return nil, awserr.New("InvalidRouteTableID.NotFound", fmt.Sprintf("RouteTable %q not found", id), nil)
+	}
+
+	var propagatingVgws []*ec2.PropagatingVgw
+	for _, p := range rt.PropagatingVgws {
+		if aws.StringValue(p.GatewayId) == aws.StringValue(request.GatewayId) {
+			continue
+		}
+		propagatingVgws = append(propagatingVgws, p)
+	}
+	rt.PropagatingVgws = propagatingVgws
+
+	return &ec2.DisableVgwRoutePropagationOutput{}, nil
+}
+
+func (m *MockEC2) DisableVgwRoutePropagationWithContext(aws.Context, *ec2.DisableVgwRoutePropagationInput, ...request.Option) (*ec2.DisableVgwRoutePropagationOutput, error) {
+	panic("Not implemented")
+}
+
+func (m *MockEC2) DisableVgwRoutePropagationRequest(*ec2.DisableVgwRoutePropagationInput) (*request.Request, *ec2.DisableVgwRoutePropagationOutput) {
+	panic("Not implemented")
+}
@@ -18,13 +18,28 @@ package mockec2
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
 )
 
+func (m *MockEC2) AddSecurityGroup(sg *ec2.SecurityGroup) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.SecurityGroups == nil {
+		m.SecurityGroups = make(map[string]*ec2.SecurityGroup)
+	}
+
+	m.addTags(*sg.GroupId, sg.Tags...)
+
+	m.SecurityGroups[*sg.GroupId] = sg
+}
+
 func (m *MockEC2) CreateSecurityGroupRequest(*ec2.CreateSecurityGroupInput) (*request.Request, *ec2.CreateSecurityGroupOutput) {
 	panic("MockEC2 CreateSecurityGroupRequest not implemented")
 }
@@ -81,7 +96,12 @@ func (m *MockEC2) DeleteSecurityGroup(request *ec2.DeleteSecurityGroupInput) (*e
 	id := aws.StringValue(request.GroupId)
 	o := m.SecurityGroups[id]
 	if o == nil {
-		return nil, fmt.Errorf("SecurityGroup %q not found", id)
+		return nil, awserr.New("InvalidGroup.NotFound", fmt.Sprintf("SecurityGroup %q not found", id), nil)
+	}
+	for otherID, sg := range m.SecurityGroups {
+		if otherID != id && securityGroupReferences(sg, id) {
+			return nil, awserr.New("DependencyViolation", fmt.Sprintf("resource %s has a dependent object", id), nil)
+		}
 	}
 	delete(m.SecurityGroups, id)
 
@@ -96,8 +116,83 @@ func (m *MockEC2) DescribeSecurityGroupReferencesWithContext(aws.Context, *ec2.D
 	panic("Not implemented")
 }
 
-func (m *MockEC2) DescribeSecurityGroupReferences(*ec2.DescribeSecurityGroupReferencesInput) (*ec2.DescribeSecurityGroupReferencesOutput, error) {
-	panic("Not implemented")
+// DescribeSecurityGroupReferences reports the VPCs with security groups whose rules reference the requested groups.
+// Unlike AWS, the mock doesn't require the VPCs to be peered.
+func (m *MockEC2) DescribeSecurityGroupReferences(request *ec2.DescribeSecurityGroupReferencesInput) (*ec2.DescribeSecurityGroupReferencesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeSecurityGroupReferences: %v", request)
+
+	response := &ec2.DescribeSecurityGroupReferencesOutput{}
+	for _, groupID := range request.GroupId {
+		target := m.SecurityGroups[aws.StringValue(groupID)]
+		if target == nil {
+			return nil, fmt.Errorf("SecurityGroup %q not found", aws.StringValue(groupID))
+		}
+
+		referencingVPCs := make(map[string]bool)
+		for _, sg := range m.SecurityGroups {
+			if aws.StringValue(sg.VpcId) == aws.StringValue(target.VpcId) {
+				continue
+			}
+			if securityGroupReferences(sg, aws.StringValue(groupID)) {
+				referencingVPCs[aws.StringValue(sg.VpcId)] = true
+			}
+		}
+		for vpcID := range referencingVPCs {
+			response.SecurityGroupReferenceSet = append(response.SecurityGroupReferenceSet, &ec2.SecurityGroupReference{
+				GroupId:          groupID,
+				ReferencingVpcId: aws.String(vpcID),
+			})
+		}
+	}
+
+	return response, nil
+}
+
+// securityGroupReferences returns true if any ingress or egress rule of sg references the group
+func securityGroupReferences(sg *ec2.SecurityGroup, groupID string) bool {
+	for _, permissions := range [][]*ec2.IpPermission{sg.IpPermissions, sg.IpPermissionsEgress} {
+		for _, permission := range permissions {
+			for _, pair := range permission.UserIdGroupPairs {
+				if aws.StringValue(pair.GroupId) == groupID {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// revokePermissions returns the rules that are not among the revoked rules
+func revokePermissions(permissions []*ec2.IpPermission, revoked []*ec2.IpPermission) []*ec2.IpPermission {
+	var kept []*ec2.IpPermission
+	for _, permission := range permissions {
+		found := false
+		for _, r := range revoked {
+			if reflect.DeepEqual(permission, r) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			kept = append(kept, permission)
+		}
+	}
+	return kept
+}
+
+// permissionsReferencePrefixList returns true if any of the rules references the managed prefix list
+func permissionsReferencePrefixList(permissions []*ec2.IpPermission, prefixListID string) bool {
+	for _, permission := range permissions {
+		for _, prefixList := range permission.PrefixListIds {
+			if aws.StringValue(prefixList.PrefixListId) == prefixListID {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (m *MockEC2) DescribeSecurityGroupsRequest(*ec2.DescribeSecurityGroupsInput) (*request.Request, *ec2.DescribeSecurityGroupsOutput) {
@@ -144,6 +239,38 @@ func (m *MockEC2) DescribeSecurityGroups(request *ec2.DescribeSecurityGroupsInpu
 						match = true
 					}
 				}
+			case "ip-permission.group-id":
+				for _, v := range filter.Values {
+					for _, permission := range sg.IpPermissions {
+						for _, pair := range permission.UserIdGroupPairs {
+							if aws.StringValue(pair.GroupId) == *v {
+								match = true
+							}
+						}
+					}
+				}
+			case "egress.ip-permission.group-id":
+				for _, v := range filter.Values {
+					for _, permission := range sg.IpPermissionsEgress {
+						for _, pair := range permission.UserIdGroupPairs {
+							if aws.StringValue(pair.GroupId) == *v {
+								match = true
+							}
+						}
+					}
+				}
+			case "ip-permission.prefix-list-id":
+				for _, v := range filter.Values {
+					if permissionsReferencePrefixList(sg.IpPermissions, *v) {
+						match = true
+					}
+				}
+			case "egress.ip-permission.prefix-list-id":
+				for _, v := range filter.Values {
+					if permissionsReferencePrefixList(sg.IpPermissionsEgress, *v) {
+						match = true
+					}
+				}
 
 			default:
 				match = m.hasTag(ec2.ResourceTypeSecurityGroup, *sg.GroupId, filter)
@@ -191,8 +318,29 @@ func (m *MockEC2) RevokeSecurityGroupEgressWithContext(aws.Context, *ec2.RevokeS
 	panic("Not implemented")
 }
 
-func (m *MockEC2) RevokeSecurityGroupEgress(*ec2.RevokeSecurityGroupEgressInput) (*ec2.RevokeSecurityGroupEgressOutput, error) {
-	panic("Not implemented")
+func (m *MockEC2) RevokeSecurityGroupEgress(request *ec2.RevokeSecurityGroupEgressInput) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("RevokeSecurityGroupEgress: %v", request)
+
+	if aws.StringValue(request.GroupId) == "" {
+		return nil, fmt.Errorf("GroupId not specified")
+	}
+
+	if request.DryRun != nil {
+		klog.Fatalf("DryRun")
+	}
+
+	sg := m.SecurityGroups[*request.GroupId]
+	if sg == nil {
+		return nil, fmt.Errorf("SecurityGroup not found")
+	}
+
+	sg.IpPermissionsEgress = revokePermissions(sg.IpPermissionsEgress, request.IpPermissions)
+
+	response := &ec2.RevokeSecurityGroupEgressOutput{}
+	return response, nil
 }
 
 func (m *MockEC2) RevokeSecurityGroupIngressRequest(*ec2.RevokeSecurityGroupIngressInput) (*request.Request, *ec2.RevokeSecurityGroupIngressOutput) {
@@ -225,7 +373,7 @@ func (m *MockEC2) RevokeSecurityGroupIngress(request *ec2.RevokeSecurityGroupIng
 		return nil, fmt.Errorf("SecurityGroup not found")
 	}
 
-	klog.Warningf("RevokeSecurityGroupIngress mock not implemented - does not actually revoke permissions")
+	sg.IpPermissions = revokePermissions(sg.IpPermissions, request.IpPermissions)
 
 	response := &ec2.RevokeSecurityGroupIngressOutput{}
 	return response, nil
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockec2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+)
+
+func (m *MockEC2) AddSnapshot(snapshot *ec2.Snapshot) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.Snapshots == nil {
+		m.Snapshots = make(map[string]*ec2.Snapshot)
+	}
+
+	m.addTags(*snapshot.SnapshotId, snapshot.Tags...)
+
+	m.Snapshots[*snapshot.SnapshotId] = snapshot
+}
+
+func (m *MockEC2) DescribeSnapshots(request *ec2.DescribeSnapshotsInput) (*ec2.DescribeSnapshotsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeSnapshots: %v", request)
+
+	if len(request.SnapshotIds) != 0 {
+		request.Filters = append(request.Filters, &ec2.Filter{Name: s("snapshot-id"), Values: request.SnapshotIds})
+	}
+
+	var snapshots []*ec2.Snapshot
+	for id, snapshot := range m.Snapshots {
+		allFiltersMatch := true
+		for _, filter := range request.Filters {
+			match := false
+			switch *filter.Name {
+			case "snapshot-id":
+				for _, v := range filter.Values {
+					if id == *v {
+						match = true
+					}
+				}
+			case "volume-id":
+				for _, v := range filter.Values {
+					if aws.StringValue(snapshot.VolumeId) == *v {
+						match = true
+					}
+				}
+			default:
+				if strings.HasPrefix(*filter.Name, "tag:") || *filter.Name == "tag-key" {
+					match = m.hasTag(ec2.ResourceTypeSnapshot, id, filter)
+				} else {
+					return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+				}
+			}
+
+			if !match {
+				allFiltersMatch = false
+				break
+			}
+		}
+
+		if !allFiltersMatch {
+			continue
+		}
+
+		copy := *snapshot
+		copy.Tags = m.getTags(ec2.ResourceTypeSnapshot, id)
+		snapshots = append(snapshots, &copy)
+	}
+
+	response := &ec2.DescribeSnapshotsOutput{
+		Snapshots: snapshots,
+	}
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeSnapshotsPages(request *ec2.DescribeSnapshotsInput, callback func(*ec2.DescribeSnapshotsOutput, bool) bool) error {
+	// For the mock, we just send everything in one page
+	page, err := m.DescribeSnapshots(request)
+	if err != nil {
+		return err
+	}
+
+	callback(page, false)
+
+	return nil
+}
+
+func (m *MockEC2) DeleteSnapshot(request *ec2.DeleteSnapshotInput) (*ec2.DeleteSnapshotOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteSnapshot: %v", request)
+
+	id := aws.StringValue(request.SnapshotId)
+	if m.Snapshots[id] == nil {
+		return nil, awserr.New("InvalidSnapshot.NotFound", fmt.Sprintf("The snapshot '%s' does not exist", id), nil)
+	}
+	for _, image := range m.Images {
+		for _, mapping := range image.BlockDeviceMappings {
+			if mapping.Ebs != nil && aws.StringValue(mapping.Ebs.SnapshotId) == id {
+				return nil, awserr.New("InvalidSnapshot.InUse", fmt.Sprintf("The snapshot %s is currently in use by %s", id, aws.StringValue(image.ImageId)), nil)
+			}
+		}
+	}
+
+	delete(m.Snapshots, id)
+
+	return &ec2.DeleteSnapshotOutput{}, nil
+}
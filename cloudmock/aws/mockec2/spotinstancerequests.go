@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockec2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+)
+
+func (m *MockEC2) AddSpotInstanceRequest(spotRequest *ec2.SpotInstanceRequest) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.SpotInstanceRequests == nil {
+		m.SpotInstanceRequests = make(map[string]*ec2.SpotInstanceRequest)
+	}
+
+	m.addTags(*spotRequest.SpotInstanceRequestId, spotRequest.Tags...)
+
+	m.SpotInstanceRequests[*spotRequest.SpotInstanceRequestId] = spotRequest
+}
+
+func (m *MockEC2) DescribeSpotInstanceRequests(request *ec2.DescribeSpotInstanceRequestsInput) (*ec2.DescribeSpotInstanceRequestsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeSpotInstanceRequests: %v", request)
+
+	if len(request.SpotInstanceRequestIds) != 0 {
+		request.Filters = append(request.Filters, &ec2.Filter{Name: s("spot-instance-request-id"), Values: request.SpotInstanceRequestIds})
+	}
+
+	var spotRequests []*ec2.SpotInstanceRequest
+	for id, spotRequest := range m.SpotInstanceRequests {
+		allFiltersMatch := true
+		for _, filter := range request.Filters {
+			match := false
+			switch *filter.Name {
+			case "spot-instance-request-id":
+				for _, v := range filter.Values {
+					if id == *v {
+						match = true
+					}
+				}
+			case "state":
+				for _, v := range filter.Values {
+					if aws.StringValue(spotRequest.State) == *v {
+						match = true
+					}
+				}
+			default:
+				if strings.HasPrefix(*filter.Name, "tag:") || *filter.Name == "tag-key" {
+					match = m.hasTag(ec2.ResourceTypeSpotInstancesRequest, id, filter)
+				} else {
+					return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+				}
+			}
+
+			if !match {
+				allFiltersMatch = false
+				break
+			}
+		}
+
+		if !allFiltersMatch {
+			continue
+		}
+
+		copy := *spotRequest
+		copy.Tags = m.getTags(ec2.ResourceTypeSpotInstancesRequest, id)
+		spotRequests = append(spotRequests, &copy)
+	}
+
+	response := &ec2.DescribeSpotInstanceRequestsOutput{
+		SpotInstanceRequests: spotRequests,
+	}
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeSpotInstanceRequestsPages(request *ec2.DescribeSpotInstanceRequestsInput, callback func(*ec2.DescribeSpotInstanceRequestsOutput, bool) bool) error {
+	// For the mock, we just send everything in one page
+	page, err := m.DescribeSpotInstanceRequests(request)
+	if err != nil {
+		return err
+	}
+
+	callback(page, false)
+
+	return nil
+}
+
+func (m *MockEC2) CancelSpotInstanceRequests(request *ec2.CancelSpotInstanceRequestsInput) (*ec2.CancelSpotInstanceRequestsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("CancelSpotInstanceRequests: %v", request)
+
+	response := &ec2.CancelSpotInstanceRequestsOutput{}
+	for _, id := range aws.StringValueSlice(request.SpotInstanceRequestIds) {
+		spotRequest := m.SpotInstanceRequests[id]
+		if spotRequest == nil {
+			return nil, awserr.New("InvalidSpotInstanceRequestID.NotFound", fmt.Sprintf("The spot instance request ID '%s' does not exist", id), nil)
+		}
+		// Cancelling the request doesn't terminate the instance it launched
+		spotRequest.State = aws.String(ec2.SpotInstanceStateCancelled)
+		response.CancelledSpotInstanceRequests = append(response.CancelledSpotInstanceRequests, &ec2.CancelledSpotInstanceRequest{
+			SpotInstanceRequestId: aws.String(id),
+			State:                 aws.String(ec2.CancelSpotInstanceRequestStateCancelled),
+		})
+	}
+
+	return response, nil
+}
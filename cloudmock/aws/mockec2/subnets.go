@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
@@ -55,6 +56,22 @@ func (m *MockEC2) SubnetIds() []string {
 	return ids
 }
 
+// AddSubnet adds a subnet as is, e.g. with an OwnerId
+func (m *MockEC2) AddSubnet(subnet *ec2.Subnet) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.subnets == nil {
+		m.subnets = make(map[string]*subnetInfo)
+	}
+
+	m.addTags(*subnet.SubnetId, subnet.Tags...)
+
+	m.subnets[*subnet.SubnetId] = &subnetInfo{
+		main: *subnet,
+	}
+}
+
 func (m *MockEC2) CreateSubnetRequest(*ec2.CreateSubnetInput) (*request.Request, *ec2.CreateSubnetOutput) {
 	panic("Not implemented")
 }
@@ -231,6 +248,44 @@ func (m *MockEC2) AssociateRouteTableRequest(*ec2.AssociateRouteTableInput) (*re
 	panic("Not implemented")
 }
 
+func (m *MockEC2) DisassociateRouteTable(request *ec2.DisassociateRouteTableInput) (*ec2.DisassociateRouteTableOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DisassociateRouteTable: %v", request)
+
+	if request.DryRun != nil {
+		klog.Fatalf("DryRun")
+	}
+
+	id := aws.StringValue(request.AssociationId)
+	for _, rt := range m.RouteTables {
+		var associations []*ec2.RouteTableAssociation
+		found := false
+		for _, a := range rt.Associations {
+			if aws.StringValue(a.RouteTableAssociationId) == id {
+				found = true
+				continue
+			}
+			associations = append(associations, a)
+		}
+		if found {
+			rt.Associations = associations
+			return &ec2.DisassociateRouteTableOutput{}, nil
+		}
+	}
+
+	return nil, awserr.New("InvalidAssociationID.NotFound", fmt.Sprintf("RouteTableAssociation %q not found", id), nil)
+}
+
+func (m *MockEC2) DisassociateRouteTableWithContext(aws.Context, *ec2.DisassociateRouteTableInput, ...request.Option) (*ec2.DisassociateRouteTableOutput, error) {
+	panic("Not implemented")
+}
+
+func (m *MockEC2) DisassociateRouteTableRequest(*ec2.DisassociateRouteTableInput) (*request.Request, *ec2.DisassociateRouteTableOutput) {
+	panic("Not implemented")
+}
+
 func (m *MockEC2) DeleteSubnet(request *ec2.DeleteSubnetInput) (*ec2.DeleteSubnetOutput, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -240,7 +295,12 @@ func (m *MockEC2) DeleteSubnet(request *ec2.DeleteSubnetInput) (*ec2.DeleteSubne
 	id := aws.StringValue(request.SubnetId)
 	o := m.subnets[id]
 	if o == nil {
-		return nil, fmt.Errorf("Subnet %q not found", id)
+		return nil, awserr.New("InvalidSubnetID.NotFound", fmt.Sprintf("Subnet %q not found", id), nil)
+	}
+	for _, ngw := range m.NatGateways {
+		if aws.StringValue(ngw.SubnetId) == id {
+			return nil, awserr.New("DependencyViolation", fmt.Sprintf("The subnet '%s' has dependencies and cannot be deleted", id), nil)
+		}
 	}
 	delete(m.subnets, id)
 
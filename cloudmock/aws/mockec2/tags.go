@@ -75,10 +75,42 @@ func (m *MockEC2) addTags(resourceId string, tags ...*ec2.Tag) {
 		resourceType = ec2.ResourceTypeLaunchTemplate
 	} else if strings.HasPrefix(resourceId, "key-") {
 		resourceType = ec2.ResourceTypeKeyPair
+	} else if strings.HasPrefix(resourceId, "cvpn-endpoint-") {
+		resourceType = ec2.ResourceTypeClientVpnEndpoint
+	} else if strings.HasPrefix(resourceId, "eni-") {
+		resourceType = ec2.ResourceTypeNetworkInterface
+	} else if strings.HasPrefix(resourceId, "pl-") {
+		resourceType = ec2.ResourceTypePrefixList
+	} else if strings.HasPrefix(resourceId, "pcx-") {
+		resourceType = ec2.ResourceTypeVpcPeeringConnection
+	} else if strings.HasPrefix(resourceId, "vpce-") {
+		resourceType = ec2.ResourceTypeVpcEndpoint
+	} else if strings.HasPrefix(resourceId, "tgw-attach-") {
+		resourceType = ec2.ResourceTypeTransitGatewayAttachment
+	} else if strings.HasPrefix(resourceId, "tgw-") {
+		resourceType = ec2.ResourceTypeTransitGateway
+	} else if strings.HasPrefix(resourceId, "sir-") {
+		resourceType = ec2.ResourceTypeSpotInstancesRequest
+	} else if strings.HasPrefix(resourceId, "snap-") {
+		resourceType = ec2.ResourceTypeSnapshot
+	} else if strings.HasPrefix(resourceId, "i-") {
+		resourceType = ec2.ResourceTypeInstance
 	} else {
 		klog.Fatalf("Unknown resource-type in create tags: %v", resourceId)
 	}
 	for _, tag := range tags {
+		// As in AWS, tagging with an existing key overwrites its value
+		replaced := false
+		for _, existing := range m.Tags {
+			if *existing.ResourceId == resourceId && *existing.Key == *tag.Key {
+				existing.Value = tag.Value
+				replaced = true
+			}
+		}
+		if replaced {
+			continue
+		}
+
 		t := &ec2.TagDescription{
 			Key:          tag.Key,
 			Value:        tag.Value,
@@ -89,6 +121,51 @@ func (m *MockEC2) addTags(resourceId string, tags ...*ec2.Tag) {
 	}
 }
 
+func (m *MockEC2) DeleteTagsRequest(*ec2.DeleteTagsInput) (*request.Request, *ec2.DeleteTagsOutput) {
+	panic("Not implemented")
+}
+
+func (m *MockEC2) DeleteTagsWithContext(aws.Context, *ec2.DeleteTagsInput, ...request.Option) (*ec2.DeleteTagsOutput, error) {
+	panic("Not implemented")
+}
+
+func (m *MockEC2) DeleteTags(request *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteTags %v", request)
+
+	var tags []*ec2.TagDescription
+	for _, existing := range m.Tags {
+		if !deletesTag(request, existing) {
+			tags = append(tags, existing)
+		}
+	}
+	m.Tags = tags
+
+	response := &ec2.DeleteTagsOutput{}
+	return response, nil
+}
+
+// deletesTag returns true if the DeleteTags request removes the tag.
+// As in AWS, a tag with a value in the request is only removed if the value matches.
+func deletesTag(request *ec2.DeleteTagsInput, tag *ec2.TagDescription) bool {
+	for _, resourceId := range request.Resources {
+		if *resourceId != *tag.ResourceId {
+			continue
+		}
+		for _, t := range request.Tags {
+			if *t.Key != *tag.Key {
+				continue
+			}
+			if t.Value == nil || *t.Value == *tag.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (m *MockEC2) DescribeTagsRequest(*ec2.DescribeTagsInput) (*request.Request, *ec2.DescribeTagsOutput) {
 	panic("Not implemented")
 }
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockec2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+)
+
+func (m *MockEC2) AddTransitGateway(transitGateway *ec2.TransitGateway) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.TransitGateways == nil {
+		m.TransitGateways = make(map[string]*ec2.TransitGateway)
+	}
+
+	m.addTags(*transitGateway.TransitGatewayId, transitGateway.Tags...)
+
+	m.TransitGateways[*transitGateway.TransitGatewayId] = transitGateway
+}
+
+func (m *MockEC2) AddTransitGatewayVpcAttachment(attachment *ec2.TransitGatewayVpcAttachment) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.TransitGatewayVpcAttachments == nil {
+		m.TransitGatewayVpcAttachments = make(map[string]*ec2.TransitGatewayVpcAttachment)
+	}
+
+	m.addTags(*attachment.TransitGatewayAttachmentId, attachment.Tags...)
+
+	m.TransitGatewayVpcAttachments[*attachment.TransitGatewayAttachmentId] = attachment
+}
+
+func (m *MockEC2) DescribeTransitGatewayVpcAttachments(request *ec2.DescribeTransitGatewayVpcAttachmentsInput) (*ec2.DescribeTransitGatewayVpcAttachmentsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeTransitGatewayVpcAttachments: %v", request)
+
+	if len(request.TransitGatewayAttachmentIds) != 0 {
+		request.Filters = append(request.Filters, &ec2.Filter{Name: s("transit-gateway-attachment-id"), Values: request.TransitGatewayAttachmentIds})
+	}
+
+	var attachments []*ec2.TransitGatewayVpcAttachment
+	for id, attachment := range m.TransitGatewayVpcAttachments {
+		allFiltersMatch := true
+		for _, filter := range request.Filters {
+			match := false
+			switch *filter.Name {
+			case "transit-gateway-attachment-id":
+				for _, v := range filter.Values {
+					if id == *v {
+						match = true
+					}
+				}
+			case "transit-gateway-id":
+				for _, v := range filter.Values {
+					if aws.StringValue(attachment.TransitGatewayId) == *v {
+						match = true
+					}
+				}
+			case "vpc-id":
+				for _, v := range filter.Values {
+					if aws.StringValue(attachment.VpcId) == *v {
+						match = true
+					}
+				}
+			default:
+				if strings.HasPrefix(*filter.Name, "tag:") || *filter.Name == "tag-key" {
+					match = m.hasTag(ec2.ResourceTypeTransitGatewayAttachment, id, filter)
+				} else {
+					return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+				}
+			}
+
+			if !match {
+				allFiltersMatch = false
+				break
+			}
+		}
+
+		if !allFiltersMatch {
+			continue
+		}
+
+		copy := *attachment
+		copy.Tags = m.getTags(ec2.ResourceTypeTransitGatewayAttachment, id)
+		attachments = append(attachments, &copy)
+	}
+
+	response := &ec2.DescribeTransitGatewayVpcAttachmentsOutput{
+		TransitGatewayVpcAttachments: attachments,
+	}
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeTransitGatewayVpcAttachmentsPages(request *ec2.DescribeTransitGatewayVpcAttachmentsInput, callback func(*ec2.DescribeTransitGatewayVpcAttachmentsOutput, bool) bool) error {
+	// For the mock, we just send everything in one page
+	page, err := m.DescribeTransitGatewayVpcAttachments(request)
+	if err != nil {
+		return err
+	}
+
+	callback(page, false)
+
+	return nil
+}
+
+func (m *MockEC2) DeleteTransitGatewayVpcAttachment(request *ec2.DeleteTransitGatewayVpcAttachmentInput) (*ec2.DeleteTransitGatewayVpcAttachmentOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteTransitGatewayVpcAttachment: %v", request)
+
+	id := aws.StringValue(request.TransitGatewayAttachmentId)
+	attachment := m.TransitGatewayVpcAttachments[id]
+	if attachment == nil {
+		return nil, awserr.New("InvalidTransitGatewayAttachmentID.NotFound", fmt.Sprintf("The transitGatewayAttachment ID '%s' does not exist", id), nil)
+	}
+
+	delete(m.TransitGatewayVpcAttachments, id)
+
+	copy := *attachment
+	copy.State = aws.String(ec2.TransitGatewayAttachmentStateDeleting)
+	return &ec2.DeleteTransitGatewayVpcAttachmentOutput{TransitGatewayVpcAttachment: &copy}, nil
+}
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
@@ -45,6 +46,7 @@ func (m *MockEC2) CreateVolume(request *ec2.CreateVolumeInput) (*ec2.Volume, err
 		Encrypted:        request.Encrypted,
 		Iops:             request.Iops,
 		KmsKeyId:         request.KmsKeyId,
+		OutpostArn:       request.OutpostArn,
 		Size:             request.Size,
 		SnapshotId:       request.SnapshotId,
 		Throughput:       request.Throughput,
@@ -180,8 +182,11 @@ func (m *MockEC2) DescribeVolumesPages(request *ec2.DescribeVolumesInput, callba
 	return nil
 }
 
-func (m *MockEC2) DescribeVolumesPagesWithContext(aws.Context, *ec2.DescribeVolumesInput, func(*ec2.DescribeVolumesOutput, bool) bool, ...request.Option) error {
-	panic("Not implemented")
+func (m *MockEC2) DescribeVolumesPagesWithContext(ctx aws.Context, request *ec2.DescribeVolumesInput, callback func(*ec2.DescribeVolumesOutput, bool) bool, options ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.DescribeVolumesPages(request, callback)
 }
 
 func (m *MockEC2) DescribeVolumesModifications(*ec2.DescribeVolumesModificationsInput) (*ec2.DescribeVolumesModificationsOutput, error) {
@@ -205,7 +210,7 @@ func (m *MockEC2) DeleteVolume(request *ec2.DeleteVolumeInput) (*ec2.DeleteVolum
 	id := aws.StringValue(request.VolumeId)
 	o := m.Volumes[id]
 	if o == nil {
-		return nil, fmt.Errorf("Volume %q not found", id)
+		return nil, awserr.New("InvalidVolume.NotFound", fmt.Sprintf("Volume %q not found", id), nil)
 	}
 	delete(m.Volumes, id)
 
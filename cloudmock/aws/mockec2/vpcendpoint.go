@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockec2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+)
+
+func (m *MockEC2) AddVpcEndpoint(endpoint *ec2.VpcEndpoint) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.VpcEndpoints == nil {
+		m.VpcEndpoints = make(map[string]*ec2.VpcEndpoint)
+	}
+
+	m.addTags(*endpoint.VpcEndpointId, endpoint.Tags...)
+
+	m.VpcEndpoints[*endpoint.VpcEndpointId] = endpoint
+}
+
+func (m *MockEC2) DescribeVpcEndpoints(request *ec2.DescribeVpcEndpointsInput) (*ec2.DescribeVpcEndpointsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeVpcEndpoints: %v", request)
+
+	if len(request.VpcEndpointIds) != 0 {
+		request.Filters = append(request.Filters, &ec2.Filter{Name: s("vpc-endpoint-id"), Values: request.VpcEndpointIds})
+	}
+
+	var endpoints []*ec2.VpcEndpoint
+	for id, endpoint := range m.VpcEndpoints {
+		allFiltersMatch := true
+		for _, filter := range request.Filters {
+			match := false
+			switch *filter.Name {
+			case "vpc-endpoint-id":
+				for _, v := range filter.Values {
+					if id == *v {
+						match = true
+					}
+				}
+			case "vpc-id":
+				for _, v := range filter.Values {
+					if aws.StringValue(endpoint.VpcId) == *v {
+						match = true
+					}
+				}
+			case "vpc-endpoint-type":
+				for _, v := range filter.Values {
+					if aws.StringValue(endpoint.VpcEndpointType) == *v {
+						match = true
+					}
+				}
+			default:
+				if strings.HasPrefix(*filter.Name, "tag:") || *filter.Name == "tag-key" {
+					match = m.hasTag(ec2.ResourceTypeVpcEndpoint, id, filter)
+				} else {
+					return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+				}
+			}
+
+			if !match {
+				allFiltersMatch = false
+				break
+			}
+		}
+
+		if !allFiltersMatch {
+			continue
+		}
+
+		copy := *endpoint
+		copy.Tags = m.getTags(ec2.ResourceTypeVpcEndpoint, id)
+		endpoints = append(endpoints, &copy)
+	}
+
+	response := &ec2.DescribeVpcEndpointsOutput{
+		VpcEndpoints: endpoints,
+	}
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeVpcEndpointsPages(request *ec2.DescribeVpcEndpointsInput, callback func(*ec2.DescribeVpcEndpointsOutput, bool) bool) error {
+	// For the mock, we just send everything in one page
+	page, err := m.DescribeVpcEndpoints(request)
+	if err != nil {
+		return err
+	}
+
+	callback(page, false)
+
+	return nil
+}
+
+func (m *MockEC2) DeleteVpcEndpoints(request *ec2.DeleteVpcEndpointsInput) (*ec2.DeleteVpcEndpointsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteVpcEndpoints: %v", request)
+
+	// Like EC2, report the endpoints that can't be deleted individually
+	response := &ec2.DeleteVpcEndpointsOutput{}
+	for _, id := range aws.StringValueSlice(request.VpcEndpointIds) {
+		if m.VpcEndpoints[id] == nil {
+			response.Unsuccessful = append(response.Unsuccessful, &ec2.UnsuccessfulItem{
+				ResourceId: aws.String(id),
+				Error: &ec2.UnsuccessfulItemError{
+					Code:    aws.String("InvalidVpcEndpointId.NotFound"),
+					Message: aws.String(fmt.Sprintf("The vpcEndpoint ID '%s' does not exist", id)),
+				},
+			})
+			continue
+		}
+		delete(m.VpcEndpoints, id)
+	}
+
+	return response, nil
+}
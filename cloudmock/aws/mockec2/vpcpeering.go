@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockec2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+)
+
+func (m *MockEC2) AddVpcPeeringConnection(connection *ec2.VpcPeeringConnection) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.VpcPeeringConnections == nil {
+		m.VpcPeeringConnections = make(map[string]*ec2.VpcPeeringConnection)
+	}
+
+	m.addTags(*connection.VpcPeeringConnectionId, connection.Tags...)
+
+	m.VpcPeeringConnections[*connection.VpcPeeringConnectionId] = connection
+}
+
+func (m *MockEC2) DescribeVpcPeeringConnections(request *ec2.DescribeVpcPeeringConnectionsInput) (*ec2.DescribeVpcPeeringConnectionsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeVpcPeeringConnections: %v", request)
+
+	if len(request.VpcPeeringConnectionIds) != 0 {
+		request.Filters = append(request.Filters, &ec2.Filter{Name: s("vpc-peering-connection-id"), Values: request.VpcPeeringConnectionIds})
+	}
+
+	var connections []*ec2.VpcPeeringConnection
+	for id, connection := range m.VpcPeeringConnections {
+		allFiltersMatch := true
+		for _, filter := range request.Filters {
+			match := false
+			switch *filter.Name {
+			case "vpc-peering-connection-id":
+				for _, v := range filter.Values {
+					if id == *v {
+						match = true
+					}
+				}
+			case "requester-vpc-info.vpc-id":
+				for _, v := range filter.Values {
+					if connection.RequesterVpcInfo != nil && aws.StringValue(connection.RequesterVpcInfo.VpcId) == *v {
+						match = true
+					}
+				}
+			case "accepter-vpc-info.vpc-id":
+				for _, v := range filter.Values {
+					if connection.AccepterVpcInfo != nil && aws.StringValue(connection.AccepterVpcInfo.VpcId) == *v {
+						match = true
+					}
+				}
+			default:
+				if strings.HasPrefix(*filter.Name, "tag:") || *filter.Name == "tag-key" {
+					match = m.hasTag(ec2.ResourceTypeVpcPeeringConnection, id, filter)
+				} else {
+					return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+				}
+			}
+
+			if !match {
+				allFiltersMatch = false
+				break
+			}
+		}
+
+		if !allFiltersMatch {
+			continue
+		}
+
+		copy := *connection
+		copy.Tags = m.getTags(ec2.ResourceTypeVpcPeeringConnection, id)
+		connections = append(connections, &copy)
+	}
+
+	response := &ec2.DescribeVpcPeeringConnectionsOutput{
+		VpcPeeringConnections: connections,
+	}
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeVpcPeeringConnectionsPages(request *ec2.DescribeVpcPeeringConnectionsInput, callback func(*ec2.DescribeVpcPeeringConnectionsOutput, bool) bool) error {
+	// For the mock, we just send everything in one page
+	page, err := m.DescribeVpcPeeringConnections(request)
+	if err != nil {
+		return err
+	}
+
+	callback(page, false)
+
+	return nil
+}
+
+func (m *MockEC2) DeleteVpcPeeringConnection(request *ec2.DeleteVpcPeeringConnectionInput) (*ec2.DeleteVpcPeeringConnectionOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteVpcPeeringConnection: %v", request)
+
+	id := aws.StringValue(request.VpcPeeringConnectionId)
+	if m.VpcPeeringConnections[id] == nil {
+		return nil, awserr.New("InvalidVpcPeeringConnectionID.NotFound", fmt.Sprintf("The vpcPeeringConnection ID '%s' does not exist", id), nil)
+	}
+
+	delete(m.VpcPeeringConnections, id)
+
+	return &ec2.DeleteVpcPeeringConnectionOutput{Return: aws.Bool(true)}, nil
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
@@ -224,7 +225,7 @@ func (m *MockEC2) DeleteVpc(request *ec2.DeleteVpcInput) (*ec2.DeleteVpcOutput,
 	id := aws.StringValue(request.VpcId)
 	o := m.Vpcs[id]
 	if o == nil {
-		return nil, fmt.Errorf("VPC %q not found", id)
+		return nil, awserr.New("InvalidVpcID.NotFound", fmt.Sprintf("VPC %q not found", id), nil)
 	}
 	delete(m.Vpcs, id)
 
@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockefs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	efstypes "github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"k8s.io/klog/v2"
+	"k8s.io/kops/util/pkg/awsinterfaces"
+)
+
+type MockEFS struct {
+	awsinterfaces.EFSAPI
+	mutex sync.Mutex
+
+	// FileSystems are the file systems by id
+	FileSystems map[string]*efstypes.FileSystemDescription
+	// MountTargets are the mount targets by id
+	MountTargets map[string]*efstypes.MountTargetDescription
+	// SecurityGroupsByMountTarget are the security groups of the mount targets, by mount target id
+	SecurityGroupsByMountTarget map[string][]string
+}
+
+var _ awsinterfaces.EFSAPI = &MockEFS{}
+
+// MockCreateFileSystem adds a file system
+func (m *MockEFS) MockCreateFileSystem(fileSystem *efstypes.FileSystemDescription) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.FileSystems == nil {
+		m.FileSystems = make(map[string]*efstypes.FileSystemDescription)
+	}
+	m.FileSystems[aws.ToString(fileSystem.FileSystemId)] = fileSystem
+}
+
+// MockCreateMountTarget adds a mount target, with its security groups
+func (m *MockEFS) MockCreateMountTarget(mountTarget *efstypes.MountTargetDescription, securityGroups []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.MountTargets == nil {
+		m.MountTargets = make(map[string]*efstypes.MountTargetDescription)
+	}
+	if m.SecurityGroupsByMountTarget == nil {
+		m.SecurityGroupsByMountTarget = make(map[string][]string)
+	}
+	id := aws.ToString(mountTarget.MountTargetId)
+	m.MountTargets[id] = mountTarget
+	m.SecurityGroupsByMountTarget[id] = securityGroups
+}
+
+func fileSystemNotFound(id string) error {
+	return &efstypes.FileSystemNotFound{Message: aws.String(fmt.Sprintf("File system '%s' does not exist.", id))}
+}
+
+func mountTargetNotFound(id string) error {
+	return &efstypes.MountTargetNotFound{Message: aws.String(fmt.Sprintf("Mount target '%s' does not exist.", id))}
+}
+
+func (m *MockEFS) DescribeFileSystems(ctx context.Context, input *efs.DescribeFileSystemsInput, optFns ...func(*efs.Options)) (*efs.DescribeFileSystemsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeFileSystems: %v", input)
+
+	var ids []string
+	for id := range m.FileSystems {
+		if input.FileSystemId == nil || aws.ToString(input.FileSystemId) == id {
+			ids = append(ids, id)
+		}
+	}
+	if input.FileSystemId != nil && len(ids) == 0 {
+		return nil, fileSystemNotFound(aws.ToString(input.FileSystemId))
+	}
+	sort.Strings(ids)
+
+	response := &efs.DescribeFileSystemsOutput{}
+	for _, id := range ids {
+		response.FileSystems = append(response.FileSystems, *m.FileSystems[id])
+	}
+	return response, nil
+}
+
+func (m *MockEFS) DescribeMountTargets(ctx context.Context, input *efs.DescribeMountTargetsInput, optFns ...func(*efs.Options)) (*efs.DescribeMountTargetsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeMountTargets: %v", input)
+
+	fileSystemID := aws.ToString(input.FileSystemId)
+	if _, found := m.FileSystems[fileSystemID]; !found {
+		return nil, fileSystemNotFound(fileSystemID)
+	}
+
+	var ids []string
+	for id, mountTarget := range m.MountTargets {
+		if aws.ToString(mountTarget.FileSystemId) == fileSystemID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	response := &efs.DescribeMountTargetsOutput{}
+	for _, id := range ids {
+		response.MountTargets = append(response.MountTargets, *m.MountTargets[id])
+	}
+	return response, nil
+}
+
+func (m *MockEFS) DescribeMountTargetSecurityGroups(ctx context.Context, input *efs.DescribeMountTargetSecurityGroupsInput, optFns ...func(*efs.Options)) (*efs.DescribeMountTargetSecurityGroupsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeMountTargetSecurityGroups: %v", input)
+
+	id := aws.ToString(input.MountTargetId)
+	if _, found := m.MountTargets[id]; !found {
+		return nil, mountTargetNotFound(id)
+	}
+	return &efs.DescribeMountTargetSecurityGroupsOutput{SecurityGroups: m.SecurityGroupsByMountTarget[id]}, nil
+}
+
+func (m *MockEFS) DeleteMountTarget(ctx context.Context, input *efs.DeleteMountTargetInput, optFns ...func(*efs.Options)) (*efs.DeleteMountTargetOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteMountTarget: %v", input)
+
+	id := aws.ToString(input.MountTargetId)
+	if _, found := m.MountTargets[id]; !found {
+		return nil, mountTargetNotFound(id)
+	}
+	delete(m.MountTargets, id)
+	delete(m.SecurityGroupsByMountTarget, id)
+
+	return &efs.DeleteMountTargetOutput{}, nil
+}
+
+func (m *MockEFS) DeleteFileSystem(ctx context.Context, input *efs.DeleteFileSystemInput, optFns ...func(*efs.Options)) (*efs.DeleteFileSystemOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteFileSystem: %v", input)
+
+	id := aws.ToString(input.FileSystemId)
+	if _, found := m.FileSystems[id]; !found {
+		return nil, fileSystemNotFound(id)
+	}
+	for _, mountTarget := range m.MountTargets {
+		if aws.ToString(mountTarget.FileSystemId) == id {
+			return nil, &efstypes.FileSystemInUse{Message: aws.String(fmt.Sprintf("File system '%s' has mount targets created in it.", id))}
+		}
+	}
+	delete(m.FileSystems, id)
+
+	return &efs.DeleteFileSystemOutput{}, nil
+}
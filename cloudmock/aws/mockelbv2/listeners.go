@@ -105,13 +105,50 @@ func (m *MockELBV2) DeleteListener(ctx context.Context, request *elbv2.DeleteLis
 	klog.Infof("DeleteListenerWithContext v2 %v", request)
 
 	lARN := aws.ToString(request.ListenerArn)
-	if _, ok := m.Listeners[lARN]; !ok {
-		return nil, fmt.Errorf("Listener not found %v", lARN)
+	l, ok := m.Listeners[lARN]
+	if !ok {
+		return nil, &elbv2types.ListenerNotFoundException{Message: aws.String(fmt.Sprintf("Listener not found %v", lARN))}
 	}
 	delete(m.Listeners, lARN)
+	m.unlinkTargetGroups(l.description)
 	return nil, nil
 }
 
+// unlinkTargetGroups removes the load balancer of a deleted listener from the target groups that the listener forwarded to,
+// unless another listener of the load balancer still forwards to them
+func (m *MockELBV2) unlinkTargetGroups(deleted elbv2types.Listener) {
+	lbARN := aws.ToString(deleted.LoadBalancerArn)
+	for _, action := range deleted.DefaultActions {
+		tg := m.TargetGroups[aws.ToString(action.TargetGroupArn)]
+		if tg == nil {
+			continue
+		}
+
+		inUse := false
+		for _, l := range m.Listeners {
+			if aws.ToString(l.description.LoadBalancerArn) != lbARN {
+				continue
+			}
+			for _, a := range l.description.DefaultActions {
+				if aws.ToString(a.TargetGroupArn) == aws.ToString(action.TargetGroupArn) {
+					inUse = true
+				}
+			}
+		}
+		if inUse {
+			continue
+		}
+
+		var lbARNs []string
+		for _, arn := range tg.description.LoadBalancerArns {
+			if arn != lbARN {
+				lbARNs = append(lbARNs, arn)
+			}
+		}
+		tg.description.LoadBalancerArns = lbARNs
+	}
+}
+
 func (m *MockELBV2) ModifyListener(request *elbv2.ModifyListenerInput) (*elbv2.ModifyListenerOutput, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -216,6 +216,7 @@ func (m *MockELBV2) DeleteLoadBalancer(ctx context.Context, request *elbv2.Delet
 	for listenerARN, listener := range m.Listeners {
 		if aws.ToString(listener.description.LoadBalancerArn) == arn {
 			delete(m.Listeners, listenerARN)
+			m.unlinkTargetGroups(listener.description)
 		}
 	}
 	return &elbv2.DeleteLoadBalancerOutput{}, nil
@@ -118,6 +118,9 @@ func (m *MockELBV2) DeleteTargetGroup(ctx context.Context, request *elbv2.Delete
 	klog.Infof("DeleteTargetGroup %v", request)
 
 	arn := aws.ToString(request.TargetGroupArn)
+	if tg := m.TargetGroups[arn]; tg != nil && len(tg.description.LoadBalancerArns) != 0 {
+		return nil, &elbv2types.ResourceInUseException{Message: aws.String(fmt.Sprintf("Target group %q is currently in use by a listener or a rule", arn))}
+	}
 	delete(m.TargetGroups, arn)
 	return &elbv2.DeleteTargetGroupOutput{}, nil
 }
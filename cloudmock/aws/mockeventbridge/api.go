@@ -18,10 +18,15 @@ package mockeventbridge
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
 	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/smithy-go"
 	"k8s.io/kops/util/pkg/awsinterfaces"
 )
 
@@ -44,6 +49,7 @@ func (m *MockEventBridge) PutRule(ctx context.Context, input *eventbridge.PutRul
 	arn := "arn:aws-test:events:us-east-1:012345678901:rule/" + name
 
 	rule := &eventbridgetypes.Rule{
+		Name:         &name,
 		Arn:          &arn,
 		EventPattern: input.EventPattern,
 	}
@@ -68,16 +74,35 @@ func (m *MockEventBridge) ListRules(ctx context.Context, input *eventbridge.List
 
 	response := &eventbridge.ListRulesOutput{}
 
-	rule := m.Rules[*input.NamePrefix]
-	if rule == nil {
-		return response, nil
+	var names []string
+	for name := range m.Rules {
+		if strings.HasPrefix(name, aws.ToString(input.NamePrefix)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		response.Rules = append(response.Rules, *m.Rules[name])
 	}
-	response.Rules = []eventbridgetypes.Rule{*rule}
 	return response, nil
 }
 
 func (m *MockEventBridge) DeleteRule(ctx context.Context, input *eventbridge.DeleteRuleInput, optFns ...func(*eventbridge.Options)) (*eventbridge.DeleteRuleOutput, error) {
-	panic("Not implemented")
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	name := aws.ToString(input.Name)
+	rule := m.Rules[name]
+	if rule == nil {
+		return nil, &eventbridgetypes.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("Rule %s does not exist.", name))}
+	}
+	if len(m.TargetsByRule[name]) != 0 {
+		return nil, &smithy.GenericAPIError{Code: "ValidationException", Message: "Rule can't be deleted since it has targets."}
+	}
+	delete(m.TagsByArn, aws.ToString(rule.Arn))
+	delete(m.Rules, name)
+
+	return &eventbridge.DeleteRuleOutput{}, nil
 }
 
 func (m *MockEventBridge) ListTagsForResource(ctx context.Context, input *eventbridge.ListTagsForResourceInput, optFns ...func(*eventbridge.Options)) (*eventbridge.ListTagsForResourceOutput, error) {
@@ -113,5 +138,22 @@ func (m *MockEventBridge) ListTargetsByRule(ctx context.Context, input *eventbri
 }
 
 func (m *MockEventBridge) RemoveTargets(ctx context.Context, input *eventbridge.RemoveTargetsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.RemoveTargetsOutput, error) {
-	panic("Not implemented")
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	remove := make(map[string]bool)
+	for _, id := range input.Ids {
+		remove[id] = true
+	}
+	var targets []eventbridgetypes.Target
+	for _, target := range m.TargetsByRule[*input.Rule] {
+		if !remove[aws.ToString(target.Id)] {
+			targets = append(targets, target)
+		}
+	}
+	if m.TargetsByRule != nil {
+		m.TargetsByRule[*input.Rule] = targets
+	}
+
+	return &eventbridge.RemoveTargetsOutput{}, nil
 }
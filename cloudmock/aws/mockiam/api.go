@@ -19,8 +19,10 @@ package mockiam
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 	"sync"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"k8s.io/kops/util/pkg/awsinterfaces"
@@ -43,3 +45,13 @@ var _ awsinterfaces.IAMAPI = &MockIAM{}
 func (m *MockIAM) createID() string {
 	return "AID" + fmt.Sprintf("%x", rand.Int63())
 }
+
+// hasPathPrefix reports whether an entity with the given path is listed for the request's PathPrefix.
+// IAM entities created without a path have the path "/".
+func hasPathPrefix(path *string, pathPrefix *string) bool {
+	p := aws.ToString(path)
+	if p == "" {
+		p = "/"
+	}
+	return strings.HasPrefix(p, aws.ToString(pathPrefix))
+}
@@ -147,7 +147,7 @@ func (m *MockIAM) RemoveRoleFromInstanceProfile(ctx context.Context, request *ia
 	}
 
 	if !found {
-		return nil, fmt.Errorf("role not found")
+		return nil, &iamtypes.NoSuchEntityException{Message: aws.String(fmt.Sprintf("role %q not found in instance profile", aws.ToString(request.RoleName)))}
 	}
 	ip.Roles = newRoles
 
@@ -160,13 +160,12 @@ func (m *MockIAM) ListInstanceProfiles(ctx context.Context, request *iam.ListIns
 
 	klog.Infof("ListInstanceProfiles: %v", request)
 
-	if request.PathPrefix != nil {
-		klog.Fatalf("MockIAM ListInstanceProfiles PathPrefix not implemented")
-	}
-
 	var instanceProfiles []iamtypes.InstanceProfile
 
 	for _, ip := range m.InstanceProfiles {
+		if !hasPathPrefix(ip.Path, request.PathPrefix) {
+			continue
+		}
 		copy := *ip
 		instanceProfiles = append(instanceProfiles, copy)
 	}
@@ -187,7 +186,10 @@ func (m *MockIAM) DeleteInstanceProfile(ctx context.Context, request *iam.Delete
 	id := aws.ToString(request.InstanceProfileName)
 	o := m.InstanceProfiles[id]
 	if o == nil {
-		return nil, fmt.Errorf("InstanceProfile %q not found", id)
+		return nil, &iamtypes.NoSuchEntityException{Message: aws.String(fmt.Sprintf("InstanceProfile %q not found", id))}
+	}
+	if len(o.Roles) != 0 {
+		return nil, &iamtypes.DeleteConflictException{Message: aws.String(fmt.Sprintf("InstanceProfile %q has roles and cannot be deleted", id))}
 	}
 	delete(m.InstanceProfiles, id)
 
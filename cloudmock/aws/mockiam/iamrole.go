@@ -19,6 +19,7 @@ package mockiam
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
@@ -72,13 +73,12 @@ func (m *MockIAM) ListRoles(ctx context.Context, request *iam.ListRolesInput, op
 
 	klog.Infof("ListRoles: %v", request)
 
-	if request.PathPrefix != nil {
-		klog.Fatalf("MockIAM ListRoles PathPrefix not implemented")
-	}
-
 	var roles []iamtypes.Role
 
 	for _, r := range m.Roles {
+		if !hasPathPrefix(r.Path, request.PathPrefix) {
+			continue
+		}
 		copy := *r
 		roles = append(roles, copy)
 	}
@@ -101,6 +101,15 @@ func (m *MockIAM) DeleteRole(ctx context.Context, request *iam.DeleteRoleInput,
 	if o == nil {
 		return nil, fmt.Errorf("role %q not found", id)
 	}
+	// Like IAM, refuse to delete a role that still has policies
+	for _, rp := range m.RolePolicies {
+		if rp.RoleName == id {
+			return nil, &iamtypes.DeleteConflictException{Message: aws.String(fmt.Sprintf("role %q has inline policy %q", id, rp.PolicyName))}
+		}
+	}
+	if len(m.AttachedPolicies[id]) != 0 {
+		return nil, &iamtypes.DeleteConflictException{Message: aws.String(fmt.Sprintf("role %q has attached policies", id))}
+	}
 	delete(m.Roles, id)
 
 	return &iam.DeleteRoleOutput{}, nil
@@ -113,7 +122,7 @@ func (m *MockIAM) ListAttachedRolePolicies(ctx context.Context, request *iam.Lis
 	klog.Infof("ListAttachedRolePolicies: %s", aws.ToString(request.RoleName))
 
 	for _, r := range m.Roles {
-		if r.RoleName == request.RoleName {
+		if aws.ToString(r.RoleName) == aws.ToString(request.RoleName) {
 			role := aws.ToString(r.RoleName)
 
 			return &iam.ListAttachedRolePoliciesOutput{
@@ -124,3 +133,55 @@ func (m *MockIAM) ListAttachedRolePolicies(ctx context.Context, request *iam.Lis
 
 	return &iam.ListAttachedRolePoliciesOutput{}, nil
 }
+
+func (m *MockIAM) AttachRolePolicy(ctx context.Context, request *iam.AttachRolePolicyInput, optFns ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("AttachRolePolicy: %v", request)
+
+	role := aws.ToString(request.RoleName)
+	if m.Roles[role] == nil {
+		return nil, &iamtypes.NoSuchEntityException{Message: aws.String(fmt.Sprintf("role %q not found", role))}
+	}
+	for _, policy := range m.AttachedPolicies[role] {
+		if aws.ToString(policy.PolicyArn) == aws.ToString(request.PolicyArn) {
+			return &iam.AttachRolePolicyOutput{}, nil
+		}
+	}
+
+	if m.AttachedPolicies == nil {
+		m.AttachedPolicies = make(map[string][]iamtypes.AttachedPolicy)
+	}
+	arn := aws.ToString(request.PolicyArn)
+	m.AttachedPolicies[role] = append(m.AttachedPolicies[role], iamtypes.AttachedPolicy{
+		PolicyArn:  aws.String(arn),
+		PolicyName: aws.String(arn[strings.LastIndex(arn, "/")+1:]),
+	})
+
+	return &iam.AttachRolePolicyOutput{}, nil
+}
+
+func (m *MockIAM) DetachRolePolicy(ctx context.Context, request *iam.DetachRolePolicyInput, optFns ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DetachRolePolicy: %v", request)
+
+	role := aws.ToString(request.RoleName)
+	found := false
+	var policies []iamtypes.AttachedPolicy
+	for _, policy := range m.AttachedPolicies[role] {
+		if aws.ToString(policy.PolicyArn) == aws.ToString(request.PolicyArn) {
+			found = true
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	if !found {
+		return nil, &iamtypes.NoSuchEntityException{Message: aws.String(fmt.Sprintf("policy %q is not attached to role %q", aws.ToString(request.PolicyArn), role))}
+	}
+	m.AttachedPolicies[role] = policies
+
+	return &iam.DetachRolePolicyOutput{}, nil
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockkms
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
+	"k8s.io/klog/v2"
+	"k8s.io/kops/util/pkg/awsinterfaces"
+)
+
+type MockKMS struct {
+	awsinterfaces.KMSAPI
+	mutex sync.Mutex
+
+	// Keys are the keys by key id
+	Keys map[string]*kmstypes.KeyMetadata
+	// TagsByKeyID are the tags of the keys, by key id
+	TagsByKeyID map[string][]kmstypes.Tag
+	// Aliases are the aliases by alias name, e.g. alias/my-key
+	Aliases map[string]*kmstypes.AliasListEntry
+	// AccessDeniedKeyIDs are the ids of the keys whose key policy doesn't let the caller describe them or list their tags
+	AccessDeniedKeyIDs []string
+}
+
+var _ awsinterfaces.KMSAPI = &MockKMS{}
+
+// MockCreateKey adds a key, with an ARN derived from its id if it has none
+func (m *MockKMS) MockCreateKey(key *kmstypes.KeyMetadata, tags []kmstypes.Tag) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.Keys == nil {
+		m.Keys = make(map[string]*kmstypes.KeyMetadata)
+	}
+	if m.TagsByKeyID == nil {
+		m.TagsByKeyID = make(map[string][]kmstypes.Tag)
+	}
+
+	keyID := aws.ToString(key.KeyId)
+	if key.Arn == nil {
+		key.Arn = aws.String("arn:aws-test:kms:us-east-1:012345678901:key/" + keyID)
+	}
+	m.Keys[keyID] = key
+	m.TagsByKeyID[keyID] = tags
+}
+
+// MockCreateAlias adds an alias for the key
+func (m *MockKMS) MockCreateAlias(aliasName, keyID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.Aliases == nil {
+		m.Aliases = make(map[string]*kmstypes.AliasListEntry)
+	}
+	m.Aliases[aliasName] = &kmstypes.AliasListEntry{
+		AliasName:   aws.String(aliasName),
+		AliasArn:    aws.String("arn:aws-test:kms:us-east-1:012345678901:" + aliasName),
+		TargetKeyId: aws.String(keyID),
+	}
+}
+
+// findKey returns the key identified by its id, ARN or alias, as in the KeyId of the KMS API
+func (m *MockKMS) findKey(keyID string) *kmstypes.KeyMetadata {
+	if strings.HasPrefix(keyID, "alias/") {
+		alias := m.Aliases[keyID]
+		if alias == nil {
+			return nil
+		}
+		keyID = aws.ToString(alias.TargetKeyId)
+	}
+	if key := m.Keys[keyID]; key != nil {
+		return key
+	}
+	for _, key := range m.Keys {
+		if aws.ToString(key.Arn) == keyID {
+			return key
+		}
+	}
+	return nil
+}
+
+func notFound(keyID string) error {
+	return &kmstypes.NotFoundException{Message: aws.String(fmt.Sprintf("Key '%s' does not exist", keyID))}
+}
+
+// accessDenied returns an error if the key policy doesn't let the caller use the key
+func (m *MockKMS) accessDenied(key *kmstypes.KeyMetadata, action string) error {
+	for _, keyID := range m.AccessDeniedKeyIDs {
+		if keyID == aws.ToString(key.KeyId) {
+			return &smithy.GenericAPIError{Code: "AccessDeniedException", Message: fmt.Sprintf("User is not authorized to perform: %s on resource: %s", action, aws.ToString(key.Arn))}
+		}
+	}
+	return nil
+}
+
+func (m *MockKMS) ListKeys(ctx context.Context, input *kms.ListKeysInput, optFns ...func(*kms.Options)) (*kms.ListKeysOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("ListKeys: %v", input)
+
+	response := &kms.ListKeysOutput{}
+	for _, key := range m.Keys {
+		response.Keys = append(response.Keys, kmstypes.KeyListEntry{
+			KeyId:  key.KeyId,
+			KeyArn: key.Arn,
+		})
+	}
+	sort.Slice(response.Keys, func(i, j int) bool {
+		return aws.ToString(response.Keys[i].KeyId) < aws.ToString(response.Keys[j].KeyId)
+	})
+	return response, nil
+}
+
+func (m *MockKMS) ListAliases(ctx context.Context, input *kms.ListAliasesInput, optFns ...func(*kms.Options)) (*kms.ListAliasesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("ListAliases: %v", input)
+
+	response := &kms.ListAliasesOutput{}
+	for _, alias := range m.Aliases {
+		if input.KeyId != nil && aws.ToString(alias.TargetKeyId) != aws.ToString(input.KeyId) {
+			continue
+		}
+		response.Aliases = append(response.Aliases, *alias)
+	}
+	sort.Slice(response.Aliases, func(i, j int) bool {
+		return aws.ToString(response.Aliases[i].AliasName) < aws.ToString(response.Aliases[j].AliasName)
+	})
+	return response, nil
+}
+
+func (m *MockKMS) DescribeKey(ctx context.Context, input *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DescribeKey: %v", input)
+
+	key := m.findKey(aws.ToString(input.KeyId))
+	if key == nil {
+		return nil, notFound(aws.ToString(input.KeyId))
+	}
+	if err := m.accessDenied(key, "kms:DescribeKey"); err != nil {
+		return nil, err
+	}
+	copy := *key
+	return &kms.DescribeKeyOutput{KeyMetadata: &copy}, nil
+}
+
+func (m *MockKMS) ListResourceTags(ctx context.Context, input *kms.ListResourceTagsInput, optFns ...func(*kms.Options)) (*kms.ListResourceTagsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("ListResourceTags: %v", input)
+
+	key := m.findKey(aws.ToString(input.KeyId))
+	if key == nil {
+		return nil, notFound(aws.ToString(input.KeyId))
+	}
+	if err := m.accessDenied(key, "kms:ListResourceTags"); err != nil {
+		return nil, err
+	}
+	return &kms.ListResourceTagsOutput{Tags: m.TagsByKeyID[aws.ToString(key.KeyId)]}, nil
+}
+
+func (m *MockKMS) DeleteAlias(ctx context.Context, input *kms.DeleteAliasInput, optFns ...func(*kms.Options)) (*kms.DeleteAliasOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("DeleteAlias: %v", input)
+
+	aliasName := aws.ToString(input.AliasName)
+	if m.Aliases[aliasName] == nil {
+		return nil, &kmstypes.NotFoundException{Message: aws.String(fmt.Sprintf("Alias '%s' does not exist", aliasName))}
+	}
+	delete(m.Aliases, aliasName)
+	return &kms.DeleteAliasOutput{}, nil
+}
+
+func (m *MockKMS) ScheduleKeyDeletion(ctx context.Context, input *kms.ScheduleKeyDeletionInput, optFns ...func(*kms.Options)) (*kms.ScheduleKeyDeletionOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.Infof("ScheduleKeyDeletion: %v", input)
+
+	key := m.findKey(aws.ToString(input.KeyId))
+	if key == nil {
+		return nil, notFound(aws.ToString(input.KeyId))
+	}
+	if key.KeyManager == kmstypes.KeyManagerTypeAws {
+		return nil, &kmstypes.UnsupportedOperationException{Message: aws.String("AWS managed keys can't be deleted")}
+	}
+
+	pendingWindowInDays := aws.ToInt32(input.PendingWindowInDays)
+	if pendingWindowInDays == 0 {
+		pendingWindowInDays = 30
+	}
+	key.KeyState = kmstypes.KeyStatePendingDeletion
+	key.DeletionDate = aws.Time(time.Now().AddDate(0, 0, int(pendingWindowInDays)))
+
+	return &kms.ScheduleKeyDeletionOutput{
+		KeyId:               key.KeyId,
+		KeyState:            key.KeyState,
+		DeletionDate:        key.DeletionDate,
+		PendingWindowInDays: aws.Int32(pendingWindowInDays),
+	}, nil
+}
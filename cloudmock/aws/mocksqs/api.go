@@ -19,10 +19,13 @@ package mocksqs
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"k8s.io/kops/util/pkg/awsinterfaces"
 )
 
@@ -74,8 +77,15 @@ func (m *MockSQS) ListQueues(ctx context.Context, input *sqs.ListQueuesInput, op
 
 	response := &sqs.ListQueuesOutput{}
 
-	if queue, ok := m.Queues[*input.QueueNamePrefix]; ok {
-		response.QueueUrls = []string{aws.ToString(queue.url)}
+	var names []string
+	for name := range m.Queues {
+		if strings.HasPrefix(name, aws.ToString(input.QueueNamePrefix)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		response.QueueUrls = append(response.QueueUrls, aws.ToString(m.Queues[name].url))
 	}
 	return response, nil
 }
@@ -111,5 +121,14 @@ func (m *MockSQS) ListQueueTags(ctx context.Context, input *sqs.ListQueueTagsInp
 }
 
 func (m *MockSQS) DeleteQueue(ctx context.Context, input *sqs.DeleteQueueInput, optFns ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error) {
-	panic("Not implemented")
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for name, v := range m.Queues {
+		if *v.url == *input.QueueUrl {
+			delete(m.Queues, name)
+			return &sqs.DeleteQueueOutput{}, nil
+		}
+	}
+	return nil, &sqstypes.QueueDoesNotExist{Message: aws.String(fmt.Sprintf("queue %q does not exist", *input.QueueUrl))}
 }
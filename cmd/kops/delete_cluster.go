@@ -18,11 +18,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 	"k8s.io/kops/cmd/kops/util"
@@ -30,10 +37,12 @@ import (
 	"k8s.io/kops/pkg/commands/commandutils"
 	"k8s.io/kops/pkg/kubeconfig"
 	"k8s.io/kops/pkg/resources"
+	awsresources "k8s.io/kops/pkg/resources/aws"
 	resourceops "k8s.io/kops/pkg/resources/ops"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/util/pkg/maps"
 	"k8s.io/kops/util/pkg/tables"
 	"k8s.io/kubectl/pkg/util/i18n"
 	"k8s.io/kubectl/pkg/util/templates"
@@ -45,15 +54,64 @@ type DeleteClusterOptions struct {
 	External    bool
 	Unregister  bool
 	ClusterName string
-	wait        time.Duration
-	count       int
-	interval    time.Duration
+	// ListPermissions prints the cloud API actions needed to delete the cluster resources, instead of deleting them
+	ListPermissions bool
+	// Plan prints the deletion plan as JSON, with the dependencies between the resources, instead of deleting them
+	Plan bool
+	// AuditUntagged reports the resources that are named for the cluster but not tagged as belonging to it, instead of deleting anything
+	AuditUntagged bool
+	// OutpostARN restricts the deletion of resources placed on an Outpost to those on this Outpost
+	OutpostARN string
+	// IAMPathPrefix restricts the deletion of IAM resources to those under this path
+	IAMPathPrefix string
+	// MultiClusterPolicy is what to do with resources that other clusters own too: error, skip or delete-if-all-listed
+	MultiClusterPolicy string
+	// ListedClusters are the other clusters whose co-owned resources may be deleted with the delete-if-all-listed policy
+	ListedClusters []string
+	// UntaggedRouteTableMinAge is the minimum age of the untagged route tables in the cluster VPC that are deleted
+	UntaggedRouteTableMinAge time.Duration
+	// UntaggedRouteTableVPCs restricts the deletion of untagged route tables to those in these VPCs
+	UntaggedRouteTableVPCs []string
+	// AllowedRegions are the only cloud regions that resources may be deleted in
+	AllowedRegions []string
+	// ProtectedIDs are the ids of the cloud resources that are never deleted, whatever their tags say
+	ProtectedIDs []string
+	// DisassociateSharedSubnets allows owned route tables to be disassociated from shared subnets
+	DisassociateSharedSubnets bool
+	// DisassociateSubnetsInUse allows route tables to be disassociated from subnets in which instances are running
+	DisassociateSubnetsInUse bool
+	// TagSharedResources tags the shared resources left behind with the id of the delete run
+	TagSharedResources bool
+	// NoIAM leaves the AWS IAM roles and instance profiles alone, e.g. when they are managed outside kops
+	NoIAM bool
+	// OwnershipTagKeys are additional tag keys whose value names the cluster that owns a resource
+	OwnershipTagKeys []string
+	// BestEffort keeps deleting the independent resources when a resource can't be deleted, and reports every failure at the end
+	BestEffort bool
+	// ResourceFilter restricts the deletion to the cloud resources of these types
+	ResourceFilter []string
+	// ELBTags, if set, are the tags that the cluster's classic load balancers are matched by; values can be globs, and empty values match any value
+	ELBTags map[string]string
+	// ClusterTagScheme is the set of cluster tags that the route tables are looked up by: all, legacy or ownership
+	ClusterTagScheme string
+
+	wait     time.Duration
+	count    int
+	interval time.Duration
+	seed     int64
+
+	maxConcurrentDeletes int
 }
 
 func (o *DeleteClusterOptions) InitDefaults() {
 	o.count = 0
 	o.interval = 10 * time.Second
 	o.wait = 10 * time.Minute
+	o.MultiClusterPolicy = string(resources.MultiClusterPolicyError)
+	o.ClusterTagScheme = string(resources.ClusterTagSchemeAll)
+	if allowedRegions := os.Getenv("KOPS_ALLOWED_REGIONS"); allowedRegions != "" {
+		o.AllowedRegions = strings.Split(allowedRegions, ",")
+	}
 }
 
 var (
@@ -91,13 +149,50 @@ func NewCmdDeleteCluster(f *util.Factory, out io.Writer) *cobra.Command {
 	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Specify --yes to delete the cluster")
 	cmd.Flags().BoolVar(&options.Unregister, "unregister", options.Unregister, "Don't delete cloud resources, just unregister the cluster")
 	cmd.Flags().BoolVar(&options.External, "external", options.External, "Delete an external cluster")
+	cmd.Flags().BoolVar(&options.AuditUntagged, "audit-untagged", options.AuditUntagged, "Don't delete anything, just list the resources that are named for the cluster but not tagged as belonging to it")
+	cmd.Flags().BoolVar(&options.TagSharedResources, "tag-shared-resources", options.TagSharedResources, "Tag the shared cloud resources that are left behind with the id of the delete run, as "+awsresources.TagLastDeleteRun)
+	cmd.Flags().BoolVar(&options.Plan, "plan", options.Plan, "Don't delete anything, just print the resources that would be deleted or skipped, and the order they would be deleted in, as JSON")
+	cmd.Flags().BoolVar(&options.ListPermissions, "list-permissions", options.ListPermissions, "Don't delete anything, just list the cloud API actions that deleting the cluster resources requires")
+
+	cmd.Flags().StringVar(&options.OutpostARN, "outpost-arn", options.OutpostARN, "Only delete resources placed on this AWS Outpost, or not placed on any Outpost")
+	cmd.Flags().StringVar(&options.MultiClusterPolicy, "multi-cluster-policy", options.MultiClusterPolicy, "What to do with resources that other clusters own too: error, skip, or delete-if-all-listed")
+	cmd.RegisterFlagCompletionFunc("multi-cluster-policy", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var policies []string
+		for _, policy := range resources.MultiClusterPolicies {
+			policies = append(policies, string(policy))
+		}
+		return policies, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.Flags().StringSliceVar(&options.ResourceFilter, "resource-filter", options.ResourceFilter, "Only look for and delete AWS resources of these types, e.g. route-table,iam-role")
+	cmd.Flags().StringSliceVar(&options.ListedClusters, "listed-clusters", options.ListedClusters, "Other clusters whose co-owned resources may be deleted, with --multi-cluster-policy=delete-if-all-listed")
+	cmd.Flags().DurationVar(&options.UntaggedRouteTableMinAge, "untagged-route-table-min-age", options.UntaggedRouteTableMinAge, "Only delete untagged route tables in the cluster VPC that are known to be at least this old")
+	cmd.Flags().BoolVar(&options.DisassociateSharedSubnets, "disassociate-shared-subnets", options.DisassociateSharedSubnets, "Disassociate the cluster route tables from shared subnets, so that they can be deleted; the other users of the subnets may lose their routes")
+	cmd.Flags().BoolVar(&options.DisassociateSubnetsInUse, "disassociate-subnets-in-use", options.DisassociateSubnetsInUse, "Disassociate the cluster route tables from subnets in which instances are still running, so that they can be deleted; the instances may lose their routes")
+	cmd.Flags().StringSliceVar(&options.UntaggedRouteTableVPCs, "untagged-route-table-vpcs", options.UntaggedRouteTableVPCs, "Only delete untagged route tables in these VPCs of the cluster")
+	cmd.Flags().StringVar(&options.IAMPathPrefix, "iam-path-prefix", options.IAMPathPrefix, "Only look for AWS IAM roles and instance profiles under this path, e.g. /kops/")
+	cmd.Flags().StringSliceVar(&options.OwnershipTagKeys, "ownership-tag-keys", options.OwnershipTagKeys, "Additional AWS tag keys whose value is the name of the cluster that owns a resource, e.g. company.com/cluster")
+	cmd.Flags().StringVar(&options.ClusterTagScheme, "cluster-tag-scheme", options.ClusterTagScheme, "Only look up the AWS route tables by these cluster tags, saving API calls when the others are known to be absent: all, legacy (KubernetesCluster), or ownership (kubernetes.io/cluster/<name>)")
+	cmd.RegisterFlagCompletionFunc("cluster-tag-scheme", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var schemes []string
+		for _, scheme := range resources.ClusterTagSchemes {
+			schemes = append(schemes, string(scheme))
+		}
+		return schemes, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.Flags().StringToStringVar(&options.ELBTags, "elb-tags", options.ELBTags, "Match the cluster's classic load balancers by these tags instead of the cluster tags; a value can be a glob such as owned-*, and an empty value matches any value")
+	cmd.Flags().BoolVar(&options.NoIAM, "no-iam", options.NoIAM, "Don't delete the AWS IAM roles and instance profiles, e.g. when they are managed outside kops")
 
+	cmd.Flags().StringSliceVar(&options.AllowedRegions, "allowed-regions", options.AllowedRegions, "If set, refuse to delete cloud resources in any other region. Defaults to the comma-separated KOPS_ALLOWED_REGIONS environment variable")
+	cmd.Flags().StringSliceVar(&options.ProtectedIDs, "protected-ids", options.ProtectedIDs, "IDs of cloud resources that must never be deleted, even if they are tagged as belonging to the cluster, e.g. vpc-1234,subnet-5678")
 	cmd.Flags().StringVar(&options.Region, "region", options.Region, "External cluster's cloud region")
 	cmd.RegisterFlagCompletionFunc("region", completeRegion)
 
 	cmd.Flags().DurationVar(&options.wait, "wait", options.wait, "Amount of time to wait for the cluster resources to de deleted")
 	cmd.Flags().IntVar(&options.count, "count", options.count, "Number of consecutive failures to make progress deleting the cluster resources")
 	cmd.Flags().DurationVar(&options.interval, "interval", options.interval, "Time in duration to wait between deletion attempts")
+	cmd.Flags().BoolVar(&options.BestEffort, "best-effort", options.BestEffort, "Keep deleting the resources that don't depend on a resource that can't be deleted, and list every failure at the end")
+	cmd.Flags().Int64Var(&options.seed, "order-seed", options.seed, "If non-zero, shuffle the order in which independent resources are deleted, reproducibly for the same seed")
+	cmd.Flags().IntVar(&options.maxConcurrentDeletes, "max-concurrent-deletes", options.maxConcurrentDeletes, "Maximum number of independent resources to delete at the same time; 0 means no limit")
 
 	return cmd
 }
@@ -140,56 +235,178 @@ func RunDeleteCluster(ctx context.Context, f *util.Factory, out io.Writer, optio
 			}
 		}
 
+		listOptions := resources.ListOptions{
+			AWSOutpostARN:                options.OutpostARN,
+			AWSIAMPathPrefix:             options.IAMPathPrefix,
+			MultiClusterPolicy:           resources.MultiClusterPolicy(options.MultiClusterPolicy),
+			ListedClusters:               options.ListedClusters,
+			AWSUntaggedRouteTableMinAge:  options.UntaggedRouteTableMinAge,
+			AWSUntaggedRouteTableVPCs:    options.UntaggedRouteTableVPCs,
+			AllowedRegions:               options.AllowedRegions,
+			AWSDisassociateSharedSubnets: options.DisassociateSharedSubnets,
+			AWSDisassociateSubnetsInUse:  options.DisassociateSubnetsInUse,
+			AWSSkipIAM:                   options.NoIAM,
+			AWSResourceTypes:             options.ResourceFilter,
+			OwnershipTagKeys:             options.OwnershipTagKeys,
+			AWSELBTags:                   options.ELBTags,
+			AWSClusterTagScheme:          resources.ClusterTagScheme(options.ClusterTagScheme),
+		}
+
+		// A service that fails the check for another reason than a missing permission is reported when it is listed
+		if err := resourceops.PreflightCheck(cloud, cluster, listOptions); err != nil {
+			var serviceFailures *resources.ServiceFailuresError
+			if !errors.As(err, &serviceFailures) {
+				return err
+			}
+			klog.Warningf("%v", err)
+		}
+
+		if options.AuditUntagged {
+			untagged, err := resourceops.AuditUntaggedResources(cloud, clusterName)
+			if err != nil {
+				return err
+			}
+			if len(untagged) == 0 {
+				fmt.Fprintf(out, "No untagged resources named for the cluster\n")
+				return nil
+			}
+			fmt.Fprintf(out, "These resources are named for the cluster, but not tagged as belonging to it, so will not be deleted:\n\n")
+			return renderResources(out, untagged)
+		}
+
 		klog.Info("Looking for cloud resources to delete")
-		allResources, err := resourceops.ListResources(cloud, cluster)
+		allResources, err := resourceops.ListResourcesWithOptions(cloud, cluster, listOptions)
+		// If only some services could not be listed, we still delete the resources of the others,
+		// but report the failures at the end (and leave the cluster registered so the deletion can be retried).
+		var serviceFailures *resources.ServiceFailuresError
 		if err != nil {
-			return err
+			if !errors.As(err, &serviceFailures) {
+				return err
+			}
+			klog.Warningf("%v", err)
+		}
+
+		// A dependency on a resource that wasn't listed can stall the deletion, or let a resource be deleted too early
+		if err := resourceops.ValidateDependencies(allResources); err != nil {
+			klog.Warningf("%v", err)
+		}
+
+		if options.Plan {
+			plan := resourceops.BuildDeletionPlan(allResources)
+			plan.MarkProtected(options.ProtectedIDs)
+			b, err := json.MarshalIndent(plan, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error marshaling deletion plan: %v", err)
+			}
+			fmt.Fprintf(out, "%s\n", b)
+			return nil
 		}
 
+		protectedIDs := sets.NewString(options.ProtectedIDs...)
 		clusterResources := make(map[string]*resources.Resource)
+		// toDelete are the cluster resources that aren't protected;
+		// the protected ones are still passed to DeleteResources, so that the resources they block are deleted
+		toDelete := make(map[string]*resources.Resource)
+		var sharedResources []*resources.Resource
+		var protectedResources []string
 		for k, resource := range allResources {
 			if resource.Shared {
+				sharedResources = append(sharedResources, resource)
 				continue
 			}
 			clusterResources[k] = resource
+			if protectedIDs.Has(resource.ID) {
+				protectedResources = append(protectedResources, k)
+				continue
+			}
+			toDelete[k] = resource
 		}
 
-		if len(clusterResources) == 0 {
+		if options.ListPermissions {
+			for _, action := range resourceops.RequiredActions(toDelete) {
+				fmt.Fprintf(out, "%s\n", action)
+			}
+			return nil
+		}
+
+		if len(sharedResources) != 0 {
+			// Explain why these resources are left behind
+			shared := resourceops.SummarizeShared(sharedResources)
+			fmt.Fprintf(out, "Skipping %d shared resources, which are not owned by the cluster:\n", len(sharedResources))
+			for _, resourceType := range maps.SortedKeys(shared) {
+				fmt.Fprintf(out, "  %s: %s\n", resourceType, strings.Join(shared[resourceType], ", "))
+			}
+			fmt.Fprintf(out, "\n")
+		}
+
+		if len(protectedResources) != 0 {
+			sort.Strings(protectedResources)
+			fmt.Fprintf(out, "Skipping %d protected resources: %s\n\n", len(protectedResources), strings.Join(protectedResources, ", "))
+		}
+
+		if len(toDelete) == 0 {
 			fmt.Fprintf(out, "No cloud resources to delete\n")
 		} else {
 			wouldDeleteCloudResources = true
 
-			t := &tables.Table{}
-			t.AddColumn("TYPE", func(r *resources.Resource) string {
-				return r.Type
-			})
-			t.AddColumn("ID", func(r *resources.Resource) string {
-				return r.ID
-			})
-			t.AddColumn("NAME", func(r *resources.Resource) string {
-				return r.Name
-			})
 			var l []*resources.Resource
-			for _, v := range clusterResources {
+			for _, v := range toDelete {
 				l = append(l, v)
 			}
 
-			err := t.Render(l, out, "TYPE", "NAME", "ID")
-			if err != nil {
+			if err := renderResources(out, l); err != nil {
 				return err
 			}
 
+			fmt.Fprintf(out, "\nEstimated time to delete: %s\n", resourceops.EstimateDeletionTime(toDelete).Round(time.Second))
+
+			if serviceFailures != nil {
+				fmt.Fprintf(out, "\nThe resources of these services could not be listed, so will not be deleted: %v\n", serviceFailures)
+			}
+
 			if !options.Yes {
 				fmt.Fprintf(out, "\nMust specify --yes to delete cluster\n")
 				return nil
 			}
 
-			fmt.Fprintf(out, "\n")
-
-			err = resourceops.DeleteResources(cloud, clusterResources, options.count, options.interval, options.wait)
+			runID := uuid.New().String()
+			fmt.Fprintf(out, "\nDelete run: %s\n\n", runID)
+
+			deleteOptions := &resourceops.DeleteOptions{
+				Count:                options.count,
+				Interval:             options.interval,
+				Wait:                 options.wait,
+				Seed:                 options.seed,
+				MaxConcurrentDeletes: options.maxConcurrentDeletes,
+				AllowedRegions:       options.AllowedRegions,
+				ProtectedIDs:         options.ProtectedIDs,
+				RunID:                runID,
+				BestEffort:           options.BestEffort,
+			}
+			err = resourceops.DeleteResourcesWithOptions(cloud, clusterResources, deleteOptions)
 			if err != nil {
 				return err
 			}
+
+			if err := resourceops.RemoveStaleRoutes(cloud, allResources); err != nil {
+				return err
+			}
+
+			if options.TagSharedResources {
+				if err := resourceops.TagSharedResources(cloud, allResources, runID); err != nil {
+					return err
+				}
+			}
+		}
+
+		if serviceFailures != nil {
+			return serviceFailures
+		}
+
+		if len(options.ResourceFilter) != 0 {
+			// The cluster's other resources are still there, so we must not unregister it
+			fmt.Fprintf(out, "\nOnly resources of types %s were looked for; not unregistering the cluster\n", strings.Join(options.ResourceFilter, ", "))
+			return nil
 		}
 	}
 
@@ -227,3 +444,18 @@ func completeRegion(cmd *cobra.Command, args []string, toComplete string) ([]str
 	// TODO call into cloud provider(s) to get list of valid regions
 	return nil, cobra.ShellCompDirectiveNoFileComp
 }
+
+// renderResources prints a table of the cloud resources
+func renderResources(out io.Writer, l []*resources.Resource) error {
+	t := &tables.Table{}
+	t.AddColumn("TYPE", func(r *resources.Resource) string {
+		return r.Type
+	})
+	t.AddColumn("ID", func(r *resources.Resource) string {
+		return r.ID
+	})
+	t.AddColumn("NAME", func(r *resources.Resource) string {
+		return r.Name
+	})
+	return t.Render(l, out, "TYPE", "NAME", "ID")
+}
@@ -18,7 +18,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -70,6 +69,12 @@ type ToolboxDumpOptions struct {
 	SSHUser      string
 	MaxNodes     int
 	K8sResources bool
+	// ShardDir, if set, is where the cloud resources are written, one JSON file per resource type
+	ShardDir string
+	// GroupByVPC nests the cloud resources under the id of the VPC they belong to
+	GroupByVPC bool
+	// IncludeSecrets keeps the user-data of instances and launch templates, which is redacted by default
+	IncludeSecrets bool
 }
 
 func (o *ToolboxDumpOptions) InitDefaults() {
@@ -103,6 +108,10 @@ func NewCmdToolboxDump(f commandutils.Factory, out io.Writer) *cobra.Command {
 
 	cmd.Flags().StringVar(&options.Dir, "dir", options.Dir, "Target directory; if specified will collect logs and other information.")
 	cmd.MarkFlagDirname("dir")
+	cmd.Flags().StringVar(&options.ShardDir, "shard-dir", options.ShardDir, "If specified, write the cloud resources to this directory as one JSON file per resource type, instead of to stdout")
+	cmd.MarkFlagDirname("shard-dir")
+	cmd.Flags().BoolVar(&options.GroupByVPC, "group-by-vpc", options.GroupByVPC, "Group the cloud resources by the VPC they belong to")
+	cmd.Flags().BoolVar(&options.IncludeSecrets, "include-secrets", options.IncludeSecrets, "Include the user-data of instances and launch templates, which may contain secrets, instead of redacting it")
 	cmd.Flags().BoolVar(&options.K8sResources, "k8s-resources", options.K8sResources, "Include k8s resources in the dump")
 	cmd.Flags().IntVar(&options.MaxNodes, "max-nodes", options.MaxNodes, "The maximum number of nodes from which to dump logs")
 	cmd.Flags().StringVar(&options.PrivateKey, "private-key", options.PrivateKey, "File containing private key to use for SSH access to instances")
@@ -113,6 +122,10 @@ func NewCmdToolboxDump(f commandutils.Factory, out io.Writer) *cobra.Command {
 }
 
 func RunToolboxDump(ctx context.Context, f commandutils.Factory, out io.Writer, options *ToolboxDumpOptions) error {
+	if options.GroupByVPC && options.ShardDir != "" {
+		return fmt.Errorf("--group-by-vpc cannot be used with --shard-dir, which groups the resources by type")
+	}
+
 	clientset, err := f.KopsClient()
 	if err != nil {
 		return err
@@ -136,10 +149,13 @@ func RunToolboxDump(ctx context.Context, f commandutils.Factory, out io.Writer,
 	if err != nil {
 		return err
 	}
-	d, err := resources.BuildDump(ctx, cloud, resourceMap)
+	d, err := resources.BuildDumpWithOptions(ctx, cloud, resourceMap, resources.DumpOptions{IncludeSecrets: options.IncludeSecrets})
 	if err != nil {
 		return err
 	}
+	if options.GroupByVPC {
+		resources.GroupDumpByVPC(d, resourceMap)
+	}
 
 	if options.Dir != "" {
 		privateKeyPath := options.PrivateKey
@@ -254,6 +270,10 @@ func RunToolboxDump(ctx context.Context, f commandutils.Factory, out io.Writer,
 		}
 	}
 
+	if options.ShardDir != "" {
+		return resources.WriteShardedDump(d, options.ShardDir)
+	}
+
 	switch options.Output {
 	case OutputYaml:
 		b, err := kops.ToRawYaml(d)
@@ -267,7 +287,7 @@ func RunToolboxDump(ctx context.Context, f commandutils.Factory, out io.Writer,
 		return nil
 
 	case OutputJSON:
-		b, err := json.MarshalIndent(d, "", "  ")
+		b, err := d.ToJSON()
 		if err != nil {
 			return fmt.Errorf("error marshaling json: %v", err)
 		}
@@ -98,6 +98,8 @@ var (
 	Metal = new("Metal", Bool(false))
 	// AWSSingleNodesInstanceGroup enables the creation of a single node instance group instead of one per availability zone.
 	AWSSingleNodesInstanceGroup = new("AWSSingleNodesInstanceGroup", Bool(false))
+	// AWSRequestTracing logs the operation, request id and latency of every AWS API call.
+	AWSRequestTracing = new("AWSRequestTracing", Bool(false))
 )
 
 // FeatureFlag defines a feature flag
@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// foreignOwnerChecker tells whether resources are owned by an account other than the one we are running as,
+// e.g. the VPC and subnets that are shared in from another account with AWS RAM, which we must never delete.
+type foreignOwnerChecker struct {
+	cloud awsup.AWSCloud
+	// accountID is the account we are running as, looked up when first needed
+	accountID string
+}
+
+// IsForeign returns true if the owner is known, and is not the account we are running as
+func (f *foreignOwnerChecker) IsForeign(ownerID *string) (bool, error) {
+	if aws.ToString(ownerID) == "" {
+		return false, nil
+	}
+	if f.accountID == "" {
+		accountID, _, err := f.cloud.AccountInfo(context.TODO())
+		if err != nil {
+			return false, fmt.Errorf("error getting AWS account: %w", err)
+		}
+		f.accountID = accountID
+	}
+	return aws.ToString(ownerID) != f.accountID, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// AuditUntaggedResources returns the IAM roles and instance profiles that are named for the cluster
+// ("<name>.<clustername>"), but don't carry any cluster ownership tag.
+// Old versions of kops created such resources; we never delete them, because the name alone doesn't prove
+// that we own them, but we report them so that operators can decide whether to adopt or delete them.
+// The returned resources have no Deleter.
+func AuditUntaggedResources(cloud awsup.AWSCloud, clusterName string) ([]*resources.Resource, error) {
+	ctx := context.TODO()
+
+	var untagged []*resources.Resource
+
+	{
+		paginator := iam.NewListRolesPaginator(cloud.IAM(), &iam.ListRolesInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("error listing IAM roles: %v", err)
+			}
+			for _, r := range page.Roles {
+				name := aws.ToString(r.RoleName)
+				if !strings.HasSuffix(name, "."+clusterName) {
+					continue
+				}
+				response, err := cloud.IAM().GetRole(ctx, &iam.GetRoleInput{RoleName: r.RoleName})
+				if err != nil {
+					if awsup.IsIAMNoSuchEntityException(err) {
+						continue
+					}
+					return nil, fmt.Errorf("calling IAM GetRole on %s: %w", name, err)
+				}
+				if hasIAMClusterTag(response.Role.Tags) {
+					continue
+				}
+				untagged = append(untagged, &resources.Resource{
+					Name: name,
+					ID:   name,
+					Type: "iam-role",
+					Obj:  response.Role,
+				})
+			}
+		}
+	}
+
+	{
+		paginator := iam.NewListInstanceProfilesPaginator(cloud.IAM(), &iam.ListInstanceProfilesInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("error listing IAM instance profiles: %v", err)
+			}
+			for _, p := range page.InstanceProfiles {
+				name := aws.ToString(p.InstanceProfileName)
+				if !strings.HasSuffix(name, "."+clusterName) {
+					continue
+				}
+				response, err := cloud.IAM().GetInstanceProfile(ctx, &iam.GetInstanceProfileInput{InstanceProfileName: p.InstanceProfileName})
+				if err != nil {
+					if awsup.IsIAMNoSuchEntityException(err) {
+						continue
+					}
+					return nil, fmt.Errorf("calling IAM GetInstanceProfile on %s: %w", name, err)
+				}
+				if hasIAMClusterTag(response.InstanceProfile.Tags) {
+					continue
+				}
+				untagged = append(untagged, &resources.Resource{
+					Name: name,
+					ID:   name,
+					Type: "iam-instance-profile",
+					Obj:  response.InstanceProfile,
+				})
+			}
+		}
+	}
+
+	for _, r := range untagged {
+		klog.V(2).Infof("%s %q is named for the cluster but not tagged as belonging to any cluster", r.Type, r.Name)
+	}
+
+	return untagged, nil
+}
+
+// hasIAMClusterTag returns true if the tags mark the IAM resource as belonging to any cluster, owned or shared
+func hasIAMClusterTag(tags []iamtypes.Tag) bool {
+	for _, tag := range tags {
+		key := aws.ToString(tag.Key)
+		if key == awsup.TagClusterName || strings.HasPrefix(key, "kubernetes.io/cluster/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"k8s.io/kops/cloudmock/aws/mockiam"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestAuditUntaggedResources(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+	otherOwnershipTagKey := "kubernetes.io/cluster/foo." + clusterName
+
+	c := &mockiam.MockIAM{
+		Roles:            make(map[string]*iamtypes.Role),
+		InstanceProfiles: make(map[string]*iamtypes.InstanceProfile),
+	}
+	cloud.MockIAM = c
+
+	for name, tags := range map[string][]iamtypes.Tag{
+		// Owned by the cluster
+		"tagged." + clusterName: {{Key: &ownershipTagKey, Value: fi.PtrTo("owned")}},
+		// Owned by another cluster
+		"other." + clusterName: {{Key: &otherOwnershipTagKey, Value: fi.PtrTo("owned")}},
+		// Named for the cluster, but untagged
+		"untagged." + clusterName: nil,
+		// Not named for the cluster
+		clusterName + ".not-prefixed": nil,
+	} {
+		c.Roles[name] = &iamtypes.Role{
+			RoleName: &name,
+			Tags:     tags,
+		}
+	}
+
+	untagged, err := AuditUntaggedResources(cloud, clusterName)
+	if err != nil {
+		t.Fatalf("error auditing untagged resources: %v", err)
+	}
+	var keys []string
+	for _, r := range untagged {
+		keys = append(keys, r.Type+":"+r.ID)
+		if r.Deleter != nil || r.GroupDeleter != nil {
+			t.Errorf("expected audited resource %q not to be deletable", r.ID)
+		}
+	}
+	expected := []string{"iam-role:untagged." + clusterName}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("unexpected audit report: actual=%v, expected=%v", keys, expected)
+	}
+
+	resourceTrackers, err := ListIAMRoles(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing IAM roles: %v", err)
+	}
+	for _, r := range resourceTrackers {
+		if r.ID == "untagged."+clusterName {
+			t.Errorf("expected untagged role not to be listed for deletion")
+		}
+	}
+}
@@ -20,10 +20,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
 	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
@@ -35,6 +39,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"golang.org/x/sync/errgroup"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	"k8s.io/kops/pkg/dns"
@@ -43,6 +48,8 @@ import (
 	"k8s.io/kops/pkg/resources/spotinst"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/util/pkg/maps"
+	"k8s.io/kops/util/pkg/vfs"
 )
 
 const (
@@ -52,59 +59,241 @@ const (
 	TypeEventBridgeRule         = "eventbridge-rule"
 	TypeLoadBalancer            = "load-balancer"
 	TypeTargetGroup             = "target-group"
+	TypeKMSAlias                = "kms-alias"
+	TypeKMSKey                  = "kms-key"
+	TypeS3Object                = "s3-object"
+	TypeLogGroup                = "log-group"
+	TypeEFSFileSystem           = "efs-file-system"
+	TypeEFSMountTarget          = "efs-mount-target"
 )
 
+// iamListConcurrency is the number of IAM roles or instance profiles whose tags are looked up at the same time
+var iamListConcurrency = 8
+
 type listFn func(fi.Cloud, string, string) ([]*resources.Resource, error)
 
+// ownedListFn is a listFn that matches the cluster tags, including the additional ownership tag keys
+type ownedListFn func(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error)
+
+// withOwnershipTagKeys binds the additional ownership tag keys to the lister
+func withOwnershipTagKeys(fn ownedListFn, ownershipTagKeys []string) listFn {
+	return func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+		return fn(cloud, vpcID, clusterName, ownershipTagKeys)
+	}
+}
+
+// contextListFn is a listFn that stops listing when the context is cancelled
+type contextListFn func(ctx context.Context, cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error)
+
+// iamListFn lists the cluster's IAM resources, scoped server-side to those under an IAM path prefix
+type iamListFn func(ctx context.Context, cloud fi.Cloud, clusterName, pathPrefix string) ([]*resources.Resource, error)
+
+// typedListFn is a listFn with the types of the resources that it lists, so that it only runs if they are wanted
+type typedListFn struct {
+	types []string
+	fn    listFn
+	// ctxFn is used instead of fn by the listers that take a context
+	ctxFn contextListFn
+}
+
+// list runs the lister, unless the context is already done
+func (f typedListFn) list(ctx context.Context, cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.ctxFn != nil {
+		return f.ctxFn(ctx, cloud, vpcID, clusterName)
+	}
+	return f.fn(cloud, vpcID, clusterName)
+}
+
+// ListResourcesAWS lists the cluster's resources, restricted to the types in clusterInfo.AWSResourceTypes if set
 func ListResourcesAWS(cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo) (map[string]*resources.Resource, error) {
+	return ListResourcesAWSWithContext(context.Background(), cloud, clusterInfo)
+}
+
+// ListResourcesAWSWithContext is ListResourcesAWS, giving up when the context is cancelled or its deadline passes
+func ListResourcesAWSWithContext(ctx context.Context, cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo) (map[string]*resources.Resource, error) {
+	return ListResourcesFilteredWithContext(ctx, cloud, clusterInfo, clusterInfo.AWSResourceTypes)
+}
+
+// ListResourcesFiltered lists the cluster's resources of the given types (e.g. "route-table", "iam-role"),
+// only calling the listers that can return them; if types is empty, all the cluster's resources are listed.
+func ListResourcesFiltered(cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo, types []string) (map[string]*resources.Resource, error) {
+	return ListResourcesFilteredWithContext(context.Background(), cloud, clusterInfo, types)
+}
+
+// ListResourcesFilteredWithContext is ListResourcesFiltered, giving up when the context is cancelled or its deadline passes.
+// Cancellation aborts the whole listing, rather than being recorded as the failure of a service.
+func ListResourcesFilteredWithContext(ctx context.Context, cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo, types []string) (map[string]*resources.Resource, error) {
 	clusterName := clusterInfo.Name
 	clusterUsesNoneDNS := clusterInfo.UsesNoneDNS
+	ownershipTagKeys := clusterInfo.OwnershipTagKeys
+
+	// Several listers describe the route tables, so we only describe them once for this listing
+	cloud = newRouteTableCachingCloud(cloud)
 
 	resourceTrackers := make(map[string]*resources.Resource)
 
 	// These are the functions that are used for looking up
 	// cluster resources by their tags.
-	listFunctions := []listFn{
+	// The EC2 listers are the core of the cluster (and the later lookups depend on them), so their failure aborts.
+	listFunctions := []typedListFn{
 		// EC2
-		ListAutoScalingGroups,
-		ListInstances,
-		ListKeypairs,
-		ListSecurityGroups,
-		ListVolumes,
+		{types: []string{ec2.ResourceTypeInstance}, fn: ListInstances},
+		{types: []string{"keypair"}, fn: ListKeypairs},
+		{types: []string{ec2.ResourceTypeSecurityGroup}, fn: withOwnershipTagKeys(ListSecurityGroups, ownershipTagKeys)},
+		{types: []string{"volume", TypeElasticIp}, ctxFn: ListVolumesWithContext},
+		{types: []string{TypeElasticIp}, fn: withOwnershipTagKeys(ListElasticIPs, ownershipTagKeys)},
 		// EC2 VPC
-		ListDhcpOptions,
-		ListInternetGateways,
-		ListEgressOnlyInternetGateways,
-		ListRouteTables,
-		ListSubnets,
-		ListENIs,
-		// ELBs
-		ListELBs,
-		ListELBV2s,
-		ListTargetGroups,
-		// IAM
-		ListIAMInstanceProfiles,
-		ListIAMRoles,
-		ListIAMOIDCProviders,
-		// SQS
-		ListSQSQueues,
-		// EventBridge
-		ListEventBridgeRules,
+		{types: []string{"dhcp-options"}, fn: withOwnershipTagKeys(ListDhcpOptions, ownershipTagKeys)},
+		{types: []string{"internet-gateway"}, fn: ListInternetGateways},
+		{types: []string{"egress-only-internet-gateway"}, fn: ListEgressOnlyInternetGateways},
+		// The NAT gateways linked to our route tables (and their elastic IPs) are found from the route tables
+		{types: []string{ec2.ResourceTypeRouteTable, TypeNatGateway, TypeElasticIp}, ctxFn: func(ctx context.Context, cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+			return ListRouteTablesMultiCluster(ctx, cloud, vpcID, clusterName, clusterInfo.ListOptions)
+		}},
+		{types: []string{ec2.ResourceTypeSubnet, TypeElasticIp, TypeNatGateway}, fn: ListSubnets},
+	}
+
+	// These are the listers of the other AWS services, by service.
+	// If a service is unavailable, we record the failure and carry on with the other services,
+	// so that (for example) an unreachable IAM endpoint doesn't prevent the EC2 resources from being deleted.
+	serviceListFunctions := map[string][]typedListFn{
+		// The EC2 resources that aren't part of the core of the cluster are isolated in the same way, each on its own:
+		// older IAM policies may not permit describing them, which mustn't prevent the rest of the cluster from being deleted.
+		"ec2/spot-instances-request": {
+			{types: []string{ec2.ResourceTypeSpotInstancesRequest}, fn: func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+				return ListSpotInstanceRequests(cloud, clusterName, ownershipTagKeys)
+			}},
+		},
+		"ec2/snapshot": {
+			{types: []string{ec2.ResourceTypeSnapshot}, fn: func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+				return ListEBSSnapshots(cloud, clusterName, ownershipTagKeys)
+			}},
+		},
+		"ec2/network-interface": {
+			{types: []string{ec2.ResourceTypeNetworkInterface}, fn: withOwnershipTagKeys(ListNetworkInterfaces, ownershipTagKeys)},
+		},
+		"ec2/vpc-endpoint": {
+			{types: []string{ec2.ResourceTypeVpcEndpoint}, fn: withOwnershipTagKeys(ListVPCEndpoints, ownershipTagKeys)},
+		},
+		"ec2/client-vpn-endpoint": {
+			{types: []string{ec2.ResourceTypeClientVpnEndpoint}, fn: withOwnershipTagKeys(ListClientVPNEndpoints, ownershipTagKeys)},
+		},
+		"ec2/prefix-list": {
+			{types: []string{ec2.ResourceTypePrefixList}, fn: func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+				return ListManagedPrefixLists(cloud, clusterName, ownershipTagKeys)
+			}},
+		},
+		"ec2/vpc-peering-connection": {
+			{types: []string{ec2.ResourceTypeVpcPeeringConnection}, fn: func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+				return ListVPCPeeringConnections(cloud, clusterName, ownershipTagKeys)
+			}},
+		},
+		"ec2/transit-gateway-attachment": {
+			{types: []string{ec2.ResourceTypeTransitGatewayAttachment}, fn: func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+				return ListTransitGatewayAttachments(cloud, clusterName, ownershipTagKeys)
+			}},
+		},
+		"autoscaling": {
+			{types: []string{"autoscaling-group"}, fn: ListAutoScalingGroups},
+		},
+		"elasticloadbalancing": {
+			{types: []string{TypeLoadBalancer}, fn: func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+				return ListELBsMatchingTags(cloud, clusterName, clusterInfo.AWSELBTags)
+			}},
+			{types: []string{TypeLoadBalancer}, fn: ListELBV2s},
+			{types: []string{TypeTargetGroup}, fn: ListTargetGroups},
+		},
+		"iam": {
+			{types: []string{"oidc-provider"}, fn: func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+				return ListIAMOIDCProviders(cloud, clusterName, clusterInfo.ServiceAccountIssuer)
+			}},
+		},
+		"sqs": {
+			{types: []string{"sqs"}, fn: withOwnershipTagKeys(ListSQSQueues, ownershipTagKeys)},
+		},
+		"events": {
+			{types: []string{TypeEventBridgeRule}, fn: withOwnershipTagKeys(ListEventBridgeRules, ownershipTagKeys)},
+		},
+		"kms": {
+			{types: []string{TypeKMSAlias, TypeKMSKey}, ctxFn: func(ctx context.Context, cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+				return ListKMSKeys(ctx, cloud, clusterName, ownershipTagKeys)
+			}},
+		},
+		"elasticfilesystem": {
+			{types: []string{TypeEFSFileSystem, TypeEFSMountTarget}, fn: func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+				return ListEFSFileSystems(cloud, clusterName, ownershipTagKeys)
+			}},
+		},
+		"logs": {
+			{types: []string{TypeLogGroup}, fn: func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+				return ListCloudWatchLogGroups(cloud, clusterName, ownershipTagKeys)
+			}},
+		},
+	}
+
+	iamListFunctions := []struct {
+		resourceType string
+		fn           iamListFn
+	}{
+		{resourceType: "iam-instance-profile", fn: ListIAMInstanceProfilesWithPathPrefix},
+		{resourceType: "iam-role", fn: ListIAMRolesWithPathPrefix},
+	}
+	for _, iamListFunction := range iamListFunctions {
+		serviceListFunctions["iam"] = append(serviceListFunctions["iam"], typedListFn{types: []string{iamListFunction.resourceType}, ctxFn: func(ctx context.Context, cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+			if clusterInfo.AWSSkipIAM {
+				klog.V(2).Infof("Not listing %s resources, as IAM is managed outside kops", iamListFunction.resourceType)
+				return nil, nil
+			}
+			return iamListFunction.fn(ctx, cloud, clusterName, clusterInfo.AWSIAMPathPrefix)
+		}})
 	}
 
 	if !dns.IsGossipClusterName(clusterName) && !clusterUsesNoneDNS {
 		// Route 53
-		listFunctions = append(listFunctions, ListRoute53Records)
+		serviceListFunctions["route53"] = []typedListFn{{types: []string{"route53-record"}, fn: ListRoute53Records}}
+	}
+
+	if clusterInfo.DiscoveryStore != "" {
+		// S3
+		serviceListFunctions["s3"] = []typedListFn{{types: []string{TypeS3Object}, fn: func(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+			discoveryStore, err := vfs.Context.BuildVfsPath(clusterInfo.DiscoveryStore)
+			if err != nil {
+				return nil, err
+			}
+			return ListS3DiscoveryObjects(cloud, clusterName, discoveryStore)
+		}}}
 	}
 
 	if featureflag.Spotinst.Enabled() {
 		// Spotinst resources
-		listFunctions = append(listFunctions, ListSpotinstResources)
+		serviceListFunctions["spotinst"] = []typedListFn{{types: []string{string(spotinst.ResourceTypeInstanceGroup), string(spotinst.ResourceTypeLaunchSpec)}, fn: ListSpotinstResources}}
+	}
+
+	// The VPC is always listed, because the other listers need its id, and these types are found by the lookups below
+	knownTypes := sets.NewString(ec2.ResourceTypeVpc, TypeAutoscalingScheduledAction, TypeAutoscalingLaunchConfig)
+	for _, fn := range listFunctions {
+		knownTypes.Insert(fn.types...)
+	}
+	for _, fns := range serviceListFunctions {
+		for _, fn := range fns {
+			knownTypes.Insert(fn.types...)
+		}
+	}
+	wantedTypes := sets.NewString(types...)
+	if unknown := wantedTypes.Difference(knownTypes); unknown.Len() != 0 {
+		return nil, fmt.Errorf("unknown resource types %s; known types are %s", strings.Join(unknown.List(), ", "), strings.Join(knownTypes.List(), ", "))
+	}
+	// wanted returns true if any of the resource types are wanted
+	wanted := func(resourceTypes ...string) bool {
+		return wantedTypes.Len() == 0 || wantedTypes.HasAny(resourceTypes...)
 	}
 
 	var vpcID string
 	{
-		r, err := ListVPCs(cloud, clusterName)
+		r, err := ListVPCs(cloud, clusterName, ownershipTagKeys)
 		if err != nil {
 			return nil, err
 		}
@@ -116,7 +305,10 @@ func ListResourcesAWS(cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo) (
 	}
 
 	for _, fn := range listFunctions {
-		rt, err := fn(cloud, vpcID, clusterName)
+		if !wanted(fn.types...) {
+			continue
+		}
+		rt, err := fn.list(ctx, cloud, vpcID, clusterName)
 		if err != nil {
 			return nil, err
 		}
@@ -125,40 +317,60 @@ func ListResourcesAWS(cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo) (
 		}
 	}
 
-	{
-		// Gateways weren't tagged in kube-up
-		// If we are deleting the VPC, we should delete the attached gateway
-		// (no real reason not to; easy to recreate; no real state etc)
-
-		gateways, err := DescribeInternetGatewaysIgnoreTags(cloud)
-		if err != nil {
-			return nil, err
+	serviceFailures := make(map[string]error)
+	for service, fns := range serviceListFunctions {
+		serviceTrackers := make(map[string]*resources.Resource)
+		for _, fn := range fns {
+			if !wanted(fn.types...) {
+				continue
+			}
+			rt, err := fn.list(ctx, cloud, vpcID, clusterName)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, err
+				}
+				klog.Warningf("error listing %s resources; skipping the service: %v", service, err)
+				serviceFailures[service] = err
+				break
+			}
+			for _, t := range rt {
+				serviceTrackers[t.Type+":"+t.ID] = t
+			}
 		}
+		if serviceFailures[service] != nil {
+			// Don't delete some of the resources of a service, but not others
+			continue
+		}
+		for k, t := range serviceTrackers {
+			resourceTrackers[k] = t
+		}
+	}
 
-		for _, igw := range gateways {
-			for _, attachment := range igw.Attachments {
-				vpcID := aws.ToString(attachment.VpcId)
-				igwID := aws.ToString(igw.InternetGatewayId)
-				if vpcID == "" || igwID == "" {
-					continue
-				}
-				vpc := resourceTrackers["vpc:"+vpcID]
-				if vpc != nil && resourceTrackers["internet-gateway:"+igwID] == nil {
-					resourceTrackers["internet-gateway:"+igwID] = &resources.Resource{
-						Name:    FindName(igw.Tags),
-						ID:      igwID,
-						Obj:     igw,
-						Type:    "internet-gateway",
-						Dumper:  DumpInternetGateway,
-						Deleter: DeleteInternetGateway,
-						Shared:  vpc.Shared, // Shared iff the VPC is shared
-					}
-				}
+	if clusterInfo.AWSOutpostARN != "" {
+		FilterResourcesByOutpost(resourceTrackers, clusterInfo.AWSOutpostARN)
+	}
+
+	if wanted(TypeAutoscalingScheduledAction) {
+		// Scheduled actions aren't tagged, and may outlive their autoscaling group
+		if serviceFailures["autoscaling"] == nil {
+			r, err := ListASGOrphans(cloud, clusterName)
+			if err != nil {
+				klog.Warningf("error listing autoscaling resources; skipping the service: %v", err)
+				serviceFailures["autoscaling"] = err
+			}
+			for _, t := range r {
+				resourceTrackers[t.Type+":"+t.ID] = t
 			}
 		}
 	}
 
-	{
+	if wanted("internet-gateway") {
+		if err := addUntaggedInternetGateways(cloud, clusterName, resourceTrackers); err != nil {
+			return nil, err
+		}
+	}
+
+	if wanted(TypeAutoscalingLaunchConfig) {
 		// We delete a launch configuration if it is bound to one of the tagged security groups
 		securityGroups := sets.NewString()
 		for k := range resourceTrackers {
@@ -168,20 +380,23 @@ func ListResourcesAWS(cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo) (
 			id := strings.TrimPrefix(k, "security-group:")
 			securityGroups.Insert(id)
 		}
-		lts, err := FindAutoScalingLaunchTemplates(cloud, clusterName)
+		lts, err := ListLaunchTemplates(cloud, clusterName)
 		if err != nil {
 			return nil, err
 		}
 		for _, t := range lts {
 			resourceTrackers[t.Type+":"+t.ID] = t
 		}
+		blockLaunchTemplatesByName(resourceTrackers, lts)
 	}
 
-	if err := addUntaggedRouteTables(cloud, clusterName, resourceTrackers); err != nil {
-		return nil, err
+	if wanted(ec2.ResourceTypeRouteTable, TypeNatGateway, TypeElasticIp) {
+		if err := addUntaggedRouteTables(cloud, clusterName, resourceTrackers, clusterInfo.AWSUntaggedRouteTableMinAge, clusterInfo.AWSUntaggedRouteTableVPCs); err != nil {
+			return nil, err
+		}
 	}
 
-	{
+	if wanted(TypeNatGateway, TypeElasticIp) {
 		// We delete a NAT gateway if it is linked to our route table
 		routeTableIds := make(map[string]*resources.Resource)
 		for _, resource := range resourceTrackers {
@@ -191,7 +406,7 @@ func ListResourcesAWS(cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo) (
 			id := resource.ID
 			routeTableIds[id] = resource
 		}
-		natGateways, err := FindNatGateways(cloud, routeTableIds, clusterName)
+		natGateways, err := FindNatGateways(cloud, routeTableIds, clusterName, ownershipTagKeys)
 		if err != nil {
 			return nil, err
 		}
@@ -201,14 +416,50 @@ func ListResourcesAWS(cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo) (
 		}
 	}
 
+	blockKMSResourcesByVolumes(resourceTrackers)
+
+	FilterEKSManagedResources(resourceTrackers)
+
+	pruned := sets.NewString()
 	for k, t := range resourceTrackers {
-		if t.Done {
+		if t.Done || !wanted(t.Type) {
+			pruned.Insert(k)
 			delete(resourceTrackers, k)
+			continue
 		}
+		retryDeletes(t)
+		wrapDeletionErrors(t)
+	}
+	// The resources we pruned, or didn't list because of their type, aren't deleted by this run,
+	// so nothing should wait for them to be deleted
+	ignored := func(dep string) bool {
+		depType, _, _ := strings.Cut(dep, ":")
+		return pruned.Has(dep) || !wanted(depType)
+	}
+	for _, t := range resourceTrackers {
+		t.Blocks = withoutDependencies(t.Blocks, ignored)
+		t.Blocked = withoutDependencies(t.Blocked, ignored)
+	}
+
+	if len(serviceFailures) != 0 {
+		return resourceTrackers, &resources.ServiceFailuresError{Failures: serviceFailures}
 	}
 	return resourceTrackers, nil
 }
 
+// withoutDependencies returns the dependencies that aren't ignored
+func withoutDependencies(dependencies []string, ignored func(dep string) bool) []string {
+	var kept []string
+	for _, dep := range dependencies {
+		if ignored(dep) {
+			klog.V(4).Infof("ignoring dependency on %q, which is not being deleted", dep)
+			continue
+		}
+		kept = append(kept, dep)
+	}
+	return kept
+}
+
 func BuildEC2Filters(cloud fi.Cloud) []*ec2.Filter {
 	awsCloud := cloud.(awsup.AWSCloud)
 	tags := awsCloud.Tags()
@@ -221,10 +472,13 @@ func BuildEC2Filters(cloud fi.Cloud) []*ec2.Filter {
 	return filters
 }
 
-func addUntaggedRouteTables(cloud awsup.AWSCloud, clusterName string, resources map[string]*resources.Resource) error {
+func addUntaggedRouteTables(cloud awsup.AWSCloud, clusterName string, resources map[string]*resources.Resource, minAge time.Duration, vpcIDs []string) error {
 	// We sometimes have trouble tagging the route table (eventual consistency, e.g. #597)
 	// If we are deleting the VPC, we should delete the route table
 	// (no real reason not to; easy to recreate; no real state etc)
+	// A very new route table may belong to an operation by another tool that is still in progress,
+	// so if minAge is set we leave alone the route tables we know to be younger than that.
+	// In clusters with several VPCs, vpcIDs (if set) restricts this to the route tables in those VPCs.
 	routeTables, err := DescribeRouteTablesIgnoreTags(cloud)
 	if err != nil {
 		return err
@@ -242,6 +496,11 @@ func addUntaggedRouteTables(cloud awsup.AWSCloud, clusterName string, resources
 			continue
 		}
 
+		if len(vpcIDs) != 0 && !slices.Contains(vpcIDs, vpcID) {
+			klog.V(4).Infof("ignoring route table %q in VPC %q, which isn't one of %v", rtID, vpcID, vpcIDs)
+			continue
+		}
+
 		clusterTag, _ := awsup.FindEC2Tag(rt.Tags, awsup.TagClusterName)
 		if clusterTag != "" && clusterTag != clusterName {
 			klog.Infof("Skipping route table in VPC, but with wrong cluster tag (%q)", clusterTag)
@@ -259,7 +518,15 @@ func addUntaggedRouteTables(cloud awsup.AWSCloud, clusterName string, resources
 			continue
 		}
 
-		t := buildTrackerForRouteTable(rt, clusterName)
+		if minAge > 0 {
+			if created, found := routeTableCreationTime(rt); found && time.Since(created) < minAge {
+				klog.Infof("Skipping untagged route table %q, which was created less than %v ago", rtID, minAge)
+				continue
+			}
+		}
+
+		// Untagged route tables carry no ownership tags, so the additional keys don't matter
+		t := buildTrackerForRouteTable(rt, clusterName, nil)
 		if resources[t.Type+":"+t.ID] == nil {
 			resources[t.Type+":"+t.ID] = t
 		}
@@ -268,33 +535,92 @@ func addUntaggedRouteTables(cloud awsup.AWSCloud, clusterName string, resources
 	return nil
 }
 
-func matchesElbTags(tags map[string]string, actual []elbtypes.Tag) bool {
-	for k, v := range tags {
-		found := false
-		for _, a := range actual {
-			if aws.ToString(a.Key) == k {
-				if aws.ToString(a.Value) == v {
-					found = true
-					break
-				}
+func addUntaggedInternetGateways(cloud awsup.AWSCloud, clusterName string, resourceTrackers map[string]*resources.Resource) error {
+	// Gateways weren't tagged in kube-up
+	// If we are deleting the VPC, we should delete the attached gateway
+	// (no real reason not to; easy to recreate; no real state etc)
+	gateways, err := DescribeInternetGatewaysIgnoreTags(cloud)
+	if err != nil {
+		return err
+	}
+
+	for _, igw := range gateways {
+		igwID := aws.ToString(igw.InternetGatewayId)
+		if igwID == "" || resourceTrackers["internet-gateway:"+igwID] != nil {
+			continue
+		}
+
+		clusterTag, _ := awsup.FindEC2Tag(igw.Tags, awsup.TagClusterName)
+		if clusterTag != "" && clusterTag != clusterName {
+			klog.Infof("Skipping internet gateway %q attached to VPC, but with wrong cluster tag (%q)", igwID, clusterTag)
+			continue
+		}
+
+		for _, attachment := range igw.Attachments {
+			vpcID := aws.ToString(attachment.VpcId)
+			if vpcID == "" {
+				continue
+			}
+			vpc := resourceTrackers["vpc:"+vpcID]
+			if vpc == nil {
+				// Not deleting this VPC; ignore
+				continue
+			}
+
+			resourceTrackers["internet-gateway:"+igwID] = &resources.Resource{
+				Name:    FindName(igw.Tags),
+				ID:      igwID,
+				Obj:     igw,
+				Type:    "internet-gateway",
+				Dumper:  DumpInternetGateway,
+				Deleter: DeleteInternetGateway,
+				Actions: deleteInternetGatewayActions,
+				Shared:  vpc.Shared, // Shared iff the VPC is shared
+				Blocks:  []string{"vpc:" + vpcID},
 			}
+			break
 		}
-		if !found {
-			return false
+	}
+
+	return nil
+}
+
+// tagMatchOptions controls how the values of the wanted tags are matched; by default they must be equal
+type tagMatchOptions struct {
+	// KeyOnlyIfEmpty makes a wanted tag with an empty value match any value, i.e. only the key must exist
+	KeyOnlyIfEmpty bool
+	// Glob makes a wanted value a pattern in path.Match syntax, e.g. "owned-*" matches "owned-2024".
+	// A value without any of the special characters *, ? and [ still has to be equal, and an invalid pattern only matches itself.
+	Glob bool
+}
+
+// matchesValue returns true if the actual value of a tag matches the wanted value
+func (o tagMatchOptions) matchesValue(want, actual string) bool {
+	if want == "" && o.KeyOnlyIfEmpty {
+		return true
+	}
+	if o.Glob {
+		if matched, err := path.Match(want, actual); err == nil {
+			return matched
 		}
 	}
-	return true
+	return want == actual
 }
 
-func matchesElbV2Tags(tags map[string]string, actual []elbv2types.Tag) bool {
-	for k, v := range tags {
+// matchesTags returns true if each of the wanted tags is among the actual tags, with the same value.
+// key returns the key and value of an actual tag, so that the tags of any AWS service can be matched.
+func matchesTags[T any](want map[string]string, actual []T, key func(T) (string, string)) bool {
+	return matchesTagsWithOptions(want, actual, key, tagMatchOptions{})
+}
+
+// matchesTagsWithOptions is matchesTags, matching the values as set by the options
+func matchesTagsWithOptions[T any](want map[string]string, actual []T, key func(T) (string, string), options tagMatchOptions) bool {
+	for k, v := range want {
 		found := false
 		for _, a := range actual {
-			if aws.ToString(a.Key) == k {
-				if aws.ToString(a.Value) == v {
-					found = true
-					break
-				}
+			if ak, av := key(a); ak == k && options.matchesValue(v, av) {
+				found = true
+				break
 			}
 		}
 		if !found {
@@ -304,22 +630,54 @@ func matchesElbV2Tags(tags map[string]string, actual []elbv2types.Tag) bool {
 	return true
 }
 
+func matchesElbTags(tags map[string]string, actual []elbtypes.Tag) bool {
+	return matchesElbTagsWithOptions(tags, actual, tagMatchOptions{})
+}
+
+// matchesElbTagsWithOptions is matchesElbTags, matching the values as set by the options
+func matchesElbTagsWithOptions(tags map[string]string, actual []elbtypes.Tag, options tagMatchOptions) bool {
+	return matchesTagsWithOptions(tags, actual, func(t elbtypes.Tag) (string, string) {
+		return aws.ToString(t.Key), aws.ToString(t.Value)
+	}, options)
+}
+
+func matchesElbV2Tags(tags map[string]string, actual []elbv2types.Tag) bool {
+	return matchesTags(tags, actual, func(t elbv2types.Tag) (string, string) {
+		return aws.ToString(t.Key), aws.ToString(t.Value)
+	})
+}
+
 func matchesIAMTags(tags map[string]string, actual []iamtypes.Tag) bool {
-	for k, v := range tags {
-		found := false
-		for _, a := range actual {
-			if aws.ToString(a.Key) == k {
-				if aws.ToString(a.Value) == v {
-					found = true
-					break
-				}
-			}
-		}
-		if !found {
-			return false
+	return matchesTags(tags, actual, func(t iamtypes.Tag) (string, string) {
+		return aws.ToString(t.Key), aws.ToString(t.Value)
+	})
+}
+
+// hasSharedIAMTag returns true if the IAM tags mark the entity as shared with the cluster, rather than owned by it
+func hasSharedIAMTag(actual []iamtypes.Tag, clusterName string) bool {
+	tagKey := "kubernetes.io/cluster/" + clusterName
+	for _, a := range actual {
+		if aws.ToString(a.Key) == tagKey && aws.ToString(a.Value) == "shared" {
+			return true
 		}
 	}
-	return true
+	return false
+}
+
+// matchesOIDCIssuerURL returns true if the URL of an IAM OIDC provider is the issuer URL.
+// IAM returns the URL of a provider without its scheme.
+func matchesOIDCIssuerURL(issuerURL, providerURL string) bool {
+	if issuerURL == "" {
+		return false
+	}
+	issuer := strings.TrimSuffix(strings.TrimPrefix(issuerURL, "https://"), "/")
+	provider := strings.TrimSuffix(strings.TrimPrefix(providerURL, "https://"), "/")
+	return issuer == provider
+}
+
+// deleteInstancesActions are the AWS API actions invoked by DeleteInstances
+var deleteInstancesActions = []string{
+	"ec2:TerminateInstances",
 }
 
 func DeleteInstances(cloud fi.Cloud, t []*resources.Resource) error {
@@ -339,10 +697,10 @@ func DeleteInstances(cloud fi.Cloud, t []*resources.Resource) error {
 		ids = []*string{}
 		_, err := c.EC2().TerminateInstances(request)
 		if err != nil {
-			if awsup.AWSErrorCode(err) == "InvalidInstanceID.NotFound" {
+			if isNotFoundErr(err) {
 				klog.V(2).Infof("Got InvalidInstanceID.NotFound error terminating instances; will treat as already terminated")
 			} else {
-				return fmt.Errorf("error terminating instances: %v", err)
+				return fmt.Errorf("error terminating instances: %w", err)
 			}
 		}
 	}
@@ -372,6 +730,7 @@ func ListInstances(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Reso
 					ID:           id,
 					Type:         ec2.ResourceTypeInstance,
 					GroupDeleter: DeleteInstances,
+					Actions:      deleteInstancesActions,
 					GroupKey:     fi.ValueOf(instance.SubnetId),
 					Dumper:       DumpInstance,
 					Obj:          instance,
@@ -534,6 +893,42 @@ func DumpInstance(op *resources.DumpOperation, r *resources.Resource) error {
 	return nil
 }
 
+// findClusterInstances returns the ids of those of the instances that ListInstances collects for the cluster,
+// so that the resources that depend on them can refer to them
+func findClusterInstances(c awsup.AWSCloud, vpcID string, instanceIDs []string) (sets.String, error) {
+	clusterInstances := sets.NewString()
+	// EC2 accepts at most 200 values per filter
+	for len(instanceIDs) != 0 {
+		n := min(len(instanceIDs), 200)
+		filters := BuildEC2Filters(c)
+		filters = append(filters, awsup.NewEC2Filter("vpc-id", vpcID))
+		filters = append(filters, awsup.NewEC2Filter("instance-state-name", ec2.InstanceStateNameRunning))
+		filters = append(filters, awsup.NewEC2Filter("instance-id", instanceIDs[:n]...))
+		instanceIDs = instanceIDs[n:]
+
+		request := &ec2.DescribeInstancesInput{
+			Filters: filters,
+		}
+		err := c.EC2().DescribeInstancesPages(request, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.Reservations {
+				for _, instance := range reservation.Instances {
+					clusterInstances.Insert(aws.ToString(instance.InstanceId))
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error describing attached instances: %w", err)
+		}
+	}
+	return clusterInstances, nil
+}
+
+// deleteVolumeActions are the AWS API actions invoked by DeleteVolume
+var deleteVolumeActions = []string{
+	"ec2:DeleteVolume",
+}
+
 func DeleteVolume(cloud fi.Cloud, r *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
@@ -545,28 +940,63 @@ func DeleteVolume(cloud fi.Cloud, r *resources.Resource) error {
 	}
 	_, err := c.EC2().DeleteVolume(request)
 	if err != nil {
-		if awsup.AWSErrorCode(err) == "InvalidVolume.NotFound" {
+		if isNotFoundErr(err) {
 			klog.V(2).Infof("Got InvalidVolume.NotFound error deleting Volume %q; will treat as already-deleted", id)
 			return nil
 		}
 		if IsDependencyViolation(err) {
 			return err
 		}
-		return fmt.Errorf("error deleting Volume %q: %v", id, err)
+		return fmt.Errorf("error deleting Volume %q: %w", id, err)
+	}
+	return nil
+}
+
+// DumpVolume dumps the volume, surfacing the KMS key it is encrypted with
+func DumpVolume(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["type"] = ec2.ResourceTypeVolume
+	data["raw"] = r.Obj
+	if volume, ok := r.Obj.(*ec2.Volume); ok && aws.ToString(volume.KmsKeyId) != "" {
+		data["kmsKeyId"] = aws.ToString(volume.KmsKeyId)
 	}
+	op.Dump.Resources = append(op.Dump.Resources, data)
 	return nil
 }
 
 func ListVolumes(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+	return ListVolumesWithContext(context.Background(), cloud, vpcID, clusterName)
+}
+
+// ListVolumesWithContext is ListVolumes, giving up when the context is cancelled or its deadline passes
+func ListVolumesWithContext(ctx context.Context, cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
 	c := cloud.(awsup.AWSCloud)
 
-	volumes, err := DescribeVolumes(cloud)
+	volumes, err := DescribeVolumes(ctx, cloud)
 	if err != nil {
 		return nil, err
 	}
 	var resourceTrackers []*resources.Resource
 
+	// A volume can't be deleted while it is attached to an instance.
+	// Volumes attached to the cluster's instances are deleted once the instances are gone,
+	// but those attached to other instances would fail with VolumeInUse, so we leave them alone.
+	attachedInstances := sets.NewString()
+	for _, volume := range volumes {
+		for _, attachment := range volume.Attachments {
+			if aws.ToString(attachment.State) != ec2.VolumeAttachmentStateDetached {
+				attachedInstances.Insert(aws.ToString(attachment.InstanceId))
+			}
+		}
+	}
+	clusterInstances, err := findClusterInstances(c, vpcID, attachedInstances.List())
+	if err != nil {
+		return nil, err
+	}
+
 	elasticIPs := make(map[string]bool)
+volumes:
 	for _, volume := range volumes {
 		id := aws.ToString(volume.VolumeId)
 
@@ -581,19 +1011,31 @@ func ListVolumes(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resour
 			continue
 		}
 
+		var blocked []string
+		for _, attachment := range volume.Attachments {
+			if aws.ToString(attachment.State) == ec2.VolumeAttachmentStateDetached {
+				continue
+			}
+			instanceID := aws.ToString(attachment.InstanceId)
+			if !clusterInstances.Has(instanceID) {
+				klog.Warningf("not deleting volume %q, which is attached to instance %q that isn't part of the cluster", id, instanceID)
+				continue volumes
+			}
+			blocked = append(blocked, ec2.ResourceTypeInstance+":"+instanceID)
+		}
+
 		resourceTracker := &resources.Resource{
 			Name:    FindName(volume.Tags),
 			ID:      id,
 			Type:    "volume",
 			Deleter: DeleteVolume,
+			Actions: deleteVolumeActions,
+			Dumper:  DumpVolume,
 			Shared:  HasSharedTag(ec2.ResourceTypeVolume+":"+id, volume.Tags, clusterName),
+			Obj:     volume,
+			Blocked: blocked,
 		}
 
-		var blocks []string
-		// blocks = append(blocks, "vpc:" + aws.ValueOf(rt.VpcId))
-
-		resourceTracker.Blocks = blocks
-
 		resourceTrackers = append(resourceTrackers, resourceTracker)
 
 		// Check for an elastic IP tag
@@ -631,7 +1073,7 @@ func ListVolumes(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resour
 	return resourceTrackers, nil
 }
 
-func DescribeVolumes(cloud fi.Cloud) ([]*ec2.Volume, error) {
+func DescribeVolumes(ctx context.Context, cloud fi.Cloud) ([]*ec2.Volume, error) {
 	c := cloud.(awsup.AWSCloud)
 
 	var volumes []*ec2.Volume
@@ -641,7 +1083,7 @@ func DescribeVolumes(cloud fi.Cloud) ([]*ec2.Volume, error) {
 		Filters: BuildEC2Filters(c),
 	}
 
-	err := c.EC2().DescribeVolumesPages(request, func(p *ec2.DescribeVolumesOutput, lastPage bool) bool {
+	err := c.EC2().DescribeVolumesPagesWithContext(ctx, request, func(p *ec2.DescribeVolumesOutput, lastPage bool) bool {
 		volumes = append(volumes, p.Volumes...)
 		return true
 	})
@@ -652,6 +1094,11 @@ func DescribeVolumes(cloud fi.Cloud) ([]*ec2.Volume, error) {
 	return volumes, nil
 }
 
+// deleteKeypairActions are the AWS API actions invoked by DeleteKeypair
+var deleteKeypairActions = []string{
+	"ec2:DeleteKeyPair",
+}
+
 func DeleteKeypair(cloud fi.Cloud, r *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
@@ -663,7 +1110,11 @@ func DeleteKeypair(cloud fi.Cloud, r *resources.Resource) error {
 	}
 	_, err := c.EC2().DeleteKeyPair(request)
 	if err != nil {
-		return fmt.Errorf("error deleting KeyPair %q: %v", id, err)
+		if isNotFoundErr(err) {
+			klog.V(2).Infof("Got %s error deleting KeyPair %q; will treat as already-deleted", awsup.AWSErrorCode(err), id)
+			return nil
+		}
+		return fmt.Errorf("error deleting KeyPair %q: %w", id, err)
 	}
 	return nil
 }
@@ -701,6 +1152,7 @@ func ListKeypairs(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resou
 			ID:      id,
 			Type:    "keypair",
 			Deleter: DeleteKeypair,
+			Actions: deleteKeypairActions,
 		}
 
 		resourceTrackers = append(resourceTrackers, resourceTracker)
@@ -709,6 +1161,11 @@ func ListKeypairs(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resou
 	return resourceTrackers, nil
 }
 
+// deleteSubnetActions are the AWS API actions invoked by DeleteSubnet
+var deleteSubnetActions = []string{
+	"ec2:DeleteSubnet",
+}
+
 func DeleteSubnet(cloud fi.Cloud, tracker *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
@@ -720,13 +1177,22 @@ func DeleteSubnet(cloud fi.Cloud, tracker *resources.Resource) error {
 	}
 	_, err := c.EC2().DeleteSubnet(request)
 	if err != nil {
-		if awsup.AWSErrorCode(err) == "InvalidSubnetID.NotFound" {
+		if isNotFoundErr(err) {
 			klog.V(2).Infof("Got InvalidSubnetID.NotFound error deleting subnet %q; will treat as already-deleted", id)
 			return nil
 		} else if IsDependencyViolation(err) {
+			if deletedConcurrently("subnet:"+id, func() (bool, error) {
+				response, err := c.EC2().DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: []*string{&id}})
+				if err != nil {
+					return false, err
+				}
+				return len(response.Subnets) != 0, nil
+			}) {
+				return nil
+			}
 			return err
 		}
-		return fmt.Errorf("error deleting Subnet %q: %v", id, err)
+		return fmt.Errorf("error deleting Subnet %q: %w", id, err)
 	}
 	return nil
 }
@@ -743,15 +1209,27 @@ func ListSubnets(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resour
 	ownedElasticIPs := sets.NewString()
 	natGatewayIds := sets.NewString()
 	ownedNatGatewayIds := sets.NewString()
+	owners := &foreignOwnerChecker{cloud: c}
 	for _, subnet := range subnets {
 		subnetID := aws.ToString(subnet.SubnetId)
 
 		shared := HasSharedTag("subnet:"+subnetID, subnet.Tags, clusterName)
+		if !shared {
+			foreign, err := owners.IsForeign(subnet.OwnerId)
+			if err != nil {
+				return nil, err
+			}
+			if foreign {
+				klog.V(2).Infof("Subnet %q is owned by account %q; treating as shared", subnetID, aws.ToString(subnet.OwnerId))
+				shared = true
+			}
+		}
 		resourceTracker := &resources.Resource{
 			Name:    FindName(subnet.Tags),
 			ID:      subnetID,
 			Type:    ec2.ResourceTypeSubnet,
 			Deleter: DeleteSubnet,
+			Actions: deleteSubnetActions,
 			Dumper:  DumpSubnet,
 			Shared:  shared,
 			Obj:     subnet,
@@ -785,6 +1263,11 @@ func ListSubnets(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resour
 		}
 	}
 
+	// A NAT gateway must be deleted before the subnet that it lives in
+	if err := blockSubnetsOnNatGateways(c, resourceTrackers); err != nil {
+		return nil, err
+	}
+
 	// Associated Elastic IPs
 	if elasticIPs.Len() != 0 {
 		klog.V(2).Infof("Querying EC2 Elastic IPs")
@@ -799,7 +1282,7 @@ func ListSubnets(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resour
 			if !elasticIPs.Has(ip) {
 				continue
 			}
-			resourceTrackers = append(resourceTrackers, buildElasticIPResource(address, ownedElasticIPs.Has(ip), clusterName))
+			resourceTrackers = append(resourceTrackers, buildElasticIPResource(address, !ownedElasticIPs.Has(ip), clusterName))
 		}
 	}
 
@@ -808,15 +1291,11 @@ func ListSubnets(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resour
 	// Since we don't have tagging on the NGWs, we have to read the route tables
 	if natGatewayIds.Len() != 0 {
 
-		rtRequest := &ec2.DescribeRouteTablesInput{}
-		rtResponse, err := c.EC2().DescribeRouteTables(rtRequest)
-		if err != nil && awsup.AWSErrorCode(err) != "InvalidRouteTableID.NotFound" {
-			return nil, fmt.Errorf("error describing RouteTables: %v", err)
-		}
 		// sharedNgwIds is the set of IDs for shared NGWs, that we should not delete
 		sharedNgwIds := sets.NewString()
-		if rtResponse != nil {
-			for _, rt := range rtResponse.RouteTables {
+		rtRequest := &ec2.DescribeRouteTablesInput{}
+		err := c.EC2().DescribeRouteTablesPagesWithContext(context.TODO(), rtRequest, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+			for _, rt := range page.RouteTables {
 				for _, t := range rt.Tags {
 					k := aws.ToString(t.Key)
 					v := aws.ToString(t.Value)
@@ -826,6 +1305,10 @@ func ListSubnets(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resour
 					}
 				}
 			}
+			return true
+		})
+		if err != nil && awsup.AWSErrorCode(err) != "InvalidRouteTableID.NotFound" {
+			return nil, fmt.Errorf("error describing RouteTables: %v", err)
 		}
 
 		klog.V(2).Infof("Querying Nat Gateways")
@@ -846,47 +1329,358 @@ func ListSubnets(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resour
 			resourceTrackers = append(resourceTrackers, r)
 		}
 	}
-
-	return resourceTrackers, nil
+
+	return resourceTrackers, nil
+}
+
+// blockSubnetsOnNatGateways adds the NAT gateways in each of the subnets to the subnet's Blocked list,
+// whether or not the NAT gateway itself is deleted with the cluster
+func blockSubnetsOnNatGateways(c awsup.AWSCloud, subnets []*resources.Resource) error {
+	subnetsByID := make(map[string]*resources.Resource)
+	for _, subnet := range subnets {
+		subnetsByID[subnet.ID] = subnet
+	}
+	if len(subnetsByID) == 0 {
+		return nil
+	}
+
+	klog.V(2).Infof("Querying Nat Gateways in subnets")
+	request := &ec2.DescribeNatGatewaysInput{
+		Filter: []*ec2.Filter{awsup.NewEC2Filter("subnet-id", sets.StringKeySet(subnetsByID).List()...)},
+	}
+	err := c.EC2().DescribeNatGatewaysPages(request, func(p *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		for _, ngw := range p.NatGateways {
+			if aws.ToString(ngw.State) == ec2.NatGatewayStateDeleted {
+				continue
+			}
+			subnet := subnetsByID[aws.ToString(ngw.SubnetId)]
+			if subnet == nil {
+				continue
+			}
+			subnet.Blocked = append(subnet.Blocked, TypeNatGateway+":"+aws.ToString(ngw.NatGatewayId))
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("error describing NatGateways: %w", err)
+	}
+	return nil
+}
+
+func DescribeSubnets(cloud fi.Cloud) ([]*ec2.Subnet, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	klog.V(2).Infof("Listing EC2 subnets")
+	request := &ec2.DescribeSubnetsInput{
+		Filters: BuildEC2Filters(cloud),
+	}
+	response, err := c.EC2().DescribeSubnets(request)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subnets: %v", err)
+	}
+
+	return response.Subnets, nil
+}
+
+// deleteRouteTableActions are the AWS API actions invoked by DeleteRouteTable
+var deleteRouteTableActions = []string{
+	"ec2:DescribeRouteTables",
+	"ec2:DescribeSubnets",
+	"ec2:DescribeInstances",
+	"ec2:DisassociateRouteTable",
+	"ec2:DisableVgwRoutePropagation",
+	"ec2:DeleteRouteTable",
+}
+
+// DeleteRouteTable deletes the route table, but refuses to disassociate it from shared subnets,
+// because the other owners of a shared subnet may still depend on its routes,
+// and from subnets with running instances, because they would lose their routes.
+func DeleteRouteTable(cloud fi.Cloud, r *resources.Resource) error {
+	d := &routeTableDeleter{}
+	return d.Delete(cloud, r)
+}
+
+// DeleteRouteTableDisassociatingSharedSubnets deletes the route table, even if that disassociates it from shared subnets
+func DeleteRouteTableDisassociatingSharedSubnets(cloud fi.Cloud, r *resources.Resource) error {
+	d := &routeTableDeleter{disassociateSharedSubnets: true}
+	return d.Delete(cloud, r)
+}
+
+// routeTableDeleter deletes route tables, with the checks on their subnet associations that it isn't told to skip
+type routeTableDeleter struct {
+	// disassociateSharedSubnets allows the route table to be disassociated from shared subnets
+	disassociateSharedSubnets bool
+	// disassociateSubnetsInUse allows the route table to be disassociated from subnets with running instances
+	disassociateSubnetsInUse bool
+	// ownershipTagKeys are the additional tag keys that name the clusters owning the route table and its subnets
+	ownershipTagKeys []string
+}
+
+// Delete is the Deleter for route tables.
+// Its refusal to disassociate shared subnets is terminal, as retrying can't change the outcome.
+func (d *routeTableDeleter) Delete(cloud fi.Cloud, r *resources.Resource) error {
+	c := cloud.(awsup.AWSCloud)
+
+	id := r.ID
+
+	// Explicit associations prevent the route table from being deleted, so remove them first.
+	// The main route table association can't be removed, but the main route table is deleted with the VPC.
+	// We re-read the associations after removing them, because a subnet deletion in flight can remove
+	// (or still be removing) an association concurrently.
+	for attempt := 0; ; attempt++ {
+		associations, found, err := describeRouteTableAssociations(c, id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			klog.V(2).Infof("RouteTable %q not found; will treat as already-deleted", id)
+			return nil
+		}
+		if len(associations) == 0 {
+			break
+		}
+		if attempt > 0 {
+			return fmt.Errorf("RouteTable %q is still associated with %d subnets", id, len(associations))
+		}
+
+		if !d.disassociateSharedSubnets {
+			shared, err := sharedAssociatedSubnets(c, r, associations, d.ownershipTagKeys)
+			if err != nil {
+				return err
+			}
+			if len(shared) != 0 {
+				return &DeletionError{
+					Type:     r.Type,
+					ID:       id,
+					Err:      fmt.Errorf("RouteTable %q is associated with shared subnets %s; refusing to disassociate them", id, strings.Join(shared, ", ")),
+					Terminal: true,
+				}
+			}
+		}
+
+		inUse, err := associatedSubnetsInUse(c, associations)
+		if err != nil {
+			return fmt.Errorf("error checking subnets of RouteTable %q for running instances: %w", id, err)
+		}
+		if len(inUse) != 0 {
+			if !d.disassociateSubnetsInUse {
+				return fmt.Errorf("RouteTable %q is associated with subnets %s, which have running instances; refusing to disassociate them", id, strings.Join(inUse, ", "))
+			}
+			klog.Warningf("disassociating RouteTable %q from subnets %s, which have running instances", id, strings.Join(inUse, ", "))
+		}
+
+		for _, a := range associations {
+			klog.V(2).Infof("Disassociating RouteTable %q from subnet %q", id, aws.ToString(a.SubnetId))
+			request := &ec2.DisassociateRouteTableInput{
+				AssociationId: a.RouteTableAssociationId,
+			}
+			if _, err := c.EC2().DisassociateRouteTable(request); err != nil {
+				if isNotFoundErr(err) {
+					klog.V(2).Infof("Got InvalidAssociationID.NotFound error disassociating RouteTable %q; will treat as already-disassociated", id)
+					continue
+				}
+				return fmt.Errorf("error disassociating RouteTable %q from subnet %q: %w", id, aws.ToString(a.SubnetId), err)
+			}
+		}
+	}
+
+	if err := disableRoutePropagations(c, r); err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		klog.V(2).Infof("Deleting EC2 RouteTable %q", id)
+		request := &ec2.DeleteRouteTableInput{
+			RouteTableId: &id,
+		}
+		_, err := c.EC2().DeleteRouteTable(request)
+		if err == nil {
+			return nil
+		}
+		if isNotFoundErr(err) {
+			klog.V(2).Infof("Got InvalidRouteTableID.NotFound error describing RouteTable %q; will treat as already-deleted", id)
+			return nil
+		}
+		if !IsDependencyViolation(err) {
+			return fmt.Errorf("error deleting RouteTable %q: %w", id, err)
+		}
+		if attempt > 0 {
+			if deletedConcurrently("route-table:"+id, func() (bool, error) {
+				_, found, err := describeRouteTableAssociations(c, id)
+				return found, err
+			}) {
+				return nil
+			}
+			return err
+		}
+
+		// An association removed just before the delete may not yet be visible to DeleteRouteTable;
+		// if it is gone by now, try once more instead of waiting for the next pass.
+		associations, found, describeErr := describeRouteTableAssociations(c, id)
+		if describeErr != nil {
+			return describeErr
+		}
+		if !found {
+			return nil
+		}
+		if len(associations) != 0 {
+			return err
+		}
+		klog.V(2).Infof("RouteTable %q has no remaining associations; retrying deletion", id)
+	}
+}
+
+// disableRoutePropagations stops the virtual private gateways that propagate routes to the route table from doing so,
+// which would otherwise prevent the route table from being deleted
+func disableRoutePropagations(c awsup.AWSCloud, r *resources.Resource) error {
+	rt, ok := r.Obj.(*ec2.RouteTable)
+	if !ok {
+		return nil
+	}
+
+	for _, p := range rt.PropagatingVgws {
+		gatewayID := aws.ToString(p.GatewayId)
+		klog.V(2).Infof("Disabling route propagation from %q to RouteTable %q", gatewayID, r.ID)
+		request := &ec2.DisableVgwRoutePropagationInput{
+			RouteTableId: aws.String(r.ID),
+			GatewayId:    p.GatewayId,
+		}
+		if _, err := c.EC2().DisableVgwRoutePropagation(request); err != nil {
+			if isNotFoundErr(err) {
+				klog.V(2).Infof("Got %s error disabling route propagation from %q to RouteTable %q; will treat as already-disabled", awsup.AWSErrorCode(err), gatewayID, r.ID)
+				continue
+			}
+			return fmt.Errorf("error disabling route propagation from %q to RouteTable %q: %v", gatewayID, r.ID, err)
+		}
+	}
+	return nil
+}
+
+// describeRouteTableAssociations returns the explicit (non-main) associations of the route table,
+// and whether the route table was found.
+func describeRouteTableAssociations(c awsup.AWSCloud, id string) ([]*ec2.RouteTableAssociation, bool, error) {
+	request := &ec2.DescribeRouteTablesInput{
+		RouteTableIds: []*string{&id},
+	}
+	response, err := c.EC2().DescribeRouteTables(request)
+	if err != nil {
+		if isNotFoundErr(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("error describing RouteTable %q: %v", id, err)
+	}
+	if len(response.RouteTables) == 0 {
+		return nil, false, nil
+	}
+
+	var associations []*ec2.RouteTableAssociation
+	for _, rt := range response.RouteTables {
+		for _, a := range rt.Associations {
+			if aws.ToBool(a.Main) || aws.ToString(a.RouteTableAssociationId) == "" {
+				continue
+			}
+			associations = append(associations, a)
+		}
+	}
+	return associations, true, nil
+}
+
+// associatedSubnetsInUse returns the ids of the associated subnets in which running (or starting) instances have network interfaces, sorted
+func associatedSubnetsInUse(c awsup.AWSCloud, associations []*ec2.RouteTableAssociation) ([]string, error) {
+	associated := sets.NewString()
+	for _, a := range associations {
+		if a.SubnetId != nil {
+			associated.Insert(aws.ToString(a.SubnetId))
+		}
+	}
+	if associated.Len() == 0 {
+		return nil, nil
+	}
+
+	request := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			awsup.NewEC2Filter("network-interface.subnet-id", associated.List()...),
+			awsup.NewEC2Filter("instance-state-name", ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning),
+		},
+	}
+	inUse := sets.NewString()
+	err := c.EC2().DescribeInstancesPages(request, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				for _, eni := range instance.NetworkInterfaces {
+					// An instance may also have interfaces in subnets that aren't associated
+					if subnetID := aws.ToString(eni.SubnetId); associated.Has(subnetID) {
+						inUse.Insert(subnetID)
+					}
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inUse.List(), nil
 }
 
-func DescribeSubnets(cloud fi.Cloud) ([]*ec2.Subnet, error) {
-	c := cloud.(awsup.AWSCloud)
+// sharedAssociatedSubnets returns the ids of the associated subnets that aren't owned by a cluster that owns the route table,
+// or that are owned by another account.
+// We can only tell for route tables that are tagged with their owners; adopted untagged route tables aren't checked.
+func sharedAssociatedSubnets(c awsup.AWSCloud, r *resources.Resource, associations []*ec2.RouteTableAssociation, ownershipTagKeys []string) ([]string, error) {
+	rt, ok := r.Obj.(*ec2.RouteTable)
+	if !ok {
+		return nil, nil
+	}
+	owners := ownerClusters(rt.Tags, ownershipTagKeys)
+	if len(owners) == 0 {
+		return nil, nil
+	}
+
+	var subnetIDs []*string
+	for _, a := range associations {
+		if a.SubnetId != nil {
+			subnetIDs = append(subnetIDs, a.SubnetId)
+		}
+	}
+	if len(subnetIDs) == 0 {
+		return nil, nil
+	}
 
-	klog.V(2).Infof("Listing EC2 subnets")
 	request := &ec2.DescribeSubnetsInput{
-		Filters: BuildEC2Filters(cloud),
+		SubnetIds: subnetIDs,
 	}
 	response, err := c.EC2().DescribeSubnets(request)
 	if err != nil {
-		return nil, fmt.Errorf("error listing subnets: %v", err)
+		if awsup.AWSErrorCode(err) == "InvalidSubnetID.NotFound" {
+			// A subnet deleted concurrently is no longer anyone's to protect; the next pass will see the rest
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error describing subnets of RouteTable %q: %v", r.ID, err)
 	}
 
-	return response.Subnets, nil
-}
-
-func DeleteRouteTable(cloud fi.Cloud, r *resources.Resource) error {
-	c := cloud.(awsup.AWSCloud)
+	foreignOwners := &foreignOwnerChecker{cloud: c}
+	var shared []string
+	for _, subnet := range response.Subnets {
+		subnetID := aws.ToString(subnet.SubnetId)
 
-	id := r.ID
+		foreign, err := foreignOwners.IsForeign(subnet.OwnerId)
+		if err != nil {
+			return nil, err
+		}
 
-	klog.V(2).Infof("Deleting EC2 RouteTable %q", id)
-	request := &ec2.DeleteRouteTableInput{
-		RouteTableId: &id,
-	}
-	_, err := c.EC2().DeleteRouteTable(request)
-	if err != nil {
-		if awsup.AWSErrorCode(err) == "InvalidRouteTableID.NotFound" {
-			klog.V(2).Infof("Got InvalidRouteTableID.NotFound error describing RouteTable %q; will treat as already-deleted", id)
-			return nil
+		owned := false
+		for _, owner := range owners {
+			if HasOwnedTag("subnet:"+subnetID, subnet.Tags, owner, ownershipTagKeys) {
+				owned = true
+			}
 		}
 
-		if IsDependencyViolation(err) {
-			return err
+		if foreign || !owned {
+			shared = append(shared, subnetID)
 		}
-		return fmt.Errorf("error deleting RouteTable %q: %v", id, err)
 	}
-	return nil
+	slices.Sort(shared)
+	return shared, nil
 }
 
 // DescribeRouteTablesIgnoreTags returns all ec2.RouteTable, ignoring tags
@@ -894,13 +1688,22 @@ func DescribeRouteTablesIgnoreTags(cloud fi.Cloud) ([]*ec2.RouteTable, error) {
 	c := cloud.(awsup.AWSCloud)
 
 	klog.V(2).Infof("Listing all RouteTables")
+	var routeTables []*ec2.RouteTable
 	request := &ec2.DescribeRouteTablesInput{}
-	response, err := c.EC2().DescribeRouteTables(request)
+	err := c.EC2().DescribeRouteTablesPagesWithContext(context.TODO(), request, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+		routeTables = append(routeTables, page.RouteTables...)
+		return true
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error listing RouteTables: %v", err)
 	}
 
-	return response.RouteTables, nil
+	return routeTables, nil
+}
+
+// deleteDhcpOptionsActions are the AWS API actions invoked by DeleteDhcpOptions
+var deleteDhcpOptionsActions = []string{
+	"ec2:DeleteDhcpOptions",
 }
 
 func DeleteDhcpOptions(cloud fi.Cloud, r *resources.Resource) error {
@@ -914,19 +1717,22 @@ func DeleteDhcpOptions(cloud fi.Cloud, r *resources.Resource) error {
 	}
 	_, err := c.EC2().DeleteDhcpOptions(request)
 	if err != nil {
-		if awsup.AWSErrorCode(err) == "InvalidDhcpOptionsID.NotFound" {
+		if isNotFoundErr(err) {
 			klog.V(2).Infof("Got InvalidDhcpOptionsID.NotFound error deleting DhcpOptions %q; will treat as already-deleted", id)
 			return nil
 		} else if IsDependencyViolation(err) {
 			return err
 		}
-		return fmt.Errorf("error deleting DhcpOptions %q: %v", id, err)
+		return fmt.Errorf("error deleting DhcpOptions %q: %w", id, err)
 	}
 	return nil
 }
 
-func ListDhcpOptions(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
-	dhcpOptions, err := DescribeDhcpOptions(cloud)
+// ListDhcpOptions lists the DHCP options sets of the cluster.
+// The sets that the cluster doesn't own (for example, those tagged as shared) are left alone.
+// The VPC blocks its DHCP options set, so that the set is only deleted once it is no longer associated.
+func ListDhcpOptions(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	dhcpOptions, err := DescribeDhcpOptionsForCluster(cloud, clusterName, ownershipTagKeys)
 	if err != nil {
 		return nil, err
 	}
@@ -934,24 +1740,49 @@ func ListDhcpOptions(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Re
 	var resourceTrackers []*resources.Resource
 
 	for _, o := range dhcpOptions {
+		id := aws.ToString(o.DhcpOptionsId)
 		resourceTracker := &resources.Resource{
 			Name:    FindName(o.Tags),
-			ID:      aws.ToString(o.DhcpOptionsId),
+			ID:      id,
 			Type:    "dhcp-options",
 			Deleter: DeleteDhcpOptions,
-			Shared:  HasSharedTag(ec2.ResourceTypeDhcpOptions+":"+aws.ToString(o.DhcpOptionsId), o.Tags, clusterName),
+			Actions: deleteDhcpOptionsActions,
+			Shared:  !HasOwnedTag(ec2.ResourceTypeDhcpOptions+":"+id, o.Tags, clusterName, ownershipTagKeys),
 		}
 
-		var blocks []string
-
-		resourceTracker.Blocks = blocks
-
 		resourceTrackers = append(resourceTrackers, resourceTracker)
 	}
 
 	return resourceTrackers, nil
 }
 
+// DescribeDhcpOptionsForCluster returns the DHCP options sets tagged for the cluster, with either the legacy or the new cluster tag
+func DescribeDhcpOptionsForCluster(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) ([]*ec2.DhcpOptions, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	dhcpOptions := make(map[string]*ec2.DhcpOptions)
+	klog.V(2).Infof("Listing EC2 DhcpOptions")
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
+		request := &ec2.DescribeDhcpOptionsInput{
+			Filters: filters,
+		}
+		response, err := c.EC2().DescribeDhcpOptions(request)
+		if err != nil {
+			return nil, fmt.Errorf("error listing DhcpOptions: %v", err)
+		}
+
+		for _, o := range response.DhcpOptions {
+			dhcpOptions[aws.ToString(o.DhcpOptionsId)] = o
+		}
+	}
+
+	var ret []*ec2.DhcpOptions
+	for _, id := range maps.SortedKeys(dhcpOptions) {
+		ret = append(ret, dhcpOptions[id])
+	}
+	return ret, nil
+}
+
 func DescribeDhcpOptions(cloud fi.Cloud) ([]*ec2.DhcpOptions, error) {
 	c := cloud.(awsup.AWSCloud)
 
@@ -967,6 +1798,13 @@ func DescribeDhcpOptions(cloud fi.Cloud) ([]*ec2.DhcpOptions, error) {
 	return response.DhcpOptions, nil
 }
 
+// deleteInternetGatewayActions are the AWS API actions invoked by DeleteInternetGateway
+var deleteInternetGatewayActions = []string{
+	"ec2:DescribeInternetGateways",
+	"ec2:DetachInternetGateway",
+	"ec2:DeleteInternetGateway",
+}
+
 func DeleteInternetGateway(cloud fi.Cloud, r *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
@@ -979,12 +1817,12 @@ func DeleteInternetGateway(cloud fi.Cloud, r *resources.Resource) error {
 		}
 		response, err := c.EC2().DescribeInternetGateways(request)
 		if err != nil {
-			if awsup.AWSErrorCode(err) == "InvalidInternetGatewayID.NotFound" {
+			if isNotFoundErr(err) {
 				klog.Infof("Internet gateway %q not found; assuming already deleted", id)
 				return nil
 			}
 
-			return fmt.Errorf("error describing InternetGateway %q: %v", id, err)
+			return fmt.Errorf("error describing InternetGateway %q: %w", id, err)
 		}
 		if response == nil || len(response.InternetGateways) == 0 {
 			return nil
@@ -1006,7 +1844,7 @@ func DeleteInternetGateway(cloud fi.Cloud, r *resources.Resource) error {
 			if IsDependencyViolation(err) {
 				return err
 			}
-			return fmt.Errorf("error detaching InternetGateway %q: %v", id, err)
+			return fmt.Errorf("error detaching InternetGateway %q: %w", id, err)
 		}
 	}
 
@@ -1020,11 +1858,11 @@ func DeleteInternetGateway(cloud fi.Cloud, r *resources.Resource) error {
 			if IsDependencyViolation(err) {
 				return err
 			}
-			if awsup.AWSErrorCode(err) == "InvalidInternetGatewayID.NotFound" {
+			if isNotFoundErr(err) {
 				klog.Infof("Internet gateway %q not found; assuming already deleted", id)
 				return nil
 			}
-			return fmt.Errorf("error deleting InternetGateway %q: %v", id, err)
+			return fmt.Errorf("error deleting InternetGateway %q: %w", id, err)
 		}
 	}
 
@@ -1054,6 +1892,7 @@ func ListInternetGateways(cloud fi.Cloud, vpcID, clusterName string) ([]*resourc
 			ID:      aws.ToString(o.InternetGatewayId),
 			Type:    "internet-gateway",
 			Deleter: DeleteInternetGateway,
+			Actions: deleteInternetGatewayActions,
 			Shared:  HasSharedTag(ec2.ResourceTypeInternetGateway+":"+aws.ToString(o.InternetGatewayId), o.Tags, clusterName),
 		}
 
@@ -1118,6 +1957,11 @@ func DumpEgressOnlyInternetGateway(op *resources.DumpOperation, r *resources.Res
 	return nil
 }
 
+// deleteEgressOnlyInternetGatewayActions are the AWS API actions invoked by DeleteEgressOnlyInternetGateway
+var deleteEgressOnlyInternetGatewayActions = []string{
+	"ec2:DeleteEgressOnlyInternetGateway",
+}
+
 func DeleteEgressOnlyInternetGateway(cloud fi.Cloud, r *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
@@ -1133,11 +1977,11 @@ func DeleteEgressOnlyInternetGateway(cloud fi.Cloud, r *resources.Resource) erro
 			if IsDependencyViolation(err) {
 				return err
 			}
-			if awsup.AWSErrorCode(err) == "InvalidEgressOnlyInternetGatewayID.NotFound" {
+			if isNotFoundErr(err) {
 				klog.Infof("Egress-only internet gateway %q not found; assuming already deleted", id)
 				return nil
 			}
-			return fmt.Errorf("error deleting EgressOnlyInternetGateway %q: %v", id, err)
+			return fmt.Errorf("error deleting EgressOnlyInternetGateway %q: %w", id, err)
 		}
 	}
 
@@ -1160,6 +2004,7 @@ func ListEgressOnlyInternetGateways(cloud fi.Cloud, vpcID, clusterName string) (
 			Obj:     o,
 			Dumper:  DumpEgressOnlyInternetGateway,
 			Deleter: DeleteEgressOnlyInternetGateway,
+			Actions: deleteEgressOnlyInternetGatewayActions,
 			Shared:  HasSharedTag(ec2.ResourceTypeEgressOnlyInternetGateway+":"+aws.ToString(o.EgressOnlyInternetGatewayId), o.Tags, clusterName),
 		}
 
@@ -1195,6 +2040,12 @@ func DescribeEgressOnlyInternetGateways(cloud fi.Cloud) ([]*ec2.EgressOnlyIntern
 	return gateways, nil
 }
 
+// deleteAutoScalingGroupActions are the AWS API actions invoked by DeleteAutoScalingGroup
+var deleteAutoScalingGroupActions = []string{
+	"autoscaling:DeleteWarmPool",
+	"autoscaling:DeleteAutoScalingGroup",
+}
+
 func DeleteAutoScalingGroup(cloud fi.Cloud, r *resources.Resource) error {
 	ctx := context.TODO()
 
@@ -1202,6 +2053,18 @@ func DeleteAutoScalingGroup(cloud fi.Cloud, r *resources.Resource) error {
 
 	id := r.ID
 
+	// Force deleting the group can leave the instances of its warm pool behind, so we delete the warm pool first
+	if asg, ok := r.Obj.(*autoscalingtypes.AutoScalingGroup); ok && asg.WarmPoolConfiguration != nil {
+		klog.V(2).Infof("Deleting warm pool of autoscaling group %q", id)
+		_, err := c.Autoscaling().DeleteWarmPool(ctx, &autoscaling.DeleteWarmPoolInput{
+			AutoScalingGroupName: aws.String(id),
+			ForceDelete:          aws.Bool(true),
+		})
+		if err != nil && !isNotFoundErr(err) {
+			return fmt.Errorf("error deleting warm pool of autoscaling group %q: %w", id, err)
+		}
+	}
+
 	klog.V(2).Infof("Deleting autoscaling group %q", id)
 	request := &autoscaling.DeleteAutoScalingGroupInput{
 		AutoScalingGroupName: &id,
@@ -1212,7 +2075,7 @@ func DeleteAutoScalingGroup(cloud fi.Cloud, r *resources.Resource) error {
 		if IsDependencyViolation(err) {
 			return err
 		}
-		return fmt.Errorf("error deleting autoscaling group %q: %v", id, err)
+		return fmt.Errorf("error deleting autoscaling group %q: %w", id, err)
 	}
 	return nil
 }
@@ -1235,6 +2098,8 @@ func ListAutoScalingGroups(cloud fi.Cloud, vpcID, clusterName string) ([]*resour
 			ID:      aws.ToString(asg.AutoScalingGroupName),
 			Type:    "autoscaling-group",
 			Deleter: DeleteAutoScalingGroup,
+			Actions: deleteAutoScalingGroupActions,
+			Obj:     asg,
 		}
 
 		var blocks []string
@@ -1248,8 +2113,11 @@ func ListAutoScalingGroups(cloud fi.Cloud, vpcID, clusterName string) ([]*resour
 		if asg.LaunchConfigurationName != nil {
 			blocks = append(blocks, TypeAutoscalingLaunchConfig+":"+aws.ToString(asg.LaunchConfigurationName))
 		}
-		if asg.LaunchTemplate != nil {
-			blocks = append(blocks, TypeAutoscalingLaunchConfig+":"+aws.ToString(asg.LaunchTemplate.LaunchTemplateName))
+		// Launch templates are tracked by id; those referenced by name are linked once the templates are listed
+		for _, spec := range asgLaunchTemplates(asg) {
+			if id := aws.ToString(spec.LaunchTemplateId); id != "" {
+				blocks = append(blocks, TypeAutoscalingLaunchConfig+":"+id)
+			}
 		}
 
 		resourceTracker.Blocks = blocks
@@ -1260,8 +2128,10 @@ func ListAutoScalingGroups(cloud fi.Cloud, vpcID, clusterName string) ([]*resour
 	return resourceTrackers, nil
 }
 
-// FindAutoScalingLaunchTemplates finds any launch templates owned by the cluster (by tag).
-func FindAutoScalingLaunchTemplates(cloud fi.Cloud, clusterName string) ([]*resources.Resource, error) {
+// ListLaunchTemplates finds the launch templates owned by the cluster (by tag).
+// Deleting a template deletes all its versions, so the stale versions left by the updates of the instance groups go too.
+// A template can't be deleted while an autoscaling group uses it; the groups block the templates they reference.
+func ListLaunchTemplates(cloud fi.Cloud, clusterName string) ([]*resources.Resource, error) {
 	c := cloud.(awsup.AWSCloud)
 
 	klog.V(2).Infof("Finding all AutoScaling LaunchTemplates owned by the cluster")
@@ -1283,6 +2153,7 @@ func FindAutoScalingLaunchTemplates(cloud fi.Cloud, clusterName string) ([]*reso
 				ID:      aws.ToString(lt.LaunchTemplateId),
 				Type:    TypeAutoscalingLaunchConfig,
 				Deleter: DeleteAutoScalingGroupLaunchTemplate,
+				Actions: deleteAutoScalingGroupLaunchTemplateActions,
 			})
 		}
 		return true
@@ -1294,7 +2165,43 @@ func FindAutoScalingLaunchTemplates(cloud fi.Cloud, clusterName string) ([]*reso
 	return list, nil
 }
 
-func FindNatGateways(cloud fi.Cloud, routeTables map[string]*resources.Resource, clusterName string) ([]*resources.Resource, error) {
+// blockLaunchTemplatesByName makes the autoscaling groups that reference a launch template by name block that template,
+// which is tracked by id, so that the template is only deleted once the groups are gone
+func blockLaunchTemplatesByName(resourceTrackers map[string]*resources.Resource, launchTemplates []*resources.Resource) {
+	templateIDs := make(map[string]string)
+	for _, lt := range launchTemplates {
+		templateIDs[lt.Name] = lt.ID
+	}
+
+	for _, t := range resourceTrackers {
+		asg, ok := t.Obj.(*autoscalingtypes.AutoScalingGroup)
+		if !ok {
+			continue
+		}
+		for _, spec := range asgLaunchTemplates(asg) {
+			if spec.LaunchTemplateId != nil {
+				continue
+			}
+			if id, found := templateIDs[aws.ToString(spec.LaunchTemplateName)]; found {
+				t.Blocks = append(t.Blocks, TypeAutoscalingLaunchConfig+":"+id)
+			}
+		}
+	}
+}
+
+// asgLaunchTemplates returns the launch templates that the autoscaling group launches instances from
+func asgLaunchTemplates(asg *autoscalingtypes.AutoScalingGroup) []*autoscalingtypes.LaunchTemplateSpecification {
+	var specs []*autoscalingtypes.LaunchTemplateSpecification
+	if asg.LaunchTemplate != nil {
+		specs = append(specs, asg.LaunchTemplate)
+	}
+	if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil && asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification != nil {
+		specs = append(specs, asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification)
+	}
+	return specs
+}
+
+func FindNatGateways(cloud fi.Cloud, routeTables map[string]*resources.Resource, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
 	if len(routeTables) == 0 {
 		return nil, nil
 	}
@@ -1384,7 +2291,9 @@ func FindNatGateways(cloud fi.Cloud, routeTables map[string]*resources.Resource,
 					}
 
 					for _, eip := range response.Addresses {
-						eipTracker := buildElasticIPResource(eip, !ownedNatGatewayIds.Has(natGatewayId), clusterName)
+						// An address that isn't tagged as ours was brought by the user, so we must not release it
+						owned := HasOwnedTag(TypeElasticIp+":"+aws.ToString(eip.AllocationId), eip.Tags, clusterName, ownershipTagKeys)
+						eipTracker := buildElasticIPResource(eip, !ownedNatGatewayIds.Has(natGatewayId) || !owned, clusterName)
 						resourceTrackers = append(resourceTrackers, eipTracker)
 					}
 				}
@@ -1395,6 +2304,11 @@ func FindNatGateways(cloud fi.Cloud, routeTables map[string]*resources.Resource,
 	return resourceTrackers, nil
 }
 
+// deleteAutoScalingGroupLaunchTemplateActions are the AWS API actions invoked by DeleteAutoScalingGroupLaunchTemplate
+var deleteAutoScalingGroupLaunchTemplateActions = []string{
+	"ec2:DeleteLaunchTemplate",
+}
+
 // DeleteAutoScalingGroupLaunchTemplate deletes
 func DeleteAutoScalingGroupLaunchTemplate(cloud fi.Cloud, r *resources.Resource) error {
 	c, ok := cloud.(awsup.AWSCloud)
@@ -1406,12 +2320,21 @@ func DeleteAutoScalingGroupLaunchTemplate(cloud fi.Cloud, r *resources.Resource)
 	if _, err := c.EC2().DeleteLaunchTemplate(&ec2.DeleteLaunchTemplateInput{
 		LaunchTemplateId: fi.PtrTo(r.ID),
 	}); err != nil {
-		return fmt.Errorf("error deleting ec2 LaunchTemplate %q: %v", r.ID, err)
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+		return fmt.Errorf("error deleting ec2 LaunchTemplate %q: %w", r.ID, err)
 	}
 
 	return nil
 }
 
+// deleteELBActions are the AWS API actions invoked by DeleteELB
+var deleteELBActions = []string{
+	"elasticloadbalancing:DeleteLoadBalancer",
+}
+
 func DeleteELB(cloud fi.Cloud, r *resources.Resource) error {
 	ctx := context.TODO()
 	c := cloud.(awsup.AWSCloud)
@@ -1427,30 +2350,70 @@ func DeleteELB(cloud fi.Cloud, r *resources.Resource) error {
 		if IsDependencyViolation(err) {
 			return err
 		}
-		return fmt.Errorf("error deleting LoadBalancer %q: %v", id, err)
+		return fmt.Errorf("error deleting LoadBalancer %q: %w", id, err)
 	}
 	return nil
 }
 
+// deleteELBV2Actions are the AWS API actions invoked by DeleteELBV2
+var deleteELBV2Actions = []string{
+	"elasticloadbalancing:DescribeListeners",
+	"elasticloadbalancing:DeleteListener",
+	"elasticloadbalancing:DeleteLoadBalancer",
+}
+
+// DeleteELBV2 deletes the listeners of the NLB or ALB, which forward to its target groups, and then the load balancer
 func DeleteELBV2(cloud fi.Cloud, r *resources.Resource) error {
 	ctx := context.TODO()
 	c := cloud.(awsup.AWSCloud)
 	id := r.ID
 
+	listeners, err := c.ELBV2().DescribeListeners(ctx, &elbv2.DescribeListenersInput{
+		LoadBalancerArn: aws.String(id),
+	})
+	if err != nil {
+		if isNotFoundErr(err) {
+			klog.V(2).Infof("Got %s error describing listeners of ELBV2 %q; will treat as already-deleted", awsup.AWSErrorCode(err), id)
+			return nil
+		}
+		return fmt.Errorf("error describing listeners of V2 LoadBalancer %q: %w", id, err)
+	}
+	for _, listener := range listeners.Listeners {
+		listenerARN := aws.ToString(listener.ListenerArn)
+		klog.V(2).Infof("Deleting listener %q of ELBV2 %q", listenerARN, id)
+		if _, err := c.ELBV2().DeleteListener(ctx, &elbv2.DeleteListenerInput{
+			ListenerArn: listener.ListenerArn,
+		}); err != nil {
+			if isNotFoundErr(err) {
+				continue
+			}
+			return fmt.Errorf("error deleting listener %q of V2 LoadBalancer %q: %w", listenerARN, id, err)
+		}
+	}
+
 	klog.V(2).Infof("Deleting ELBV2 %q", id)
 	request := &elbv2.DeleteLoadBalancerInput{
 		LoadBalancerArn: aws.String(id),
 	}
-	_, err := c.ELBV2().DeleteLoadBalancer(ctx, request)
+	_, err = c.ELBV2().DeleteLoadBalancer(ctx, request)
 	if err != nil {
+		if isNotFoundErr(err) {
+			klog.V(2).Infof("Got %s error deleting ELBV2 %q; will treat as already-deleted", awsup.AWSErrorCode(err), id)
+			return nil
+		}
 		if IsDependencyViolation(err) {
 			return err
 		}
-		return fmt.Errorf("error deleting V2 LoadBalancer %q: %v", id, err)
+		return fmt.Errorf("error deleting V2 LoadBalancer %q: %w", id, err)
 	}
 	return nil
 }
 
+// deleteTargetGroupActions are the AWS API actions invoked by DeleteTargetGroup
+var deleteTargetGroupActions = []string{
+	"elasticloadbalancing:DeleteTargetGroup",
+}
+
 func DeleteTargetGroup(cloud fi.Cloud, r *resources.Resource) error {
 	ctx := context.TODO()
 	c := cloud.(awsup.AWSCloud)
@@ -1462,10 +2425,14 @@ func DeleteTargetGroup(cloud fi.Cloud, r *resources.Resource) error {
 	}
 	_, err := c.ELBV2().DeleteTargetGroup(ctx, request)
 	if err != nil {
+		if isNotFoundErr(err) {
+			klog.V(2).Infof("Got %s error deleting TargetGroup %q; will treat as already-deleted", awsup.AWSErrorCode(err), id)
+			return nil
+		}
 		if IsDependencyViolation(err) {
 			return err
 		}
-		return fmt.Errorf("error deleting TargetGroup %q: %v", id, err)
+		return fmt.Errorf("error deleting TargetGroup %q: %w", id, err)
 	}
 	return nil
 }
@@ -1479,8 +2446,23 @@ func DumpELB(op *resources.DumpOperation, r *resources.Resource) error {
 	return nil
 }
 
+// legacyELBNames returns the names that kops gives the cluster's classic load balancers.
+// Load balancers created before they were reliably tagged can only be matched by these names.
+func legacyELBNames(clusterName string) sets.String {
+	return sets.NewString(
+		awsup.GetResourceName32(clusterName, "api"),
+		awsup.GetResourceName32(clusterName, "bastion"),
+	)
+}
+
 func ListELBs(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
-	elbs, elbTags, err := DescribeELBs(cloud)
+	return ListELBsMatchingTags(cloud, clusterName, nil)
+}
+
+// ListELBsMatchingTags lists the cluster's classic load balancers. If wantTags is set, the load balancers are matched
+// by these tags instead of the cloud's: a tag with an empty value only requires the key, and a value can be a glob.
+func ListELBsMatchingTags(cloud fi.Cloud, clusterName string, wantTags map[string]string) ([]*resources.Resource, error) {
+	elbs, elbTags, err := describeELBs(cloud, wantTags, legacyELBNames(clusterName))
 	if err != nil {
 		return nil, err
 	}
@@ -1493,6 +2475,7 @@ func ListELBs(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource,
 			ID:      id,
 			Type:    TypeLoadBalancer,
 			Deleter: DeleteELB,
+			Actions: deleteELBActions,
 			Dumper:  DumpELB,
 			Obj:     elb,
 		}
@@ -1515,9 +2498,21 @@ func ListELBs(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource,
 }
 
 func DescribeELBs(cloud fi.Cloud) ([]elbtypes.LoadBalancerDescription, map[string][]elbtypes.Tag, error) {
+	return describeELBs(cloud, nil, nil)
+}
+
+// describeELBs returns the classic load balancers that are tagged for the cluster,
+// and those that aren't but whose name is one of legacyNames.
+// The tags are the cloud's, unless wantTags is set, whose empty values only require the key and whose values can be globs.
+func describeELBs(cloud fi.Cloud, wantTags map[string]string, legacyNames sets.String) ([]elbtypes.LoadBalancerDescription, map[string][]elbtypes.Tag, error) {
 	ctx := context.TODO()
 	c := cloud.(awsup.AWSCloud)
 	tags := c.Tags()
+	var matchOptions tagMatchOptions
+	if wantTags != nil {
+		tags = wantTags
+		matchOptions = tagMatchOptions{KeyOnlyIfEmpty: true, Glob: true}
+	}
 
 	klog.V(2).Infof("Listing all ELBs")
 
@@ -1555,8 +2550,11 @@ func DescribeELBs(cloud fi.Cloud) ([]elbtypes.LoadBalancerDescription, map[strin
 		for _, t := range tagResponse.TagDescriptions {
 			elbName := aws.ToString(t.LoadBalancerName)
 
-			if !matchesElbTags(tags, t.Tags) {
-				continue
+			if !matchesElbTagsWithOptions(tags, t.Tags, matchOptions) {
+				if !legacyNames.Has(elbName) {
+					continue
+				}
+				klog.Infof("Matched classic load balancer %q by its name, because it isn't tagged for the cluster", elbName)
 			}
 
 			elbTags[elbName] = t.Tags
@@ -1586,6 +2584,7 @@ func ListELBV2s(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resourc
 			ID:      string(*elb.LoadBalancerArn),
 			Type:    TypeLoadBalancer,
 			Deleter: DeleteELBV2,
+			Actions: deleteELBV2Actions,
 			Dumper:  DumpELB,
 			Obj:     elb,
 		}
@@ -1614,11 +2613,25 @@ func DumpTargetGroup(op *resources.DumpOperation, r *resources.Resource) error {
 	return nil
 }
 
+// ListTargetGroups lists the cluster's target groups. A target group is deleted after the cluster's load balancers
+// that forward to it, and not at all if a load balancer that isn't the cluster's still forwards to it.
 func ListTargetGroups(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
 	targetGroups, err := listMatchingTargetGroups(cloud)
 	if err != nil {
 		return nil, err
 	}
+	if len(targetGroups) == 0 {
+		return nil, nil
+	}
+
+	loadBalancers, err := awsup.ListELBV2LoadBalancers(context.TODO(), cloud.(awsup.AWSCloud))
+	if err != nil {
+		return nil, err
+	}
+	clusterLoadBalancers := sets.NewString()
+	for _, loadBalancer := range loadBalancers {
+		clusterLoadBalancers.Insert(loadBalancer.ARN())
+	}
 
 	var resourceTrackers []*resources.Resource
 	for _, targetGroup := range targetGroups {
@@ -1629,10 +2642,20 @@ func ListTargetGroups(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.R
 			ID:      targetGroup.ARN,
 			Type:    TypeTargetGroup,
 			Deleter: DeleteTargetGroup,
+			Actions: deleteTargetGroupActions,
 			Dumper:  DumpTargetGroup,
 			Obj:     tg,
 		}
 
+		for _, loadBalancerARN := range tg.LoadBalancerArns {
+			if !clusterLoadBalancers.Has(loadBalancerARN) {
+				klog.V(2).Infof("TargetGroup %q is used by load balancer %q, which isn't the cluster's; treating as shared", id, loadBalancerARN)
+				resourceTracker.Shared = true
+				continue
+			}
+			resourceTracker.Blocked = append(resourceTracker.Blocked, TypeLoadBalancer+":"+loadBalancerARN)
+		}
+
 		resourceTrackers = append(resourceTrackers, resourceTracker)
 	}
 	return resourceTrackers, nil
@@ -1661,6 +2684,11 @@ func listMatchingTargetGroups(cloud fi.Cloud) ([]*awsup.TargetGroupInfo, error)
 	return matches, nil
 }
 
+// deleteElasticIPActions are the AWS API actions invoked by DeleteElasticIP
+var deleteElasticIPActions = []string{
+	"ec2:ReleaseAddress",
+}
+
 func DeleteElasticIP(cloud fi.Cloud, t *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
@@ -1672,7 +2700,7 @@ func DeleteElasticIP(cloud fi.Cloud, t *resources.Resource) error {
 	}
 	_, err := c.EC2().ReleaseAddress(request)
 	if err != nil {
-		if awsup.AWSErrorCode(err) == "InvalidAllocationID.NotFound" {
+		if isNotFoundErr(err) {
 			klog.V(2).Infof("Got InvalidAllocationID.NotFound error deleting ElasticIP %q; will treat as already-deleted", id)
 			return nil
 		}
@@ -1680,11 +2708,16 @@ func DeleteElasticIP(cloud fi.Cloud, t *resources.Resource) error {
 		if IsDependencyViolation(err) {
 			return err
 		}
-		return fmt.Errorf("error deleting elastic ip %q: %v", t.Name, err)
+		return fmt.Errorf("error deleting elastic ip %q: %w", t.Name, err)
 	}
 	return nil
 }
 
+// deleteNatGatewayActions are the AWS API actions invoked by DeleteNatGateway
+var deleteNatGatewayActions = []string{
+	"ec2:DeleteNatGateway",
+}
+
 func DeleteNatGateway(cloud fi.Cloud, t *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
@@ -1696,14 +2729,23 @@ func DeleteNatGateway(cloud fi.Cloud, t *resources.Resource) error {
 	}
 	_, err := c.EC2().DeleteNatGateway(request)
 	if err != nil {
+		if isNotFoundErr(err) {
+			klog.V(2).Infof("Got %s error deleting NatGateway %q; will treat as already-deleted", awsup.AWSErrorCode(err), id)
+			return nil
+		}
 		if IsDependencyViolation(err) {
 			return err
 		}
-		return fmt.Errorf("error deleting ngw %q: %v", t.Name, err)
+		return fmt.Errorf("error deleting ngw %q: %w", t.Name, err)
 	}
 	return nil
 }
 
+// deleteRoute53RecordsActions are the AWS API actions invoked by deleteRoute53Records
+var deleteRoute53RecordsActions = []string{
+	"route53:ChangeResourceRecordSets",
+}
+
 func deleteRoute53Records(ctx context.Context, cloud fi.Cloud, zone route53types.HostedZone, resourceTrackers []*resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
@@ -1728,7 +2770,7 @@ func deleteRoute53Records(ctx context.Context, cloud fi.Cloud, zone route53types
 	}
 	_, err := c.Route53().ChangeResourceRecordSets(ctx, request)
 	if err != nil {
-		return fmt.Errorf("error deleting route53 record %q: %v", human, err)
+		return fmt.Errorf("error deleting route53 record %q: %w", human, err)
 	}
 	return nil
 }
@@ -1821,7 +2863,8 @@ func ListRoute53Records(cloud fi.Cloud, vpcID, clusterName string) ([]*resources
 					GroupDeleter: func(cloud fi.Cloud, resourceTrackers []*resources.Resource) error {
 						return deleteRoute53Records(ctx, cloud, zone, resourceTrackers)
 					},
-					Obj: &rrs,
+					Actions: deleteRoute53RecordsActions,
+					Obj:     &rrs,
 				}
 				resourceTrackers = append(resourceTrackers, resourceTracker)
 			}
@@ -1831,6 +2874,17 @@ func ListRoute53Records(cloud fi.Cloud, vpcID, clusterName string) ([]*resources
 	return resourceTrackers, nil
 }
 
+// deleteIAMRoleActions are the AWS API actions invoked by DeleteIAMRole
+var deleteIAMRoleActions = []string{
+	"iam:ListRolePolicies",
+	"iam:ListAttachedRolePolicies",
+	"iam:DeleteRolePolicy",
+	"iam:DetachRolePolicy",
+	"iam:DeleteRole",
+}
+
+// DeleteIAMRole deletes the role, after deleting its inline policies and detaching its managed policies, as IAM refuses to delete a role that has either.
+// The managed policies themselves are kept, as they may be attached elsewhere.
 func DeleteIAMRole(cloud fi.Cloud, r *resources.Resource) error {
 	ctx := context.TODO()
 	var attachedPolicies []iamtypes.AttachedPolicy
@@ -1852,7 +2906,7 @@ func DeleteIAMRole(cloud fi.Cloud, r *resources.Resource) error {
 					klog.V(2).Infof("Got NoSuchEntity describing IAM RolePolicy %q; will treat as already-deleted", roleName)
 					return nil
 				}
-				return fmt.Errorf("error listing IAM role policies for %q: %v", roleName, err)
+				return fmt.Errorf("error listing IAM role policies for %q: %w", roleName, err)
 			}
 			policyNames = append(policyNames, page.PolicyNames...)
 		}
@@ -1871,7 +2925,7 @@ func DeleteIAMRole(cloud fi.Cloud, r *resources.Resource) error {
 					klog.V(2).Infof("Got NoSuchEntity describing IAM RolePolicy %q; will treat as already-deleted", roleName)
 					return nil
 				}
-				return fmt.Errorf("error listing IAM role policies for %q: %v", roleName, err)
+				return fmt.Errorf("error listing IAM role policies for %q: %w", roleName, err)
 			}
 			attachedPolicies = append(attachedPolicies, page.AttachedPolicies...)
 		}
@@ -1886,7 +2940,7 @@ func DeleteIAMRole(cloud fi.Cloud, r *resources.Resource) error {
 		}
 		_, err := c.IAM().DeleteRolePolicy(ctx, request)
 		if err != nil {
-			return fmt.Errorf("error deleting IAM role policy %q %q: %v", roleName, policyName, err)
+			return fmt.Errorf("error deleting IAM role policy %q %q: %w", roleName, policyName, err)
 		}
 	}
 
@@ -1899,7 +2953,7 @@ func DeleteIAMRole(cloud fi.Cloud, r *resources.Resource) error {
 		}
 		_, err := c.IAM().DetachRolePolicy(ctx, request)
 		if err != nil {
-			return fmt.Errorf("error detaching IAM role policy %q %q: %v", roleName, *policy.PolicyArn, err)
+			return fmt.Errorf("error detaching IAM role policy %q %q: %w", roleName, *policy.PolicyArn, err)
 		}
 	}
 
@@ -1911,7 +2965,7 @@ func DeleteIAMRole(cloud fi.Cloud, r *resources.Resource) error {
 		}
 		_, err := c.IAM().DeleteRole(ctx, request)
 		if err != nil {
-			return fmt.Errorf("error deleting IAM role %q: %v", r.Name, err)
+			return fmt.Errorf("error deleting IAM role %q: %w", r.Name, err)
 		}
 	}
 
@@ -1919,51 +2973,109 @@ func DeleteIAMRole(cloud fi.Cloud, r *resources.Resource) error {
 }
 
 func ListIAMRoles(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
-	ctx := context.TODO()
+	return ListIAMRolesWithPathPrefix(context.Background(), cloud, clusterName, "")
+}
+
+// ListIAMRolesWithPathPrefix lists the IAM roles owned by the cluster, only considering roles under pathPrefix.
+// An empty pathPrefix considers all roles.
+func ListIAMRolesWithPathPrefix(ctx context.Context, cloud fi.Cloud, clusterName, pathPrefix string) ([]*resources.Resource, error) {
 	c := cloud.(awsup.AWSCloud)
 
-	var resourceTrackers []*resources.Resource
-	// Find roles owned by the cluster
+	var roles []iamtypes.Role
 	{
-		ownershipTag := "kubernetes.io/cluster/" + clusterName
 		request := &iam.ListRolesInput{}
+		if pathPrefix != "" {
+			request.PathPrefix = aws.String(pathPrefix)
+		}
 		paginator := iam.NewListRolesPaginator(c.IAM(), request)
 		for paginator.HasMorePages() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			page, err := paginator.NextPage(ctx)
 			if err != nil {
 				return nil, fmt.Errorf("error listing IAM roles: %v", err)
 			}
-			for _, r := range page.Roles {
-				name := aws.ToString(r.RoleName)
+			roles = append(roles, page.Roles...)
+		}
+	}
+
+	// Find roles owned by the cluster
+	// Each role's tags need a GetRole call, which dominates in accounts with thousands of roles, so we make them concurrently.
+	// Each lookup stores its result at the index of the role, so that the order of the list is kept.
+	ownershipTag := "kubernetes.io/cluster/" + clusterName
+	owned := make([]*resources.Resource, len(roles))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(iamListConcurrency)
+	for i := range roles {
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			resourceTracker, err := getOwnedIAMRole(gctx, c, aws.ToString(roles[i].RoleName), ownershipTag)
+			if err != nil {
+				return err
+			}
+			owned[i] = resourceTracker
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var resourceTrackers []*resources.Resource
+	for _, resourceTracker := range owned {
+		if resourceTracker != nil {
+			resourceTrackers = append(resourceTrackers, resourceTracker)
+		}
+	}
+	return resourceTrackers, nil
+}
 
-				getRequest := &iam.GetRoleInput{RoleName: r.RoleName}
-				roleOutput, err := c.IAM().GetRole(ctx, getRequest)
+// getOwnedIAMRole returns the resource for the role if it has the ownership tag.
+// It returns nil if it doesn't, or if the role can't be read.
+func getOwnedIAMRole(ctx context.Context, c awsup.AWSCloud, name, ownershipTag string) (*resources.Resource, error) {
+	getRequest := &iam.GetRoleInput{RoleName: aws.String(name)}
+	roleOutput, err := c.IAM().GetRole(ctx, getRequest)
+	if err != nil {
+		if awsup.IsIAMNoSuchEntityException(err) {
+			klog.Warningf("could not find role %q. Resource may already have been deleted: %v", name, err)
+			return nil, nil
+		} else if awsup.AWSErrorCode(err) == "403" {
+			klog.Warningf("failed to determine ownership of %q: %v", name, err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("calling IAM GetRole on %s: %w", name, err)
+	}
+	role := *roleOutput.Role
+	for _, tag := range role.Tags {
+		if fi.ValueOf(tag.Key) == ownershipTag && fi.ValueOf(tag.Value) == "owned" {
+			if role.Arn == nil {
+				roleARN, err := buildIAMARN(ctx, c, "role", aws.ToString(role.Path), name)
 				if err != nil {
-					if awsup.IsIAMNoSuchEntityException(err) {
-						klog.Warningf("could not find role %q. Resource may already have been deleted: %v", name, err)
-						continue
-					} else if awsup.AWSErrorCode(err) == "403" {
-						klog.Warningf("failed to determine ownership of %q: %v", name, err)
-						continue
-					}
-					return nil, fmt.Errorf("calling IAM GetRole on %s: %w", name, err)
-				}
-				for _, tag := range roleOutput.Role.Tags {
-					if fi.ValueOf(tag.Key) == ownershipTag && fi.ValueOf(tag.Value) == "owned" {
-						resourceTracker := &resources.Resource{
-							Name:    name,
-							ID:      name,
-							Type:    "iam-role",
-							Deleter: DeleteIAMRole,
-						}
-						resourceTrackers = append(resourceTrackers, resourceTracker)
-					}
+					return nil, err
 				}
+				role.Arn = aws.String(roleARN)
 			}
+			return &resources.Resource{
+				Name:    name,
+				ID:      name,
+				Type:    "iam-role",
+				Deleter: DeleteIAMRole,
+				Actions: deleteIAMRoleActions,
+				Obj:     &role,
+			}, nil
 		}
 	}
+	return nil, nil
+}
 
-	return resourceTrackers, nil
+// deleteIAMInstanceProfileActions are the AWS API actions invoked by DeleteIAMInstanceProfile
+var deleteIAMInstanceProfileActions = []string{
+	"iam:GetInstanceProfile",
+	"iam:RemoveRoleFromInstanceProfile",
+	"iam:DeleteInstanceProfile",
 }
 
 func DeleteIAMInstanceProfile(cloud fi.Cloud, r *resources.Resource) error {
@@ -1973,9 +3085,27 @@ func DeleteIAMInstanceProfile(cloud fi.Cloud, r *resources.Resource) error {
 	profile := r.Obj.(iamtypes.InstanceProfile)
 	name := aws.ToString(profile.InstanceProfileName)
 
+	// An instance profile can't be deleted while it has roles, and the roles may have changed since we listed it,
+	// so we look up the roles it has now.
+	var roles []iamtypes.Role
+	{
+		request := &iam.GetInstanceProfileInput{
+			InstanceProfileName: profile.InstanceProfileName,
+		}
+		response, err := c.IAM().GetInstanceProfile(ctx, request)
+		if err != nil {
+			if awsup.IsIAMNoSuchEntityException(err) {
+				klog.V(2).Infof("Got NoSuchEntity describing IAM instance profile %q; will treat as already-deleted", name)
+				return nil
+			}
+			return fmt.Errorf("error getting IAM instance profile %q: %w", name, err)
+		}
+		roles = response.InstanceProfile.Roles
+	}
+
 	// Remove roles
 	{
-		for _, role := range profile.Roles {
+		for _, role := range roles {
 			klog.V(2).Infof("Removing role %q from IAM instance profile %q", aws.ToString(role.RoleName), name)
 			request := &iam.RemoveRoleFromInstanceProfileInput{
 				InstanceProfileName: profile.InstanceProfileName,
@@ -1983,7 +3113,11 @@ func DeleteIAMInstanceProfile(cloud fi.Cloud, r *resources.Resource) error {
 			}
 			_, err := c.IAM().RemoveRoleFromInstanceProfile(ctx, request)
 			if err != nil {
-				return fmt.Errorf("error removing role %q from IAM instance profile %q: %v", aws.ToString(role.RoleName), name, err)
+				if awsup.IsIAMNoSuchEntityException(err) {
+					klog.V(2).Infof("Got NoSuchEntity removing role %q from IAM instance profile %q; will treat as already-removed", aws.ToString(role.RoleName), name)
+					continue
+				}
+				return fmt.Errorf("error removing role %q from IAM instance profile %q: %w", aws.ToString(role.RoleName), name, err)
 			}
 		}
 	}
@@ -1996,7 +3130,11 @@ func DeleteIAMInstanceProfile(cloud fi.Cloud, r *resources.Resource) error {
 		}
 		_, err := c.IAM().DeleteInstanceProfile(ctx, request)
 		if err != nil {
-			return fmt.Errorf("error deleting IAM instance profile %q: %v", name, err)
+			if awsup.IsIAMNoSuchEntityException(err) {
+				klog.V(2).Infof("Got NoSuchEntity deleting IAM instance profile %q; will treat as already-deleted", name)
+				return nil
+			}
+			return fmt.Errorf("error deleting IAM instance profile %q: %w", name, err)
 		}
 	}
 
@@ -2004,52 +3142,68 @@ func DeleteIAMInstanceProfile(cloud fi.Cloud, r *resources.Resource) error {
 }
 
 func ListIAMInstanceProfiles(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
-	ctx := context.TODO()
+	return ListIAMInstanceProfilesWithPathPrefix(context.Background(), cloud, clusterName, "")
+}
+
+// ListIAMInstanceProfilesWithPathPrefix lists the IAM instance profiles owned by the cluster,
+// only considering instance profiles under pathPrefix. An empty pathPrefix considers all instance profiles.
+func ListIAMInstanceProfilesWithPathPrefix(ctx context.Context, cloud fi.Cloud, clusterName, pathPrefix string) ([]*resources.Resource, error) {
 	c := cloud.(awsup.AWSCloud)
 
-	var profiles []iamtypes.InstanceProfile
+	var listed []iamtypes.InstanceProfile
 	ownershipTag := "kubernetes.io/cluster/" + clusterName
 
 	request := &iam.ListInstanceProfilesInput{}
+	if pathPrefix != "" {
+		request.PathPrefix = aws.String(pathPrefix)
+	}
 	paginator := iam.NewListInstanceProfilesPaginator(c.IAM(), request)
 	for paginator.HasMorePages() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("error listing IAM instance profiles: %v", err)
 		}
-		for _, p := range page.InstanceProfiles {
-			name := aws.ToString(p.InstanceProfileName)
+		listed = append(listed, page.InstanceProfiles...)
+	}
 
-			getRequest := &iam.GetInstanceProfileInput{InstanceProfileName: p.InstanceProfileName}
-			profileOutput, err := c.IAM().GetInstanceProfile(ctx, getRequest)
-			if err != nil {
-				if awsup.IsIAMNoSuchEntityException(err) {
-					klog.Warningf("could not find role %q. Resource may already have been deleted: %v", name, err)
-					continue
-				} else if awsup.AWSErrorCode(err) == "403" {
-					klog.Warningf("failed to determine ownership of %q: %v", *p.InstanceProfileName, err)
-					continue
-				}
-				return nil, fmt.Errorf("calling IAM GetInstanceProfile on %s: %w", name, err)
+	// As for roles, we look up the tags of the instance profiles concurrently, keeping the order of the list
+	owned := make([]*iamtypes.InstanceProfile, len(listed))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(iamListConcurrency)
+	for i := range listed {
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				return err
 			}
-			for _, tag := range profileOutput.InstanceProfile.Tags {
-				if fi.ValueOf(tag.Key) == ownershipTag && fi.ValueOf(tag.Value) == "owned" {
-					profiles = append(profiles, p)
-				}
+			profile, err := getOwnedIAMInstanceProfile(gctx, c, listed[i], ownershipTag)
+			if err != nil {
+				return err
 			}
-		}
+			owned[i] = profile
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
 	}
 
 	var resourceTrackers []*resources.Resource
 
-	for _, profile := range profiles {
+	for _, profile := range owned {
+		if profile == nil {
+			continue
+		}
 		name := aws.ToString(profile.InstanceProfileName)
 		resourceTracker := &resources.Resource{
 			Name:    name,
 			ID:      name,
 			Type:    "iam-instance-profile",
 			Deleter: DeleteIAMInstanceProfile,
-			Obj:     profile,
+			Actions: deleteIAMInstanceProfileActions,
+			Obj:     *profile,
 		}
 		resourceTracker.Blocks = append(resourceTracker.Blocks, "iam-role:"+name)
 
@@ -2059,7 +3213,63 @@ func ListIAMInstanceProfiles(cloud fi.Cloud, vpcID, clusterName string) ([]*reso
 	return resourceTrackers, nil
 }
 
-func ListIAMOIDCProviders(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+// getOwnedIAMInstanceProfile returns the listed instance profile if it has the ownership tag.
+// It returns nil if it doesn't, or if the instance profile can't be read.
+func getOwnedIAMInstanceProfile(ctx context.Context, c awsup.AWSCloud, p iamtypes.InstanceProfile, ownershipTag string) (*iamtypes.InstanceProfile, error) {
+	name := aws.ToString(p.InstanceProfileName)
+
+	getRequest := &iam.GetInstanceProfileInput{InstanceProfileName: p.InstanceProfileName}
+	profileOutput, err := c.IAM().GetInstanceProfile(ctx, getRequest)
+	if err != nil {
+		// An instance profile deleted between the list and the get is not ours to delete;
+		// any other error (e.g. throttling) means we can't tell whether we own it, so we fail.
+		var noSuchEntity *iamtypes.NoSuchEntityException
+		if errors.As(err, &noSuchEntity) {
+			klog.Warningf("could not find instance profile %q. Resource may already have been deleted: %v", name, err)
+			return nil, nil
+		} else if awsup.AWSErrorCode(err) == "403" {
+			klog.Warningf("failed to determine ownership of %q: %v", name, err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("calling IAM GetInstanceProfile on %s: %w", name, err)
+	}
+	for _, tag := range profileOutput.InstanceProfile.Tags {
+		if fi.ValueOf(tag.Key) == ownershipTag && fi.ValueOf(tag.Value) == "owned" {
+			if p.Arn == nil {
+				profileARN, err := buildIAMARN(ctx, c, "instance-profile", aws.ToString(p.Path), name)
+				if err != nil {
+					return nil, err
+				}
+				p.Arn = aws.String(profileARN)
+			}
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+// buildIAMARN builds the ARN of an IAM entity, for when the API did not return it.
+// The partition is taken from the cloud, so that GovCloud (aws-us-gov) and China (aws-cn) clusters get valid ARNs.
+func buildIAMARN(ctx context.Context, c awsup.AWSCloud, resourceType, path, name string) (string, error) {
+	accountID, partition, err := c.AccountInfo(ctx)
+	if err != nil {
+		return "", fmt.Errorf("building ARN for IAM %s %q: %w", resourceType, name, err)
+	}
+	if path == "" {
+		path = "/"
+	}
+	return arn.ARN{
+		Partition: partition,
+		Service:   "iam",
+		AccountID: accountID,
+		Resource:  resourceType + path + name,
+	}.String(), nil
+}
+
+// ListIAMOIDCProviders lists the IAM OIDC providers of the cluster: those carrying the cluster tags,
+// and, if issuerURL is set, the provider that trusts the cluster's service account issuer.
+// Providers tagged as shared with the cluster are never listed.
+func ListIAMOIDCProviders(cloud fi.Cloud, clusterName, issuerURL string) ([]*resources.Resource, error) {
 	ctx := context.TODO()
 	c := cloud.(awsup.AWSCloud)
 	tags := c.Tags()
@@ -2087,7 +3297,11 @@ func ListIAMOIDCProviders(cloud fi.Cloud, vpcID, clusterName string) ([]*resourc
 				}
 				return nil, fmt.Errorf("error getting IAM OIDC Provider %q: %w", aws.ToString(arn), err)
 			}
-			if !matchesIAMTags(tags, resp.Tags) {
+			if hasSharedIAMTag(resp.Tags, clusterName) {
+				klog.V(2).Infof("not deleting IAM OIDC Provider %q, which is shared", aws.ToString(arn))
+				continue
+			}
+			if !matchesIAMTags(tags, resp.Tags) && !matchesOIDCIssuerURL(issuerURL, aws.ToString(resp.Url)) {
 				continue
 			}
 			providers = append(providers, arn)
@@ -2102,6 +3316,7 @@ func ListIAMOIDCProviders(cloud fi.Cloud, vpcID, clusterName string) ([]*resourc
 			ID:      aws.ToString(arn),
 			Type:    "oidc-provider",
 			Deleter: DeleteIAMOIDCProvider,
+			Actions: deleteIAMOIDCProviderActions,
 		}
 		resourceTrackers = append(resourceTrackers, resourceTracker)
 	}
@@ -2109,6 +3324,11 @@ func ListIAMOIDCProviders(cloud fi.Cloud, vpcID, clusterName string) ([]*resourc
 	return resourceTrackers, nil
 }
 
+// deleteIAMOIDCProviderActions are the AWS API actions invoked by DeleteIAMOIDCProvider
+var deleteIAMOIDCProviderActions = []string{
+	"iam:DeleteOpenIDConnectProvider",
+}
+
 func DeleteIAMOIDCProvider(cloud fi.Cloud, r *resources.Resource) error {
 	ctx := context.TODO()
 	c := cloud.(awsup.AWSCloud)
@@ -2124,7 +3344,7 @@ func DeleteIAMOIDCProvider(cloud fi.Cloud, r *resources.Resource) error {
 				klog.V(2).Infof("Got NoSuchEntity deleting IAM OIDC Provider %v; will treat as already-deleted", arn)
 				return nil
 			}
-			return fmt.Errorf("error deleting IAM OIDC Provider %v: %v", arn, err)
+			return fmt.Errorf("error deleting IAM OIDC Provider %v: %w", arn, err)
 		}
 	}
 
@@ -2135,9 +3355,31 @@ func ListSpotinstResources(cloud fi.Cloud, vpcID, clusterName string) ([]*resour
 	return spotinst.ListResources(cloud.(awsup.AWSCloud).Spotinst(), clusterName)
 }
 
+// nameTagKeys are the keys of the tags that FindName looks for, in order of precedence.
+// Other tooling sometimes spells the "Name" tag differently.
+var nameTagKeys = []string{"Name", "name", "NAME"}
+
+// FindName returns the value of the first of the nameTagKeys tags found.
+// Failing that, the tag whose key is "name" in some other case is used; if there are several, the lowest key wins.
 func FindName(tags []*ec2.Tag) string {
-	if name, found := awsup.FindEC2Tag(tags, "Name"); found {
-		return name
+	for _, key := range nameTagKeys {
+		if name, found := awsup.FindEC2Tag(tags, key); found {
+			return name
+		}
+	}
+
+	var fallback *ec2.Tag
+	for _, tag := range tags {
+		key := aws.ToString(tag.Key)
+		if !strings.EqualFold(key, "Name") {
+			continue
+		}
+		if fallback == nil || key < aws.ToString(fallback.Key) {
+			fallback = tag
+		}
+	}
+	if fallback != nil {
+		return aws.ToString(fallback.Value)
 	}
 	return ""
 }
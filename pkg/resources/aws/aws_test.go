@@ -17,16 +17,46 @@ limitations under the License.
 package aws
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
 	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
 	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/smithy-go"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kops/cloudmock/aws/mockautoscaling"
+	"k8s.io/kops/cloudmock/aws/mockcloudwatchlogs"
 	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockefs"
+	"k8s.io/kops/cloudmock/aws/mockelb"
+	"k8s.io/kops/cloudmock/aws/mockelbv2"
+	"k8s.io/kops/cloudmock/aws/mockeventbridge"
 	"k8s.io/kops/cloudmock/aws/mockiam"
+	"k8s.io/kops/cloudmock/aws/mockkms"
+	"k8s.io/kops/cloudmock/aws/mockroute53"
+	"k8s.io/kops/cloudmock/aws/mocksqs"
 	"k8s.io/kops/pkg/resources"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
@@ -78,7 +108,7 @@ func TestAddUntaggedRouteTables(t *testing.T) {
 
 	resourceTrackers["vpc:vpc-1234"] = &resources.Resource{}
 
-	err := addUntaggedRouteTables(cloud, clusterName, resourceTrackers)
+	err := addUntaggedRouteTables(cloud, clusterName, resourceTrackers, 0, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -94,6 +124,167 @@ func TestAddUntaggedRouteTables(t *testing.T) {
 	}
 }
 
+func TestAddUntaggedInternetGateways(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	resourceTrackers := make(map[string]*resources.Resource)
+
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	// Matches by vpc id
+	c.AddInternetGateway(&ec2.InternetGateway{
+		InternetGatewayId: aws.String("igw-1234"),
+		Attachments: []*ec2.InternetGatewayAttachment{
+			{VpcId: aws.String("vpc-1234")},
+		},
+	})
+
+	// Skips internet gateway tagged with other cluster
+	c.AddInternetGateway(&ec2.InternetGateway{
+		InternetGatewayId: aws.String("igw-1234other"),
+		Attachments: []*ec2.InternetGatewayAttachment{
+			{VpcId: aws.String("vpc-1234")},
+		},
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String(awsup.TagClusterName),
+				Value: aws.String("other.example.com"),
+			},
+		},
+	})
+
+	// Ignores detached internet gateways
+	c.AddInternetGateway(&ec2.InternetGateway{
+		InternetGatewayId: aws.String("igw-detached"),
+	})
+
+	// Ignores non-matching vpcs
+	c.AddInternetGateway(&ec2.InternetGateway{
+		InternetGatewayId: aws.String("igw-5555"),
+		Attachments: []*ec2.InternetGatewayAttachment{
+			{VpcId: aws.String("vpc-5555")},
+		},
+	})
+
+	resourceTrackers["vpc:vpc-1234"] = &resources.Resource{}
+
+	err := addUntaggedInternetGateways(cloud, clusterName, resourceTrackers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var keys []string
+	for k := range resourceTrackers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	expected := []string{"internet-gateway:igw-1234", "vpc:vpc-1234"}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Fatalf("expected=%q, actual=%q", expected, keys)
+	}
+
+	// The gateway must be detached and deleted before the VPC
+	if blocks := resourceTrackers["internet-gateway:igw-1234"].Blocks; !reflect.DeepEqual(blocks, []string{"vpc:vpc-1234"}) {
+		t.Errorf("unexpected blocks for internet gateway: %v", blocks)
+	}
+}
+
+func TestAddUntaggedRouteTablesMinAge(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	resourceTrackers := make(map[string]*resources.Resource)
+
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	// Too new to adopt
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-new"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("CreationTimestamp"),
+				Value: aws.String(time.Now().Add(-time.Minute).Format(time.RFC3339)),
+			},
+		},
+	})
+
+	// Old enough to adopt
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-old"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("CreationTimestamp"),
+				Value: aws.String(time.Now().Add(-48 * time.Hour).Format(time.RFC3339)),
+			},
+		},
+	})
+
+	// Creation time can't be inferred, so adopted
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-unknown"),
+	})
+
+	resourceTrackers["vpc:vpc-1234"] = &resources.Resource{}
+
+	err := addUntaggedRouteTables(cloud, clusterName, resourceTrackers, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var keys []string
+	for k := range resourceTrackers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	expected := []string{"route-table:rtb-old", "route-table:rtb-unknown", "vpc:vpc-1234"}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Fatalf("expected=%q, actual=%q", expected, keys)
+	}
+}
+
+func TestAddUntaggedRouteTablesRestrictedVPCs(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	resourceTrackers := make(map[string]*resources.Resource)
+
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+	})
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-5555"),
+		RouteTableId: aws.String("rtb-5555"),
+	})
+
+	resourceTrackers["vpc:vpc-1234"] = &resources.Resource{}
+	resourceTrackers["vpc:vpc-5555"] = &resources.Resource{}
+
+	err := addUntaggedRouteTables(cloud, clusterName, resourceTrackers, 0, []string{"vpc-1234"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var keys []string
+	for k := range resourceTrackers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	expected := []string{"route-table:rtb-1234", "vpc:vpc-1234", "vpc:vpc-5555"}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Fatalf("expected=%q, actual=%q", expected, keys)
+	}
+}
+
 func TestListIAMInstanceProfiles(t *testing.T) {
 	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
 	// resources := make(map[string]*Resource)
@@ -168,6 +359,131 @@ func TestListIAMInstanceProfiles(t *testing.T) {
 	}
 }
 
+// failingGetInstanceProfileIAM fails GetInstanceProfile for one instance profile
+type failingGetInstanceProfileIAM struct {
+	*mockiam.MockIAM
+	name string
+	err  error
+}
+
+func (m *failingGetInstanceProfileIAM) GetInstanceProfile(ctx context.Context, request *iam.GetInstanceProfileInput, optFns ...func(*iam.Options)) (*iam.GetInstanceProfileOutput, error) {
+	if aws.ToString(request.InstanceProfileName) == m.name {
+		return nil, m.err
+	}
+	return m.MockIAM.GetInstanceProfile(ctx, request, optFns...)
+}
+
+func TestListIAMInstanceProfilesGetErrors(t *testing.T) {
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+
+	grid := []struct {
+		name          string
+		err           error
+		expectedError bool
+	}{
+		{
+			name: "no such entity",
+			err:  &iamtypes.NoSuchEntityException{Message: aws.String("The instance profile cannot be found")},
+		},
+		{
+			name:          "throttling",
+			err:           &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"},
+			expectedError: true,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			c := &mockiam.MockIAM{
+				InstanceProfiles: make(map[string]*iamtypes.InstanceProfile),
+			}
+			for _, name := range []string{"owned." + clusterName, "failing." + clusterName} {
+				c.InstanceProfiles[name] = &iamtypes.InstanceProfile{
+					InstanceProfileName: fi.PtrTo(name),
+					Tags:                []iamtypes.Tag{{Key: &ownershipTagKey, Value: fi.PtrTo("owned")}},
+				}
+			}
+			cloud.MockIAM = &failingGetInstanceProfileIAM{MockIAM: c, name: "failing." + clusterName, err: g.err}
+
+			resourceTrackers, err := ListIAMInstanceProfiles(cloud, "", clusterName)
+			if g.expectedError {
+				if err == nil || !strings.Contains(err.Error(), "Rate exceeded") {
+					t.Fatalf("expected the error to be returned, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error listing IAM instance profiles: %v", err)
+			}
+			if len(resourceTrackers) != 1 || resourceTrackers[0].ID != "owned."+clusterName {
+				t.Errorf("expected only the readable instance profile to be listed, got %v", resourceTrackers)
+			}
+		})
+	}
+}
+
+// callRecordingIAM records the instance profile calls made against the mock
+type callRecordingIAM struct {
+	*mockiam.MockIAM
+	calls []string
+}
+
+func (m *callRecordingIAM) RemoveRoleFromInstanceProfile(ctx context.Context, request *iam.RemoveRoleFromInstanceProfileInput, optFns ...func(*iam.Options)) (*iam.RemoveRoleFromInstanceProfileOutput, error) {
+	m.calls = append(m.calls, "RemoveRoleFromInstanceProfile "+aws.ToString(request.RoleName))
+	return m.MockIAM.RemoveRoleFromInstanceProfile(ctx, request, optFns...)
+}
+
+func (m *callRecordingIAM) DeleteInstanceProfile(ctx context.Context, request *iam.DeleteInstanceProfileInput, optFns ...func(*iam.Options)) (*iam.DeleteInstanceProfileOutput, error) {
+	m.calls = append(m.calls, "DeleteInstanceProfile "+aws.ToString(request.InstanceProfileName))
+	return m.MockIAM.DeleteInstanceProfile(ctx, request, optFns...)
+}
+
+func TestDeleteIAMInstanceProfileRemovesRoles(t *testing.T) {
+	ctx := context.Background()
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &callRecordingIAM{MockIAM: &mockiam.MockIAM{}}
+	cloud.MockIAM = c
+
+	name := "nodes." + clusterName
+	if _, err := c.CreateRole(ctx, &iam.CreateRoleInput{RoleName: aws.String(name)}); err != nil {
+		t.Fatalf("error creating role: %v", err)
+	}
+	if _, err := c.CreateInstanceProfile(ctx, &iam.CreateInstanceProfileInput{InstanceProfileName: aws.String(name)}); err != nil {
+		t.Fatalf("error creating instance profile: %v", err)
+	}
+
+	// The profile is listed before the role is added, so the tracker doesn't know about the role
+	r := &resources.Resource{
+		Name:    name,
+		ID:      name,
+		Type:    "iam-instance-profile",
+		Deleter: DeleteIAMInstanceProfile,
+		Obj:     *c.InstanceProfiles[name],
+	}
+
+	if _, err := c.AddRoleToInstanceProfile(ctx, &iam.AddRoleToInstanceProfileInput{InstanceProfileName: aws.String(name), RoleName: aws.String(name)}); err != nil {
+		t.Fatalf("error adding role to instance profile: %v", err)
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting instance profile: %v", err)
+	}
+
+	expected := []string{
+		"RemoveRoleFromInstanceProfile " + name,
+		"DeleteInstanceProfile " + name,
+	}
+	if !reflect.DeepEqual(c.calls, expected) {
+		t.Errorf("unexpected calls: actual=%v, expected=%v", c.calls, expected)
+	}
+	if _, found := c.InstanceProfiles[name]; found {
+		t.Errorf("expected instance profile to be deleted")
+	}
+}
+
 func TestListIAMRoles(t *testing.T) {
 	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
 	// resources := make(map[string]*Resource)
@@ -234,81 +550,2070 @@ func TestListIAMRoles(t *testing.T) {
 	}
 }
 
-func TestListRouteTables(t *testing.T) {
-	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
-	// resources := make(map[string]*Resource)
-	clusterName := "me.example.com"
+// buildManyIAMEntities returns a mock with n roles and instance profiles, of which every other one is owned by the cluster,
+// and an instance profile that appears in the list but can't be read
+func buildManyIAMEntities(n int, clusterName string) *mockiam.MockIAM {
 	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
 
-	c := &mockec2.MockEC2{}
-	cloud.MockEC2 = c
+	c := &mockiam.MockIAM{
+		Roles:            make(map[string]*iamtypes.Role),
+		InstanceProfiles: make(map[string]*iamtypes.InstanceProfile),
+	}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("entity-%d.%s", i, clusterName)
+		var tags []iamtypes.Tag
+		if i%2 == 0 {
+			tags = []iamtypes.Tag{{Key: &ownershipTagKey, Value: fi.PtrTo("owned")}}
+		}
+		c.Roles[name] = &iamtypes.Role{RoleName: fi.PtrTo(name), Tags: tags}
+		c.InstanceProfiles[name] = &iamtypes.InstanceProfile{InstanceProfileName: fi.PtrTo(name), Tags: tags}
+	}
+	name := "__no_entity__." + clusterName
+	c.InstanceProfiles[name] = &iamtypes.InstanceProfile{InstanceProfileName: &name}
+	return c
+}
 
-	c.AddRouteTable(&ec2.RouteTable{
-		VpcId:        aws.String("vpc-1234"),
-		RouteTableId: aws.String("rtb-shared"),
-		Tags: []*ec2.Tag{
-			{
-				Key:   aws.String("KubernetesCluster"),
-				Value: aws.String(clusterName),
-			},
-			{
-				Key:   aws.String(ownershipTagKey),
-				Value: aws.String("shared"),
-			},
-		},
-	})
-	c.AddRouteTable(&ec2.RouteTable{
-		VpcId:        aws.String("vpc-1234"),
-		RouteTableId: aws.String("rtb-owned"),
-		Tags: []*ec2.Tag{
-			{
-				Key:   aws.String("KubernetesCluster"),
-				Value: aws.String(clusterName),
-			},
-			{
-				Key:   aws.String(ownershipTagKey),
-				Value: aws.String("owned"),
-			},
-		},
-	})
+func TestListIAMEntitiesConcurrently(t *testing.T) {
+	clusterName := "me.example.com"
+	defer func(concurrency int) { iamListConcurrency = concurrency }(iamListConcurrency)
 
-	resourceTrackers, err := ListRouteTables(cloud, "", clusterName)
-	if err != nil {
-		t.Fatalf("error listing route tables: %v", err)
-	}
-	for _, rt := range resourceTrackers {
-		if rt.ID == "rtb-shared" && !rt.Shared {
-			t.Fatalf("expected Shared: true, got: %v", rt.Shared)
+	var expected []string
+	for _, concurrency := range []int{1, 8, 64} {
+		iamListConcurrency = concurrency
+
+		cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+		cloud.MockIAM = buildManyIAMEntities(100, clusterName)
+
+		var actual []string
+		for _, fn := range []listFn{ListIAMRoles, ListIAMInstanceProfiles} {
+			resourceTrackers, err := fn(cloud, "", clusterName)
+			if err != nil {
+				t.Fatalf("error listing IAM entities with concurrency %d: %v", concurrency, err)
+			}
+			for _, r := range resourceTrackers {
+				actual = append(actual, r.Type+":"+r.ID)
+			}
 		}
-		if rt.ID == "rtb-owned" && rt.Shared {
-			t.Fatalf("expected Shared: false, got: %v", rt.Shared)
+		sort.Strings(actual)
+
+		if len(actual) != 100 {
+			t.Errorf("expected 50 roles and 50 instance profiles with concurrency %d, got %d resources", concurrency, len(actual))
+		}
+		if expected == nil {
+			expected = actual
+		} else if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("unexpected resources with concurrency %d: actual=%v, expected=%v", concurrency, actual, expected)
 		}
 	}
 }
 
-func TestSharedVolume(t *testing.T) {
+// slowIAM adds latency to GetRole, as in a real account
+type slowIAM struct {
+	*mockiam.MockIAM
+}
+
+func (m *slowIAM) GetRole(ctx context.Context, request *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	time.Sleep(time.Millisecond)
+	return m.MockIAM.GetRole(ctx, request, optFns...)
+}
+
+func BenchmarkListIAMRoles(b *testing.B) {
+	clusterName := "me.example.com"
+	defer func(concurrency int) { iamListConcurrency = concurrency }(iamListConcurrency)
+
 	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	cloud.MockIAM = &slowIAM{MockIAM: buildManyIAMEntities(200, clusterName)}
+
+	for _, concurrency := range []int{1, 8} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			iamListConcurrency = concurrency
+			for i := 0; i < b.N; i++ {
+				if _, err := ListIAMRoles(cloud, "", clusterName); err != nil {
+					b.Fatalf("error listing IAM roles: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestListIAMRolesGovCloudPartition(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-gov-west-1", "a")
 	clusterName := "me.example.com"
 	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
 
-	c := &mockec2.MockEC2{}
-	cloud.MockEC2 = c
+	c := &mockiam.MockIAM{
+		Roles: make(map[string]*iamtypes.Role),
+	}
+	cloud.MockIAM = c
 
-	sharedVolume, err := c.CreateVolume(&ec2.CreateVolumeInput{
-		TagSpecifications: []*ec2.TagSpecification{
-			{
-				ResourceType: aws.String(ec2.ResourceTypeVolume),
-				Tags: []*ec2.Tag{
-					{
-						Key:   aws.String(ownershipTagKey),
-						Value: aws.String("shared"),
-					},
-				},
-			},
+	tags := []iamtypes.Tag{
+		{
+			Key:   &ownershipTagKey,
+			Value: fi.PtrTo("owned"),
 		},
-	})
-	if err != nil {
-		t.Fatalf("error creating volume: %v", err)
+	}
+	// The mock IAM doesn't return the ARN of this role, so it is built
+	masters := "masters." + clusterName
+	c.Roles[masters] = &iamtypes.Role{
+		RoleName: &masters,
+		Tags:     tags,
+	}
+	// The ARN that IAM returns is kept as is
+	nodes := "nodes." + clusterName
+	nodesARN := "arn:aws-us-gov:iam::111122223333:role/kops/" + nodes
+	c.Roles[nodes] = &iamtypes.Role{
+		RoleName: &nodes,
+		Path:     fi.PtrTo("/kops/"),
+		Arn:      &nodesARN,
+		Tags:     tags,
+	}
+
+	resourceTrackers, err := ListIAMRoles(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing IAM roles: %v", err)
+	}
+	if len(resourceTrackers) != 2 {
+		t.Fatalf("Unexpected number of resources to delete. Expected 2, got %d", len(resourceTrackers))
+	}
+
+	expected := map[string]string{
+		masters: "arn:aws-us-gov:iam::123456789012:role/" + masters,
+		nodes:   nodesARN,
+	}
+	for _, resourceTracker := range resourceTrackers {
+		role := resourceTracker.Obj.(*iamtypes.Role)
+		if aws.ToString(role.Arn) != expected[resourceTracker.Name] {
+			t.Errorf("unexpected ARN of role %q: actual=%q, expected=%q", resourceTracker.Name, aws.ToString(role.Arn), expected[resourceTracker.Name])
+		}
+	}
+}
+
+// getRoleRecordingIAM records the roles that GetRole is called for
+type getRoleRecordingIAM struct {
+	*mockiam.MockIAM
+	// mutex guards getRoleCalls, because roles are looked up concurrently
+	mutex        sync.Mutex
+	getRoleCalls []string
+}
+
+func (m *getRoleRecordingIAM) GetRole(ctx context.Context, request *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	m.mutex.Lock()
+	m.getRoleCalls = append(m.getRoleCalls, aws.ToString(request.RoleName))
+	m.mutex.Unlock()
+	return m.MockIAM.GetRole(ctx, request, optFns...)
+}
+
+func TestListIAMRolesWithPathPrefix(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+
+	c := &getRoleRecordingIAM{
+		MockIAM: &mockiam.MockIAM{
+			Roles: make(map[string]*iamtypes.Role),
+		},
+	}
+	cloud.MockIAM = c
+
+	tags := []iamtypes.Tag{
+		{
+			Key:   &ownershipTagKey,
+			Value: fi.PtrTo("owned"),
+		},
+	}
+	for name, path := range map[string]string{
+		"masters." + clusterName: "/kops/",
+		"nodes." + clusterName:   "/kops/nodes/",
+		"other." + clusterName:   "/",
+		"unset." + clusterName:   "",
+	} {
+		c.Roles[name] = &iamtypes.Role{
+			RoleName: aws.String(name),
+			Path:     aws.String(path),
+			Tags:     tags,
+		}
+	}
+
+	resourceTrackers, err := ListIAMRolesWithPathPrefix(context.TODO(), cloud, clusterName, "/kops/")
+	if err != nil {
+		t.Fatalf("error listing IAM roles: %v", err)
+	}
+
+	var names []string
+	for _, r := range resourceTrackers {
+		names = append(names, r.Name)
+	}
+	sort.Strings(names)
+	expected := []string{"masters." + clusterName, "nodes." + clusterName}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("unexpected roles: actual=%v, expected=%v", names, expected)
+	}
+
+	sort.Strings(c.getRoleCalls)
+	if !reflect.DeepEqual(c.getRoleCalls, expected) {
+		t.Errorf("unexpected GetRole calls: actual=%v, expected=%v", c.getRoleCalls, expected)
+	}
+}
+
+func TestListIAMOIDCProviders(t *testing.T) {
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+	issuerURL := "https://discovery.example.com/" + clusterName
+
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockiam.MockIAM{}
+	mockCloud.MockIAM = c
+	cloud := mockCloud.WithTags(map[string]string{awsup.TagClusterName: clusterName})
+
+	addProvider := func(url string, tags map[string]string) string {
+		var iamTags []iamtypes.Tag
+		for k, v := range tags {
+			iamTags = append(iamTags, iamtypes.Tag{Key: fi.PtrTo(k), Value: fi.PtrTo(v)})
+		}
+		response, err := c.CreateOpenIDConnectProvider(context.TODO(), &iam.CreateOpenIDConnectProviderInput{
+			Url:  fi.PtrTo(url),
+			Tags: iamTags,
+		})
+		if err != nil {
+			t.Fatalf("error creating IAM OIDC provider: %v", err)
+		}
+		return aws.ToString(response.OpenIDConnectProviderArn)
+	}
+
+	owned := addProvider("owned.example.com", map[string]string{awsup.TagClusterName: clusterName, ownershipTagKey: "owned"})
+	issuer := addProvider("discovery.example.com/"+clusterName, nil)
+	addProvider("shared.example.com", map[string]string{awsup.TagClusterName: clusterName, ownershipTagKey: "shared"})
+	addProvider("discovery.example.com/"+clusterName+"/shared", map[string]string{ownershipTagKey: "shared"})
+	addProvider("other.example.com", map[string]string{awsup.TagClusterName: "other.example.com"})
+
+	grid := []struct {
+		name      string
+		issuerURL string
+		expected  []string
+	}{
+		{
+			name:     "tags only",
+			expected: []string{owned},
+		},
+		{
+			name:      "tags and issuer",
+			issuerURL: issuerURL,
+			expected:  []string{issuer, owned},
+		},
+		{
+			name:      "shared issuer",
+			issuerURL: issuerURL + "/shared",
+			expected:  []string{owned},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			resourceTrackers, err := ListIAMOIDCProviders(cloud, clusterName, g.issuerURL)
+			if err != nil {
+				t.Fatalf("error listing IAM OIDC providers: %v", err)
+			}
+
+			var actual []string
+			for _, r := range resourceTrackers {
+				if r.Type != "oidc-provider" {
+					t.Errorf("unexpected resource type %q", r.Type)
+				}
+				actual = append(actual, r.ID)
+			}
+			sort.Strings(actual)
+			if !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("unexpected IAM OIDC providers: expected %v, got %v", g.expected, actual)
+			}
+		})
+	}
+}
+
+func TestListRouteTables(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	// resources := make(map[string]*Resource)
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-shared"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("KubernetesCluster"),
+				Value: aws.String(clusterName),
+			},
+			{
+				Key:   aws.String(ownershipTagKey),
+				Value: aws.String("shared"),
+			},
+		},
+	})
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-owned"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("KubernetesCluster"),
+				Value: aws.String(clusterName),
+			},
+			{
+				Key:   aws.String(ownershipTagKey),
+				Value: aws.String("owned"),
+			},
+		},
+	})
+
+	resourceTrackers, err := ListRouteTables(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing route tables: %v", err)
+	}
+	for _, rt := range resourceTrackers {
+		if rt.ID == "rtb-shared" && !rt.Shared {
+			t.Fatalf("expected Shared: true, got: %v", rt.Shared)
+		}
+		if rt.ID == "rtb-owned" && rt.Shared {
+			t.Fatalf("expected Shared: false, got: %v", rt.Shared)
+		}
+	}
+}
+
+func TestDescribeRouteTablesPaginates(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	expected := sets.NewString()
+	for i := 0; i < 250; i++ {
+		id := fmt.Sprintf("rtb-%04d", i)
+		owner := clusterName
+		if i%5 == 0 {
+			owner = "other.example.com"
+		} else {
+			expected.Insert(id)
+		}
+		c.AddRouteTable(&ec2.RouteTable{
+			VpcId:        aws.String("vpc-1234"),
+			RouteTableId: aws.String(id),
+			Tags: []*ec2.Tag{
+				{
+					Key:   aws.String("kubernetes.io/cluster/" + owner),
+					Value: aws.String("owned"),
+				},
+			},
+		})
+	}
+
+	routeTables, err := DescribeRouteTables(cloud, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error describing route tables: %v", err)
+	}
+	actual := sets.NewString()
+	for id := range routeTables {
+		actual.Insert(id)
+	}
+	if !actual.Equal(expected) {
+		t.Errorf("unexpected route tables: missing=%v, unexpected=%v", expected.Difference(actual).List(), actual.Difference(expected).List())
+	}
+}
+
+// deletedVPCEC2 is a MockEC2 where the VPC has been deleted by another process
+type deletedVPCEC2 struct {
+	*mockec2.MockEC2
+}
+
+func (m *deletedVPCEC2) DescribeRouteTablesPagesWithContext(ctx context.Context, request *ec2.DescribeRouteTablesInput, callback func(*ec2.DescribeRouteTablesOutput, bool) bool, options ...request.Option) error {
+	for _, filter := range request.Filters {
+		if aws.ToString(filter.Name) == "vpc-id" {
+			return awserr.New("InvalidVpcID.NotFound", "The vpc ID does not exist", nil)
+		}
+	}
+	return m.MockEC2.DescribeRouteTablesPagesWithContext(ctx, request, callback, options...)
+}
+
+func TestListRouteTablesInVPC(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	for id, vpcID := range map[string]string{
+		"rtb-1a": "vpc-1",
+		"rtb-1b": "vpc-1",
+		"rtb-2":  "vpc-2",
+	} {
+		c.AddRouteTable(&ec2.RouteTable{
+			VpcId:        aws.String(vpcID),
+			RouteTableId: aws.String(id),
+			Tags: []*ec2.Tag{
+				{
+					Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+					Value: aws.String("owned"),
+				},
+			},
+		})
+	}
+
+	grid := []struct {
+		vpcID    string
+		expected []string
+	}{
+		{
+			vpcID:    "",
+			expected: []string{"rtb-1a", "rtb-1b", "rtb-2"},
+		},
+		{
+			vpcID:    "vpc-1",
+			expected: []string{"rtb-1a", "rtb-1b"},
+		},
+		{
+			vpcID:    "vpc-3",
+			expected: nil,
+		},
+	}
+	for _, g := range grid {
+		resourceTrackers, err := ListRouteTables(cloud, g.vpcID, clusterName)
+		if err != nil {
+			t.Fatalf("error listing route tables in %q: %v", g.vpcID, err)
+		}
+		var actual []string
+		for _, r := range resourceTrackers {
+			actual = append(actual, r.ID)
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, g.expected) {
+			t.Errorf("unexpected route tables in %q: actual=%v, expected=%v", g.vpcID, actual, g.expected)
+		}
+	}
+}
+
+func TestListRouteTablesDeletedVPC(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-owned"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("owned"),
+			},
+		},
+	})
+
+	cloud.MockEC2 = c
+	resourceTrackers, err := ListRouteTables(cloud, "vpc-1234", clusterName)
+	if err != nil {
+		t.Fatalf("error listing route tables: %v", err)
+	}
+	if len(resourceTrackers) != 1 {
+		t.Fatalf("expected the route table in the VPC to be listed, got: %v", resourceTrackers)
+	}
+
+	cloud.MockEC2 = &deletedVPCEC2{MockEC2: c}
+	resourceTrackers, err = ListRouteTables(cloud, "vpc-1234", clusterName)
+	if err != nil {
+		t.Fatalf("expected a deleted VPC to be tolerated, got: %v", err)
+	}
+	if len(resourceTrackers) != 0 {
+		t.Errorf("expected no route tables, got: %v", resourceTrackers)
+	}
+}
+
+func TestListRouteTablesMultiCluster(t *testing.T) {
+	clusterName := "me.example.com"
+	otherClusterName := "other.example.com"
+
+	grid := []struct {
+		Name           string
+		Policy         resources.MultiClusterPolicy
+		ListedClusters []string
+		ExpectError    bool
+		ExpectShared   bool
+	}{
+		{
+			Name:        "default policy",
+			ExpectError: true,
+		},
+		{
+			Name:        "error",
+			Policy:      resources.MultiClusterPolicyError,
+			ExpectError: true,
+		},
+		{
+			Name:         "skip",
+			Policy:       resources.MultiClusterPolicySkip,
+			ExpectShared: true,
+		},
+		{
+			Name:         "delete-if-all-listed, other cluster not listed",
+			Policy:       resources.MultiClusterPolicyDeleteIfAllListed,
+			ExpectShared: true,
+		},
+		{
+			Name:           "delete-if-all-listed, other cluster listed",
+			Policy:         resources.MultiClusterPolicyDeleteIfAllListed,
+			ListedClusters: []string{otherClusterName},
+			ExpectShared:   false,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.Name, func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			c := &mockec2.MockEC2{}
+			cloud.MockEC2 = c
+
+			c.AddRouteTable(&ec2.RouteTable{
+				VpcId:        aws.String("vpc-1234"),
+				RouteTableId: aws.String("rtb-1234"),
+				Tags: []*ec2.Tag{
+					{
+						Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+						Value: aws.String("owned"),
+					},
+					{
+						Key:   aws.String("kubernetes.io/cluster/" + otherClusterName),
+						Value: aws.String("owned"),
+					},
+				},
+			})
+
+			options := resources.ListOptions{
+				MultiClusterPolicy: g.Policy,
+				ListedClusters:     g.ListedClusters,
+			}
+			resourceTrackers, err := ListRouteTablesMultiCluster(context.TODO(), cloud, "", clusterName, options)
+			if g.ExpectError {
+				if err == nil {
+					t.Fatalf("expected error listing route table owned by two clusters")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error listing route tables: %v", err)
+			}
+			if len(resourceTrackers) != 1 {
+				t.Fatalf("expected 1 route table, got %d", len(resourceTrackers))
+			}
+			if resourceTrackers[0].Shared != g.ExpectShared {
+				t.Errorf("expected Shared: %v, got: %v", g.ExpectShared, resourceTrackers[0].Shared)
+			}
+		})
+	}
+}
+
+func TestListRouteTablesClusterTagScheme(t *testing.T) {
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &countingRouteTablesEC2{MockEC2: &mockec2.MockEC2{}}
+	mockCloud.MockEC2 = c
+
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("owned"),
+			},
+		},
+	})
+
+	grid := []struct {
+		scheme   resources.ClusterTagScheme
+		expected int
+		calls    int
+	}{
+		{scheme: "", expected: 1, calls: 2},
+		{scheme: resources.ClusterTagSchemeAll, expected: 1, calls: 2},
+		{scheme: resources.ClusterTagSchemeOwnership, expected: 1, calls: 1},
+		// The route table only has the ownership tag, so isn't found by the legacy tag
+		{scheme: resources.ClusterTagSchemeLegacy, expected: 0, calls: 1},
+	}
+	for _, g := range grid {
+		t.Run(string(g.scheme), func(t *testing.T) {
+			c.calls = 0
+			options := resources.ListOptions{AWSClusterTagScheme: g.scheme}
+			resourceTrackers, err := ListRouteTablesMultiCluster(context.TODO(), mockCloud, "vpc-1234", clusterName, options)
+			if err != nil {
+				t.Fatalf("error listing route tables: %v", err)
+			}
+			if len(resourceTrackers) != g.expected {
+				t.Errorf("unexpected route tables: actual=%v, expected %d", resourceTrackers, g.expected)
+			}
+			if c.calls != g.calls {
+				t.Errorf("unexpected calls to describe route tables: actual=%d, expected=%d", c.calls, g.calls)
+			}
+		})
+	}
+
+	options := resources.ListOptions{AWSClusterTagScheme: "new"}
+	if _, err := ListRouteTablesMultiCluster(context.TODO(), mockCloud, "vpc-1234", clusterName, options); err == nil {
+		t.Errorf("expected an unknown tag scheme to fail the listing")
+	}
+}
+
+func TestDumpRouteTableSharded(t *testing.T) {
+	clusterName := "me.example.com"
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+	}
+	subnet := &ec2.Subnet{
+		VpcId:            aws.String("vpc-1234"),
+		SubnetId:         aws.String("subnet-1234"),
+		AvailabilityZone: aws.String("us-east-1a"),
+	}
+	resourceMap := map[string]*resources.Resource{
+		"route-table:rtb-1234": buildTrackerForRouteTable(rt, clusterName, nil),
+		"subnet:subnet-1234": {
+			Type:   ec2.ResourceTypeSubnet,
+			ID:     "subnet-1234",
+			Obj:    subnet,
+			Dumper: DumpSubnet,
+		},
+	}
+
+	d, err := resources.BuildDump(context.Background(), nil, resourceMap)
+	if err != nil {
+		t.Fatalf("error building dump: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := resources.WriteShardedDump(d, dir); err != nil {
+		t.Fatalf("error writing sharded dump: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("error reading dump directory: %v", err)
+	}
+	var files []string
+	for _, e := range entries {
+		files = append(files, e.Name())
+	}
+	expectedFiles := []string{"route-tables.json", "subnets.json", "summary.json"}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Fatalf("unexpected shard files: actual=%v, expected=%v", files, expectedFiles)
+	}
+
+	routeTables, err := os.ReadFile(filepath.Join(dir, "route-tables.json"))
+	if err != nil {
+		t.Fatalf("error reading route table shard: %v", err)
+	}
+	if !strings.Contains(string(routeTables), "rtb-1234") {
+		t.Errorf("expected route table shard to contain the route table, got %s", routeTables)
+	}
+
+	subnets, err := os.ReadFile(filepath.Join(dir, "subnets.json"))
+	if err != nil {
+		t.Fatalf("error reading subnet shard: %v", err)
+	}
+	if strings.Contains(string(subnets), "rtb-1234") {
+		t.Errorf("expected subnet shard not to contain the route table, got %s", subnets)
+	}
+}
+
+func TestGroupDumpByVPC(t *testing.T) {
+	clusterName := "me.example.com"
+
+	resourceMap := make(map[string]*resources.Resource)
+	for _, rt := range []*ec2.RouteTable{
+		{VpcId: aws.String("vpc-1"), RouteTableId: aws.String("rtb-1a")},
+		{VpcId: aws.String("vpc-1"), RouteTableId: aws.String("rtb-1b")},
+		{VpcId: aws.String("vpc-2"), RouteTableId: aws.String("rtb-2a")},
+	} {
+		tracker := buildTrackerForRouteTable(rt, clusterName, nil)
+		resourceMap[tracker.Type+":"+tracker.ID] = tracker
+	}
+
+	d, err := resources.BuildDump(context.Background(), nil, resourceMap)
+	if err != nil {
+		t.Fatalf("error building dump: %v", err)
+	}
+	resources.GroupDumpByVPC(d, resourceMap)
+
+	if len(d.Resources) != 0 {
+		t.Errorf("expected all resources to be grouped, got ungrouped %v", d.Resources)
+	}
+	actual := make(map[string][]string)
+	for vpcID, grouped := range d.ResourcesByVPC {
+		for _, r := range grouped {
+			actual[vpcID] = append(actual[vpcID], r.(*resources.RouteTableDump).ID)
+		}
+		sort.Strings(actual[vpcID])
+	}
+	expected := map[string][]string{
+		"vpc-1": {"rtb-1a", "rtb-1b"},
+		"vpc-2": {"rtb-2a"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected grouping: actual=%v, expected=%v", actual, expected)
+	}
+}
+
+func TestDumpRouteTableRoutes(t *testing.T) {
+	clusterName := "me.example.com"
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Routes: []*ec2.Route{
+			{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local"), State: aws.String("active")},
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-1234"), State: aws.String("active")},
+			{DestinationIpv6CidrBlock: aws.String("::/0"), EgressOnlyInternetGatewayId: aws.String("eigw-1234"), State: aws.String("active")},
+			{DestinationPrefixListId: aws.String("pl-1234"), NetworkInterfaceId: aws.String("eni-1234"), State: aws.String("blackhole")},
+		},
+	}
+	tracker := buildTrackerForRouteTable(rt, clusterName, nil)
+
+	dump, err := resources.BuildDump(context.TODO(), nil, map[string]*resources.Resource{
+		tracker.Type + ":" + tracker.ID: tracker,
+	})
+	if err != nil {
+		t.Fatalf("error building dump: %v", err)
+	}
+	if len(dump.Resources) != 1 {
+		t.Fatalf("expected 1 resource, got: %v", dump.Resources)
+	}
+	actual := dump.Resources[0].(*resources.RouteTableDump)
+	if actual.ID != "rtb-1234" || actual.Type != ec2.ResourceTypeRouteTable || actual.Raw != rt {
+		t.Errorf("unexpected route table dump: %+v", actual)
+	}
+	expected := []resources.RouteDump{
+		{Destination: "10.0.0.0/16", Target: "local", State: "active"},
+		{Destination: "0.0.0.0/0", Target: "nat-1234", State: "active"},
+		{Destination: "::/0", Target: "eigw-1234", State: "active"},
+		{Destination: "pl-1234", Target: "eni-1234", State: "blackhole"},
+	}
+	if !reflect.DeepEqual(actual.Routes, expected) {
+		t.Errorf("unexpected routes: actual=%+v, expected=%+v", actual.Routes, expected)
+	}
+
+	// The dump can still be loaded from the raw route table
+	dumpJSON, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("error serializing dump: %v", err)
+	}
+	loaded, err := LoadDumpAsResources(dumpJSON, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error loading dump: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].ID != "rtb-1234" {
+		t.Errorf("unexpected loaded resources: %v", loaded)
+	}
+}
+
+func TestDumpRedactsUserData(t *testing.T) {
+	userData := "dG9rZW46IHNlY3JldA=="
+	request := &ec2.SpotInstanceRequest{
+		SpotInstanceRequestId: aws.String("sir-1234"),
+		LaunchSpecification: &ec2.LaunchSpecification{
+			ImageId:  aws.String("ami-1234"),
+			UserData: aws.String(userData),
+		},
+	}
+	resourceMap := map[string]*resources.Resource{
+		"spot-instances-request:sir-1234": {
+			ID:     "sir-1234",
+			Type:   ec2.ResourceTypeSpotInstancesRequest,
+			Dumper: DumpSpotInstanceRequest,
+			Obj:    request,
+		},
+	}
+
+	grid := []struct {
+		includeSecrets bool
+		expected       string
+	}{
+		{includeSecrets: false, expected: resources.RedactedMarker},
+		{includeSecrets: true, expected: userData},
+	}
+	for _, g := range grid {
+		t.Run(fmt.Sprintf("includeSecrets=%v", g.includeSecrets), func(t *testing.T) {
+			dump, err := resources.BuildDumpWithOptions(context.TODO(), nil, resourceMap, resources.DumpOptions{IncludeSecrets: g.includeSecrets})
+			if err != nil {
+				t.Fatalf("error building dump: %v", err)
+			}
+			dumpJSON, err := dump.ToJSON()
+			if err != nil {
+				t.Fatalf("error serializing dump: %v", err)
+			}
+
+			var actual struct {
+				Resources []struct {
+					Raw struct {
+						SpotInstanceRequestId string
+						LaunchSpecification   struct {
+							ImageId  string
+							UserData string
+						}
+					} `json:"raw"`
+				} `json:"resources"`
+			}
+			if err := json.Unmarshal(dumpJSON, &actual); err != nil {
+				t.Fatalf("error parsing dump: %v", err)
+			}
+			if len(actual.Resources) != 1 {
+				t.Fatalf("expected one resource, got %s", dumpJSON)
+			}
+			raw := actual.Resources[0].Raw
+			if raw.LaunchSpecification.UserData != g.expected {
+				t.Errorf("unexpected user-data: actual=%q, expected=%q", raw.LaunchSpecification.UserData, g.expected)
+			}
+			if raw.SpotInstanceRequestId != "sir-1234" || raw.LaunchSpecification.ImageId != "ami-1234" {
+				t.Errorf("expected the other fields to be kept, got %s", dumpJSON)
+			}
+		})
+	}
+
+	// The tracked object itself is left alone
+	if aws.ToString(request.LaunchSpecification.UserData) != userData {
+		t.Errorf("expected the resource not to be modified, got user-data %q", aws.ToString(request.LaunchSpecification.UserData))
+	}
+}
+
+func TestDumpToJSON(t *testing.T) {
+	clusterName := "me.example.com"
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Routes: []*ec2.Route{
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-1234"), State: aws.String("active")},
+		},
+	}
+	tracker := buildTrackerForRouteTable(rt, clusterName, nil)
+
+	dump, err := resources.BuildDump(context.TODO(), nil, map[string]*resources.Resource{
+		tracker.Type + ":" + tracker.ID: tracker,
+	})
+	if err != nil {
+		t.Fatalf("error building dump: %v", err)
+	}
+	dumpJSON, err := dump.ToJSON()
+	if err != nil {
+		t.Fatalf("error serializing dump: %v", err)
+	}
+
+	var actual struct {
+		SchemaVersion string `json:"schemaVersion"`
+		Resources     []struct {
+			ID     string                 `json:"id"`
+			Type   string                 `json:"type"`
+			Routes []resources.RouteDump  `json:"routes"`
+			Raw    map[string]interface{} `json:"raw"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(dumpJSON, &actual); err != nil {
+		t.Fatalf("error parsing dump: %v", err)
+	}
+	if actual.SchemaVersion != resources.DumpSchemaVersion {
+		t.Errorf("unexpected schemaVersion: actual=%q, expected=%q", actual.SchemaVersion, resources.DumpSchemaVersion)
+	}
+	if len(actual.Resources) != 1 {
+		t.Fatalf("expected 1 resource, got: %s", dumpJSON)
+	}
+	r := actual.Resources[0]
+	if r.ID != "rtb-1234" || r.Type != ec2.ResourceTypeRouteTable {
+		t.Errorf("unexpected route table: %s", dumpJSON)
+	}
+	if expected := []resources.RouteDump{{Destination: "0.0.0.0/0", Target: "nat-1234", State: "active"}}; !reflect.DeepEqual(r.Routes, expected) {
+		t.Errorf("unexpected routes: actual=%+v, expected=%+v", r.Routes, expected)
+	}
+	if r.Raw["RouteTableId"] != "rtb-1234" || r.Raw["VpcId"] != "vpc-1234" {
+		t.Errorf("unexpected raw route table: %v", r.Raw)
+	}
+
+	// The versioned document can still be loaded
+	loaded, err := LoadDumpAsResources(dumpJSON, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error loading dump: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].ID != "rtb-1234" {
+		t.Errorf("unexpected loaded resources: %v", loaded)
+	}
+}
+
+func TestLoadDumpAsResources(t *testing.T) {
+	clusterName := "me.example.com"
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Associations: []*ec2.RouteTableAssociation{
+			{
+				RouteTableId: aws.String("rtb-1234"),
+				SubnetId:     aws.String("subnet-1234"),
+			},
+		},
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("owned"),
+			},
+		},
+	}
+	tracker := buildTrackerForRouteTable(rt, clusterName, nil)
+
+	dump, err := resources.BuildDump(context.TODO(), nil, map[string]*resources.Resource{
+		tracker.Type + ":" + tracker.ID: tracker,
+	})
+	if err != nil {
+		t.Fatalf("error building dump: %v", err)
+	}
+	dumpJSON, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("error serializing dump: %v", err)
+	}
+
+	loaded, err := LoadDumpAsResources(dumpJSON, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error loading dump: %v", err)
+	}
+	if len(loaded) != 1 {
+		t.Fatalf("expected 1 resource, got: %v", loaded)
+	}
+	r := loaded[0]
+	if r.Type != ec2.ResourceTypeRouteTable || r.ID != "rtb-1234" || r.Shared {
+		t.Errorf("unexpected resource: %+v", r)
+	}
+	if expected := []string{"vpc:vpc-1234"}; !reflect.DeepEqual(r.Blocks, expected) {
+		t.Errorf("unexpected blocks: actual=%v, expected=%v", r.Blocks, expected)
+	}
+	if expected := []string{"subnet:subnet-1234"}; !reflect.DeepEqual(r.Blocked, expected) {
+		t.Errorf("unexpected blocked: actual=%v, expected=%v", r.Blocked, expected)
+	}
+	if r.Deleter == nil {
+		t.Errorf("expected deleter to be reattached")
+	}
+}
+
+func TestDeleteRouteTableActions(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Associations: []*ec2.RouteTableAssociation{
+			{
+				RouteTableAssociationId: aws.String("rtbassoc-1234"),
+				RouteTableId:            aws.String("rtb-1234"),
+				SubnetId:                aws.String("subnet-1234"),
+			},
+		},
+	}
+	c.AddRouteTable(rt)
+
+	r := buildTrackerForRouteTable(rt, clusterName, nil)
+	for _, expected := range []string{"ec2:DeleteRouteTable", "ec2:DisassociateRouteTable"} {
+		found := false
+		for _, action := range r.Actions {
+			if action == expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected route table Deleter to declare %q, got %v", expected, r.Actions)
+		}
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting route table: %v", err)
+	}
+	if _, found := c.RouteTables["rtb-1234"]; found {
+		t.Fatalf("expected route table to be deleted")
+	}
+	if len(rt.Associations) != 0 {
+		t.Fatalf("expected route table to be disassociated, got %v", rt.Associations)
+	}
+}
+
+func TestDeleteRouteTableRefusesSharedSubnet(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddSubnet(&ec2.Subnet{
+		SubnetId: aws.String("subnet-shared"),
+		VpcId:    aws.String("vpc-1234"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("shared"),
+			},
+		},
+	})
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Associations: []*ec2.RouteTableAssociation{
+			{
+				RouteTableAssociationId: aws.String("rtbassoc-1234"),
+				RouteTableId:            aws.String("rtb-1234"),
+				SubnetId:                aws.String("subnet-shared"),
+			},
+		},
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("owned"),
+			},
+		},
+	}
+	c.AddRouteTable(rt)
+
+	r := buildTrackerForRouteTable(rt, clusterName, nil)
+	err := r.Deleter(cloud, r)
+	if err == nil || !strings.Contains(err.Error(), "subnet-shared") {
+		t.Fatalf("expected refusal to disassociate the shared subnet, got: %v", err)
+	}
+	if !IsTerminal(err) {
+		t.Errorf("expected the refusal to be terminal, got: %v", err)
+	}
+	if _, found := c.RouteTables["rtb-1234"]; !found {
+		t.Fatalf("expected route table not to be deleted")
+	}
+	if len(rt.Associations) != 1 {
+		t.Fatalf("expected route table to stay associated, got %v", rt.Associations)
+	}
+
+	if err := DeleteRouteTableDisassociatingSharedSubnets(cloud, r); err != nil {
+		t.Fatalf("error deleting route table with override: %v", err)
+	}
+	if _, found := c.RouteTables["rtb-1234"]; found {
+		t.Fatalf("expected route table to be deleted with override")
+	}
+}
+
+func TestDeleteRouteTableRefusesSubnetInUse(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	ownedTags := []*ec2.Tag{
+		{
+			Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+			Value: aws.String("owned"),
+		},
+	}
+	c.AddSubnet(&ec2.Subnet{
+		SubnetId: aws.String("subnet-1234"),
+		VpcId:    aws.String("vpc-1234"),
+		Tags:     ownedTags,
+	})
+	c.AddInstance(&ec2.Instance{
+		InstanceId: aws.String("i-running"),
+		State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{SubnetId: aws.String("subnet-1234")},
+		},
+	})
+	c.AddInstance(&ec2.Instance{
+		InstanceId: aws.String("i-stopped"),
+		State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopped)},
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{SubnetId: aws.String("subnet-1234")},
+		},
+	})
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Associations: []*ec2.RouteTableAssociation{
+			{
+				RouteTableAssociationId: aws.String("rtbassoc-1234"),
+				RouteTableId:            aws.String("rtb-1234"),
+				SubnetId:                aws.String("subnet-1234"),
+			},
+		},
+		Tags: ownedTags,
+	}
+	c.AddRouteTable(rt)
+
+	r := buildTrackerForRouteTable(rt, clusterName, nil)
+	err := r.Deleter(cloud, r)
+	if err == nil || !strings.Contains(err.Error(), "running instances") {
+		t.Fatalf("expected refusal to disassociate the subnet with a running instance, got: %v", err)
+	}
+	if len(rt.Associations) != 1 {
+		t.Fatalf("expected route table to stay associated, got %v", rt.Associations)
+	}
+
+	d := &routeTableDeleter{disassociateSubnetsInUse: true}
+	if err := d.Delete(cloud, r); err != nil {
+		t.Fatalf("error deleting route table with force: %v", err)
+	}
+	if _, found := c.RouteTables["rtb-1234"]; found {
+		t.Fatalf("expected route table to be deleted with force")
+	}
+}
+
+func TestDeleteRouteTableWithPropagatingVGW(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		PropagatingVgws: []*ec2.PropagatingVgw{
+			{GatewayId: aws.String("vgw-1234")},
+		},
+		Associations: []*ec2.RouteTableAssociation{
+			{
+				RouteTableAssociationId: aws.String("rtbassoc-1234"),
+				RouteTableId:            aws.String("rtb-1234"),
+				GatewayId:               aws.String("vgw-1234"),
+			},
+		},
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("owned"),
+			},
+		},
+	}
+	c.AddRouteTable(rt)
+
+	r := buildTrackerForRouteTable(rt, clusterName, nil)
+	// VPN gateways aren't listed, so the route table doesn't block them
+	expectedBlocks := []string{"vpc:vpc-1234"}
+	if !reflect.DeepEqual(r.Blocks, expectedBlocks) {
+		t.Errorf("unexpected blocks: actual=%v, expected=%v", r.Blocks, expectedBlocks)
+	}
+	if len(r.Blocked) != 0 {
+		t.Errorf("expected a gateway association not to be blocked by a subnet, got %v", r.Blocked)
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting route table: %v", err)
+	}
+	if _, found := c.RouteTables["rtb-1234"]; found {
+		t.Fatalf("expected route table to be deleted")
+	}
+}
+
+// disappearingAssociationEC2 simulates a subnet deletion in flight, which removes the route table
+// association after it has been described, but before it is disassociated.
+type disappearingAssociationEC2 struct {
+	*mockec2.MockEC2
+	described bool
+}
+
+func (m *disappearingAssociationEC2) DescribeRouteTables(request *ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error) {
+	response, err := m.MockEC2.DescribeRouteTables(request)
+	if err == nil && !m.described {
+		m.described = true
+		for _, rt := range m.MockEC2.RouteTables {
+			rt.Associations = nil
+		}
+	}
+	return response, err
+}
+
+func TestDeleteRouteTableAssociationRemovedConcurrently(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = &disappearingAssociationEC2{MockEC2: c}
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Associations: []*ec2.RouteTableAssociation{
+			{
+				RouteTableAssociationId: aws.String("rtbassoc-1234"),
+				RouteTableId:            aws.String("rtb-1234"),
+				SubnetId:                aws.String("subnet-1234"),
+			},
+		},
+	}
+	c.AddRouteTable(rt)
+
+	r := buildTrackerForRouteTable(rt, clusterName, nil)
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("unexpected error deleting route table: %v", err)
+	}
+	if _, found := c.RouteTables["rtb-1234"]; found {
+		t.Fatalf("expected route table to be deleted")
+	}
+}
+
+func TestFindNatGatewaysElasticIPs(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	allocateAddress := func(id string, tags ...*ec2.Tag) {
+		request := &ec2.AllocateAddressInput{}
+		if len(tags) != 0 {
+			request.TagSpecifications = []*ec2.TagSpecification{
+				{ResourceType: aws.String(ec2.ResourceTypeElasticIp), Tags: tags},
+			}
+		}
+		if _, err := c.AllocateAddressWithId(request, id); err != nil {
+			t.Fatalf("error allocating address: %v", err)
+		}
+	}
+	allocateAddress("eipalloc-owned", &ec2.Tag{Key: aws.String(ownershipTagKey), Value: aws.String("owned")})
+	// An address brought by the user isn't tagged
+	allocateAddress("eipalloc-byo")
+
+	var routes []*ec2.Route
+	for _, ngw := range []struct{ id, allocationID string }{
+		{id: "nat-owned", allocationID: "eipalloc-owned"},
+		{id: "nat-byo", allocationID: "eipalloc-byo"},
+	} {
+		if _, err := c.CreateNatGatewayWithId(&ec2.CreateNatGatewayInput{
+			AllocationId: aws.String(ngw.allocationID),
+			SubnetId:     aws.String("subnet-1234"),
+		}, ngw.id); err != nil {
+			t.Fatalf("error creating NAT gateway: %v", err)
+		}
+		routes = append(routes, &ec2.Route{
+			DestinationCidrBlock: aws.String("0.0.0.0/0"),
+			NatGatewayId:         aws.String(ngw.id),
+		})
+	}
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Routes:       routes,
+		Tags: []*ec2.Tag{
+			{Key: aws.String(ownershipTagKey), Value: aws.String("owned")},
+		},
+	}
+	c.AddRouteTable(rt)
+
+	resourceTrackers, err := FindNatGateways(cloud, map[string]*resources.Resource{
+		"rtb-1234": buildTrackerForRouteTable(rt, clusterName, nil),
+	}, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error finding NAT gateways: %v", err)
+	}
+
+	trackers := make(map[string]*resources.Resource)
+	for _, r := range resourceTrackers {
+		trackers[r.Type+":"+r.ID] = r
+	}
+
+	// The NAT gateways are deleted before their addresses are released
+	for _, k := range []string{"nat-gateway:nat-owned", "nat-gateway:nat-byo"} {
+		ngw := trackers[k]
+		if ngw == nil || ngw.Shared {
+			t.Fatalf("expected owned NAT gateway %s, got %+v", k, ngw)
+		}
+	}
+	if blocks := trackers["nat-gateway:nat-owned"].Blocks; !reflect.DeepEqual(blocks, []string{"elastic-ip:eipalloc-owned"}) {
+		t.Errorf("unexpected blocks for NAT gateway: %v", blocks)
+	}
+
+	if eip := trackers["elastic-ip:eipalloc-owned"]; eip == nil || eip.Shared {
+		t.Errorf("expected owned Elastic IP to be released, got %+v", eip)
+	}
+	if eip := trackers["elastic-ip:eipalloc-byo"]; eip == nil || !eip.Shared {
+		t.Errorf("expected Elastic IP brought by the user to be shared, got %+v", eip)
+	}
+
+	listed, err := ListElasticIPs(cloud, "vpc-1234", clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing Elastic IPs: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != "eipalloc-owned" || listed[0].Shared {
+		t.Errorf("expected only the owned Elastic IP to be listed, got %v", listed)
+	}
+}
+
+func TestRemoveStaleRoutes(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-shared"),
+		Routes: []*ec2.Route{
+			{
+				DestinationCidrBlock: aws.String("10.0.0.0/16"),
+				GatewayId:            aws.String("local"),
+				State:                aws.String(ec2.RouteStateActive),
+			},
+			{
+				DestinationCidrBlock: aws.String("0.0.0.0/0"),
+				NatGatewayId:         aws.String("nat-deleted"),
+				State:                aws.String(ec2.RouteStateBlackhole),
+			},
+			{
+				DestinationCidrBlock: aws.String("172.16.0.0/16"),
+				NetworkInterfaceId:   aws.String("eni-other"),
+				State:                aws.String(ec2.RouteStateBlackhole),
+			},
+			{
+				DestinationCidrBlock: aws.String("192.168.0.0/16"),
+				NatGatewayId:         aws.String("nat-other"),
+				State:                aws.String(ec2.RouteStateActive),
+			},
+			{
+				// EC2 reports both the instance and its network interface
+				DestinationCidrBlock: aws.String("10.1.0.0/16"),
+				InstanceId:           aws.String("i-deleted"),
+				NetworkInterfaceId:   aws.String("eni-instance"),
+				State:                aws.String(ec2.RouteStateBlackhole),
+			},
+		},
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("shared")},
+		},
+	}
+	c.AddRouteTable(rt)
+
+	resourceMap := map[string]*resources.Resource{
+		"route-table:rtb-shared": buildTrackerForRouteTable(rt, clusterName, nil),
+		"nat-gateway:nat-deleted": {
+			Type: TypeNatGateway,
+			ID:   "nat-deleted",
+		},
+		"instance:i-deleted": {
+			Type: ec2.ResourceTypeInstance,
+			ID:   "i-deleted",
+		},
+		// Deleted by someone else in the meantime
+		"route-table:rtb-gone": {
+			Type:   ec2.ResourceTypeRouteTable,
+			ID:     "rtb-gone",
+			Shared: true,
+		},
+	}
+
+	// Enough other shared route tables that rtb-shared is only described on a later page
+	for i := 0; i < 150; i++ {
+		id := fmt.Sprintf("rtb-%03d", i)
+		c.AddRouteTable(&ec2.RouteTable{
+			VpcId:        aws.String("vpc-1234"),
+			RouteTableId: aws.String(id),
+		})
+		resourceMap["route-table:"+id] = &resources.Resource{
+			Type:   ec2.ResourceTypeRouteTable,
+			ID:     id,
+			Shared: true,
+		}
+	}
+
+	if err := RemoveStaleRoutes(cloud, resourceMap); err != nil {
+		t.Fatalf("unexpected error removing stale routes: %v", err)
+	}
+
+	var actual []string
+	for _, route := range c.RouteTables["rtb-shared"].Routes {
+		actual = append(actual, aws.ToString(route.DestinationCidrBlock))
+	}
+	expected := []string{"10.0.0.0/16", "172.16.0.0/16", "192.168.0.0/16"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected remaining routes: actual=%v, expected=%v", actual, expected)
+	}
+}
+
+func TestDescribeRouteTablesIgnoreTagsPaginated(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	for i := 0; i < 150; i++ {
+		c.AddRouteTable(&ec2.RouteTable{
+			VpcId:        aws.String("vpc-1234"),
+			RouteTableId: aws.String(fmt.Sprintf("rtb-%03d", i)),
+		})
+	}
+
+	routeTables, err := DescribeRouteTablesIgnoreTags(cloud)
+	if err != nil {
+		t.Fatalf("error describing route tables: %v", err)
+	}
+	if len(routeTables) != 150 {
+		t.Errorf("expected all the pages of route tables, got %d route tables", len(routeTables))
+	}
+}
+
+// unavailableIAM is a MockIAM where the IAM service can't be reached
+type unavailableIAM struct {
+	*mockiam.MockIAM
+}
+
+func (m *unavailableIAM) ListRoles(ctx context.Context, request *iam.ListRolesInput, optFns ...func(*iam.Options)) (*iam.ListRolesOutput, error) {
+	return nil, &iamtypes.ServiceFailureException{Message: aws.String("service unavailable")}
+}
+
+func TestListResourcesIsolatesServiceFailures(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.k8s.local"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+	cloud.MockIAM = &unavailableIAM{MockIAM: &mockiam.MockIAM{}}
+	cloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+	cloud.MockELB = &mockelb.MockELB{}
+	cloud.MockELBV2 = &mockelbv2.MockELBV2{}
+	cloud.MockSQS = &mocksqs.MockSQS{}
+	cloud.MockEventBridge = &mockeventbridge.MockEventBridge{}
+	cloud.MockKMS = &mockkms.MockKMS{}
+	cloud.MockLogs = &mockcloudwatchlogs.MockCloudWatchLogs{}
+	cloud.MockEFS = &mockefs.MockEFS{}
+
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-owned"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("owned"),
+			},
+		},
+	})
+
+	resourceTrackers, err := ListResourcesAWS(cloud, resources.ClusterInfo{Name: clusterName})
+	var serviceFailures *resources.ServiceFailuresError
+	if !errors.As(err, &serviceFailures) {
+		t.Fatalf("expected service failures, got: %v", err)
+	}
+	if _, found := serviceFailures.Failures["iam"]; !found || len(serviceFailures.Failures) != 1 {
+		t.Fatalf("expected only iam to fail, got: %v", serviceFailures)
+	}
+
+	rt := resourceTrackers["route-table:rtb-owned"]
+	if rt == nil {
+		t.Fatalf("expected route table to be listed, got: %v", resourceTrackers)
+	}
+	if err := rt.Deleter(cloud, rt); err != nil {
+		t.Fatalf("error deleting route table: %v", err)
+	}
+	if len(c.RouteTables) != 0 {
+		t.Errorf("expected route table to be deleted")
+	}
+}
+
+// spotRequestsDeniedEC2 is a MockEC2 where the IAM policy doesn't permit describing the spot instance requests
+type spotRequestsDeniedEC2 struct {
+	*mockec2.MockEC2
+}
+
+func (m *spotRequestsDeniedEC2) DescribeSpotInstanceRequestsPages(request *ec2.DescribeSpotInstanceRequestsInput, callback func(*ec2.DescribeSpotInstanceRequestsOutput, bool) bool) error {
+	return awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil)
+}
+
+func TestListResourcesIsolatesEC2PermissionFailures(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.k8s.local"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = &spotRequestsDeniedEC2{MockEC2: c}
+	cloud.MockIAM = &mockiam.MockIAM{}
+	cloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+	cloud.MockELB = &mockelb.MockELB{}
+	cloud.MockELBV2 = &mockelbv2.MockELBV2{}
+	cloud.MockSQS = &mocksqs.MockSQS{}
+	cloud.MockEventBridge = &mockeventbridge.MockEventBridge{}
+
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-owned"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("owned"),
+			},
+		},
+	})
+
+	// A policy written before kops deleted spot instance requests doesn't stop the other resources from being listed
+	resourceTrackers, err := ListResourcesAWS(cloud, resources.ClusterInfo{Name: clusterName})
+	var serviceFailures *resources.ServiceFailuresError
+	if !errors.As(err, &serviceFailures) {
+		t.Fatalf("expected service failures, got: %v", err)
+	}
+	if _, found := serviceFailures.Failures["ec2/spot-instances-request"]; !found || len(serviceFailures.Failures) != 1 {
+		t.Fatalf("expected only the spot instance requests to fail, got: %v", serviceFailures)
+	}
+	if resourceTrackers["route-table:rtb-owned"] == nil {
+		t.Errorf("expected route table to be listed, got: %v", resourceTrackers)
+	}
+}
+
+func TestListELBsMatchesLegacyName(t *testing.T) {
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	c := &mockelb.MockELB{}
+	mockCloud.MockELB = c
+	cloud := mockCloud.WithTags(map[string]string{awsup.TagClusterName: clusterName})
+
+	legacyName := awsup.GetResourceName32(clusterName, "api")
+	for _, name := range []string{legacyName, "api-other-example-com-abcdef"} {
+		if _, err := c.CreateLoadBalancer(context.TODO(), &elb.CreateLoadBalancerInput{
+			LoadBalancerName: aws.String(name),
+		}); err != nil {
+			t.Fatalf("error creating load balancer: %v", err)
+		}
+	}
+	if _, err := c.CreateLoadBalancer(context.TODO(), &elb.CreateLoadBalancerInput{
+		LoadBalancerName: aws.String("tagged"),
+	}); err != nil {
+		t.Fatalf("error creating load balancer: %v", err)
+	}
+	if _, err := c.AddTags(context.TODO(), &elb.AddTagsInput{
+		LoadBalancerNames: []string{"tagged"},
+		Tags: []elbtypes.Tag{
+			{Key: aws.String(awsup.TagClusterName), Value: aws.String(clusterName)},
+		},
+	}); err != nil {
+		t.Fatalf("error tagging load balancer: %v", err)
+	}
+
+	resourceTrackers, err := ListELBs(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing load balancers: %v", err)
+	}
+	var actual []string
+	for _, r := range resourceTrackers {
+		actual = append(actual, r.ID)
+	}
+	sort.Strings(actual)
+	expected := []string{legacyName, "tagged"}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected load balancers: actual=%v, expected=%v", actual, expected)
+	}
+}
+
+func TestListELBsMatchingTags(t *testing.T) {
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipKey := "kubernetes.io/cluster/" + clusterName
+	c := &mockelb.MockELB{}
+	mockCloud.MockELB = c
+	cloud := mockCloud.WithTags(map[string]string{ownershipKey: "owned"})
+
+	for name, tags := range map[string][]elbtypes.Tag{
+		"owned":      {{Key: aws.String(ownershipKey), Value: aws.String("owned")}},
+		"owned-2024": {{Key: aws.String(ownershipKey), Value: aws.String("owned-2024")}, {Key: aws.String("team"), Value: aws.String("a")}},
+		"team-only":  {{Key: aws.String("team"), Value: aws.String("b")}},
+	} {
+		if _, err := c.CreateLoadBalancer(context.TODO(), &elb.CreateLoadBalancerInput{
+			LoadBalancerName: aws.String(name),
+		}); err != nil {
+			t.Fatalf("error creating load balancer: %v", err)
+		}
+		if _, err := c.AddTags(context.TODO(), &elb.AddTagsInput{
+			LoadBalancerNames: []string{name},
+			Tags:              tags,
+		}); err != nil {
+			t.Fatalf("error tagging load balancer: %v", err)
+		}
+	}
+
+	grid := []struct {
+		name     string
+		wantTags map[string]string
+		expected []string
+	}{
+		{
+			name:     "cloud tags",
+			expected: []string{"owned"},
+		},
+		{
+			name:     "exact",
+			wantTags: map[string]string{ownershipKey: "owned-2024"},
+			expected: []string{"owned-2024"},
+		},
+		{
+			name:     "glob",
+			wantTags: map[string]string{ownershipKey: "owned*"},
+			expected: []string{"owned", "owned-2024"},
+		},
+		{
+			name:     "key only",
+			wantTags: map[string]string{"team": ""},
+			expected: []string{"owned-2024", "team-only"},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			resourceTrackers, err := ListELBsMatchingTags(cloud, clusterName, g.wantTags)
+			if err != nil {
+				t.Fatalf("error listing load balancers: %v", err)
+			}
+			var actual []string
+			for _, r := range resourceTrackers {
+				actual = append(actual, r.ID)
+			}
+			sort.Strings(actual)
+			if !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("unexpected load balancers: actual=%v, expected=%v", actual, g.expected)
+			}
+		})
+	}
+}
+
+func TestDeleteNLBAndTargetGroups(t *testing.T) {
+	ctx := context.TODO()
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	c := &mockelbv2.MockELBV2{EC2: &mockec2.MockEC2{}}
+	mockCloud.MockELBV2 = c
+	cloud := mockCloud.WithTags(map[string]string{awsup.TagClusterName: clusterName})
+
+	clusterTags := []elbv2types.Tag{{Key: aws.String(awsup.TagClusterName), Value: aws.String(clusterName)}}
+	createLoadBalancer := func(name string, tags []elbv2types.Tag) string {
+		response, err := c.CreateLoadBalancer(ctx, &elbv2.CreateLoadBalancerInput{
+			Name: aws.String(name),
+			Type: elbv2types.LoadBalancerTypeEnumNetwork,
+			Tags: tags,
+		})
+		if err != nil {
+			t.Fatalf("error creating load balancer: %v", err)
+		}
+		return aws.ToString(response.LoadBalancers[0].LoadBalancerArn)
+	}
+	createTargetGroup := func(name string) string {
+		response, err := c.CreateTargetGroup(ctx, &elbv2.CreateTargetGroupInput{
+			Name: aws.String(name),
+			Tags: clusterTags,
+		})
+		if err != nil {
+			t.Fatalf("error creating target group: %v", err)
+		}
+		return aws.ToString(response.TargetGroups[0].TargetGroupArn)
+	}
+	createListener := func(loadBalancerARN, targetGroupARN string) {
+		if _, err := c.CreateListener(ctx, &elbv2.CreateListenerInput{
+			LoadBalancerArn: aws.String(loadBalancerARN),
+			DefaultActions: []elbv2types.Action{
+				{Type: elbv2types.ActionTypeEnumForward, TargetGroupArn: aws.String(targetGroupARN)},
+			},
+		}); err != nil {
+			t.Fatalf("error creating listener: %v", err)
+		}
+	}
+
+	nlb := createLoadBalancer("api-me-example-com", clusterTags)
+	tg := createTargetGroup("tcp-me-example-com")
+	createListener(nlb, tg)
+
+	// A target group of the cluster that a load balancer outside the cluster still forwards to
+	otherNLB := createLoadBalancer("other", nil)
+	usedTG := createTargetGroup("used-me-example-com")
+	createListener(otherNLB, usedTG)
+
+	loadBalancers, err := ListELBV2s(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing load balancers: %v", err)
+	}
+	if len(loadBalancers) != 1 || loadBalancers[0].ID != nlb {
+		t.Fatalf("expected only the cluster's load balancer, got %v", loadBalancers)
+	}
+	targetGroups, err := ListTargetGroups(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing target groups: %v", err)
+	}
+	byID := make(map[string]*resources.Resource)
+	for _, r := range targetGroups {
+		byID[r.ID] = r
+	}
+	if r := byID[tg]; r == nil || r.Shared || !reflect.DeepEqual(r.Blocked, []string{TypeLoadBalancer + ":" + nlb}) {
+		t.Fatalf("expected the target group to be deleted after the load balancer, got %+v", r)
+	}
+	if r := byID[usedTG]; r == nil || !r.Shared {
+		t.Fatalf("expected the target group used by another load balancer to be shared, got %+v", r)
+	}
+
+	// The target group can't be deleted while the load balancer forwards to it
+	if err := byID[tg].Deleter(cloud, byID[tg]); !IsDependencyViolation(err) {
+		t.Fatalf("expected a dependency violation deleting the target group in use, got %v", err)
+	}
+
+	if err := loadBalancers[0].Deleter(cloud, loadBalancers[0]); err != nil {
+		t.Fatalf("error deleting load balancer: %v", err)
+	}
+	if err := byID[tg].Deleter(cloud, byID[tg]); err != nil {
+		t.Fatalf("error deleting target group: %v", err)
+	}
+	if _, found := c.LoadBalancers[nlb]; found {
+		t.Errorf("expected the load balancer to be deleted")
+	}
+	if _, found := c.TargetGroups[tg]; found {
+		t.Errorf("expected the target group to be deleted")
+	}
+	if len(c.Listeners) != 1 {
+		t.Errorf("expected only the listener of the other load balancer to remain, got %d listeners", len(c.Listeners))
+	}
+
+	// Deleting again is a no-op
+	if err := loadBalancers[0].Deleter(cloud, loadBalancers[0]); err != nil {
+		t.Errorf("expected deleting a deleted load balancer to succeed, got %v", err)
+	}
+}
+
+// cancellingIAM is a MockIAM where the listing of roles is cancelled while it pages through them
+type cancellingIAM struct {
+	*mockiam.MockIAM
+	cancel    context.CancelFunc
+	listRoles int
+	getRole   int
+}
+
+func (m *cancellingIAM) ListRoles(ctx context.Context, request *iam.ListRolesInput, optFns ...func(*iam.Options)) (*iam.ListRolesOutput, error) {
+	m.listRoles++
+	m.cancel()
+	return &iam.ListRolesOutput{
+		Roles:       []iamtypes.Role{{RoleName: aws.String("role-1")}},
+		IsTruncated: true,
+		Marker:      aws.String(fmt.Sprintf("page-%d", m.listRoles)),
+	}, nil
+}
+
+func (m *cancellingIAM) GetRole(ctx context.Context, request *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	m.getRole++
+	return m.MockIAM.GetRole(ctx, request, optFns...)
+}
+
+func TestListResourcesCancelled(t *testing.T) {
+	clusterName := "me.k8s.local"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelling := &cancellingIAM{MockIAM: &mockiam.MockIAM{}, cancel: cancel}
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	cloud.MockIAM = cancelling
+
+	_, err := ListIAMRolesWithPathPrefix(ctx, cloud, clusterName, "")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the listing to be cancelled, got: %v", err)
+	}
+	if cancelling.listRoles != 1 || cancelling.getRole != 0 {
+		t.Errorf("expected the listing to stop after the first page, got %d ListRoles and %d GetRole calls", cancelling.listRoles, cancelling.getRole)
+	}
+
+	// The cancellation aborts the whole listing, rather than being reported as a failure of IAM
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	cancelling = &cancellingIAM{MockIAM: &mockiam.MockIAM{}, cancel: cancel}
+
+	cloud.MockEC2 = &mockec2.MockEC2{}
+	cloud.MockIAM = cancelling
+	cloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+	cloud.MockELB = &mockelb.MockELB{}
+	cloud.MockELBV2 = &mockelbv2.MockELBV2{}
+	cloud.MockSQS = &mocksqs.MockSQS{}
+	cloud.MockEventBridge = &mockeventbridge.MockEventBridge{}
+	cloud.MockKMS = &mockkms.MockKMS{}
+	cloud.MockLogs = &mockcloudwatchlogs.MockCloudWatchLogs{}
+	cloud.MockEFS = &mockefs.MockEFS{}
+
+	_, err = ListResourcesFilteredWithContext(ctx, cloud, resources.ClusterInfo{Name: clusterName}, []string{"iam-role", "sqs"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the listing to be cancelled, got: %v", err)
+	}
+	var serviceFailures *resources.ServiceFailuresError
+	if errors.As(err, &serviceFailures) {
+		t.Errorf("expected the cancellation not to be reported as a service failure, got: %v", err)
+	}
+}
+
+// recordingIAM is a MockIAM that records the roles and instance profiles being listed
+type recordingIAM struct {
+	*mockiam.MockIAM
+	calls []string
+}
+
+func (m *recordingIAM) ListRoles(ctx context.Context, request *iam.ListRolesInput, optFns ...func(*iam.Options)) (*iam.ListRolesOutput, error) {
+	m.calls = append(m.calls, "ListRoles")
+	return m.MockIAM.ListRoles(ctx, request, optFns...)
+}
+
+func (m *recordingIAM) ListInstanceProfiles(ctx context.Context, request *iam.ListInstanceProfilesInput, optFns ...func(*iam.Options)) (*iam.ListInstanceProfilesOutput, error) {
+	m.calls = append(m.calls, "ListInstanceProfiles")
+	return m.MockIAM.ListInstanceProfiles(ctx, request, optFns...)
+}
+
+func TestListResourcesSkipIAM(t *testing.T) {
+	clusterName := "me.k8s.local"
+
+	grid := []struct {
+		skipIAM  bool
+		expected []string
+	}{
+		{
+			skipIAM:  false,
+			expected: []string{"iam-instance-profile:profile", "iam-role:role", "route-table:rtb-owned"},
+		},
+		{
+			skipIAM:  true,
+			expected: []string{"route-table:rtb-owned"},
+		},
+	}
+	for _, g := range grid {
+		t.Run(fmt.Sprintf("skipIAM=%v", g.skipIAM), func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			c := &mockec2.MockEC2{}
+			cloud.MockEC2 = c
+			iamMock := &recordingIAM{MockIAM: &mockiam.MockIAM{}}
+			cloud.MockIAM = iamMock
+			cloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+			cloud.MockELB = &mockelb.MockELB{}
+			cloud.MockELBV2 = &mockelbv2.MockELBV2{}
+			cloud.MockSQS = &mocksqs.MockSQS{}
+			cloud.MockEventBridge = &mockeventbridge.MockEventBridge{}
+			cloud.MockKMS = &mockkms.MockKMS{}
+			cloud.MockLogs = &mockcloudwatchlogs.MockCloudWatchLogs{}
+			cloud.MockEFS = &mockefs.MockEFS{}
+
+			c.AddRouteTable(&ec2.RouteTable{
+				VpcId:        aws.String("vpc-1234"),
+				RouteTableId: aws.String("rtb-owned"),
+				Tags: []*ec2.Tag{
+					{
+						Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+						Value: aws.String("owned"),
+					},
+				},
+			})
+			ownedIAMTags := []iamtypes.Tag{
+				{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+			}
+			iamMock.CreateRole(context.TODO(), &iam.CreateRoleInput{
+				RoleName: aws.String("role"),
+				Tags:     ownedIAMTags,
+			})
+			iamMock.CreateInstanceProfile(context.TODO(), &iam.CreateInstanceProfileInput{
+				InstanceProfileName: aws.String("profile"),
+				Tags:                ownedIAMTags,
+			})
+
+			clusterInfo := resources.ClusterInfo{Name: clusterName}
+			clusterInfo.AWSSkipIAM = g.skipIAM
+			resourceTrackers, err := ListResourcesAWS(cloud, clusterInfo)
+			if err != nil {
+				t.Fatalf("error listing resources: %v", err)
+			}
+			var actual []string
+			for k := range resourceTrackers {
+				actual = append(actual, k)
+			}
+			sort.Strings(actual)
+			if !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("unexpected resources: actual=%v, expected=%v", actual, g.expected)
+			}
+
+			if g.skipIAM && len(iamMock.calls) != 0 {
+				t.Errorf("expected IAM roles and instance profiles not to be listed, got calls: %v", iamMock.calls)
+			}
+		})
+	}
+}
+
+func TestListResourcesFiltered(t *testing.T) {
+	clusterName := "me.k8s.local"
+	ownedTags := []*ec2.Tag{
+		{
+			Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+			Value: aws.String("owned"),
+		},
+	}
+
+	grid := []struct {
+		types    []string
+		expected []string
+	}{
+		{
+			// Only EC2 is mocked, so the listers of the other services would fail if they ran
+			types:    []string{"route-table"},
+			expected: []string{"route-table:rtb-owned"},
+		},
+		{
+			types:    []string{"vpc", "subnet"},
+			expected: []string{"subnet:subnet-owned", "vpc:vpc-1234"},
+		},
+	}
+	for _, g := range grid {
+		t.Run(strings.Join(g.types, ","), func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			c := &mockec2.MockEC2{}
+			cloud.MockEC2 = c
+
+			c.CreateVpcWithId(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, "vpc-1234")
+			c.CreateTags(&ec2.CreateTagsInput{Resources: aws.StringSlice([]string{"vpc-1234"}), Tags: ownedTags})
+			c.AddSubnet(&ec2.Subnet{
+				SubnetId: aws.String("subnet-owned"),
+				VpcId:    aws.String("vpc-1234"),
+				Tags:     ownedTags,
+			})
+			c.AddRouteTable(&ec2.RouteTable{
+				VpcId:        aws.String("vpc-1234"),
+				RouteTableId: aws.String("rtb-owned"),
+				Tags:         ownedTags,
+			})
+
+			resourceTrackers, err := ListResourcesFiltered(cloud, resources.ClusterInfo{Name: clusterName}, g.types)
+			if err != nil {
+				t.Fatalf("error listing resources: %v", err)
+			}
+			var actual []string
+			for k := range resourceTrackers {
+				actual = append(actual, k)
+			}
+			sort.Strings(actual)
+			if !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("unexpected resources: actual=%v, expected=%v", actual, g.expected)
+			}
+		})
+	}
+}
+
+func TestListResourcesFilteredUnknownType(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	cloud.MockEC2 = &mockec2.MockEC2{}
+
+	_, err := ListResourcesFiltered(cloud, resources.ClusterInfo{Name: "me.k8s.local"}, []string{"route-tables"})
+	if err == nil || !strings.Contains(err.Error(), "route-tables") {
+		t.Fatalf("expected unknown type to be rejected, got: %v", err)
+	}
+}
+
+func TestTagSharedResources(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-shared"),
+	})
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-owned"),
+	})
+
+	resourceMap := map[string]*resources.Resource{
+		"route-table:rtb-shared": {Type: ec2.ResourceTypeRouteTable, ID: "rtb-shared", Shared: true},
+		"route-table:rtb-owned":  {Type: ec2.ResourceTypeRouteTable, ID: "rtb-owned"},
+		"iam-role:shared":        {Type: "iam-role", ID: "shared", Shared: true},
+	}
+	if err := TagSharedResources(cloud, resourceMap, "run-1"); err != nil {
+		t.Fatalf("error tagging shared resources: %v", err)
+	}
+
+	for id, expected := range map[string]map[string]string{
+		"rtb-shared": {TagLastDeleteRun: "run-1"},
+		"rtb-owned":  {},
+	} {
+		actual, err := cloud.GetTags(id)
+		if err != nil {
+			t.Fatalf("error getting tags: %v", err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("unexpected tags on %s: actual=%v, expected=%v", id, actual, expected)
+		}
+	}
+}
+
+func TestListSubnetsOwnedByOtherAccount(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	accountID, _, err := cloud.AccountInfo(context.TODO())
+	if err != nil {
+		t.Fatalf("error getting account: %v", err)
+	}
+
+	for id, ownerID := range map[string]string{
+		"subnet-ours":    accountID,
+		"subnet-foreign": "210987654321",
+	} {
+		c.AddSubnet(&ec2.Subnet{
+			SubnetId: aws.String(id),
+			VpcId:    aws.String("vpc-1234"),
+			OwnerId:  aws.String(ownerID),
+			Tags: []*ec2.Tag{
+				{
+					Key:   aws.String(ownershipTagKey),
+					Value: aws.String("owned"),
+				},
+			},
+		})
+	}
+
+	resourceTrackers, err := ListSubnets(cloud, "vpc-1234", clusterName)
+	if err != nil {
+		t.Fatalf("error listing subnets: %v", err)
+	}
+	if len(resourceTrackers) != 2 {
+		t.Fatalf("expected 2 subnets, got: %v", resourceTrackers)
+	}
+	for _, r := range resourceTrackers {
+		if r.ID == "subnet-ours" && r.Shared {
+			t.Errorf("expected subnet owned by our account to be deletable")
+		}
+		if r.ID == "subnet-foreign" && !r.Shared {
+			t.Errorf("expected subnet owned by another account to be shared")
+		}
+	}
+}
+
+func TestSharedVolume(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	sharedVolume, err := c.CreateVolume(&ec2.CreateVolumeInput{
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeVolume),
+				Tags: []*ec2.Tag{
+					{
+						Key:   aws.String(ownershipTagKey),
+						Value: aws.String("shared"),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error creating volume: %v", err)
 	}
 
 	ownedVolume, err := c.CreateVolume(&ec2.CreateVolumeInput{
@@ -323,22 +2628,322 @@ func TestSharedVolume(t *testing.T) {
 			},
 		},
 	})
-	if err != nil {
-		t.Fatalf("error creating volume: %v", err)
+	if err != nil {
+		t.Fatalf("error creating volume: %v", err)
+	}
+
+	resourceTrackers, err := ListVolumes(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing volumes: %v", err)
+	}
+	t.Log(len(resourceTrackers))
+	for _, rt := range resourceTrackers {
+		if rt.ID == *sharedVolume.VolumeId && !rt.Shared {
+			t.Fatalf("expected Shared: true, got: %v", rt.Shared)
+		}
+		if rt.ID == *ownedVolume.VolumeId && rt.Shared {
+			t.Fatalf("expected Shared: false, got: %v", rt.Shared)
+		}
+	}
+}
+
+func TestListVolumesAttachedToInstances(t *testing.T) {
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	clusterTags := []*ec2.Tag{
+		{Key: aws.String(awsup.TagClusterName), Value: aws.String(clusterName)},
+	}
+
+	c := &mockec2.MockEC2{}
+	mockCloud.MockEC2 = c
+	cloud := mockCloud.WithTags(map[string]string{awsup.TagClusterName: clusterName})
+
+	running := &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)}
+	c.AddInstance(&ec2.Instance{
+		InstanceId: aws.String("i-cluster"),
+		VpcId:      aws.String("vpc-1234"),
+		State:      running,
+		Tags:       clusterTags,
+	})
+	c.AddInstance(&ec2.Instance{
+		InstanceId: aws.String("i-other"),
+		VpcId:      aws.String("vpc-1234"),
+		State:      running,
+	})
+
+	createVolume := func(attachments ...*ec2.VolumeAttachment) string {
+		volume, err := c.CreateVolume(&ec2.CreateVolumeInput{
+			TagSpecifications: []*ec2.TagSpecification{
+				{ResourceType: aws.String(ec2.ResourceTypeVolume), Tags: clusterTags},
+			},
+		})
+		if err != nil {
+			t.Fatalf("error creating volume: %v", err)
+		}
+		id := aws.ToString(volume.VolumeId)
+		c.Volumes[id].Attachments = attachments
+		return id
+	}
+
+	unattached := createVolume()
+	attachedToCluster := createVolume(&ec2.VolumeAttachment{
+		InstanceId: aws.String("i-cluster"),
+		State:      aws.String(ec2.VolumeAttachmentStateAttached),
+	})
+	createVolume(&ec2.VolumeAttachment{
+		InstanceId: aws.String("i-other"),
+		State:      aws.String(ec2.VolumeAttachmentStateAttached),
+	})
+	detachedFromOther := createVolume(&ec2.VolumeAttachment{
+		InstanceId: aws.String("i-other"),
+		State:      aws.String(ec2.VolumeAttachmentStateDetached),
+	})
+
+	resourceTrackers, err := ListVolumes(cloud, "vpc-1234", clusterName)
+	if err != nil {
+		t.Fatalf("error listing volumes: %v", err)
+	}
+
+	actual := make(map[string][]string)
+	for _, r := range resourceTrackers {
+		if r.Shared {
+			t.Errorf("expected volume %q to be owned", r.ID)
+		}
+		actual[r.ID] = r.Blocked
+	}
+	// The volume attached to an instance outside the cluster isn't deleted,
+	// and the volume attached to a cluster instance is deleted after the instance
+	expected := map[string][]string{
+		unattached:        nil,
+		attachedToCluster: {"instance:i-cluster"},
+		detachedFromOther: nil,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected volumes: actual=%v, expected=%v", actual, expected)
+	}
+}
+
+func TestListNetworkInterfaces(t *testing.T) {
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	clusterTags := []*ec2.Tag{
+		{Key: aws.String(awsup.TagClusterName), Value: aws.String(clusterName)},
+	}
+
+	c := &mockec2.MockEC2{}
+	mockCloud.MockEC2 = c
+	cloud := mockCloud.WithTags(map[string]string{awsup.TagClusterName: clusterName})
+
+	c.CreateVpcWithId(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, "vpc-1234")
+	c.CreateTags(&ec2.CreateTagsInput{
+		Resources: aws.StringSlice([]string{"vpc-1234"}),
+		Tags:      clusterTags,
+	})
+
+	running := &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)}
+	c.AddInstance(&ec2.Instance{
+		InstanceId: aws.String("i-cluster"),
+		VpcId:      aws.String("vpc-1234"),
+		State:      running,
+		Tags:       clusterTags,
+	})
+	c.AddInstance(&ec2.Instance{
+		InstanceId: aws.String("i-other"),
+		VpcId:      aws.String("vpc-1234"),
+		State:      running,
+	})
+
+	addENI := func(id string, tags []*ec2.Tag, attachment *ec2.NetworkInterfaceAttachment) {
+		status := ec2.NetworkInterfaceStatusAvailable
+		if attachment != nil {
+			status = ec2.NetworkInterfaceStatusInUse
+			// Each attachment has its own id, even if the instance has several ENIs
+			if attachment.AttachmentId == nil {
+				attachment.AttachmentId = aws.String("eni-attach-" + id)
+			}
+		}
+		c.AddNetworkInterface(&ec2.NetworkInterface{
+			NetworkInterfaceId: aws.String(id),
+			VpcId:              aws.String("vpc-1234"),
+			SubnetId:           aws.String("subnet-1234"),
+			Status:             aws.String(status),
+			Attachment:         attachment,
+			TagSet:             tags,
+		})
+	}
+	attachedTo := func(instanceID string, deleteOnTermination bool) *ec2.NetworkInterfaceAttachment {
+		return &ec2.NetworkInterfaceAttachment{
+			InstanceId:          aws.String(instanceID),
+			Status:              aws.String(ec2.AttachmentStatusAttached),
+			DeleteOnTermination: aws.Bool(deleteOnTermination),
+		}
 	}
 
-	resourceTrackers, err := ListVolumes(cloud, "", clusterName)
+	addENI("eni-available", clusterTags, nil)
+	// Untagged, but nothing else can be using an available ENI in the cluster's VPC
+	addENI("eni-untagged", nil, nil)
+	addENI("eni-cluster", clusterTags, attachedTo("i-cluster", false))
+	addENI("eni-primary", clusterTags, attachedTo("i-cluster", true))
+	addENI("eni-other", clusterTags, attachedTo("i-other", false))
+	addENI("eni-elb", clusterTags, &ec2.NetworkInterfaceAttachment{
+		AttachmentId: aws.String("eni-attach-elb"),
+		Status:       aws.String(ec2.AttachmentStatusAttached),
+	})
+
+	resourceTrackers, err := ListNetworkInterfaces(cloud, "vpc-1234", clusterName, nil)
 	if err != nil {
-		t.Fatalf("error listing volumes: %v", err)
+		t.Fatalf("error listing ENIs: %v", err)
 	}
-	t.Log(len(resourceTrackers))
-	for _, rt := range resourceTrackers {
-		if rt.ID == *sharedVolume.VolumeId && !rt.Shared {
-			t.Fatalf("expected Shared: true, got: %v", rt.Shared)
+
+	actual := make(map[string][]string)
+	for _, r := range resourceTrackers {
+		actual[r.ID] = r.Blocked
+		if r.Shared {
+			t.Errorf("expected ENI %q to be owned", r.ID)
 		}
-		if rt.ID == *ownedVolume.VolumeId && rt.Shared {
-			t.Fatalf("expected Shared: false, got: %v", rt.Shared)
+		if expected := []string{"subnet:subnet-1234", "vpc:vpc-1234"}; !reflect.DeepEqual(r.Blocks, expected) {
+			t.Errorf("unexpected blocks for ENI %q: actual=%v, expected=%v", r.ID, r.Blocks, expected)
+		}
+	}
+	// The ENIs in use by anything outside the cluster aren't deleted,
+	// and the ENI attached to a cluster instance is deleted after the instance
+	expected := map[string][]string{
+		"eni-available": nil,
+		"eni-untagged":  nil,
+		"eni-cluster":   {"instance:i-cluster"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected ENIs: actual=%v, expected=%v", actual, expected)
+	}
+
+	for _, r := range resourceTrackers {
+		if err := r.Deleter(cloud, r); err != nil {
+			t.Errorf("error deleting ENI %q: %v", r.ID, err)
 		}
+		if c.FindNetworkInterface(r.ID) != nil {
+			t.Errorf("expected ENI %q to be deleted", r.ID)
+		}
+	}
+	if c.FindNetworkInterface("eni-other") == nil {
+		t.Errorf("expected ENI %q not to be deleted", "eni-other")
+	}
+}
+
+func TestFindName(t *testing.T) {
+	tag := func(key, value string) *ec2.Tag {
+		return &ec2.Tag{Key: aws.String(key), Value: aws.String(value)}
+	}
+
+	grid := []struct {
+		name     string
+		tags     []*ec2.Tag
+		expected string
+	}{
+		{
+			name:     "exact",
+			tags:     []*ec2.Tag{tag("name", "lower"), tag("Name", "exact")},
+			expected: "exact",
+		},
+		{
+			name:     "lowercase",
+			tags:     []*ec2.Tag{tag("NAME", "upper"), tag("name", "lower")},
+			expected: "lower",
+		},
+		{
+			name:     "uppercase",
+			tags:     []*ec2.Tag{tag("nAmE", "mixed"), tag("NAME", "upper")},
+			expected: "upper",
+		},
+		{
+			name:     "other case",
+			tags:     []*ec2.Tag{tag("nAmE", "mixed"), tag("NaMe", "other")},
+			expected: "other",
+		},
+		{
+			name:     "missing",
+			tags:     []*ec2.Tag{tag("Names", "not a name"), tag("KubernetesCluster", "me.example.com")},
+			expected: "",
+		},
+		{
+			name:     "no tags",
+			expected: "",
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			if actual := FindName(g.tags); actual != g.expected {
+				t.Errorf("unexpected name: actual=%q, expected=%q", actual, g.expected)
+			}
+		})
+	}
+}
+
+func TestMatchesTags(t *testing.T) {
+	type tag struct {
+		key, value string
+	}
+	actual := []tag{
+		{key: "tagkey1", value: "tagvalue1"},
+		{key: "tagkey2", value: "tagvalue2"},
+		{key: "tagkey2", value: "othervalue"},
+	}
+
+	grid := []struct {
+		name     string
+		want     map[string]string
+		actual   []tag
+		expected bool
+	}{
+		{
+			name:     "nothing wanted",
+			actual:   actual,
+			expected: true,
+		},
+		{
+			name:     "one wanted tag",
+			want:     map[string]string{"tagkey1": "tagvalue1"},
+			actual:   actual,
+			expected: true,
+		},
+		{
+			name:     "all wanted tags",
+			want:     map[string]string{"tagkey1": "tagvalue1", "tagkey2": "tagvalue2"},
+			actual:   actual,
+			expected: true,
+		},
+		{
+			name:     "one of the values of a repeated key",
+			want:     map[string]string{"tagkey2": "othervalue"},
+			actual:   actual,
+			expected: true,
+		},
+		{
+			name:     "missing key",
+			want:     map[string]string{"tagkey1": "tagvalue1", "tagkey3": "tagvalue3"},
+			actual:   actual,
+			expected: false,
+		},
+		{
+			name:     "different value",
+			want:     map[string]string{"tagkey1": "tagvalue2"},
+			actual:   actual,
+			expected: false,
+		},
+		{
+			name:     "no actual tags",
+			want:     map[string]string{"tagkey1": "tagvalue1"},
+			expected: false,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			got := matchesTags(g.want, g.actual, func(t tag) (string, string) {
+				return t.key, t.value
+			})
+			if got != g.expected {
+				t.Errorf("expected %v, got %v", g.expected, got)
+			}
+		})
 	}
 }
 
@@ -399,3 +3004,487 @@ func TestMatchesElbTags(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchesElbTagsWithOptions(t *testing.T) {
+	actual := []elbtypes.Tag{
+		{Key: fi.PtrTo("KubernetesCluster"), Value: fi.PtrTo("me.example.com")},
+		{Key: fi.PtrTo("kubernetes.io/cluster/me.example.com"), Value: fi.PtrTo("owned-2024")},
+	}
+	ownershipKey := "kubernetes.io/cluster/me.example.com"
+
+	grid := []struct {
+		name     string
+		want     map[string]string
+		options  tagMatchOptions
+		expected bool
+	}{
+		{
+			name:     "exact",
+			want:     map[string]string{ownershipKey: "owned-2024"},
+			expected: true,
+		},
+		{
+			name:     "exact with options",
+			want:     map[string]string{ownershipKey: "owned-2024"},
+			options:  tagMatchOptions{KeyOnlyIfEmpty: true, Glob: true},
+			expected: true,
+		},
+		{
+			name:     "different value with options",
+			want:     map[string]string{ownershipKey: "owned"},
+			options:  tagMatchOptions{KeyOnlyIfEmpty: true, Glob: true},
+			expected: false,
+		},
+		{
+			name:     "key only",
+			want:     map[string]string{ownershipKey: ""},
+			options:  tagMatchOptions{KeyOnlyIfEmpty: true},
+			expected: true,
+		},
+		{
+			name:     "key only without the option",
+			want:     map[string]string{ownershipKey: ""},
+			expected: false,
+		},
+		{
+			name:     "key only for missing key",
+			want:     map[string]string{"kubernetes.io/cluster/other.example.com": ""},
+			options:  tagMatchOptions{KeyOnlyIfEmpty: true},
+			expected: false,
+		},
+		{
+			name:     "glob",
+			want:     map[string]string{ownershipKey: "owned-*", "KubernetesCluster": "*.example.com"},
+			options:  tagMatchOptions{Glob: true},
+			expected: true,
+		},
+		{
+			name:     "glob without the option",
+			want:     map[string]string{ownershipKey: "owned-*"},
+			expected: false,
+		},
+		{
+			name:     "glob that doesn't match",
+			want:     map[string]string{ownershipKey: "shared-*"},
+			options:  tagMatchOptions{Glob: true},
+			expected: false,
+		},
+		{
+			name:     "invalid glob",
+			want:     map[string]string{ownershipKey: "owned-["},
+			options:  tagMatchOptions{Glob: true},
+			expected: false,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			if got := matchesElbTagsWithOptions(g.want, actual, g.options); got != g.expected {
+				t.Errorf("expected %v, got %v", g.expected, got)
+			}
+		})
+	}
+}
+
+func TestListManagedPrefixLists(t *testing.T) {
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownedTags := []*ec2.Tag{
+		{Key: aws.String(awsup.TagClusterName), Value: aws.String(clusterName)},
+	}
+	sharedTags := []*ec2.Tag{
+		{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("shared")},
+	}
+
+	c := &mockec2.MockEC2{}
+	mockCloud.MockEC2 = c
+	cloud := mockCloud.WithTags(map[string]string{awsup.TagClusterName: clusterName})
+
+	c.AddManagedPrefixList(&ec2.ManagedPrefixList{
+		PrefixListId:   aws.String("pl-owned"),
+		PrefixListName: aws.String("owned"),
+		Tags:           ownedTags,
+	})
+	c.AddManagedPrefixList(&ec2.ManagedPrefixList{
+		PrefixListId:   aws.String("pl-shared"),
+		PrefixListName: aws.String("shared"),
+		Tags:           sharedTags,
+	})
+	c.AddSecurityGroup(&ec2.SecurityGroup{
+		GroupId: aws.String("sg-owned"),
+		VpcId:   aws.String("vpc-1234"),
+		Tags:    ownedTags,
+		IpPermissions: []*ec2.IpPermission{
+			{
+				IpProtocol:    aws.String("-1"),
+				PrefixListIds: []*ec2.PrefixListId{{PrefixListId: aws.String("pl-owned")}},
+			},
+		},
+	})
+
+	resourceTrackers, err := ListManagedPrefixLists(cloud, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing prefix lists: %v", err)
+	}
+
+	actual := make(map[string][]string)
+	for _, r := range resourceTrackers {
+		actual[r.ID] = r.Blocked
+	}
+	// The shared prefix list is left alone, and the owned one is deleted after the group referencing it
+	expected := map[string][]string{
+		"pl-owned": {"security-group:sg-owned"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected prefix lists: actual=%v, expected=%v", actual, expected)
+	}
+
+	r := resourceTrackers[0]
+	if err := r.Deleter(cloud, r); !IsDependencyViolation(err) {
+		t.Fatalf("expected a dependency violation while the group references the prefix list, got %v", err)
+	}
+	if _, err := c.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{GroupId: aws.String("sg-owned")}); err != nil {
+		t.Fatalf("error deleting security group: %v", err)
+	}
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting prefix list: %v", err)
+	}
+	if _, found := c.ManagedPrefixLists["pl-owned"]; found {
+		t.Errorf("prefix list %q was not deleted", "pl-owned")
+	}
+	if _, found := c.ManagedPrefixLists["pl-shared"]; !found {
+		t.Errorf("shared prefix list %q was deleted", "pl-shared")
+	}
+
+	// Deleting a prefix list that is already gone succeeds
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Errorf("error deleting missing prefix list: %v", err)
+	}
+}
+
+func TestListRoute53Records(t *testing.T) {
+	ctx := context.TODO()
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	r53 := &mockroute53.MockRoute53{}
+	cloud.MockRoute53 = r53
+
+	// The hosted zone is shared with other users of the domain
+	r53.MockCreateZone(&route53types.HostedZone{
+		Id:   aws.String("/hostedzone/Z1"),
+		Name: aws.String("example.com."),
+	}, nil)
+
+	var changes []route53types.Change
+	for _, record := range []struct {
+		name       string
+		recordType route53types.RRType
+	}{
+		{"example.com.", route53types.RRTypeNs},
+		{"www.example.com.", route53types.RRTypeA},
+		{"api.me.example.com.", route53types.RRTypeA},
+		{"api.me.example.com.", route53types.RRTypeAaaa},
+		{"aaaa-api.me.example.com.", route53types.RRTypeTxt},
+		{"api.internal.me.example.com.", route53types.RRTypeA},
+		{"etcd-a.internal.me.example.com.", route53types.RRTypeA},
+		{"etcd-events-a.internal.me.example.com.", route53types.RRTypeA},
+		{"app.me.example.com.", route53types.RRTypeA},
+		{"api.other.example.com.", route53types.RRTypeA},
+	} {
+		changes = append(changes, route53types.Change{
+			Action: route53types.ChangeActionCreate,
+			ResourceRecordSet: &route53types.ResourceRecordSet{
+				Name: aws.String(record.name),
+				Type: record.recordType,
+			},
+		})
+	}
+	if _, err := r53.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String("/hostedzone/Z1"),
+		ChangeBatch:  &route53types.ChangeBatch{Changes: changes},
+	}); err != nil {
+		t.Fatalf("error creating records: %v", err)
+	}
+
+	resourceTrackers, err := ListRoute53Records(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing route53 records: %v", err)
+	}
+
+	var actual []string
+	for _, r := range resourceTrackers {
+		actual = append(actual, r.ID)
+		if r.GroupKey != "Z1" {
+			t.Errorf("unexpected group key for %q: %q", r.ID, r.GroupKey)
+		}
+	}
+	sort.Strings(actual)
+	expected := []string{
+		"Z1/A/api.internal.me.example.com.",
+		"Z1/A/api.me.example.com.",
+		"Z1/A/etcd-a.internal.me.example.com.",
+		"Z1/A/etcd-events-a.internal.me.example.com.",
+		"Z1/AAAA/api.me.example.com.",
+		"Z1/TXT/aaaa-api.me.example.com.",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected records: actual=%v, expected=%v", actual, expected)
+	}
+
+	if err := resourceTrackers[0].GroupDeleter(cloud, resourceTrackers); err != nil {
+		t.Fatalf("error deleting route53 records: %v", err)
+	}
+
+	// Only the cluster's records are deleted, not the zone or the other records in it
+	response, err := r53.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{HostedZoneId: aws.String("/hostedzone/Z1")})
+	if err != nil {
+		t.Fatalf("error listing records left in the zone: %v", err)
+	}
+	var remaining []string
+	for _, rrs := range response.ResourceRecordSets {
+		remaining = append(remaining, aws.ToString(rrs.Name))
+	}
+	sort.Strings(remaining)
+	expectedRemaining := []string{"api.other.example.com.", "app.me.example.com.", "example.com.", "www.example.com."}
+	if !reflect.DeepEqual(remaining, expectedRemaining) {
+		t.Errorf("unexpected records left in the zone: actual=%v, expected=%v", remaining, expectedRemaining)
+	}
+}
+
+func TestListLaunchTemplates(t *testing.T) {
+	ctx := context.Background()
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	mockCloud.MockEC2 = c
+	asg := &mockautoscaling.MockAutoscaling{}
+	mockCloud.MockAutoscaling = asg
+	cloud := mockCloud.WithTags(map[string]string{awsup.TagClusterName: clusterName})
+
+	createLaunchTemplate := func(name, cluster string) string {
+		response, err := c.CreateLaunchTemplate(&ec2.CreateLaunchTemplateInput{
+			LaunchTemplateName: aws.String(name),
+			LaunchTemplateData: &ec2.RequestLaunchTemplateData{},
+			TagSpecifications: []*ec2.TagSpecification{
+				{
+					ResourceType: aws.String(ec2.ResourceTypeLaunchTemplate),
+					Tags: []*ec2.Tag{
+						{Key: aws.String("kubernetes.io/cluster/" + cluster), Value: aws.String("owned")},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("error creating launch template: %v", err)
+		}
+		return aws.ToString(response.LaunchTemplate.LaunchTemplateId)
+	}
+	nodesID := createLaunchTemplate("nodes."+clusterName, clusterName)
+	// A later version, as left behind by an update of the instance group
+	if _, err := c.CreateLaunchTemplateVersion(&ec2.CreateLaunchTemplateVersionInput{
+		LaunchTemplateId:   aws.String(nodesID),
+		LaunchTemplateData: &ec2.RequestLaunchTemplateData{},
+	}); err != nil {
+		t.Fatalf("error creating launch template version: %v", err)
+	}
+	otherID := createLaunchTemplate("nodes.other.example.com", "other.example.com")
+
+	createAutoScalingGroup := func(name string, spec *autoscalingtypes.LaunchTemplateSpecification) {
+		if _, err := asg.CreateAutoScalingGroup(ctx, &autoscaling.CreateAutoScalingGroupInput{
+			AutoScalingGroupName: aws.String(name),
+			LaunchTemplate:       spec,
+			Tags: []autoscalingtypes.Tag{
+				{
+					Key:          aws.String(awsup.TagClusterName),
+					Value:        aws.String(clusterName),
+					ResourceId:   aws.String(name),
+					ResourceType: aws.String("auto-scaling-group"),
+				},
+			},
+		}); err != nil {
+			t.Fatalf("error creating autoscaling group: %v", err)
+		}
+	}
+	createAutoScalingGroup("nodes."+clusterName, &autoscalingtypes.LaunchTemplateSpecification{LaunchTemplateId: aws.String(nodesID)})
+
+	resourceTrackers, err := ListLaunchTemplates(cloud, clusterName)
+	if err != nil {
+		t.Fatalf("error listing launch templates: %v", err)
+	}
+	if len(resourceTrackers) != 1 {
+		t.Fatalf("expected exactly one launch template, got %d", len(resourceTrackers))
+	}
+
+	r := resourceTrackers[0]
+	if r.ID != nodesID {
+		t.Fatalf("unexpected launch template %q", r.ID)
+	}
+	if len(r.Blocked) != 0 {
+		t.Errorf("expected the launch template not to be blocked by the autoscaling groups it knows of, got %q", r.Blocked)
+	}
+
+	// The autoscaling groups block the template, whether they reference it by id or by name
+	asgs, err := ListAutoScalingGroups(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing autoscaling groups: %v", err)
+	}
+	trackers := make(map[string]*resources.Resource)
+	for _, t := range asgs {
+		trackers[t.Type+":"+t.ID] = t
+	}
+	// The mock requires an id, so the group referencing the template by name is tracked directly
+	trackers["autoscaling-group:bastions."+clusterName] = &resources.Resource{
+		Name: "bastions." + clusterName,
+		ID:   "bastions." + clusterName,
+		Type: "autoscaling-group",
+		Obj: &autoscalingtypes.AutoScalingGroup{
+			AutoScalingGroupName: aws.String("bastions." + clusterName),
+			LaunchTemplate:       &autoscalingtypes.LaunchTemplateSpecification{LaunchTemplateName: aws.String("nodes." + clusterName)},
+		},
+	}
+	blockLaunchTemplatesByName(trackers, resourceTrackers)
+	for _, name := range []string{"nodes." + clusterName, "bastions." + clusterName} {
+		tracker, found := trackers["autoscaling-group:"+name]
+		if !found {
+			t.Fatalf("autoscaling group %q not listed", name)
+		}
+		expectedBlocks := []string{TypeAutoscalingLaunchConfig + ":" + nodesID}
+		if !reflect.DeepEqual(expectedBlocks, tracker.Blocks) {
+			t.Errorf("unexpected blocks of %q: expected=%q, actual=%q", name, expectedBlocks, tracker.Blocks)
+		}
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting launch template: %v", err)
+	}
+	if _, found := c.LaunchTemplates[nodesID]; found {
+		t.Errorf("expected launch template to be deleted, with all its versions")
+	}
+	if _, found := c.LaunchTemplates[otherID]; !found {
+		t.Errorf("expected launch template of other cluster to be kept")
+	}
+}
+
+// recordingAutoscaling records the calls that delete autoscaling groups and their warm pools
+type recordingAutoscaling struct {
+	*mockautoscaling.MockAutoscaling
+	calls []string
+}
+
+func (m *recordingAutoscaling) DeleteWarmPool(ctx context.Context, request *autoscaling.DeleteWarmPoolInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DeleteWarmPoolOutput, error) {
+	m.calls = append(m.calls, fmt.Sprintf("DeleteWarmPool %s force=%v", aws.ToString(request.AutoScalingGroupName), aws.ToBool(request.ForceDelete)))
+	return m.MockAutoscaling.DeleteWarmPool(ctx, request, optFns...)
+}
+
+func (m *recordingAutoscaling) DeleteAutoScalingGroup(ctx context.Context, request *autoscaling.DeleteAutoScalingGroupInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DeleteAutoScalingGroupOutput, error) {
+	m.calls = append(m.calls, fmt.Sprintf("DeleteAutoScalingGroup %s force=%v", aws.ToString(request.AutoScalingGroupName), aws.ToBool(request.ForceDelete)))
+	return m.MockAutoscaling.DeleteAutoScalingGroup(ctx, request, optFns...)
+}
+
+func TestDeleteAutoScalingGroupWithWarmPool(t *testing.T) {
+	ctx := context.Background()
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &recordingAutoscaling{MockAutoscaling: &mockautoscaling.MockAutoscaling{}}
+	mockCloud.MockAutoscaling = c
+	cloud := mockCloud.WithTags(map[string]string{awsup.TagClusterName: clusterName})
+
+	name := "nodes." + clusterName
+	if _, err := c.CreateAutoScalingGroup(ctx, &autoscaling.CreateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(name),
+		LaunchTemplate:       &autoscalingtypes.LaunchTemplateSpecification{LaunchTemplateId: aws.String("lt-1234")},
+		Tags: []autoscalingtypes.Tag{
+			{
+				Key:          aws.String(awsup.TagClusterName),
+				Value:        aws.String(clusterName),
+				ResourceId:   aws.String(name),
+				ResourceType: aws.String("auto-scaling-group"),
+			},
+		},
+	}); err != nil {
+		t.Fatalf("error creating autoscaling group: %v", err)
+	}
+	if _, err := c.PutWarmPool(ctx, &autoscaling.PutWarmPoolInput{
+		AutoScalingGroupName: aws.String(name),
+		MinSize:              aws.Int32(1),
+	}); err != nil {
+		t.Fatalf("error creating warm pool: %v", err)
+	}
+	c.WarmPoolInstances = map[string][]autoscalingtypes.Instance{
+		name: {{InstanceId: aws.String("i-warm")}},
+	}
+
+	resourceTrackers, err := ListAutoScalingGroups(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing autoscaling groups: %v", err)
+	}
+	if len(resourceTrackers) != 1 {
+		t.Fatalf("expected exactly one autoscaling group, got %d", len(resourceTrackers))
+	}
+	r := resourceTrackers[0]
+	if expected := []string{TypeAutoscalingLaunchConfig + ":lt-1234"}; !reflect.DeepEqual(r.Blocks, expected) {
+		t.Errorf("expected the group to block its launch template: actual=%v, expected=%v", r.Blocks, expected)
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting autoscaling group: %v", err)
+	}
+	expected := []string{
+		"DeleteWarmPool " + name + " force=true",
+		"DeleteAutoScalingGroup " + name + " force=true",
+	}
+	if !reflect.DeepEqual(c.calls, expected) {
+		t.Errorf("unexpected calls: actual=%v, expected=%v", c.calls, expected)
+	}
+	if len(c.WarmPoolInstances) != 0 || len(c.Groups) != 0 {
+		t.Errorf("expected the group and its warm pool to be deleted, got groups %v and warm pool %v", c.Groups, c.WarmPoolInstances)
+	}
+}
+
+func TestDeleteIAMRoleWithPolicies(t *testing.T) {
+	ctx := context.TODO()
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	roleName := "nodes.me.example.com"
+	policyArn := "arn:aws:iam::123456789012:policy/custom"
+
+	// Calling any other IAM API, such as DeletePolicy, panics
+	c := &mockiam.MockIAM{}
+	cloud.MockIAM = c
+
+	if _, err := c.CreateRole(ctx, &iam.CreateRoleInput{RoleName: aws.String(roleName)}); err != nil {
+		t.Fatalf("error creating role: %v", err)
+	}
+	if _, err := c.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(roleName),
+		PolicyName:     aws.String(roleName),
+		PolicyDocument: aws.String("{}"),
+	}); err != nil {
+		t.Fatalf("error creating inline policy: %v", err)
+	}
+	if _, err := c.AttachRolePolicy(ctx, &iam.AttachRolePolicyInput{
+		RoleName:  aws.String(roleName),
+		PolicyArn: aws.String(policyArn),
+	}); err != nil {
+		t.Fatalf("error attaching policy: %v", err)
+	}
+
+	if _, err := c.DeleteRole(ctx, &iam.DeleteRoleInput{RoleName: aws.String(roleName)}); err == nil {
+		t.Fatalf("expected deleting a role with policies to fail")
+	}
+
+	r := &resources.Resource{Name: roleName, ID: roleName, Type: "iam-role"}
+	if err := DeleteIAMRole(cloud, r); err != nil {
+		t.Fatalf("error deleting role: %v", err)
+	}
+	if _, found := c.Roles[roleName]; found {
+		t.Errorf("expected role to be deleted")
+	}
+	if len(c.RolePolicies) != 0 {
+		t.Errorf("expected inline policy to be deleted, got %v", c.RolePolicies)
+	}
+	if len(c.AttachedPolicies[roleName]) != 0 {
+		t.Errorf("expected policy to be detached, got %v", c.AttachedPolicies[roleName])
+	}
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// deleteClientVPNEndpointActions are the AWS API actions invoked by DeleteClientVPNEndpoint
+var deleteClientVPNEndpointActions = []string{
+	"ec2:DescribeClientVpnTargetNetworks",
+	"ec2:DisassociateClientVpnTargetNetwork",
+	"ec2:DescribeClientVpnAuthorizationRules",
+	"ec2:RevokeClientVpnIngress",
+	"ec2:DeleteClientVpnEndpoint",
+}
+
+// DeleteClientVPNEndpoint removes the target network associations and authorization rules
+// of a Client VPN endpoint, and then deletes the endpoint itself.
+func DeleteClientVPNEndpoint(cloud fi.Cloud, r *resources.Resource) error {
+	c := cloud.(awsup.AWSCloud)
+
+	id := r.ID
+
+	// Disassociate target networks
+	{
+		targetNetworks, err := describeClientVPNTargetNetworks(c, id)
+		if err != nil {
+			if isNotFoundErr(err) {
+				klog.V(2).Infof("Got InvalidClientVpnEndpointId.NotFound error describing ClientVpnEndpoint %q; will treat as already-deleted", id)
+				return nil
+			}
+			return fmt.Errorf("error describing target networks of ClientVpnEndpoint %q: %w", id, err)
+		}
+
+		for _, targetNetwork := range targetNetworks {
+			if targetNetwork.Status != nil {
+				switch aws.ToString(targetNetwork.Status.Code) {
+				case ec2.AssociationStatusCodeDisassociating, ec2.AssociationStatusCodeDisassociated:
+					continue
+				}
+			}
+
+			klog.V(2).Infof("Disassociating target network %q from ClientVpnEndpoint %q", aws.ToString(targetNetwork.TargetNetworkId), id)
+			request := &ec2.DisassociateClientVpnTargetNetworkInput{
+				ClientVpnEndpointId: &id,
+				AssociationId:       targetNetwork.AssociationId,
+			}
+			if _, err := c.EC2().DisassociateClientVpnTargetNetwork(request); err != nil {
+				if IsDependencyViolation(err) {
+					return err
+				}
+				return fmt.Errorf("error disassociating target network %q from ClientVpnEndpoint %q: %w", aws.ToString(targetNetwork.TargetNetworkId), id, err)
+			}
+		}
+	}
+
+	// Revoke authorization rules
+	{
+		var rules []*ec2.AuthorizationRule
+		request := &ec2.DescribeClientVpnAuthorizationRulesInput{
+			ClientVpnEndpointId: &id,
+		}
+		err := c.EC2().DescribeClientVpnAuthorizationRulesPages(request, func(page *ec2.DescribeClientVpnAuthorizationRulesOutput, lastPage bool) bool {
+			rules = append(rules, page.AuthorizationRules...)
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("error describing authorization rules of ClientVpnEndpoint %q: %w", id, err)
+		}
+
+		for _, rule := range rules {
+			klog.V(2).Infof("Revoking authorization rule %q from ClientVpnEndpoint %q", aws.ToString(rule.DestinationCidr), id)
+			request := &ec2.RevokeClientVpnIngressInput{
+				ClientVpnEndpointId: &id,
+				TargetNetworkCidr:   rule.DestinationCidr,
+			}
+			if aws.ToBool(rule.AccessAll) {
+				request.RevokeAllGroups = aws.Bool(true)
+			} else {
+				request.AccessGroupId = rule.GroupId
+			}
+			if _, err := c.EC2().RevokeClientVpnIngress(request); err != nil {
+				return fmt.Errorf("error revoking authorization rule %q from ClientVpnEndpoint %q: %w", aws.ToString(rule.DestinationCidr), id, err)
+			}
+		}
+	}
+
+	{
+		klog.V(2).Infof("Deleting EC2 ClientVpnEndpoint %q", id)
+		request := &ec2.DeleteClientVpnEndpointInput{
+			ClientVpnEndpointId: &id,
+		}
+		_, err := c.EC2().DeleteClientVpnEndpoint(request)
+		if err != nil {
+			if isNotFoundErr(err) {
+				klog.V(2).Infof("Got InvalidClientVpnEndpointId.NotFound error deleting ClientVpnEndpoint %q; will treat as already-deleted", id)
+				return nil
+			}
+			if IsDependencyViolation(err) {
+				return err
+			}
+			return fmt.Errorf("error deleting ClientVpnEndpoint %q: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// describeClientVPNTargetNetworks returns the target network associations of a Client VPN endpoint, from all the pages of results
+func describeClientVPNTargetNetworks(c awsup.AWSCloud, endpointID string) ([]*ec2.TargetNetwork, error) {
+	var targetNetworks []*ec2.TargetNetwork
+	request := &ec2.DescribeClientVpnTargetNetworksInput{
+		ClientVpnEndpointId: aws.String(endpointID),
+	}
+	err := c.EC2().DescribeClientVpnTargetNetworksPages(request, func(page *ec2.DescribeClientVpnTargetNetworksOutput, lastPage bool) bool {
+		targetNetworks = append(targetNetworks, page.ClientVpnTargetNetworks...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return targetNetworks, nil
+}
+
+func DumpClientVPNEndpoint(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["type"] = r.Type
+	data["raw"] = r.Obj
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// DescribeClientVPNEndpoints lists the Client VPN endpoints in the VPC that are tagged for the cluster (shared and owned)
+func DescribeClientVPNEndpoints(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) ([]*ec2.ClientVpnEndpoint, error) {
+	if vpcID == "" {
+		return nil, nil
+	}
+
+	c := cloud.(awsup.AWSCloud)
+
+	klog.V(2).Info("Listing EC2 ClientVpnEndpoints")
+
+	// DescribeClientVpnEndpoints does not support filtering by tag, so we filter here
+	var endpoints []*ec2.ClientVpnEndpoint
+	request := &ec2.DescribeClientVpnEndpointsInput{}
+	err := c.EC2().DescribeClientVpnEndpointsPages(request, func(page *ec2.DescribeClientVpnEndpointsOutput, lastPage bool) bool {
+		for _, endpoint := range page.ClientVpnEndpoints {
+			if aws.ToString(endpoint.VpcId) != vpcID {
+				continue
+			}
+			if !hasClusterTag(endpoint.Tags, clusterName, ownershipTagKeys) {
+				continue
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing ClientVpnEndpoints: %v", err)
+	}
+
+	return endpoints, nil
+}
+
+func ListClientVPNEndpoints(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	endpoints, err := DescribeClientVPNEndpoints(cloud, vpcID, clusterName, ownershipTagKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	var resourceTrackers []*resources.Resource
+	for _, endpoint := range endpoints {
+		id := aws.ToString(endpoint.ClientVpnEndpointId)
+
+		resourceTracker := &resources.Resource{
+			Name:    FindName(endpoint.Tags),
+			ID:      id,
+			Type:    ec2.ResourceTypeClientVpnEndpoint,
+			Deleter: DeleteClientVPNEndpoint,
+			Actions: deleteClientVPNEndpointActions,
+			Dumper:  DumpClientVPNEndpoint,
+			Obj:     endpoint,
+			Shared:  !HasOwnedTag(ec2.ResourceTypeClientVpnEndpoint+":"+id, endpoint.Tags, clusterName, ownershipTagKeys),
+		}
+
+		var blocks []string
+		blocks = append(blocks, "vpc:"+aws.ToString(endpoint.VpcId))
+		for _, sg := range endpoint.SecurityGroupIds {
+			blocks = append(blocks, "security-group:"+aws.ToString(sg))
+		}
+
+		// The target network associations must be removed before the subnets can be deleted
+		targetNetworks, err := describeClientVPNTargetNetworks(c, id)
+		if err != nil {
+			return nil, fmt.Errorf("error describing target networks of ClientVpnEndpoint %q: %v", id, err)
+		}
+		for _, targetNetwork := range targetNetworks {
+			blocks = append(blocks, "subnet:"+aws.ToString(targetNetwork.TargetNetworkId))
+		}
+
+		resourceTracker.Blocks = blocks
+
+		resourceTrackers = append(resourceTrackers, resourceTracker)
+	}
+
+	return resourceTrackers, nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListClientVPNEndpoints(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	// Owned by the cluster
+	c.AddClientVpnEndpoint(&ec2.ClientVpnEndpoint{
+		ClientVpnEndpointId: aws.String("cvpn-endpoint-1234"),
+		VpcId:               aws.String("vpc-1234"),
+		SecurityGroupIds:    []*string{aws.String("sg-1234")},
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("owned"),
+			},
+		},
+	})
+	c.AddClientVpnTargetNetwork(&ec2.TargetNetwork{
+		AssociationId:       aws.String("cvpn-assoc-1234"),
+		ClientVpnEndpointId: aws.String("cvpn-endpoint-1234"),
+		TargetNetworkId:     aws.String("subnet-1234"),
+		VpcId:               aws.String("vpc-1234"),
+	})
+	c.AddClientVpnAuthorizationRule(&ec2.AuthorizationRule{
+		ClientVpnEndpointId: aws.String("cvpn-endpoint-1234"),
+		DestinationCidr:     aws.String("10.0.0.0/16"),
+		AccessAll:           aws.Bool(true),
+	})
+
+	// Tagged for another cluster
+	c.AddClientVpnEndpoint(&ec2.ClientVpnEndpoint{
+		ClientVpnEndpointId: aws.String("cvpn-endpoint-5555"),
+		VpcId:               aws.String("vpc-1234"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/other.example.com"),
+				Value: aws.String("owned"),
+			},
+		},
+	})
+
+	resourceTrackers, err := ListClientVPNEndpoints(cloud, "vpc-1234", clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing Client VPN endpoints: %v", err)
+	}
+	if len(resourceTrackers) != 1 {
+		t.Fatalf("expected exactly one Client VPN endpoint, got %d", len(resourceTrackers))
+	}
+
+	r := resourceTrackers[0]
+	if r.ID != "cvpn-endpoint-1234" {
+		t.Fatalf("unexpected Client VPN endpoint %q", r.ID)
+	}
+	if r.Shared {
+		t.Errorf("expected Client VPN endpoint to be owned")
+	}
+	expectedBlocks := []string{"vpc:vpc-1234", "security-group:sg-1234", "subnet:subnet-1234"}
+	if !reflect.DeepEqual(expectedBlocks, r.Blocks) {
+		t.Errorf("unexpected blocks: expected=%q, actual=%q", expectedBlocks, r.Blocks)
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting Client VPN endpoint: %v", err)
+	}
+	if _, found := c.ClientVpnEndpoints["cvpn-endpoint-1234"]; found {
+		t.Errorf("expected Client VPN endpoint to be deleted")
+	}
+	if len(c.ClientVpnTargetNetworks) != 0 {
+		t.Errorf("expected target networks to be disassociated, got %v", c.ClientVpnTargetNetworks)
+	}
+	if len(c.ClientVpnAuthorizationRules) != 0 {
+		t.Errorf("expected authorization rules to be revoked, got %v", c.ClientVpnAuthorizationRules)
+	}
+	if _, found := c.ClientVpnEndpoints["cvpn-endpoint-5555"]; !found {
+		t.Errorf("expected Client VPN endpoint of other cluster to be kept")
+	}
+}
+
+func TestListClientVPNEndpointsPaginated(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	// Endpoints of other VPCs fill the first page of results
+	for i := 0; i < 15; i++ {
+		c.AddClientVpnEndpoint(&ec2.ClientVpnEndpoint{
+			ClientVpnEndpointId: aws.String(fmt.Sprintf("cvpn-endpoint-%04d", i)),
+			VpcId:               aws.String("vpc-5555"),
+		})
+	}
+	c.AddClientVpnEndpoint(&ec2.ClientVpnEndpoint{
+		ClientVpnEndpointId: aws.String("cvpn-endpoint-9999"),
+		VpcId:               aws.String("vpc-1234"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("owned"),
+			},
+		},
+	})
+	expectedBlocks := []string{"vpc:vpc-1234"}
+	for i := 0; i < 15; i++ {
+		subnetID := fmt.Sprintf("subnet-%04d", i)
+		c.AddClientVpnTargetNetwork(&ec2.TargetNetwork{
+			AssociationId:       aws.String(fmt.Sprintf("cvpn-assoc-%04d", i)),
+			ClientVpnEndpointId: aws.String("cvpn-endpoint-9999"),
+			TargetNetworkId:     aws.String(subnetID),
+			VpcId:               aws.String("vpc-1234"),
+		})
+		c.AddClientVpnAuthorizationRule(&ec2.AuthorizationRule{
+			ClientVpnEndpointId: aws.String("cvpn-endpoint-9999"),
+			DestinationCidr:     aws.String(fmt.Sprintf("10.%d.0.0/16", i)),
+			AccessAll:           aws.Bool(true),
+		})
+		expectedBlocks = append(expectedBlocks, "subnet:"+subnetID)
+	}
+
+	resourceTrackers, err := ListClientVPNEndpoints(cloud, "vpc-1234", clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing Client VPN endpoints: %v", err)
+	}
+	if len(resourceTrackers) != 1 {
+		t.Fatalf("expected exactly one Client VPN endpoint, got %d", len(resourceTrackers))
+	}
+
+	r := resourceTrackers[0]
+	if r.ID != "cvpn-endpoint-9999" {
+		t.Fatalf("unexpected Client VPN endpoint %q", r.ID)
+	}
+	if !reflect.DeepEqual(expectedBlocks, r.Blocks) {
+		t.Errorf("unexpected blocks: expected=%q, actual=%q", expectedBlocks, r.Blocks)
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting Client VPN endpoint: %v", err)
+	}
+	if _, found := c.ClientVpnEndpoints["cvpn-endpoint-9999"]; found {
+		t.Errorf("expected Client VPN endpoint to be deleted")
+	}
+	if len(c.ClientVpnTargetNetworks) != 0 {
+		t.Errorf("expected all target networks to be disassociated, got %d", len(c.ClientVpnTargetNetworks))
+	}
+	if len(c.ClientVpnAuthorizationRules) != 0 {
+		t.Errorf("expected all authorization rules to be revoked, got %d", len(c.ClientVpnAuthorizationRules))
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwltypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func DumpCloudWatchLogGroup(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["name"] = r.Name
+	data["type"] = r.Type
+	data["raw"] = r.Obj
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// deleteCloudWatchLogGroupActions are the AWS API actions invoked by DeleteCloudWatchLogGroup
+var deleteCloudWatchLogGroupActions = []string{
+	"logs:DeleteLogGroup",
+}
+
+func DeleteCloudWatchLogGroup(cloud fi.Cloud, r *resources.Resource) error {
+	ctx := context.TODO()
+	c := cloud.(awsup.AWSCloud)
+
+	name := r.ID
+
+	klog.V(2).Infof("Deleting CloudWatch log group %q", name)
+	request := &cloudwatchlogs.DeleteLogGroupInput{
+		LogGroupName: aws.String(name),
+	}
+	if _, err := c.CloudWatchLogs().DeleteLogGroup(ctx, request); err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+		return fmt.Errorf("error deleting CloudWatch log group %q: %w", name, err)
+	}
+	return nil
+}
+
+// ListCloudWatchLogGroups lists the CloudWatch log groups left behind by the cluster, e.g. by audit or CNI logging.
+// Only the log groups whose name starts with one of the cluster prefixes are considered, see cloudWatchLogGroupPrefixes.
+// Of those, only the log groups tagged as owned by the cluster are deleted;
+// the others are listed as shared, so that the user can see them and delete them by hand if they are no longer needed.
+func ListCloudWatchLogGroups(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	ctx := context.TODO()
+	c := cloud.(awsup.AWSCloud)
+
+	var logGroups []cwltypes.LogGroup
+	for _, prefix := range cloudWatchLogGroupPrefixes(clusterName) {
+		klog.V(2).Infof("Listing CloudWatch log groups with prefix %q", prefix)
+		paginator := cloudwatchlogs.NewDescribeLogGroupsPaginator(c.CloudWatchLogs(), &cloudwatchlogs.DescribeLogGroupsInput{
+			LogGroupNamePrefix: aws.String(prefix),
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("error listing CloudWatch log groups: %w", err)
+			}
+			for _, logGroup := range page.LogGroups {
+				// The API prefix is a plain string prefix, so it also matches e.g. the log groups of cluster "a.example.com2"
+				name := aws.ToString(logGroup.LogGroupName)
+				if name == prefix || strings.HasPrefix(name, prefix+"/") {
+					logGroups = append(logGroups, logGroup)
+				}
+			}
+		}
+	}
+
+	var resourceTrackers []*resources.Resource
+	for i := range logGroups {
+		logGroup := &logGroups[i]
+		name := aws.ToString(logGroup.LogGroupName)
+
+		tags, err := listCloudWatchLogGroupTags(ctx, c, logGroup)
+		if err != nil {
+			return nil, err
+		}
+		if tags == nil {
+			// Concurrently deleted
+			continue
+		}
+
+		shared := true
+		if hasClusterTag(tags, clusterName, ownershipTagKeys) {
+			shared = !HasOwnedTag(TypeLogGroup+":"+name, tags, clusterName, ownershipTagKeys)
+		} else {
+			klog.V(2).Infof("CloudWatch log group %q is named for the cluster but not tagged as owned; it will not be deleted", name)
+		}
+
+		resourceTrackers = append(resourceTrackers, &resources.Resource{
+			Name:    name,
+			ID:      name,
+			Type:    TypeLogGroup,
+			Deleter: DeleteCloudWatchLogGroup,
+			Actions: deleteCloudWatchLogGroupActions,
+			Dumper:  DumpCloudWatchLogGroup,
+			Obj:     logGroup,
+			Shared:  shared,
+		})
+	}
+
+	return resourceTrackers, nil
+}
+
+// cloudWatchLogGroupPrefixes returns the name prefixes of the log groups that may belong to the cluster.
+// A log group matches a prefix if its name is the prefix, or starts with the prefix followed by "/".
+func cloudWatchLogGroupPrefixes(clusterName string) []string {
+	return []string{
+		"/aws/kops/" + clusterName,
+		clusterName,
+	}
+}
+
+// listCloudWatchLogGroupTags returns the tags of the log group, as EC2 tags so that they can be checked like those of the other resources.
+// It returns nil if the log group no longer exists.
+func listCloudWatchLogGroupTags(ctx context.Context, c awsup.AWSCloud, logGroup *cwltypes.LogGroup) ([]*ec2.Tag, error) {
+	// The tagging API wants the ARN without the trailing ":*" that the older Arn field has
+	arn := aws.ToString(logGroup.LogGroupArn)
+	if arn == "" {
+		arn = strings.TrimSuffix(aws.ToString(logGroup.Arn), ":*")
+	}
+
+	response, err := c.CloudWatchLogs().ListTagsForResource(ctx, &cloudwatchlogs.ListTagsForResourceInput{
+		ResourceArn: aws.String(arn),
+	})
+	if err != nil {
+		if isNotFoundErr(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing tags of CloudWatch log group %q: %w", aws.ToString(logGroup.LogGroupName), err)
+	}
+
+	tags := []*ec2.Tag{}
+	for k, v := range response.Tags {
+		tags = append(tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return tags, nil
+}
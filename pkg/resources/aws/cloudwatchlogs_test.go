@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"testing"
+
+	"k8s.io/kops/cloudmock/aws/mockcloudwatchlogs"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListCloudWatchLogGroups(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	logs := &mockcloudwatchlogs.MockCloudWatchLogs{}
+	cloud.MockLogs = logs
+
+	logs.MockCreateLogGroup("/aws/kops/"+clusterName+"/audit", map[string]string{"kubernetes.io/cluster/" + clusterName: "owned"})
+	// Named for the cluster, but not tagged: listed, but not deleted
+	logs.MockCreateLogGroup("/aws/kops/"+clusterName+"/cni", nil)
+	logs.MockCreateLogGroup(clusterName+"/cni", map[string]string{"kubernetes.io/cluster/" + clusterName: "shared"})
+	logs.MockCreateLogGroup("/aws/kops/other.example.com/audit", map[string]string{"kubernetes.io/cluster/other.example.com": "owned"})
+	// The cluster name is only a string prefix of the name
+	logs.MockCreateLogGroup("/aws/kops/"+clusterName+"2/audit", nil)
+	// Tagged for the cluster, but not named for it: not listed
+	logs.MockCreateLogGroup("/company/cni", map[string]string{"kubernetes.io/cluster/" + clusterName: "owned"})
+
+	resourceTrackers, err := ListCloudWatchLogGroups(cloud, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing CloudWatch log groups: %v", err)
+	}
+
+	shared := make(map[string]bool)
+	for _, r := range resourceTrackers {
+		if r.Type != TypeLogGroup {
+			t.Errorf("unexpected resource type %q", r.Type)
+		}
+		shared[r.ID] = r.Shared
+	}
+	expected := map[string]bool{
+		"/aws/kops/" + clusterName + "/audit": false,
+		"/aws/kops/" + clusterName + "/cni":   true,
+		clusterName + "/cni":                  true,
+	}
+	if len(shared) != len(expected) {
+		t.Fatalf("unexpected log groups: actual=%v, expected=%v", shared, expected)
+	}
+	for id, s := range expected {
+		if actual, found := shared[id]; !found || actual != s {
+			t.Errorf("unexpected log groups: actual=%v, expected=%v", shared, expected)
+		}
+	}
+
+	for _, r := range resourceTrackers {
+		if r.Shared {
+			continue
+		}
+		if err := r.Deleter(cloud, r); err != nil {
+			t.Fatalf("error deleting %s: %v", r.ID, err)
+		}
+		// Deleting it again, as a concurrent deletion would, is not an error
+		if err := r.Deleter(cloud, r); err != nil {
+			t.Fatalf("error deleting %s again: %v", r.ID, err)
+		}
+	}
+	if _, found := logs.LogGroups["/aws/kops/"+clusterName+"/audit"]; found {
+		t.Errorf("expected the owned log group to be deleted")
+	}
+	for _, name := range []string{"/aws/kops/" + clusterName + "/cni", "/aws/kops/other.example.com/audit"} {
+		if _, found := logs.LogGroups[name]; !found {
+			t.Errorf("expected log group %q to be kept", name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+	"k8s.io/kops/pkg/resources"
+)
+
+// dumpLoader rebuilds the tracker for a resource from the raw cloud object in a dump
+type dumpLoader func(raw json.RawMessage, clusterName string, ownershipTagKeys []string) (*resources.Resource, error)
+
+// dumpLoaders are the dumpLoader for each type of resource that can be loaded from a dump
+var dumpLoaders = map[string]dumpLoader{
+	ec2.ResourceTypeRouteTable: loadRouteTableFromDump,
+}
+
+// dumpedResource is a resource as written to a dump
+type dumpedResource struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Raw  json.RawMessage `json:"raw"`
+}
+
+// LoadDumpAsResources rebuilds the resources in a dump (as written by kops toolbox dump), so that they can be deleted later.
+// As when listing, the cluster name (and the additional ownership tag keys) decide which resources are owned by the cluster and which are shared.
+// Resources of types that can't be loaded from a dump are skipped.
+func LoadDumpAsResources(dumpJSON []byte, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	var dump struct {
+		Resources []dumpedResource `json:"resources"`
+	}
+	if err := json.Unmarshal(dumpJSON, &dump); err != nil {
+		return nil, fmt.Errorf("error parsing dump: %w", err)
+	}
+
+	var l []*resources.Resource
+	for _, d := range dump.Resources {
+		loader := dumpLoaders[d.Type]
+		if loader == nil {
+			klog.Warningf("skipping %s %q in dump: resources of this type can't be loaded from a dump", d.Type, d.ID)
+			continue
+		}
+		r, err := loader(d.Raw, clusterName, ownershipTagKeys)
+		if err != nil {
+			return nil, fmt.Errorf("error loading %s %q from dump: %w", d.Type, d.ID, err)
+		}
+		retryDeletes(r)
+		l = append(l, r)
+	}
+	return l, nil
+}
+
+func loadRouteTableFromDump(raw json.RawMessage, clusterName string, ownershipTagKeys []string) (*resources.Resource, error) {
+	rt := &ec2.RouteTable{}
+	if err := json.Unmarshal(raw, rt); err != nil {
+		return nil, err
+	}
+	if rt.RouteTableId == nil {
+		return nil, fmt.Errorf("route table has no id")
+	}
+	return buildTrackerForRouteTable(rt, clusterName, ownershipTagKeys), nil
+}
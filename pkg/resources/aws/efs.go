@@ -0,0 +1,213 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	efstypes "github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func DumpEFSResource(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["name"] = r.Name
+	data["type"] = r.Type
+	data["raw"] = r.Obj
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// deleteEFSMountTargetActions are the AWS API actions invoked by DeleteEFSMountTarget
+var deleteEFSMountTargetActions = []string{
+	"elasticfilesystem:DeleteMountTarget",
+}
+
+func DeleteEFSMountTarget(cloud fi.Cloud, r *resources.Resource) error {
+	ctx := context.TODO()
+	c := cloud.(awsup.AWSCloud)
+
+	id := r.ID
+
+	klog.V(2).Infof("Deleting EFS mount target %q", id)
+	request := &efs.DeleteMountTargetInput{
+		MountTargetId: aws.String(id),
+	}
+	if _, err := c.EFS().DeleteMountTarget(ctx, request); err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+		return fmt.Errorf("error deleting EFS mount target %q: %w", id, err)
+	}
+	return nil
+}
+
+// deleteEFSFileSystemActions are the AWS API actions invoked by DeleteEFSFileSystem
+var deleteEFSFileSystemActions = []string{
+	"elasticfilesystem:DeleteFileSystem",
+}
+
+// DeleteEFSFileSystem deletes the file system, which fails with FileSystemInUse until its mount targets are gone
+func DeleteEFSFileSystem(cloud fi.Cloud, r *resources.Resource) error {
+	ctx := context.TODO()
+	c := cloud.(awsup.AWSCloud)
+
+	id := r.ID
+
+	klog.V(2).Infof("Deleting EFS file system %q", id)
+	request := &efs.DeleteFileSystemInput{
+		FileSystemId: aws.String(id),
+	}
+	if _, err := c.EFS().DeleteFileSystem(ctx, request); err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+		return fmt.Errorf("error deleting EFS file system %q: %w", id, err)
+	}
+	return nil
+}
+
+// ListEFSFileSystems lists the EFS file systems tagged for the cluster, along with their mount targets.
+// The mount targets are deleted first, and hold up the deletion of their subnets, security groups and network interfaces;
+// the file system is deleted once its mount targets are gone.
+// File systems tagged as shared are preserved, and so are their mount targets.
+func ListEFSFileSystems(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	ctx := context.TODO()
+	c := cloud.(awsup.AWSCloud)
+
+	var fileSystems []efstypes.FileSystemDescription
+	klog.V(2).Infof("Listing EFS file systems")
+	paginator := efs.NewDescribeFileSystemsPaginator(c.EFS(), &efs.DescribeFileSystemsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing EFS file systems: %w", err)
+		}
+		fileSystems = append(fileSystems, page.FileSystems...)
+	}
+
+	var resourceTrackers []*resources.Resource
+	for i := range fileSystems {
+		fileSystem := &fileSystems[i]
+		fileSystemID := aws.ToString(fileSystem.FileSystemId)
+		if state := fileSystem.LifeCycleState; state == efstypes.LifeCycleStateDeleting || state == efstypes.LifeCycleStateDeleted {
+			continue
+		}
+
+		var tags []*ec2.Tag
+		for _, tag := range fileSystem.Tags {
+			tags = append(tags, &ec2.Tag{Key: tag.Key, Value: tag.Value})
+		}
+		if !hasClusterTag(tags, clusterName, ownershipTagKeys) {
+			continue
+		}
+
+		fileSystemTracker := &resources.Resource{
+			Name:    aws.ToString(fileSystem.Name),
+			ID:      fileSystemID,
+			Type:    TypeEFSFileSystem,
+			Deleter: DeleteEFSFileSystem,
+			Actions: deleteEFSFileSystemActions,
+			Dumper:  DumpEFSResource,
+			Obj:     fileSystem,
+			Shared:  !HasOwnedTag(TypeEFSFileSystem+":"+fileSystemID, tags, clusterName, ownershipTagKeys),
+		}
+		resourceTrackers = append(resourceTrackers, fileSystemTracker)
+		if fileSystemTracker.Shared {
+			continue
+		}
+
+		mountTargets, err := listEFSMountTargets(ctx, c, fileSystemID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range mountTargets {
+			mountTarget := &mountTargets[i]
+			mountTargetID := aws.ToString(mountTarget.MountTargetId)
+			if state := mountTarget.LifeCycleState; state == efstypes.LifeCycleStateDeleting || state == efstypes.LifeCycleStateDeleted {
+				continue
+			}
+
+			securityGroups, err := c.EFS().DescribeMountTargetSecurityGroups(ctx, &efs.DescribeMountTargetSecurityGroupsInput{
+				MountTargetId: aws.String(mountTargetID),
+			})
+			if err != nil {
+				if isNotFoundErr(err) {
+					// Concurrently deleted
+					continue
+				}
+				return nil, fmt.Errorf("error describing the security groups of EFS mount target %q: %w", mountTargetID, err)
+			}
+
+			var blocks []string
+			if subnetID := aws.ToString(mountTarget.SubnetId); subnetID != "" {
+				blocks = append(blocks, ec2.ResourceTypeSubnet+":"+subnetID)
+			}
+			for _, securityGroupID := range securityGroups.SecurityGroups {
+				blocks = append(blocks, ec2.ResourceTypeSecurityGroup+":"+securityGroupID)
+			}
+			if eniID := aws.ToString(mountTarget.NetworkInterfaceId); eniID != "" {
+				blocks = append(blocks, ec2.ResourceTypeNetworkInterface+":"+eniID)
+			}
+
+			resourceTrackers = append(resourceTrackers, &resources.Resource{
+				Name:    fileSystemTracker.Name,
+				ID:      mountTargetID,
+				Type:    TypeEFSMountTarget,
+				Deleter: DeleteEFSMountTarget,
+				Actions: deleteEFSMountTargetActions,
+				Dumper:  DumpEFSResource,
+				Obj:     mountTarget,
+				Blocks:  blocks,
+			})
+			fileSystemTracker.Blocked = append(fileSystemTracker.Blocked, TypeEFSMountTarget+":"+mountTargetID)
+		}
+	}
+
+	return resourceTrackers, nil
+}
+
+// listEFSMountTargets returns the mount targets of the file system, or none if the file system no longer exists
+func listEFSMountTargets(ctx context.Context, c awsup.AWSCloud, fileSystemID string) ([]efstypes.MountTargetDescription, error) {
+	var mountTargets []efstypes.MountTargetDescription
+	paginator := efs.NewDescribeMountTargetsPaginator(c.EFS(), &efs.DescribeMountTargetsInput{
+		FileSystemId: aws.String(fileSystemID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			if isNotFoundErr(err) {
+				// Concurrently deleted
+				return nil, nil
+			}
+			return nil, fmt.Errorf("error listing the mount targets of EFS file system %q: %w", fileSystemID, err)
+		}
+		mountTargets = append(mountTargets, page.MountTargets...)
+	}
+	return mountTargets, nil
+}
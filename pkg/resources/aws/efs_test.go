@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	efstypes "github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"k8s.io/kops/cloudmock/aws/mockefs"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListEFSFileSystems(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTag := "kubernetes.io/cluster/" + clusterName
+
+	e := &mockefs.MockEFS{}
+	cloud.MockEFS = e
+
+	e.MockCreateFileSystem(&efstypes.FileSystemDescription{
+		FileSystemId:   aws.String("fs-owned"),
+		LifeCycleState: efstypes.LifeCycleStateAvailable,
+		Tags:           []efstypes.Tag{{Key: aws.String(ownershipTag), Value: aws.String("owned")}},
+	})
+	for _, mountTarget := range []struct{ id, subnet, eni string }{
+		{id: "fsmt-a", subnet: "subnet-a", eni: "eni-a"},
+		{id: "fsmt-b", subnet: "subnet-b", eni: "eni-b"},
+	} {
+		e.MockCreateMountTarget(&efstypes.MountTargetDescription{
+			FileSystemId:       aws.String("fs-owned"),
+			MountTargetId:      aws.String(mountTarget.id),
+			SubnetId:           aws.String(mountTarget.subnet),
+			NetworkInterfaceId: aws.String(mountTarget.eni),
+			LifeCycleState:     efstypes.LifeCycleStateAvailable,
+		}, []string{"sg-efs"})
+	}
+	e.MockCreateFileSystem(&efstypes.FileSystemDescription{
+		FileSystemId:   aws.String("fs-shared"),
+		LifeCycleState: efstypes.LifeCycleStateAvailable,
+		Tags:           []efstypes.Tag{{Key: aws.String(ownershipTag), Value: aws.String("shared")}},
+	})
+	e.MockCreateMountTarget(&efstypes.MountTargetDescription{
+		FileSystemId:   aws.String("fs-shared"),
+		MountTargetId:  aws.String("fsmt-shared"),
+		SubnetId:       aws.String("subnet-a"),
+		LifeCycleState: efstypes.LifeCycleStateAvailable,
+	}, nil)
+	e.MockCreateFileSystem(&efstypes.FileSystemDescription{
+		FileSystemId:   aws.String("fs-other"),
+		LifeCycleState: efstypes.LifeCycleStateAvailable,
+		Tags:           []efstypes.Tag{{Key: aws.String("kubernetes.io/cluster/other.example.com"), Value: aws.String("owned")}},
+	})
+
+	resourceTrackers, err := ListEFSFileSystems(cloud, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing EFS file systems: %v", err)
+	}
+
+	resourceMap := make(map[string]*resources.Resource)
+	for _, r := range resourceTrackers {
+		resourceMap[r.Type+":"+r.ID] = r
+	}
+	var keys []string
+	for k := range resourceMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	expectedKeys := []string{"efs-file-system:fs-owned", "efs-file-system:fs-shared", "efs-mount-target:fsmt-a", "efs-mount-target:fsmt-b"}
+	if !reflect.DeepEqual(keys, expectedKeys) {
+		t.Fatalf("unexpected resources: actual=%v, expected=%v", keys, expectedKeys)
+	}
+
+	if !resourceMap["efs-file-system:fs-shared"].Shared {
+		t.Errorf("expected the shared file system to be preserved")
+	}
+	fileSystem := resourceMap["efs-file-system:fs-owned"]
+	if fileSystem.Shared {
+		t.Errorf("expected the owned file system to be deleted")
+	}
+	if expected := []string{"efs-mount-target:fsmt-a", "efs-mount-target:fsmt-b"}; !reflect.DeepEqual(fileSystem.Blocked, expected) {
+		t.Errorf("unexpected dependencies of the file system: actual=%v, expected=%v", fileSystem.Blocked, expected)
+	}
+	if expected := []string{"subnet:subnet-a", "security-group:sg-efs", "network-interface:eni-a"}; !reflect.DeepEqual(resourceMap["efs-mount-target:fsmt-a"].Blocks, expected) {
+		t.Errorf("unexpected resources blocked by the mount target: actual=%v, expected=%v", resourceMap["efs-mount-target:fsmt-a"].Blocks, expected)
+	}
+
+	// The file system can't be deleted while it has mount targets
+	if err := fileSystem.Deleter(cloud, fileSystem); err == nil || !IsDependencyViolation(err) {
+		t.Errorf("expected the file system deletion to fail with a dependency violation, got %v", err)
+	}
+	for _, k := range []string{"efs-mount-target:fsmt-a", "efs-mount-target:fsmt-b", "efs-file-system:fs-owned"} {
+		r := resourceMap[k]
+		if err := r.Deleter(cloud, r); err != nil {
+			t.Fatalf("error deleting %s: %v", k, err)
+		}
+	}
+	if _, found := e.FileSystems["fs-owned"]; found {
+		t.Errorf("expected the owned file system to be deleted")
+	}
+	if len(e.MountTargets) != 1 {
+		t.Errorf("expected only the mount target of the shared file system to be kept, got %v", e.MountTargets)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+)
+
+// IsEKSManaged returns true if the tags mark the resource as belonging to EKS.
+// EKS also tags its resources with "kubernetes.io/cluster/<name>", so in accounts that host both
+// EKS and kops clusters these tags tell us which resources we must never touch.
+func IsEKSManaged(tags []*ec2.Tag) bool {
+	for _, tag := range tags {
+		key := aws.ToString(tag.Key)
+		if key == "eks:cluster-name" || strings.HasPrefix(key, "aws:eks:") {
+			return true
+		}
+	}
+	return false
+}
+
+// ec2Tags returns the tags of the EC2 object of the resource, or nil if it doesn't have one
+func ec2Tags(r *resources.Resource) []*ec2.Tag {
+	switch obj := r.Obj.(type) {
+	case *ec2.ClientVpnEndpoint:
+		return obj.Tags
+	case *ec2.EgressOnlyInternetGateway:
+		return obj.Tags
+	case *ec2.Instance:
+		return obj.Tags
+	case *ec2.InternetGateway:
+		return obj.Tags
+	case *ec2.NatGateway:
+		return obj.Tags
+	case *ec2.NetworkInterface:
+		return obj.TagSet
+	case *ec2.RouteTable:
+		return obj.Tags
+	case *ec2.SecurityGroup:
+		return obj.Tags
+	case *ec2.Subnet:
+		return obj.Tags
+	case *ec2.Volume:
+		return obj.Tags
+	case *ec2.Vpc:
+		return obj.Tags
+	default:
+		return nil
+	}
+}
+
+// FilterEKSManagedResources removes the resources that belong to EKS, so that we never delete EKS infrastructure
+func FilterEKSManagedResources(resourceTrackers map[string]*resources.Resource) {
+	for k, r := range resourceTrackers {
+		if !IsEKSManaged(ec2Tags(r)) {
+			continue
+		}
+		klog.V(2).Infof("Skipping %s, which is managed by EKS", k)
+		delete(resourceTrackers, k)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListRouteTablesExcludesEKSManaged(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-kops"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+		},
+	})
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-eks"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+			{Key: aws.String("eks:cluster-name"), Value: aws.String(clusterName)},
+		},
+	})
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-eks-nodegroup"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+			{Key: aws.String("aws:eks:cluster-name"), Value: aws.String(clusterName)},
+		},
+	})
+
+	resourceTrackers, err := ListRouteTables(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing route tables: %v", err)
+	}
+	if len(resourceTrackers) != 1 || resourceTrackers[0].ID != "rtb-kops" {
+		var ids []string
+		for _, r := range resourceTrackers {
+			ids = append(ids, r.ID)
+		}
+		t.Fatalf("expected only rtb-kops to be listed, got %v", ids)
+	}
+}
+
+func TestFilterEKSManagedResources(t *testing.T) {
+	resourceTrackers := map[string]*resources.Resource{
+		"vpc:vpc-kops": {
+			Type: ec2.ResourceTypeVpc,
+			ID:   "vpc-kops",
+			Obj:  &ec2.Vpc{VpcId: aws.String("vpc-kops")},
+		},
+		"network-interface:eni-eks": {
+			Type: ec2.ResourceTypeNetworkInterface,
+			ID:   "eni-eks",
+			Obj: &ec2.NetworkInterface{
+				NetworkInterfaceId: aws.String("eni-eks"),
+				TagSet: []*ec2.Tag{
+					{Key: aws.String("eks:cluster-name"), Value: aws.String("me.example.com")},
+				},
+			},
+		},
+		"iam-role:masters.me.example.com": {
+			Type: "iam-role",
+			ID:   "masters.me.example.com",
+		},
+	}
+
+	FilterEKSManagedResources(resourceTrackers)
+
+	if _, found := resourceTrackers["network-interface:eni-eks"]; found {
+		t.Errorf("expected EKS network interface to be removed")
+	}
+	if len(resourceTrackers) != 2 {
+		t.Errorf("expected 2 resources to remain, got %d", len(resourceTrackers))
+	}
+}
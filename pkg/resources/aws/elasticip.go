@@ -17,10 +17,15 @@ limitations under the License.
 package aws
 
 import (
+	"fmt"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
 
 	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 )
 
 func buildElasticIPResource(address *ec2.Address, forceShared bool, clusterName string) *resources.Resource {
@@ -37,6 +42,7 @@ func buildElasticIPResource(address *ec2.Address, forceShared bool, clusterName
 		ID:      aws.ToString(address.AllocationId),
 		Type:    TypeElasticIp,
 		Deleter: DeleteElasticIP,
+		Actions: deleteElasticIPActions,
 		Shared:  forceShared,
 	}
 
@@ -46,3 +52,80 @@ func buildElasticIPResource(address *ec2.Address, forceShared bool, clusterName
 
 	return r
 }
+
+// ListElasticIPs lists the Elastic IPs tagged for the cluster.
+// Those associated with a NAT gateway are released once the NAT gateway is deleted, because they are blocked by the NAT gateway.
+// Addresses that the cluster doesn't own are left alone.
+func ListElasticIPs(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	addresses := make(map[string]*ec2.Address)
+	klog.V(2).Infof("Querying EC2 Elastic IPs")
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
+		request := &ec2.DescribeAddressesInput{
+			Filters: filters,
+		}
+		response, err := c.EC2().DescribeAddresses(request)
+		if err != nil {
+			return nil, fmt.Errorf("error describing addresses: %v", err)
+		}
+		for _, address := range response.Addresses {
+			addresses[aws.ToString(address.AllocationId)] = address
+		}
+	}
+
+	var resourceTrackers []*resources.Resource
+	for id, address := range addresses {
+		if id == "" {
+			// EC2-Classic addresses can't be released by allocation id
+			continue
+		}
+		owned := HasOwnedTag(TypeElasticIp+":"+id, address.Tags, clusterName, ownershipTagKeys)
+		resourceTrackers = append(resourceTrackers, buildElasticIPResource(address, !owned, clusterName))
+	}
+
+	if err := blockElasticIPsOnNatGateways(c, vpcID, resourceTrackers); err != nil {
+		return nil, err
+	}
+
+	return resourceTrackers, nil
+}
+
+// blockElasticIPsOnNatGateways adds the NAT gateways that use each Elastic IP to its Blocked,
+// as an address can't be released while a NAT gateway holds it (it fails with InvalidIPAddress.InUse or AuthFailure).
+// A NAT gateway found from a route table already blocks its addresses, but the addresses may be listed without it.
+func blockElasticIPsOnNatGateways(c awsup.AWSCloud, vpcID string, eips []*resources.Resource) error {
+	eipsByID := make(map[string]*resources.Resource)
+	for _, eip := range eips {
+		eipsByID[eip.ID] = eip
+	}
+	if len(eipsByID) == 0 {
+		return nil
+	}
+
+	// NAT gateways can't be filtered by allocation id
+	klog.V(2).Infof("Querying Nat Gateways for Elastic IPs")
+	request := &ec2.DescribeNatGatewaysInput{}
+	if vpcID != "" {
+		request.Filter = []*ec2.Filter{awsup.NewEC2Filter("vpc-id", vpcID)}
+	}
+	err := c.EC2().DescribeNatGatewaysPages(request, func(p *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		for _, ngw := range p.NatGateways {
+			if aws.ToString(ngw.State) == ec2.NatGatewayStateDeleted {
+				continue
+			}
+			for _, address := range ngw.NatGatewayAddresses {
+				eip := eipsByID[aws.ToString(address.AllocationId)]
+				if eip == nil {
+					continue
+				}
+				eip.Blocked = append(eip.Blocked, TypeNatGateway+":"+aws.ToString(ngw.NatGatewayId))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("error describing NatGateways: %w", err)
+	}
+	return nil
+}
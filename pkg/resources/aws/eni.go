@@ -21,6 +21,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	"k8s.io/kops/pkg/resources"
@@ -28,26 +29,62 @@ import (
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 )
 
-func DeleteENI(cloud fi.Cloud, r *resources.Resource) error {
+// deleteNetworkInterfaceActions are the AWS API actions invoked by DeleteNetworkInterface
+var deleteNetworkInterfaceActions = []string{
+	"ec2:DescribeNetworkInterfaces",
+	"ec2:DetachNetworkInterface",
+	"ec2:DeleteNetworkInterface",
+}
+
+// DeleteNetworkInterface detaches the ENI if it is still attached, and then deletes it
+func DeleteNetworkInterface(cloud fi.Cloud, r *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
 	id := r.ID
 
+	response, err := c.EC2().DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+		NetworkInterfaceIds: []*string{&id},
+	})
+	if err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted, e.g. with the instance it was attached to
+			return nil
+		}
+		return fmt.Errorf("error describing ENI %q: %w", id, err)
+	}
+	for _, eni := range response.NetworkInterfaces {
+		if eni.Attachment == nil {
+			continue
+		}
+		switch aws.ToString(eni.Attachment.Status) {
+		case ec2.AttachmentStatusAttaching, ec2.AttachmentStatusAttached:
+			attachmentID := aws.ToString(eni.Attachment.AttachmentId)
+			klog.V(2).Infof("Detaching EC2 ENI %q (attachment %q)", id, attachmentID)
+			_, err := c.EC2().DetachNetworkInterface(&ec2.DetachNetworkInterfaceInput{
+				AttachmentId: &attachmentID,
+			})
+			if err != nil && !isNotFoundErr(err) {
+				return fmt.Errorf("error detaching ENI %q: %w", id, err)
+			}
+		}
+	}
+
 	klog.V(2).Infof("Deleting EC2 ENI %q", id)
 	request := &ec2.DeleteNetworkInterfaceInput{
 		NetworkInterfaceId: &id,
 	}
-	_, err := c.EC2().DeleteNetworkInterface(request)
+	_, err = c.EC2().DeleteNetworkInterface(request)
 	if err != nil {
-		if awsup.AWSErrorCode(err) == "InvalidNetworkInterfaceID.NotFound" {
+		if isNotFoundErr(err) {
 			// Concurrently deleted
 			return nil
 		}
 
+		// The detachment takes a few seconds, during which the ENI is still in use
 		if IsDependencyViolation(err) {
 			return err
 		}
-		return fmt.Errorf("error deleting ENI %q: %v", id, err)
+		return fmt.Errorf("error deleting ENI %q: %w", id, err)
 	}
 	return nil
 }
@@ -63,7 +100,9 @@ func DumpENI(op *resources.DumpOperation, r *resources.Resource) error {
 	return nil
 }
 
-func DescribeENIs(cloud fi.Cloud, vpcID, clusterName string) (map[string]*ec2.NetworkInterface, error) {
+// DescribeENIs returns the ENIs in the VPC that are tagged for the cluster,
+// and if the cluster owns the VPC, the ENIs in it that are no longer attached to anything.
+func DescribeENIs(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) (map[string]*ec2.NetworkInterface, error) {
 	if vpcID == "" {
 		return nil, nil
 	}
@@ -71,13 +110,27 @@ func DescribeENIs(cloud fi.Cloud, vpcID, clusterName string) (map[string]*ec2.Ne
 	c := cloud.(awsup.AWSCloud)
 
 	vpcFilter := awsup.NewEC2Filter("vpc-id", vpcID)
-	statusFilter := awsup.NewEC2Filter("status", ec2.NetworkInterfaceStatusAvailable)
+	var requests []*ec2.DescribeNetworkInterfacesInput
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
+		requests = append(requests, &ec2.DescribeNetworkInterfacesInput{
+			Filters: append(filters, vpcFilter),
+		})
+	}
+
+	ownedVPC, err := isOwnedVPC(c, vpcID, clusterName, ownershipTagKeys)
+	if err != nil {
+		return nil, err
+	}
+	if ownedVPC {
+		// Nothing else can be using an available ENI in our own VPC
+		requests = append(requests, &ec2.DescribeNetworkInterfacesInput{
+			Filters: []*ec2.Filter{vpcFilter, awsup.NewEC2Filter("status", ec2.NetworkInterfaceStatusAvailable)},
+		})
+	}
+
 	enis := make(map[string]*ec2.NetworkInterface)
 	klog.V(2).Info("Listing ENIs")
-	for _, filters := range buildEC2FiltersForCluster(clusterName) {
-		request := &ec2.DescribeNetworkInterfacesInput{
-			Filters: append(filters, vpcFilter, statusFilter),
-		}
+	for _, request := range requests {
 		err := c.EC2().DescribeNetworkInterfacesPages(request, func(dnio *ec2.DescribeNetworkInterfacesOutput, b bool) bool {
 			for _, eni := range dnio.NetworkInterfaces {
 				enis[aws.ToString(eni.NetworkInterfaceId)] = eni
@@ -92,8 +145,44 @@ func DescribeENIs(cloud fi.Cloud, vpcID, clusterName string) (map[string]*ec2.Ne
 	return enis, nil
 }
 
-func ListENIs(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
-	enis, err := DescribeENIs(cloud, vpcID, clusterName)
+// isOwnedVPC returns true if the VPC is owned by the cluster
+func isOwnedVPC(c awsup.AWSCloud, vpcID, clusterName string, ownershipTagKeys []string) (bool, error) {
+	response, err := c.EC2().DescribeVpcs(&ec2.DescribeVpcsInput{
+		VpcIds: []*string{&vpcID},
+	})
+	if err != nil {
+		return false, fmt.Errorf("error describing VPC %q: %v", vpcID, err)
+	}
+	for _, vpc := range response.Vpcs {
+		if HasOwnedTag(ec2.ResourceTypeVpc+":"+vpcID, vpc.Tags, clusterName, ownershipTagKeys) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListNetworkInterfaces returns the ENIs that were left behind by the cluster, e.g. by the CNI or by load balancers,
+// as they prevent the subnets and the VPC from being deleted.
+func ListNetworkInterfaces(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	enis, err := DescribeENIs(cloud, vpcID, clusterName, ownershipTagKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// An ENI can't be deleted while it is attached.
+	// ENIs attached to the cluster's instances are detached once the instances are gone,
+	// but we leave alone those that are in use by anything else.
+	attachedInstances := sets.NewString()
+	for _, eni := range enis {
+		if eni.Attachment != nil && aws.ToString(eni.Status) != ec2.NetworkInterfaceStatusAvailable {
+			if instanceID := aws.ToString(eni.Attachment.InstanceId); instanceID != "" {
+				attachedInstances.Insert(instanceID)
+			}
+		}
+	}
+	clusterInstances, err := findClusterInstances(c, vpcID, attachedInstances.List())
 	if err != nil {
 		return nil, err
 	}
@@ -102,16 +191,41 @@ func ListENIs(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource,
 	for _, v := range enis {
 		eniID := aws.ToString(v.NetworkInterfaceId)
 
+		var blocked []string
+		if attachment := v.Attachment; attachment != nil && aws.ToString(v.Status) != ec2.NetworkInterfaceStatusAvailable {
+			if aws.ToBool(attachment.DeleteOnTermination) {
+				// Deleted along with the instance
+				continue
+			}
+			instanceID := aws.ToString(attachment.InstanceId)
+			if !clusterInstances.Has(instanceID) {
+				klog.Warningf("not deleting ENI %q, which is in use by %q that isn't part of the cluster", eniID, describeENIUser(v))
+				continue
+			}
+			blocked = append(blocked, ec2.ResourceTypeInstance+":"+instanceID)
+		}
+
+		// An ENI without the cluster tag was found as an available ENI in the cluster's own VPC, so it is ours to delete
+		shared := false
+		if hasClusterTag(v.TagSet, clusterName, ownershipTagKeys) {
+			shared = !HasOwnedTag(ec2.ResourceTypeNetworkInterface+":"+eniID, v.TagSet, clusterName, ownershipTagKeys)
+		}
+
 		resourceTracker := &resources.Resource{
 			ID:      eniID,
 			Type:    ec2.ResourceTypeNetworkInterface,
-			Deleter: DeleteENI,
+			Deleter: DeleteNetworkInterface,
+			Actions: deleteNetworkInterfaceActions,
 			Dumper:  DumpENI,
 			Obj:     v,
-			Shared:  !HasOwnedTag(ec2.ResourceTypeNetworkInterface+":"+eniID, v.TagSet, clusterName),
+			Shared:  shared,
+			Blocked: blocked,
 		}
 
 		var blocks []string
+		if subnetID := aws.ToString(v.SubnetId); subnetID != "" {
+			blocks = append(blocks, ec2.ResourceTypeSubnet+":"+subnetID)
+		}
 		blocks = append(blocks, ec2.ResourceTypeVpc+":"+aws.ToString(v.VpcId))
 
 		resourceTracker.Blocks = blocks
@@ -121,3 +235,14 @@ func ListENIs(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource,
 
 	return resourceTrackers, nil
 }
+
+// describeENIUser returns a description of what the ENI is attached to, for messages
+func describeENIUser(eni *ec2.NetworkInterface) string {
+	if eni.Attachment != nil && aws.ToString(eni.Attachment.InstanceId) != "" {
+		return aws.ToString(eni.Attachment.InstanceId)
+	}
+	if description := aws.ToString(eni.Description); description != "" {
+		return description
+	}
+	return aws.ToString(eni.InterfaceType)
+}
@@ -17,7 +17,13 @@ limitations under the License.
 package aws
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"k8s.io/klog/v2"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 )
 
@@ -26,10 +32,120 @@ func IsDependencyViolation(err error) bool {
 	switch code {
 	case "":
 		return false
-	case "AuthFailure", "DependencyViolation", "InvalidIPAddress.InUse", "VolumeInUse", "ResourceInUse":
+	case "AuthFailure", "DependencyViolation", "InvalidIPAddress.InUse", "VolumeInUse", "ResourceInUse", "InvalidNetworkInterface.InUse", "FileSystemInUse":
 		return true
 	default:
 		klog.Infof("unexpected aws error code: %q", code)
 		return false
 	}
 }
+
+// IsThrottlingError returns true if the call failed because it was throttled, so it can be retried after a while
+func IsThrottlingError(err error) bool {
+	switch awsup.AWSErrorCode(err) {
+	case "RequestLimitExceeded", "Throttling", "ThrottlingException":
+		return true
+	default:
+		return false
+	}
+}
+
+// isNotFoundErr returns true if the error reports that the resource doesn't exist,
+// which a deleter treats as the resource having already been deleted (e.g. out-of-band or by a previous run).
+func isNotFoundErr(err error) bool {
+	code := awsup.AWSErrorCode(err)
+	switch {
+	case code == "":
+		return false
+	case strings.HasSuffix(code, ".NotFound"):
+		// EC2 reports most missing resources as e.g. InvalidVpcID.NotFound
+		return true
+	case code == "NatGatewayNotFound", code == "NotFoundException":
+		return true
+	case code == "LoadBalancerNotFound", code == "ListenerNotFound", code == "TargetGroupNotFound":
+		// Elastic Load Balancing reports missing resources without the dot
+		return true
+	case code == "FileSystemNotFound", code == "MountTargetNotFound":
+		// EFS reports missing resources without the dot
+		return true
+	case code == "ResourceNotFoundException":
+		// CloudWatch Logs reports missing log groups this way
+		return true
+	default:
+		return false
+	}
+}
+
+// deletedConcurrently is called when the deletion of a resource fails with a DependencyViolation.
+// It describes the resource again with exists, and returns true if the resource is gone by now,
+// e.g. because another kops process running against the same cluster deleted it in the meantime.
+func deletedConcurrently(description string, exists func() (bool, error)) bool {
+	found, err := exists()
+	if err != nil {
+		if isNotFoundErr(err) {
+			found = false
+		} else {
+			klog.V(2).Infof("error describing %s again after a dependency violation: %v", description, err)
+			return false
+		}
+	}
+	if !found {
+		klog.V(2).Infof("%s was deleted concurrently; will treat as deleted", description)
+	}
+	return !found
+}
+
+// DeletionError is an error deleting a resource, identifying the resource that couldn't be deleted
+type DeletionError struct {
+	// Type is the type of the resource, e.g. "route-table"
+	Type string
+	// ID is the id of the resource; for resources deleted as a group, the ids are comma-separated
+	ID string
+	// Err is the underlying error
+	Err error
+	// Terminal is set when retrying can't help, e.g. because the deleter refuses to delete the resource
+	Terminal bool
+}
+
+func (e *DeletionError) Error() string {
+	return fmt.Sprintf("deleting %s %s: %v", e.Type, e.ID, e.Err)
+}
+
+func (e *DeletionError) Unwrap() error {
+	return e.Err
+}
+
+// IsTerminal returns true if the deletion failed in a way that retrying can't fix
+func IsTerminal(err error) bool {
+	var deletionErr *DeletionError
+	return errors.As(err, &deletionErr) && deletionErr.Terminal
+}
+
+// wrapDeletionErrors makes the deleters of the resource return a DeletionError, so that the failure can be traced to the resource
+func wrapDeletionErrors(r *resources.Resource) {
+	if deleter := r.Deleter; deleter != nil {
+		r.Deleter = func(cloud fi.Cloud, r *resources.Resource) error {
+			err := deleter(cloud, r)
+			if err == nil {
+				return nil
+			}
+			var deletionErr *DeletionError
+			if errors.As(err, &deletionErr) {
+				return err
+			}
+			return &DeletionError{Type: r.Type, ID: r.ID, Err: err}
+		}
+	}
+	if groupDeleter := r.GroupDeleter; groupDeleter != nil {
+		r.GroupDeleter = func(cloud fi.Cloud, trackers []*resources.Resource) error {
+			if err := groupDeleter(cloud, trackers); err != nil {
+				var ids []string
+				for _, t := range trackers {
+					ids = append(ids, t.ID)
+				}
+				return &DeletionError{Type: trackers[0].Type, ID: strings.Join(ids, ","), Err: err}
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestIsNotFoundErr(t *testing.T) {
+	grid := []struct {
+		err      error
+		expected bool
+	}{
+		{err: nil, expected: false},
+		{err: errors.New("not found"), expected: false},
+		{err: awserr.New("InvalidVpcID.NotFound", "", nil), expected: true},
+		{err: awserr.New("InvalidGroup.NotFound", "", nil), expected: true},
+		{err: awserr.New("NatGatewayNotFound", "", nil), expected: true},
+		{err: awserr.New("NotFoundException", "", nil), expected: true},
+		{err: &elbv2types.LoadBalancerNotFoundException{}, expected: true},
+		{err: &elbv2types.TargetGroupNotFoundException{}, expected: true},
+		{err: awserr.New("DependencyViolation", "", nil), expected: false},
+	}
+	for _, g := range grid {
+		if actual := isNotFoundErr(g.err); actual != g.expected {
+			t.Errorf("isNotFoundErr(%v): expected %v, got %v", g.err, g.expected, actual)
+		}
+	}
+}
+
+func TestDeleteResourceDeletedOutOfBand(t *testing.T) {
+	grid := []struct {
+		resourceType string
+		id           string
+		deleter      func(cloud fi.Cloud, r *resources.Resource) error
+	}{
+		{resourceType: ec2.ResourceTypeVolume, id: "vol-1234", deleter: DeleteVolume},
+		{resourceType: ec2.ResourceTypeKeyPair, id: "key-1234", deleter: DeleteKeypair},
+		{resourceType: ec2.ResourceTypeSubnet, id: "subnet-1234", deleter: DeleteSubnet},
+		{resourceType: ec2.ResourceTypeRouteTable, id: "rtb-1234", deleter: DeleteRouteTable},
+		{resourceType: ec2.ResourceTypeSecurityGroup, id: "sg-1234", deleter: DeleteSecurityGroup},
+		{resourceType: ec2.ResourceTypeDhcpOptions, id: "dopt-1234", deleter: DeleteDhcpOptions},
+		{resourceType: ec2.ResourceTypeInternetGateway, id: "igw-1234", deleter: DeleteInternetGateway},
+		{resourceType: ec2.ResourceTypeEgressOnlyInternetGateway, id: "eigw-1234", deleter: DeleteEgressOnlyInternetGateway},
+		{resourceType: ec2.ResourceTypeNatgateway, id: "nat-1234", deleter: DeleteNatGateway},
+		{resourceType: ec2.ResourceTypeElasticIp, id: "eipalloc-1234", deleter: DeleteElasticIP},
+		{resourceType: ec2.ResourceTypeVpc, id: "vpc-1234", deleter: DeleteVPC},
+	}
+	for _, g := range grid {
+		t.Run(g.resourceType, func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			cloud.MockEC2 = &mockec2.MockEC2{}
+
+			r := &resources.Resource{
+				Name: g.id,
+				ID:   g.id,
+				Type: g.resourceType,
+			}
+			if err := g.deleter(cloud, r); err != nil {
+				t.Errorf("expected deleting a resource that no longer exists to succeed, got %v", err)
+			}
+		})
+	}
+}
+
+// failingDeleteVpcEC2 fails to delete VPCs with the error
+type failingDeleteVpcEC2 struct {
+	*mockec2.MockEC2
+	err error
+}
+
+func (m *failingDeleteVpcEC2) DeleteVpc(request *ec2.DeleteVpcInput) (*ec2.DeleteVpcOutput, error) {
+	return nil, m.err
+}
+
+func TestDeletionError(t *testing.T) {
+	cause := errors.New("cause")
+	grid := []struct {
+		name                string
+		err                 error
+		dependencyViolation bool
+	}{
+		{name: "error", err: cause},
+		{name: "dependency violation", err: awserr.New("DependencyViolation", "", nil), dependencyViolation: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			c := &failingDeleteVpcEC2{MockEC2: &mockec2.MockEC2{}, err: g.err}
+			cloud.MockEC2 = c
+			// The VPC still exists, so a dependency violation isn't mistaken for a concurrent deletion
+			c.CreateVpcWithId(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, "vpc-1234")
+
+			r := &resources.Resource{
+				Name:    "vpc-1234",
+				ID:      "vpc-1234",
+				Type:    ec2.ResourceTypeVpc,
+				Deleter: DeleteVPC,
+			}
+			wrapDeletionErrors(r)
+
+			err := r.Deleter(cloud, r)
+			var deletionError *DeletionError
+			if !errors.As(err, &deletionError) {
+				t.Fatalf("expected a DeletionError, got %v", err)
+			}
+			if deletionError.Type != ec2.ResourceTypeVpc || deletionError.ID != "vpc-1234" {
+				t.Errorf("unexpected resource %s:%s", deletionError.Type, deletionError.ID)
+			}
+			if !errors.Is(err, g.err) {
+				t.Errorf("expected %v to wrap %v", err, g.err)
+			}
+			// The deletion loop retries dependency violations
+			if actual := IsDependencyViolation(err); actual != g.dependencyViolation {
+				t.Errorf("IsDependencyViolation(%v): expected %v, got %v", err, g.dependencyViolation, actual)
+			}
+		})
+	}
+}
+
+// concurrentlyDeletingEC2 simulates another kops process deleting the same resources:
+// each resource disappears after it has been described, and the delete call then fails with a DependencyViolation.
+type concurrentlyDeletingEC2 struct {
+	*mockec2.MockEC2
+}
+
+func (m *concurrentlyDeletingEC2) dependencyViolation() error {
+	return awserr.New("DependencyViolation", "resource has a dependent object", nil)
+}
+
+func (m *concurrentlyDeletingEC2) DeleteSubnet(request *ec2.DeleteSubnetInput) (*ec2.DeleteSubnetOutput, error) {
+	if _, err := m.MockEC2.DeleteSubnet(request); err != nil {
+		return nil, err
+	}
+	return nil, m.dependencyViolation()
+}
+
+func (m *concurrentlyDeletingEC2) DeleteRouteTable(request *ec2.DeleteRouteTableInput) (*ec2.DeleteRouteTableOutput, error) {
+	if _, err := m.MockEC2.DeleteRouteTable(request); err != nil {
+		return nil, err
+	}
+	return nil, m.dependencyViolation()
+}
+
+func (m *concurrentlyDeletingEC2) DeleteSecurityGroup(request *ec2.DeleteSecurityGroupInput) (*ec2.DeleteSecurityGroupOutput, error) {
+	if _, err := m.MockEC2.DeleteSecurityGroup(request); err != nil {
+		return nil, err
+	}
+	return nil, m.dependencyViolation()
+}
+
+func (m *concurrentlyDeletingEC2) DeleteVpc(request *ec2.DeleteVpcInput) (*ec2.DeleteVpcOutput, error) {
+	if _, err := m.MockEC2.DeleteVpc(request); err != nil {
+		return nil, err
+	}
+	return nil, m.dependencyViolation()
+}
+
+func TestDeleteResourceDeletedConcurrently(t *testing.T) {
+	grid := []struct {
+		resourceType string
+		create       func(c *mockec2.MockEC2) string
+		deleter      func(cloud fi.Cloud, r *resources.Resource) error
+	}{
+		{
+			resourceType: ec2.ResourceTypeSubnet,
+			create: func(c *mockec2.MockEC2) string {
+				c.AddSubnet(&ec2.Subnet{SubnetId: aws.String("subnet-1234"), VpcId: aws.String("vpc-1234")})
+				return "subnet-1234"
+			},
+			deleter: DeleteSubnet,
+		},
+		{
+			resourceType: ec2.ResourceTypeRouteTable,
+			create: func(c *mockec2.MockEC2) string {
+				c.AddRouteTable(&ec2.RouteTable{RouteTableId: aws.String("rtb-1234"), VpcId: aws.String("vpc-1234")})
+				return "rtb-1234"
+			},
+			deleter: DeleteRouteTable,
+		},
+		{
+			resourceType: ec2.ResourceTypeSecurityGroup,
+			create: func(c *mockec2.MockEC2) string {
+				response, err := c.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{GroupName: aws.String("nodes"), VpcId: aws.String("vpc-1234")})
+				if err != nil {
+					t.Fatalf("error creating security group: %v", err)
+				}
+				return aws.ToString(response.GroupId)
+			},
+			deleter: DeleteSecurityGroup,
+		},
+		{
+			resourceType: ec2.ResourceTypeVpc,
+			create: func(c *mockec2.MockEC2) string {
+				c.CreateVpcWithId(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, "vpc-1234")
+				return "vpc-1234"
+			},
+			deleter: DeleteVPC,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.resourceType, func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			c := &concurrentlyDeletingEC2{MockEC2: &mockec2.MockEC2{}}
+			cloud.MockEC2 = c
+
+			id := g.create(c.MockEC2)
+			r := &resources.Resource{
+				Name: id,
+				ID:   id,
+				Type: g.resourceType,
+			}
+			if err := g.deleter(cloud, r); err != nil {
+				t.Errorf("expected deleting a resource that was deleted concurrently to succeed, got %v", err)
+			}
+		})
+	}
+}
@@ -22,6 +22,8 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
 
 	"k8s.io/kops/pkg/resources"
@@ -40,6 +42,13 @@ func DumpEventBridgeRule(op *resources.DumpOperation, r *resources.Resource) err
 	return nil
 }
 
+// eventBridgeRuleDeleterActions are the AWS API actions invoked by EventBridgeRuleDeleter
+var eventBridgeRuleDeleterActions = []string{
+	"events:ListTargetsByRule",
+	"events:RemoveTargets",
+	"events:DeleteRule",
+}
+
 func EventBridgeRuleDeleter(cloud fi.Cloud, r *resources.Resource) error {
 	return DeleteEventBridgeRule(cloud, r.Name)
 }
@@ -52,6 +61,10 @@ func DeleteEventBridgeRule(cloud fi.Cloud, ruleName string) error {
 		Rule: aws.String(ruleName),
 	})
 	if err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
 		return fmt.Errorf("listing targets for EventBridge rule %q: %w", ruleName, err)
 	}
 	if len(targets.Targets) > 0 {
@@ -75,12 +88,19 @@ func DeleteEventBridgeRule(cloud fi.Cloud, ruleName string) error {
 	}
 	_, err = c.EventBridge().DeleteRule(ctx, request)
 	if err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
 		return fmt.Errorf("deleting EventBridge rule %q: %w", ruleName, err)
 	}
 	return nil
 }
 
-func ListEventBridgeRules(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+// ListEventBridgeRules lists the EventBridge rules that kops creates for instance lifecycle events, e.g. for warm pools
+// and the node termination handler. The rule names start with the cluster name, and the rules are tagged for the cluster;
+// rules that are tagged as shared, or as owned by another cluster with a similar name, are skipped.
+func ListEventBridgeRules(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
 	ctx := context.TODO()
 	c := cloud.(awsup.AWSCloud)
 
@@ -88,29 +108,55 @@ func ListEventBridgeRules(cloud fi.Cloud, vpcID, clusterName string) ([]*resourc
 	clusterNamePrefix := awsup.GetClusterName40(clusterName)
 
 	// rule names start with the cluster name so that we can search for them
+	var rules []eventbridgetypes.Rule
 	request := &eventbridge.ListRulesInput{
-		EventBusName: nil,
-		Limit:        nil,
-		NamePrefix:   aws.String(clusterNamePrefix),
+		NamePrefix: aws.String(clusterNamePrefix),
 	}
-	response, err := c.EventBridge().ListRules(ctx, request)
-	if err != nil {
-		return nil, fmt.Errorf("error listing Eventbridge rules: %v", err)
-	}
-	if response == nil || len(response.Rules) == 0 {
-		return nil, nil
+	for {
+		response, err := c.EventBridge().ListRules(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("error listing Eventbridge rules: %v", err)
+		}
+		if response == nil {
+			break
+		}
+		rules = append(rules, response.Rules...)
+		if aws.ToString(response.NextToken) == "" {
+			break
+		}
+		request.NextToken = response.NextToken
 	}
 
 	var resourceTrackers []*resources.Resource
 
-	for _, rule := range response.Rules {
+	for i := range rules {
+		rule := &rules[i]
+		name := aws.ToString(rule.Name)
+
+		tagsResponse, err := c.EventBridge().ListTagsForResource(ctx, &eventbridge.ListTagsForResourceInput{
+			ResourceARN: rule.Arn,
+		})
+		if err != nil {
+			if isNotFoundErr(err) {
+				// Concurrently deleted
+				continue
+			}
+			return nil, fmt.Errorf("error listing tags of EventBridge rule %q: %w", name, err)
+		}
+		var tags []*ec2.Tag
+		for _, tag := range tagsResponse.Tags {
+			tags = append(tags, &ec2.Tag{Key: tag.Key, Value: tag.Value})
+		}
+
 		resourceTracker := &resources.Resource{
-			Name:    *rule.Name,
-			ID:      *rule.Name,
+			Name:    name,
+			ID:      name,
 			Type:    TypeEventBridgeRule,
 			Deleter: EventBridgeRuleDeleter,
+			Actions: eventBridgeRuleDeleterActions,
 			Dumper:  DumpEventBridgeRule,
 			Obj:     rule,
+			Shared:  sharedWithCluster(TypeEventBridgeRule+":"+name, tags, clusterName, ownershipTagKeys),
 		}
 
 		resourceTrackers = append(resourceTrackers, resourceTracker)
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"k8s.io/kops/cloudmock/aws/mockeventbridge"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListEventBridgeRules(t *testing.T) {
+	ctx := context.TODO()
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTag := "kubernetes.io/cluster/" + clusterName
+
+	events := &mockeventbridge.MockEventBridge{}
+	cloud.MockEventBridge = events
+
+	ownedRule := awsup.GetClusterName40(clusterName) + "-ASGLifecycle"
+	events.PutRule(ctx, &eventbridge.PutRuleInput{
+		Name: aws.String(ownedRule),
+		Tags: []eventbridgetypes.Tag{{Key: aws.String(ownershipTag), Value: aws.String("owned")}},
+	})
+	events.PutTargets(ctx, &eventbridge.PutTargetsInput{
+		Rule:    aws.String(ownedRule),
+		Targets: []eventbridgetypes.Target{{Id: aws.String("1"), Arn: aws.String("arn:aws-test:sqs:us-east-1:012345678901:queue")}},
+	})
+	sharedRule := awsup.GetClusterName40(clusterName) + "-SpotInterruption"
+	events.PutRule(ctx, &eventbridge.PutRuleInput{
+		Name: aws.String(sharedRule),
+		Tags: []eventbridgetypes.Tag{{Key: aws.String(ownershipTag), Value: aws.String("shared")}},
+	})
+	events.PutRule(ctx, &eventbridge.PutRuleInput{
+		Name: aws.String("other-example-com-ASGLifecycle"),
+		Tags: []eventbridgetypes.Tag{{Key: aws.String("kubernetes.io/cluster/other.example.com"), Value: aws.String("owned")}},
+	})
+
+	resourceTrackers, err := ListEventBridgeRules(cloud, "", clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing EventBridge rules: %v", err)
+	}
+
+	shared := make(map[string]bool)
+	for _, r := range resourceTrackers {
+		shared[r.ID] = r.Shared
+	}
+	if len(shared) != 2 || shared[ownedRule] || !shared[sharedRule] {
+		t.Fatalf("unexpected EventBridge rules: %v", shared)
+	}
+
+	for _, r := range resourceTrackers {
+		if r.Shared {
+			continue
+		}
+		if err := r.Deleter(cloud, r); err != nil {
+			t.Fatalf("error deleting %s: %v", r.ID, err)
+		}
+	}
+	if _, found := events.Rules[ownedRule]; found {
+		t.Errorf("expected rule %q to be deleted", ownedRule)
+	}
+	if len(events.TargetsByRule[ownedRule]) != 0 {
+		t.Errorf("expected the targets of rule %q to be removed", ownedRule)
+	}
+	if _, found := events.Rules[sharedRule]; !found {
+		t.Errorf("expected shared rule %q to be kept", sharedRule)
+	}
+}
@@ -19,22 +19,47 @@ package aws
 import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/pkg/resources"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 )
 
-// buildEc2FiltersForCluster returns the set of filters we must use to find all resources
-func buildEC2FiltersForCluster(clusterName string) [][]*ec2.Filter {
+// buildEc2FiltersForCluster returns the set of filters we must use to find all resources,
+// including those tagged for the cluster with one of the additional ownershipTagKeys
+func buildEC2FiltersForCluster(clusterName string, ownershipTagKeys []string) [][]*ec2.Filter {
+	return buildEC2FiltersForClusterWithTagScheme(clusterName, resources.ClusterTagSchemeAll, ownershipTagKeys)
+}
+
+// buildEC2FiltersForClusterWithTagScheme returns the set of filters that find the resources tagged for the cluster with the tag scheme.
+// Each filter set costs a round-trip, so restricting the scheme halves the calls when the other tags are known to be absent.
+// An empty scheme is ClusterTagSchemeAll.
+func buildEC2FiltersForClusterWithTagScheme(clusterName string, scheme resources.ClusterTagScheme, ownershipTagKeys []string) [][]*ec2.Filter {
 	var filterSets [][]*ec2.Filter
 
+	if scheme == "" {
+		scheme = resources.ClusterTagSchemeAll
+	}
+
 	// TODO: We could look for tag-key on the old & new tags, and then post-filter (we do this in k/k cloudprovider)
 
-	filterSets = append(filterSets, []*ec2.Filter{
-		{Name: aws.String("tag:" + awsup.TagClusterName), Values: aws.StringSlice([]string{clusterName})},
-	})
+	if scheme == resources.ClusterTagSchemeAll || scheme == resources.ClusterTagSchemeLegacy {
+		filterSets = append(filterSets, []*ec2.Filter{
+			{Name: aws.String("tag:" + awsup.TagClusterName), Values: aws.StringSlice([]string{clusterName})},
+		})
+	}
+
+	if scheme == resources.ClusterTagSchemeAll || scheme == resources.ClusterTagSchemeOwnership {
+		filterSets = append(filterSets, []*ec2.Filter{
+			{Name: aws.String("tag-key"), Values: aws.StringSlice([]string{"kubernetes.io/cluster/" + clusterName})},
+		})
+	}
 
-	filterSets = append(filterSets, []*ec2.Filter{
-		{Name: aws.String("tag-key"), Values: aws.StringSlice([]string{"kubernetes.io/cluster/" + clusterName})},
-	})
+	if scheme == resources.ClusterTagSchemeAll {
+		for _, key := range ownershipTagKeys {
+			filterSets = append(filterSets, []*ec2.Filter{
+				{Name: aws.String("tag:" + key), Values: aws.StringSlice([]string{clusterName})},
+			})
+		}
+	}
 
 	return filterSets
 }
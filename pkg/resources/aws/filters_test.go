@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/pkg/resources"
+)
+
+func TestBuildEC2FiltersForClusterWithTagScheme(t *testing.T) {
+	clusterName := "me.example.com"
+	legacy := []*ec2.Filter{
+		{Name: aws.String("tag:KubernetesCluster"), Values: []*string{aws.String(clusterName)}},
+	}
+	ownership := []*ec2.Filter{
+		{Name: aws.String("tag-key"), Values: []*string{aws.String("kubernetes.io/cluster/" + clusterName)}},
+	}
+
+	grid := []struct {
+		name     string
+		scheme   resources.ClusterTagScheme
+		expected [][]*ec2.Filter
+	}{
+		{name: "default", expected: [][]*ec2.Filter{legacy, ownership}},
+		{name: "all", scheme: resources.ClusterTagSchemeAll, expected: [][]*ec2.Filter{legacy, ownership}},
+		{name: "legacy", scheme: resources.ClusterTagSchemeLegacy, expected: [][]*ec2.Filter{legacy}},
+		{name: "ownership", scheme: resources.ClusterTagSchemeOwnership, expected: [][]*ec2.Filter{ownership}},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			actual := buildEC2FiltersForClusterWithTagScheme(clusterName, g.scheme, nil)
+			if !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("unexpected filters: expected=%v, actual=%v", g.expected, actual)
+			}
+		})
+	}
+
+	// The default matches both schemes
+	if actual := buildEC2FiltersForCluster(clusterName, nil); !reflect.DeepEqual(actual, [][]*ec2.Filter{legacy, ownership}) {
+		t.Errorf("unexpected default filters: %v", actual)
+	}
+}
+
+func TestBuildEC2FiltersForClusterWithOwnershipTagKeys(t *testing.T) {
+	ownershipTagKeys := []string{"company.com/cluster"}
+
+	clusterName := "me.example.com"
+	custom := []*ec2.Filter{
+		{Name: aws.String("tag:company.com/cluster"), Values: []*string{aws.String(clusterName)}},
+	}
+
+	filters := buildEC2FiltersForCluster(clusterName, ownershipTagKeys)
+	if len(filters) != 3 || !reflect.DeepEqual(filters[2], custom) {
+		t.Errorf("expected the custom ownership tag filter last, got %v", filters)
+	}
+
+	// The custom tags are neither the legacy nor the ownership scheme
+	if filters := buildEC2FiltersForClusterWithTagScheme(clusterName, resources.ClusterTagSchemeOwnership, ownershipTagKeys); len(filters) != 1 {
+		t.Errorf("unexpected ownership filters: %v", filters)
+	}
+}
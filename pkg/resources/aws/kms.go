@@ -0,0 +1,274 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// deleteKMSAliasActions are the AWS API actions invoked by DeleteKMSAlias
+var deleteKMSAliasActions = []string{
+	"kms:DeleteAlias",
+}
+
+func DeleteKMSAlias(cloud fi.Cloud, r *resources.Resource) error {
+	ctx := context.TODO()
+	c := cloud.(awsup.AWSCloud)
+
+	aliasName := r.ID
+
+	klog.V(2).Infof("Deleting KMS alias %q", aliasName)
+	request := &kms.DeleteAliasInput{
+		AliasName: aws.String(aliasName),
+	}
+	if _, err := c.KMS().DeleteAlias(ctx, request); err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+		return fmt.Errorf("error deleting KMS alias %q: %w", aliasName, err)
+	}
+	return nil
+}
+
+// scheduleKMSKeyDeletionActions are the AWS API actions invoked by ScheduleKMSKeyDeletion
+var scheduleKMSKeyDeletionActions = []string{
+	"kms:ScheduleKeyDeletion",
+}
+
+// ScheduleKMSKeyDeletion schedules the deletion of the key.
+// KMS keys can't be deleted immediately: the key is deleted at the end of its waiting period, and until then the deletion can be cancelled.
+func ScheduleKMSKeyDeletion(cloud fi.Cloud, r *resources.Resource) error {
+	ctx := context.TODO()
+	c := cloud.(awsup.AWSCloud)
+
+	keyID := r.ID
+
+	klog.V(2).Infof("Scheduling deletion of KMS key %q", keyID)
+	request := &kms.ScheduleKeyDeletionInput{
+		KeyId: aws.String(keyID),
+	}
+	response, err := c.KMS().ScheduleKeyDeletion(ctx, request)
+	if err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+		return fmt.Errorf("error scheduling deletion of KMS key %q: %w", keyID, err)
+	}
+	klog.Infof("KMS key %q will be deleted on %v", keyID, aws.ToTime(response.DeletionDate))
+	return nil
+}
+
+func DumpKMSResource(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["name"] = r.Name
+	data["type"] = r.Type
+	data["raw"] = r.Obj
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// ListKMSKeys lists the customer managed KMS keys owned by the cluster, along with their aliases.
+// The keys are listed directly, so that keys without an alias are found too.
+// The keys are removed after their aliases; see blockKMSResourcesByVolumes for the volumes encrypted with them.
+// AWS managed keys, keys that the cluster doesn't own, and keys that we are not permitted to inspect are never deleted.
+func ListKMSKeys(ctx context.Context, cloud fi.Cloud, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	keys := make(map[string]*kmstypes.KeyMetadata)
+	klog.V(2).Infof("Listing KMS keys")
+	keyPaginator := kms.NewListKeysPaginator(c.KMS(), &kms.ListKeysInput{})
+	for keyPaginator.HasMorePages() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := keyPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing KMS keys: %w", err)
+		}
+		for _, entry := range page.Keys {
+			keyID := aws.ToString(entry.KeyId)
+			key, err := getOwnedKMSKey(ctx, c, keyID, clusterName, ownershipTagKeys)
+			if err != nil {
+				return nil, err
+			}
+			if key != nil {
+				keys[keyID] = key
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	aliasesByKey := make(map[string][]kmstypes.AliasListEntry)
+	klog.V(2).Infof("Listing KMS aliases")
+	aliasPaginator := kms.NewListAliasesPaginator(c.KMS(), &kms.ListAliasesInput{})
+	for aliasPaginator.HasMorePages() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := aliasPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing KMS aliases: %w", err)
+		}
+		for _, alias := range page.Aliases {
+			aliasName := aws.ToString(alias.AliasName)
+			keyID := aws.ToString(alias.TargetKeyId)
+			// The aliases of AWS managed keys (e.g. alias/aws/ebs) are reserved
+			if keys[keyID] == nil || strings.HasPrefix(aliasName, "alias/aws/") {
+				continue
+			}
+			aliasesByKey[keyID] = append(aliasesByKey[keyID], alias)
+		}
+	}
+
+	var resourceTrackers []*resources.Resource
+	for keyID, key := range keys {
+		name := keyID
+		var aliasIDs []string
+		for i, alias := range aliasesByKey[keyID] {
+			aliasName := aws.ToString(alias.AliasName)
+			if i == 0 {
+				name = aliasName
+			}
+			resourceTrackers = append(resourceTrackers, &resources.Resource{
+				Name:    aliasName,
+				ID:      aliasName,
+				Type:    TypeKMSAlias,
+				Deleter: DeleteKMSAlias,
+				Actions: deleteKMSAliasActions,
+				Dumper:  DumpKMSResource,
+				Obj:     alias,
+			})
+			aliasIDs = append(aliasIDs, TypeKMSAlias+":"+aliasName)
+		}
+
+		if key.KeyState == kmstypes.KeyStatePendingDeletion {
+			// Already on its way out
+			continue
+		}
+		resourceTrackers = append(resourceTrackers, &resources.Resource{
+			Name:    name,
+			ID:      keyID,
+			Type:    TypeKMSKey,
+			Deleter: ScheduleKMSKeyDeletion,
+			Actions: scheduleKMSKeyDeletionActions,
+			Dumper:  DumpKMSResource,
+			Obj:     key,
+			Blocked: aliasIDs,
+		})
+	}
+
+	return resourceTrackers, nil
+}
+
+// getOwnedKMSKey returns the key if it is a customer managed key owned by the cluster, otherwise nil.
+// Keys whose key policy doesn't let us describe them are skipped: they may belong to anyone, so they mustn't fail the listing.
+func getOwnedKMSKey(ctx context.Context, c awsup.AWSCloud, keyID, clusterName string, ownershipTagKeys []string) (*kmstypes.KeyMetadata, error) {
+	response, err := c.KMS().DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		if awsup.AWSErrorCode(err) == "NotFoundException" {
+			return nil, nil
+		}
+		if isPermissionError(err) {
+			klog.V(2).Infof("Skipping KMS key %q, which we are not permitted to describe: %v", keyID, err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error describing KMS key %q: %w", keyID, err)
+	}
+	key := response.KeyMetadata
+	if key == nil || key.KeyManager != kmstypes.KeyManagerTypeCustomer {
+		return nil, nil
+	}
+
+	tagsResponse, err := c.KMS().ListResourceTags(ctx, &kms.ListResourceTagsInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		if awsup.AWSErrorCode(err) == "NotFoundException" {
+			return nil, nil
+		}
+		if isPermissionError(err) {
+			klog.V(2).Infof("Skipping KMS key %q, whose tags we are not permitted to list: %v", keyID, err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing tags of KMS key %q: %w", keyID, err)
+	}
+	var tags []*ec2.Tag
+	for _, tag := range tagsResponse.Tags {
+		tags = append(tags, &ec2.Tag{Key: tag.TagKey, Value: tag.TagValue})
+	}
+	// Most keys in the account have nothing to do with the cluster; HasOwnedTag would warn about each of them
+	if !hasClusterTag(tags, clusterName, ownershipTagKeys) {
+		return nil, nil
+	}
+	if !HasOwnedTag(TypeKMSKey+":"+keyID, tags, clusterName, ownershipTagKeys) {
+		return nil, nil
+	}
+	return key, nil
+}
+
+// blockKMSResourcesByVolumes makes the cluster's volumes that are encrypted with a KMS key block the key and its aliases,
+// so that the key isn't scheduled for deletion while a volume still needs it
+func blockKMSResourcesByVolumes(resourceTrackers map[string]*resources.Resource) {
+	// The volumes record their key by ARN, but it may also be given by id
+	keyIDs := make(map[string]string)
+	kmsResources := make(map[string][]*resources.Resource)
+	for _, t := range resourceTrackers {
+		switch obj := t.Obj.(type) {
+		case *kmstypes.KeyMetadata:
+			keyIDs[t.ID] = t.ID
+			if arn := aws.ToString(obj.Arn); arn != "" {
+				keyIDs[arn] = t.ID
+			}
+			kmsResources[t.ID] = append(kmsResources[t.ID], t)
+		case kmstypes.AliasListEntry:
+			keyID := aws.ToString(obj.TargetKeyId)
+			kmsResources[keyID] = append(kmsResources[keyID], t)
+		}
+	}
+	if len(kmsResources) == 0 {
+		return
+	}
+
+	for _, t := range resourceTrackers {
+		volume, ok := t.Obj.(*ec2.Volume)
+		if !ok {
+			continue
+		}
+		keyID, found := keyIDs[aws.ToString(volume.KmsKeyId)]
+		if !found {
+			continue
+		}
+		for _, r := range kmsResources[keyID] {
+			r.Blocked = append(r.Blocked, t.Type+":"+t.ID)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockkms"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListKMSKeys(t *testing.T) {
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTag := "kubernetes.io/cluster/" + clusterName
+
+	c := &mockec2.MockEC2{}
+	mockCloud.MockEC2 = c
+	k := &mockkms.MockKMS{}
+	mockCloud.MockKMS = k
+	cloud := mockCloud.WithTags(map[string]string{awsup.TagClusterName: clusterName})
+
+	k.MockCreateKey(&kmstypes.KeyMetadata{
+		KeyId:      aws.String("key-owned"),
+		KeyManager: kmstypes.KeyManagerTypeCustomer,
+		KeyState:   kmstypes.KeyStateEnabled,
+	}, []kmstypes.Tag{{TagKey: aws.String(ownershipTag), TagValue: aws.String("owned")}})
+	k.MockCreateAlias("alias/me-example-com-etcd", "key-owned")
+	// The key that EBS uses by default is managed by AWS
+	k.MockCreateKey(&kmstypes.KeyMetadata{
+		KeyId:      aws.String("key-aws"),
+		KeyManager: kmstypes.KeyManagerTypeAws,
+		KeyState:   kmstypes.KeyStateEnabled,
+	}, nil)
+	k.MockCreateAlias("alias/aws/ebs", "key-aws")
+	k.MockCreateKey(&kmstypes.KeyMetadata{
+		KeyId:      aws.String("key-shared"),
+		KeyManager: kmstypes.KeyManagerTypeCustomer,
+		KeyState:   kmstypes.KeyStateEnabled,
+	}, []kmstypes.Tag{{TagKey: aws.String(ownershipTag), TagValue: aws.String("shared")}})
+	k.MockCreateAlias("alias/shared", "key-shared")
+	// Keys without an alias are found too
+	k.MockCreateKey(&kmstypes.KeyMetadata{
+		KeyId:      aws.String("key-unaliased"),
+		KeyManager: kmstypes.KeyManagerTypeCustomer,
+		KeyState:   kmstypes.KeyStateEnabled,
+	}, []kmstypes.Tag{{TagKey: aws.String(ownershipTag), TagValue: aws.String("owned")}})
+	// The additional ownership tag keys name the owning cluster
+	k.MockCreateKey(&kmstypes.KeyMetadata{
+		KeyId:      aws.String("key-company"),
+		KeyManager: kmstypes.KeyManagerTypeCustomer,
+		KeyState:   kmstypes.KeyStateEnabled,
+	}, []kmstypes.Tag{{TagKey: aws.String("company.com/cluster"), TagValue: aws.String(clusterName)}})
+	k.MockCreateKey(&kmstypes.KeyMetadata{
+		KeyId:      aws.String("key-other"),
+		KeyManager: kmstypes.KeyManagerTypeCustomer,
+		KeyState:   kmstypes.KeyStateEnabled,
+	}, []kmstypes.Tag{{TagKey: aws.String("company.com/cluster"), TagValue: aws.String("other.example.com")}})
+
+	volume, err := c.CreateVolume(&ec2.CreateVolumeInput{
+		Encrypted: aws.Bool(true),
+		KmsKeyId:  aws.String("arn:aws-test:kms:us-east-1:012345678901:key/key-owned"),
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeVolume),
+				Tags:         []*ec2.Tag{{Key: aws.String(awsup.TagClusterName), Value: aws.String(clusterName)}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error creating volume: %v", err)
+	}
+	volumeID := aws.ToString(volume.VolumeId)
+
+	// A key whose key policy doesn't let us describe it doesn't fail the listing
+	k.MockCreateKey(&kmstypes.KeyMetadata{
+		KeyId:      aws.String("key-denied"),
+		KeyManager: kmstypes.KeyManagerTypeCustomer,
+		KeyState:   kmstypes.KeyStateEnabled,
+	}, nil)
+	k.AccessDeniedKeyIDs = []string{"key-denied"}
+
+	kmsTrackers, err := ListKMSKeys(context.TODO(), cloud, clusterName, []string{"company.com/cluster"})
+	if err != nil {
+		t.Fatalf("error listing KMS keys: %v", err)
+	}
+	// The volumes encrypted with the keys are found among the volumes that are deleted with the cluster
+	volumeTrackers, err := ListVolumes(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing volumes: %v", err)
+	}
+	trackers := make(map[string]*resources.Resource)
+	for _, r := range append(kmsTrackers, volumeTrackers...) {
+		trackers[r.Type+":"+r.ID] = r
+	}
+	blockKMSResourcesByVolumes(trackers)
+
+	actual := make(map[string][]string)
+	for _, r := range kmsTrackers {
+		actual[r.Type+":"+r.ID] = r.Blocked
+	}
+	// The alias is removed after the volume encrypted with its key, and the key after both
+	expected := map[string][]string{
+		"kms-alias:alias/me-example-com-etcd": {"volume:" + volumeID},
+		"kms-key:key-owned":                   {"kms-alias:alias/me-example-com-etcd", "volume:" + volumeID},
+		"kms-key:key-unaliased":               nil,
+		"kms-key:key-company":                 nil,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected KMS resources: actual=%v, expected=%v", actual, expected)
+	}
+
+	for _, r := range kmsTrackers {
+		if err := r.Deleter(cloud, r); err != nil {
+			t.Fatalf("error deleting %s %q: %v", r.Type, r.ID, err)
+		}
+	}
+	if _, found := k.Aliases["alias/me-example-com-etcd"]; found {
+		t.Errorf("alias was not deleted")
+	}
+	for _, keyID := range []string{"key-owned", "key-unaliased", "key-company"} {
+		if state := k.Keys[keyID].KeyState; state != kmstypes.KeyStatePendingDeletion {
+			t.Errorf("expected owned key %q to be pending deletion, got %q", keyID, state)
+		}
+	}
+	for _, keyID := range []string{"key-aws", "key-shared", "key-other", "key-denied"} {
+		if state := k.Keys[keyID].KeyState; state != kmstypes.KeyStateEnabled {
+			t.Errorf("expected key %q to be left alone, got %q", keyID, state)
+		}
+	}
+
+	// The volume's key is recorded when it is dumped
+	op := &resources.DumpOperation{Dump: &resources.Dump{}}
+	if err := volumeTrackers[0].Dumper(op, volumeTrackers[0]); err != nil {
+		t.Fatalf("error dumping volume: %v", err)
+	}
+	if kmsKeyID := op.Dump.Resources[0].(map[string]interface{})["kmsKeyId"]; kmsKeyID != "arn:aws-test:kms:us-east-1:012345678901:key/key-owned" {
+		t.Errorf("unexpected KMS key in the volume dump: %v", kmsKeyID)
+	}
+}
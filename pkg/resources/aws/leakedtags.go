@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// LeakedClusterTag is a "kubernetes.io/cluster/<name>=shared" tag on a shared resource,
+// left behind by a cluster that no longer exists.
+type LeakedClusterTag struct {
+	// ResourceType is the type of the tagged resource: route-table, subnet or vpc
+	ResourceType string
+	// ID is the id of the tagged resource
+	ID string
+	// ClusterName is the name of the cluster that the tag refers to
+	ClusterName string
+}
+
+// Key returns the key of the leaked tag
+func (t *LeakedClusterTag) Key() string {
+	return "kubernetes.io/cluster/" + t.ClusterName
+}
+
+// ListLeakedClusterTags returns the tags that mark route tables, subnets and VPCs as shared with the named clusters,
+// which the caller knows to be deleted. Only the "shared" tags are returned: a resource that a dead cluster owns
+// was leaked itself, and is for a deletion of that cluster to clean up.
+func ListLeakedClusterTags(cloud awsup.AWSCloud, deadClusterNames []string) ([]*LeakedClusterTag, error) {
+	var leaked []*LeakedClusterTag
+
+	for _, clusterName := range deadClusterNames {
+		filter := awsup.NewEC2Filter("tag:kubernetes.io/cluster/"+clusterName, "shared")
+
+		routeTables, err := cloud.EC2().DescribeRouteTables(&ec2.DescribeRouteTablesInput{Filters: []*ec2.Filter{filter}})
+		if err != nil {
+			return nil, fmt.Errorf("error listing route tables: %v", err)
+		}
+		for _, rt := range routeTables.RouteTables {
+			leaked = append(leaked, &LeakedClusterTag{ResourceType: ec2.ResourceTypeRouteTable, ID: aws.ToString(rt.RouteTableId), ClusterName: clusterName})
+		}
+
+		subnets, err := cloud.EC2().DescribeSubnets(&ec2.DescribeSubnetsInput{Filters: []*ec2.Filter{filter}})
+		if err != nil {
+			return nil, fmt.Errorf("error listing subnets: %v", err)
+		}
+		for _, subnet := range subnets.Subnets {
+			leaked = append(leaked, &LeakedClusterTag{ResourceType: ec2.ResourceTypeSubnet, ID: aws.ToString(subnet.SubnetId), ClusterName: clusterName})
+		}
+
+		vpcs, err := cloud.EC2().DescribeVpcs(&ec2.DescribeVpcsInput{Filters: []*ec2.Filter{filter}})
+		if err != nil {
+			return nil, fmt.Errorf("error listing VPCs: %v", err)
+		}
+		for _, vpc := range vpcs.Vpcs {
+			leaked = append(leaked, &LeakedClusterTag{ResourceType: ec2.ResourceTypeVpc, ID: aws.ToString(vpc.VpcId), ClusterName: clusterName})
+		}
+	}
+
+	sort.Slice(leaked, func(i, j int) bool {
+		if leaked[i].ID != leaked[j].ID {
+			return leaked[i].ID < leaked[j].ID
+		}
+		return leaked[i].ClusterName < leaked[j].ClusterName
+	})
+	return leaked, nil
+}
+
+// RemoveLeakedClusterTags removes the leaked tags from their resources; the resources themselves are not changed.
+// A tag is only removed while its value is still "shared".
+func RemoveLeakedClusterTags(cloud awsup.AWSCloud, leaked []*LeakedClusterTag) error {
+	for _, tag := range leaked {
+		klog.V(2).Infof("Removing tag %q from %s %q", tag.Key(), tag.ResourceType, tag.ID)
+		if err := cloud.DeleteTags(tag.ID, map[string]string{tag.Key(): "shared"}); err != nil {
+			return fmt.Errorf("error removing tag %q from %s %q: %w", tag.Key(), tag.ResourceType, tag.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestRemoveLeakedClusterTags(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-shared"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/dead.example.com"), Value: aws.String("shared")},
+			{Key: aws.String("kubernetes.io/cluster/live.example.com"), Value: aws.String("shared")},
+		},
+	})
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-owned"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/dead.example.com"), Value: aws.String("owned")},
+		},
+	})
+
+	leaked, err := ListLeakedClusterTags(cloud, []string{"dead.example.com"})
+	if err != nil {
+		t.Fatalf("error listing leaked tags: %v", err)
+	}
+	expected := []*LeakedClusterTag{
+		{ResourceType: ec2.ResourceTypeRouteTable, ID: "rtb-shared", ClusterName: "dead.example.com"},
+	}
+	if !reflect.DeepEqual(leaked, expected) {
+		t.Fatalf("unexpected leaked tags: actual=%v, expected=%v", leaked, expected)
+	}
+
+	if err := RemoveLeakedClusterTags(cloud, leaked); err != nil {
+		t.Fatalf("error removing leaked tags: %v", err)
+	}
+
+	for id, expected := range map[string]map[string]string{
+		"rtb-shared": {"kubernetes.io/cluster/live.example.com": "shared"},
+		"rtb-owned":  {"kubernetes.io/cluster/dead.example.com": "owned"},
+	} {
+		actual, err := cloud.GetTags(id)
+		if err != nil {
+			t.Fatalf("error getting tags: %v", err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("unexpected tags on %s: actual=%v, expected=%v", id, actual, expected)
+		}
+	}
+	if _, found := c.RouteTables["rtb-shared"]; !found {
+		t.Errorf("expected shared route table not to be deleted")
+	}
+}
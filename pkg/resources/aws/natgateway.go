@@ -42,6 +42,7 @@ func buildNatGatewayResource(ngw *ec2.NatGateway, forceShared bool, clusterName
 		Type:    TypeNatGateway,
 		Dumper:  DumpNatGateway,
 		Deleter: DeleteNatGateway,
+		Actions: deleteNatGatewayActions,
 		Shared:  forceShared,
 	}
 
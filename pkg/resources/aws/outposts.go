@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+)
+
+// OutpostARN returns the ARN of the Outpost the resource is placed on, or "" if it isn't placed on an Outpost
+// (or is of a type that can't be).
+func OutpostARN(r *resources.Resource) string {
+	switch obj := r.Obj.(type) {
+	case *ec2.Volume:
+		return aws.ToString(obj.OutpostArn)
+	case *ec2.Subnet:
+		return aws.ToString(obj.OutpostArn)
+	case *ec2.Instance:
+		return aws.ToString(obj.OutpostArn)
+	case *ec2.NetworkInterface:
+		return aws.ToString(obj.OutpostArn)
+	default:
+		return ""
+	}
+}
+
+// FilterResourcesByOutpost removes the resources that are placed on an Outpost other than outpostARN.
+// Regional resources (VPCs, IAM, load balancers, ...) are kept, as they are shared by all the Outposts.
+func FilterResourcesByOutpost(resourceTrackers map[string]*resources.Resource, outpostARN string) {
+	for k, r := range resourceTrackers {
+		arn := OutpostARN(r)
+		if arn == "" || arn == outpostARN {
+			continue
+		}
+		klog.V(2).Infof("Skipping %s, which is placed on Outpost %q", k, arn)
+		delete(resourceTrackers, k)
+	}
+}
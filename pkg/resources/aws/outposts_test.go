@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestFilterResourcesByOutpost(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	outpostA := "arn:aws:outposts:us-east-1:123456789012:outpost/op-aaaaaaaaaaaaaaaaa"
+	outpostB := "arn:aws:outposts:us-east-1:123456789012:outpost/op-bbbbbbbbbbbbbbbbb"
+
+	tags := []*ec2.TagSpecification{
+		{
+			ResourceType: aws.String(ec2.ResourceTypeVolume),
+			Tags: []*ec2.Tag{
+				{Key: aws.String(awsup.TagClusterName), Value: aws.String(clusterName)},
+			},
+		},
+	}
+	for _, outpostARN := range []string{outpostA, outpostB, ""} {
+		request := &ec2.CreateVolumeInput{
+			AvailabilityZone:  aws.String("us-east-1a"),
+			TagSpecifications: tags,
+		}
+		if outpostARN != "" {
+			request.OutpostArn = aws.String(outpostARN)
+		}
+		if _, err := c.CreateVolume(request); err != nil {
+			t.Fatalf("error creating volume: %v", err)
+		}
+	}
+
+	trackers, err := ListVolumes(cloud, "", clusterName)
+	if err != nil {
+		t.Fatalf("error listing volumes: %v", err)
+	}
+	if len(trackers) != 3 {
+		t.Fatalf("expected volumes on all Outposts to be listed, got %d", len(trackers))
+	}
+
+	resourceTrackers := make(map[string]*resources.Resource)
+	for _, r := range trackers {
+		resourceTrackers[r.Type+":"+r.ID] = r
+	}
+
+	FilterResourcesByOutpost(resourceTrackers, outpostA)
+
+	var keys []string
+	var outposts []string
+	for k, r := range resourceTrackers {
+		keys = append(keys, k)
+		outposts = append(outposts, OutpostARN(r))
+	}
+	sort.Strings(keys)
+	sort.Strings(outposts)
+	expectedKeys := []string{"volume:vol-1", "volume:vol-3"}
+	if !reflect.DeepEqual(expectedKeys, keys) {
+		t.Fatalf("unexpected volumes after filtering: expected=%v, actual=%v", expectedKeys, keys)
+	}
+	expectedOutposts := []string{"", outpostA}
+	if !reflect.DeepEqual(expectedOutposts, outposts) {
+		t.Fatalf("unexpected Outposts after filtering: expected=%v, actual=%v", expectedOutposts, outposts)
+	}
+}
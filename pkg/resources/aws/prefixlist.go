@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// deleteManagedPrefixListActions are the AWS API actions invoked by DeleteManagedPrefixList
+var deleteManagedPrefixListActions = []string{
+	"ec2:DeleteManagedPrefixList",
+}
+
+func DeleteManagedPrefixList(cloud fi.Cloud, r *resources.Resource) error {
+	c := cloud.(awsup.AWSCloud)
+
+	id := r.ID
+
+	klog.V(2).Infof("Deleting EC2 ManagedPrefixList %q", id)
+	request := &ec2.DeleteManagedPrefixListInput{
+		PrefixListId: &id,
+	}
+	_, err := c.EC2().DeleteManagedPrefixList(request)
+	if err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+
+		// The prefix list can't be deleted while security group rules reference it
+		if IsDependencyViolation(err) {
+			return err
+		}
+		return fmt.Errorf("error deleting ManagedPrefixList %q: %w", id, err)
+	}
+	return nil
+}
+
+func DumpManagedPrefixList(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["type"] = ec2.ResourceTypePrefixList
+	data["raw"] = r.Obj
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// ListManagedPrefixLists returns the managed prefix lists owned by the cluster.
+// Prefix lists that are merely shared with the cluster are left alone.
+func ListManagedPrefixLists(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	prefixLists := make(map[string]*ec2.ManagedPrefixList)
+	klog.V(2).Infof("Listing EC2 ManagedPrefixLists")
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
+		request := &ec2.DescribeManagedPrefixListsInput{
+			Filters: filters,
+		}
+		err := c.EC2().DescribeManagedPrefixListsPages(request, func(page *ec2.DescribeManagedPrefixListsOutput, lastPage bool) bool {
+			for _, prefixList := range page.PrefixLists {
+				prefixLists[aws.ToString(prefixList.PrefixListId)] = prefixList
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing ManagedPrefixLists: %v", err)
+		}
+	}
+
+	var resourceTrackers []*resources.Resource
+	for id, prefixList := range prefixLists {
+		if !HasOwnedTag(ec2.ResourceTypePrefixList+":"+id, prefixList.Tags, clusterName, ownershipTagKeys) {
+			continue
+		}
+
+		// The prefix list can only be deleted once the security group rules referencing it are gone.
+		// Our own groups are deleted first; we don't own the rules of other groups, so we only report them.
+		groups, err := findSecurityGroupsReferencingPrefixList(c, id)
+		if err != nil {
+			return nil, err
+		}
+		var blocked []string
+		for _, sg := range groups {
+			groupID := aws.ToString(sg.GroupId)
+			if HasOwnedTag(ec2.ResourceTypeSecurityGroup+":"+groupID, sg.Tags, clusterName, ownershipTagKeys) {
+				blocked = append(blocked, ec2.ResourceTypeSecurityGroup+":"+groupID)
+			} else {
+				klog.Warningf("ManagedPrefixList %q is referenced by rules in SecurityGroup %q; those rules must be removed before it can be deleted", id, groupID)
+			}
+		}
+
+		resourceTracker := &resources.Resource{
+			Name:    aws.ToString(prefixList.PrefixListName),
+			ID:      id,
+			Type:    ec2.ResourceTypePrefixList,
+			Deleter: DeleteManagedPrefixList,
+			Actions: deleteManagedPrefixListActions,
+			Dumper:  DumpManagedPrefixList,
+			Obj:     prefixList,
+			Blocked: blocked,
+		}
+
+		resourceTrackers = append(resourceTrackers, resourceTracker)
+	}
+
+	return resourceTrackers, nil
+}
+
+// findSecurityGroupsReferencingPrefixList returns the security groups with ingress or egress rules referencing the prefix list
+func findSecurityGroupsReferencingPrefixList(c awsup.AWSCloud, prefixListID string) ([]*ec2.SecurityGroup, error) {
+	var groups []*ec2.SecurityGroup
+	seen := sets.NewString()
+	for _, filterName := range []string{"ip-permission.prefix-list-id", "egress.ip-permission.prefix-list-id"} {
+		request := &ec2.DescribeSecurityGroupsInput{
+			Filters: []*ec2.Filter{awsup.NewEC2Filter(filterName, prefixListID)},
+		}
+		response, err := c.EC2().DescribeSecurityGroups(request)
+		if err != nil {
+			return nil, fmt.Errorf("error listing SecurityGroups referencing ManagedPrefixList %q: %v", prefixListID, err)
+		}
+		for _, sg := range response.SecurityGroups {
+			groupID := aws.ToString(sg.GroupId)
+			if seen.Has(groupID) {
+				continue
+			}
+			seen.Insert(groupID)
+			groups = append(groups, sg)
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/dns"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// preflightCheck is a lightweight, read-only call that exercises the permissions a lister needs
+type preflightCheck struct {
+	Action string
+	// Service is the service whose listers need the permission, as named in the listing failures
+	Service string
+	// Types are the resource types whose listers need the permission; if empty, the permission is always needed
+	Types []string
+	// Skip returns true if the cluster's resources are listed without the permission, e.g. because IAM is managed outside kops
+	Skip func(clusterInfo resources.ClusterInfo) bool
+	Run  func(ctx context.Context, c awsup.AWSCloud, clusterName string) error
+}
+
+var preflightChecks = []preflightCheck{
+	{
+		Action:  "ec2:DescribeRouteTables",
+		Service: "ec2",
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &ec2.DescribeRouteTablesInput{
+				Filters: []*ec2.Filter{awsup.NewEC2Filter("tag:"+awsup.TagClusterName, clusterName)},
+			}
+			_, err := c.EC2().DescribeRouteTables(request)
+			return err
+		},
+	},
+	{
+		Action:  "autoscaling:DescribeAutoScalingGroups",
+		Service: "autoscaling",
+		Types:   []string{"autoscaling-group", TypeAutoscalingScheduledAction, TypeAutoscalingLaunchConfig},
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &autoscaling.DescribeAutoScalingGroupsInput{
+				MaxRecords: aws.Int32(1),
+			}
+			_, err := c.Autoscaling().DescribeAutoScalingGroups(ctx, request)
+			return err
+		},
+	},
+	{
+		Action:  "elasticloadbalancing:DescribeLoadBalancers",
+		Service: "elasticloadbalancing",
+		Types:   []string{TypeLoadBalancer, TypeTargetGroup},
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &elb.DescribeLoadBalancersInput{
+				PageSize: aws.Int32(1),
+			}
+			// ELBv2 load balancers are covered by the same IAM action
+			_, err := c.ELB().DescribeLoadBalancers(ctx, request)
+			return err
+		},
+	},
+	{
+		Action:  "iam:ListRoles",
+		Service: "iam",
+		Types:   []string{"iam-role", "iam-instance-profile"},
+		Skip: func(clusterInfo resources.ClusterInfo) bool {
+			return clusterInfo.AWSSkipIAM
+		},
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &iam.ListRolesInput{
+				MaxItems: aws.Int32(1),
+			}
+			_, err := c.IAM().ListRoles(ctx, request)
+			return err
+		},
+	},
+	{
+		Action:  "route53:ListHostedZones",
+		Service: "route53",
+		Types:   []string{"route53-record"},
+		Skip: func(clusterInfo resources.ClusterInfo) bool {
+			return dns.IsGossipClusterName(clusterInfo.Name) || clusterInfo.UsesNoneDNS
+		},
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &route53.ListHostedZonesInput{
+				MaxItems: aws.Int32(1),
+			}
+			_, err := c.Route53().ListHostedZones(ctx, request)
+			return err
+		},
+	},
+	{
+		Action:  "sqs:ListQueues",
+		Service: "sqs",
+		Types:   []string{"sqs"},
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &sqs.ListQueuesInput{
+				QueueNamePrefix: aws.String(strings.ReplaceAll(clusterName, ".", "-")),
+			}
+			_, err := c.SQS().ListQueues(ctx, request)
+			return err
+		},
+	},
+	{
+		Action:  "events:ListRules",
+		Service: "events",
+		Types:   []string{TypeEventBridgeRule},
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &eventbridge.ListRulesInput{
+				NamePrefix: aws.String(awsup.GetClusterName40(clusterName)),
+			}
+			_, err := c.EventBridge().ListRules(ctx, request)
+			return err
+		},
+	},
+	{
+		Action:  "kms:ListKeys",
+		Service: "kms",
+		Types:   []string{TypeKMSAlias, TypeKMSKey},
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &kms.ListKeysInput{
+				Limit: aws.Int32(1),
+			}
+			_, err := c.KMS().ListKeys(ctx, request)
+			return err
+		},
+	},
+	{
+		Action:  "kms:ListAliases",
+		Service: "kms",
+		Types:   []string{TypeKMSAlias, TypeKMSKey},
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &kms.ListAliasesInput{
+				Limit: aws.Int32(1),
+			}
+			_, err := c.KMS().ListAliases(ctx, request)
+			return err
+		},
+	},
+	{
+		Action:  "elasticfilesystem:DescribeFileSystems",
+		Service: "elasticfilesystem",
+		Types:   []string{TypeEFSFileSystem, TypeEFSMountTarget},
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &efs.DescribeFileSystemsInput{
+				MaxItems: aws.Int32(1),
+			}
+			_, err := c.EFS().DescribeFileSystems(ctx, request)
+			return err
+		},
+	},
+	{
+		Action:  "logs:DescribeLogGroups",
+		Service: "logs",
+		Types:   []string{TypeLogGroup},
+		Run: func(ctx context.Context, c awsup.AWSCloud, clusterName string) error {
+			request := &cloudwatchlogs.DescribeLogGroupsInput{
+				Limit: aws.Int32(1),
+			}
+			_, err := c.CloudWatchLogs().DescribeLogGroups(ctx, request)
+			return err
+		},
+	},
+}
+
+// PreflightCheck verifies that the credentials can perform the read-only calls the listers need,
+// so that a delete run fails early with a clear message rather than partway through.
+// Only the services that will be listed for the cluster (e.g. not IAM with AWSSkipIAM, or outside AWSResourceTypes) are checked.
+// Permission errors from all services are reported together. Other errors don't stop the check of the other services,
+// and are returned as a ServiceFailuresError if no permission is missing, as the listing would carry on without those services.
+func PreflightCheck(cloud awsup.AWSCloud, clusterInfo resources.ClusterInfo) error {
+	ctx := context.TODO()
+
+	wantedTypes := sets.NewString(clusterInfo.AWSResourceTypes...)
+
+	var denied []string
+	failures := make(map[string]error)
+	for _, check := range preflightChecks {
+		if len(check.Types) != 0 && wantedTypes.Len() != 0 && !wantedTypes.HasAny(check.Types...) {
+			klog.V(2).Infof("Not checking permission for %s, as its resource types are not listed", check.Action)
+			continue
+		}
+		if check.Skip != nil && check.Skip(clusterInfo) {
+			klog.V(2).Infof("Not checking permission for %s, as it is not needed for the cluster", check.Action)
+			continue
+		}
+
+		klog.V(2).Infof("Checking permission for %s", check.Action)
+		err := check.Run(ctx, cloud, clusterInfo.Name)
+		if err == nil {
+			continue
+		}
+		if isPermissionError(err) {
+			denied = append(denied, fmt.Sprintf("%s: %s", check.Action, awsup.AWSErrorMessage(err)))
+			continue
+		}
+		failures[check.Service] = fmt.Errorf("error checking permission for %s: %w", check.Action, err)
+	}
+
+	if len(denied) != 0 {
+		return fmt.Errorf("the credentials in use are not permitted to list the cluster resources:\n\t%s", strings.Join(denied, "\n\t"))
+	}
+	if len(failures) != 0 {
+		return &resources.ServiceFailuresError{Failures: failures}
+	}
+	return nil
+}
+
+// isPermissionError returns true if the error indicates the caller is not authorized to perform the call
+func isPermissionError(err error) bool {
+	switch awsup.AWSErrorCode(err) {
+	case "AccessDenied", "AccessDeniedException", "UnauthorizedOperation", "AuthorizationError":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/smithy-go"
+	"k8s.io/kops/cloudmock/aws/mockautoscaling"
+	"k8s.io/kops/cloudmock/aws/mockcloudwatchlogs"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockefs"
+	"k8s.io/kops/cloudmock/aws/mockelb"
+	"k8s.io/kops/cloudmock/aws/mockeventbridge"
+	"k8s.io/kops/cloudmock/aws/mockiam"
+	"k8s.io/kops/cloudmock/aws/mockkms"
+	"k8s.io/kops/cloudmock/aws/mockroute53"
+	"k8s.io/kops/cloudmock/aws/mocksqs"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// accessDeniedEC2 is a MockEC2 where the caller isn't permitted to describe route tables
+type accessDeniedEC2 struct {
+	*mockec2.MockEC2
+}
+
+func (m *accessDeniedEC2) DescribeRouteTables(request *ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error) {
+	return nil, awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil)
+}
+
+// deniedIAM is a MockIAM where the caller isn't permitted to list roles
+type deniedIAM struct {
+	*mockiam.MockIAM
+}
+
+func (m *deniedIAM) ListRoles(ctx context.Context, request *iam.ListRolesInput, optFns ...func(*iam.Options)) (*iam.ListRolesOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized to perform iam:ListRoles"}
+}
+
+// unavailableSQS is a MockSQS whose endpoint can't be reached
+type unavailableSQS struct {
+	*mocksqs.MockSQS
+}
+
+func (m *unavailableSQS) ListQueues(ctx context.Context, input *sqs.ListQueuesInput, optFns ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error) {
+	return nil, fmt.Errorf("dial tcp: lookup sqs.us-east-1.amazonaws.com: no such host")
+}
+
+// unavailableKMS is a MockKMS whose endpoint can't be reached
+type unavailableKMS struct {
+	*mockkms.MockKMS
+}
+
+func (m *unavailableKMS) ListAliases(ctx context.Context, input *kms.ListAliasesInput, optFns ...func(*kms.Options)) (*kms.ListAliasesOutput, error) {
+	return nil, fmt.Errorf("dial tcp: lookup kms.us-east-1.amazonaws.com: no such host")
+}
+
+func buildPreflightMockCloud() *awsup.MockAWSCloud {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	cloud.MockEC2 = &mockec2.MockEC2{}
+	cloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+	cloud.MockELB = &mockelb.MockELB{}
+	cloud.MockIAM = &mockiam.MockIAM{}
+	cloud.MockRoute53 = &mockroute53.MockRoute53{}
+	cloud.MockSQS = &mocksqs.MockSQS{}
+	cloud.MockEventBridge = &mockeventbridge.MockEventBridge{}
+	cloud.MockKMS = &mockkms.MockKMS{}
+	cloud.MockLogs = &mockcloudwatchlogs.MockCloudWatchLogs{}
+	cloud.MockEFS = &mockefs.MockEFS{}
+	return cloud
+}
+
+func TestPreflightCheck(t *testing.T) {
+	cloud := buildPreflightMockCloud()
+
+	if err := PreflightCheck(cloud, resources.ClusterInfo{Name: "me.example.com"}); err != nil {
+		t.Fatalf("unexpected error from preflight check: %v", err)
+	}
+}
+
+func TestPreflightCheckAccessDenied(t *testing.T) {
+	cloud := buildPreflightMockCloud()
+	cloud.MockEC2 = &accessDeniedEC2{MockEC2: &mockec2.MockEC2{}}
+
+	err := PreflightCheck(cloud, resources.ClusterInfo{Name: "me.example.com"})
+	if err == nil {
+		t.Fatalf("expected preflight check to fail")
+	}
+	if !strings.Contains(err.Error(), "ec2:DescribeRouteTables") {
+		t.Fatalf("expected preflight check to report ec2:DescribeRouteTables, got: %v", err)
+	}
+}
+
+func TestPreflightCheckOnlyListedServices(t *testing.T) {
+	grid := []struct {
+		name        string
+		clusterInfo resources.ClusterInfo
+		expectError bool
+	}{
+		{
+			name:        "all services",
+			clusterInfo: resources.ClusterInfo{Name: "me.example.com"},
+			expectError: true,
+		},
+		{
+			name:        "IAM managed outside kops",
+			clusterInfo: resources.ClusterInfo{Name: "me.example.com", ListOptions: resources.ListOptions{AWSSkipIAM: true}},
+		},
+		{
+			name:        "resource filter without IAM",
+			clusterInfo: resources.ClusterInfo{Name: "me.example.com", ListOptions: resources.ListOptions{AWSResourceTypes: []string{"route-table"}}},
+		},
+		{
+			name:        "resource filter with IAM",
+			clusterInfo: resources.ClusterInfo{Name: "me.example.com", ListOptions: resources.ListOptions{AWSResourceTypes: []string{"iam-role"}}},
+			expectError: true,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cloud := buildPreflightMockCloud()
+			cloud.MockIAM = &deniedIAM{MockIAM: &mockiam.MockIAM{}}
+
+			err := PreflightCheck(cloud, g.clusterInfo)
+			if g.expectError && (err == nil || !strings.Contains(err.Error(), "iam:ListRoles")) {
+				t.Errorf("expected preflight check to report iam:ListRoles, got: %v", err)
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error from preflight check: %v", err)
+			}
+		})
+	}
+}
+
+func TestPreflightCheckGossipSkipsRoute53(t *testing.T) {
+	cloud := buildPreflightMockCloud()
+	// Listing hosted zones would fail without the mock
+	cloud.MockRoute53 = nil
+
+	if err := PreflightCheck(cloud, resources.ClusterInfo{Name: "me.k8s.local"}); err != nil {
+		t.Fatalf("unexpected error from preflight check: %v", err)
+	}
+	if err := PreflightCheck(cloud, resources.ClusterInfo{Name: "me.example.com", UsesNoneDNS: true}); err != nil {
+		t.Fatalf("unexpected error from preflight check: %v", err)
+	}
+}
+
+func TestPreflightCheckReportsServiceFailures(t *testing.T) {
+	cloud := buildPreflightMockCloud()
+	cloud.MockSQS = &unavailableSQS{MockSQS: &mocksqs.MockSQS{}}
+	cloud.MockKMS = &unavailableKMS{MockKMS: &mockkms.MockKMS{}}
+
+	err := PreflightCheck(cloud, resources.ClusterInfo{Name: "me.example.com"})
+	var serviceFailures *resources.ServiceFailuresError
+	if !errors.As(err, &serviceFailures) {
+		t.Fatalf("expected the failures of the services to be reported, got: %v", err)
+	}
+	if len(serviceFailures.Failures) != 2 || serviceFailures.Failures["sqs"] == nil || serviceFailures.Failures["kms"] == nil {
+		t.Errorf("unexpected service failures: %v", serviceFailures.Failures)
+	}
+
+	// A missing permission still fails the check
+	cloud.MockIAM = &deniedIAM{MockIAM: &mockiam.MockIAM{}}
+	err = PreflightCheck(cloud, resources.ClusterInfo{Name: "me.example.com"})
+	if err == nil || errors.As(err, &serviceFailures) || !strings.Contains(err.Error(), "iam:ListRoles") {
+		t.Errorf("expected preflight check to report iam:ListRoles, got: %v", err)
+	}
+}
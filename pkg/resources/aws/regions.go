@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// globalResourceTypes are the types of the resources of global services, which are the same whichever region they are listed in
+var globalResourceTypes = sets.NewString("iam-role", "iam-instance-profile", "oidc-provider", "route53-record")
+
+// ListResourcesInRegions lists the cluster's resources in each of the regions, with the cloud that cloudForRegion returns for it.
+// The same type and id can be used in several regions, so the keys of the merged trackers, and their dependencies,
+// are qualified with the region (e.g. "us-west-2/vpc:vpc-1234"), except for the resources of global services.
+// The deleters of each tracker use the cloud of its region, whichever cloud they are called with.
+func ListResourcesInRegions(clusterInfo resources.ClusterInfo, regions []string, cloudForRegion func(region string) (awsup.AWSCloud, error)) (map[string]*resources.Resource, error) {
+	resourceTrackers := make(map[string]*resources.Resource)
+	serviceFailures := make(map[string]error)
+	for _, region := range regions {
+		cloud, err := cloudForRegion(region)
+		if err != nil {
+			return nil, fmt.Errorf("error building cloud for region %q: %w", region, err)
+		}
+
+		regionTrackers, err := ListResourcesAWS(cloud, clusterInfo)
+		if err != nil {
+			var failures *resources.ServiceFailuresError
+			if !errors.As(err, &failures) {
+				return nil, fmt.Errorf("error listing resources in region %q: %w", region, err)
+			}
+			for service, err := range failures.Failures {
+				serviceFailures[region+"/"+service] = err
+			}
+		}
+
+		for k, t := range regionTrackers {
+			k = regionKey(region, k)
+			if _, found := resourceTrackers[k]; found {
+				// A resource of a global service, already listed in another region
+				continue
+			}
+			useRegionCloud(t, cloud)
+			t.Blocks = regionKeys(region, t.Blocks)
+			t.Blocked = regionKeys(region, t.Blocked)
+			resourceTrackers[k] = t
+		}
+	}
+
+	if len(serviceFailures) != 0 {
+		return resourceTrackers, &resources.ServiceFailuresError{Failures: serviceFailures}
+	}
+	return resourceTrackers, nil
+}
+
+// regionKey qualifies the key of a resource (e.g. "vpc:vpc-1234") with its region, unless it belongs to a global service
+func regionKey(region, key string) string {
+	resourceType, _, _ := strings.Cut(key, ":")
+	if globalResourceTypes.Has(resourceType) {
+		return key
+	}
+	return region + "/" + key
+}
+
+func regionKeys(region string, keys []string) []string {
+	var qualified []string
+	for _, k := range keys {
+		qualified = append(qualified, regionKey(region, k))
+	}
+	return qualified
+}
+
+// useRegionCloud makes the deleters of the resource use the cloud of its region
+func useRegionCloud(r *resources.Resource, cloud awsup.AWSCloud) {
+	if deleter := r.Deleter; deleter != nil {
+		r.Deleter = func(_ fi.Cloud, r *resources.Resource) error {
+			return deleter(cloud, r)
+		}
+	}
+	if groupDeleter := r.GroupDeleter; groupDeleter != nil {
+		r.GroupDeleter = func(_ fi.Cloud, trackers []*resources.Resource) error {
+			return groupDeleter(cloud, trackers)
+		}
+	}
+}
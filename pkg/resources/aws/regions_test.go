@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListResourcesInRegions(t *testing.T) {
+	clusterName := "me.k8s.local"
+	ownedTags := []*ec2.Tag{
+		{
+			Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+			Value: aws.String("owned"),
+		},
+	}
+
+	regions := []string{"us-east-1", "us-west-2"}
+	clouds := make(map[string]*awsup.MockAWSCloud)
+	mocks := make(map[string]*mockec2.MockEC2)
+	for _, region := range regions {
+		cloud := awsup.BuildMockAWSCloud(region, "abc")
+		c := &mockec2.MockEC2{}
+		cloud.MockEC2 = c
+
+		// The same ids are used in both regions
+		c.CreateVpcWithId(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, "vpc-1234")
+		c.CreateTags(&ec2.CreateTagsInput{Resources: aws.StringSlice([]string{"vpc-1234"}), Tags: ownedTags})
+		c.AddSubnet(&ec2.Subnet{
+			SubnetId: aws.String("subnet-1234"),
+			VpcId:    aws.String("vpc-1234"),
+			Tags:     ownedTags,
+		})
+
+		clouds[region] = cloud
+		mocks[region] = c
+	}
+	cloudForRegion := func(region string) (awsup.AWSCloud, error) {
+		cloud := clouds[region]
+		if cloud == nil {
+			return nil, fmt.Errorf("unexpected region %q", region)
+		}
+		return cloud, nil
+	}
+
+	clusterInfo := resources.ClusterInfo{
+		Name: clusterName,
+		// Only EC2 is mocked
+		ListOptions: resources.ListOptions{AWSResourceTypes: []string{"vpc", "subnet"}},
+	}
+	resourceTrackers, err := ListResourcesInRegions(clusterInfo, regions, cloudForRegion)
+	if err != nil {
+		t.Fatalf("error listing resources: %v", err)
+	}
+
+	var actual []string
+	for k := range resourceTrackers {
+		actual = append(actual, k)
+	}
+	sort.Strings(actual)
+	expected := []string{
+		"us-east-1/subnet:subnet-1234",
+		"us-east-1/vpc:vpc-1234",
+		"us-west-2/subnet:subnet-1234",
+		"us-west-2/vpc:vpc-1234",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected resources: actual=%v, expected=%v", actual, expected)
+	}
+
+	// The dependencies stay within the region
+	subnet := resourceTrackers["us-west-2/subnet:subnet-1234"]
+	if expected := []string{"us-west-2/vpc:vpc-1234"}; !reflect.DeepEqual(subnet.Blocks, expected) {
+		t.Errorf("unexpected blocks: actual=%v, expected=%v", subnet.Blocks, expected)
+	}
+
+	// The resource is deleted in its region, whichever cloud the deleter is called with
+	if err := subnet.Deleter(clouds["us-east-1"], subnet); err != nil {
+		t.Fatalf("error deleting subnet: %v", err)
+	}
+	if mocks["us-west-2"].FindSubnet("subnet-1234") != nil {
+		t.Errorf("expected subnet to be deleted in us-west-2")
+	}
+	if mocks["us-east-1"].FindSubnet("subnet-1234") == nil {
+		t.Errorf("expected subnet in us-east-1 to be left alone")
+	}
+}
+
+func TestRegionKey(t *testing.T) {
+	grid := []struct {
+		key      string
+		expected string
+	}{
+		{key: "vpc:vpc-1234", expected: "us-west-2/vpc:vpc-1234"},
+		{key: "iam-role:masters.example.com", expected: "iam-role:masters.example.com"},
+		{key: "route53-record:Z1234/api.example.com.", expected: "route53-record:Z1234/api.example.com."},
+	}
+	for _, g := range grid {
+		if actual := regionKey("us-west-2", g.key); actual != g.expected {
+			t.Errorf("unexpected key for %q: actual=%q, expected=%q", g.key, actual, g.expected)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/utils/clock"
+)
+
+// DeleteRetryDeadline is how long the deletion of a resource is retried while it is throttled or blocked by a dependency.
+// EC2 is eventually consistent, so a dependency is often still being deleted when we try to delete the resource.
+// It is kept short, because the deleter holds up the other deletions while it waits; longer waits are left to the
+// retry policies of the ops package, which re-queue the resource instead. Setting it to 0 disables the retries.
+var DeleteRetryDeadline = 10 * time.Second
+
+const (
+	// retryBaseDelay is the time to wait before the first retry; it doubles for each retry after that
+	retryBaseDelay = time.Second
+	// retryMaxDelay is the longest time to wait between retries
+	retryMaxDelay = 15 * time.Second
+)
+
+// retryClock is the clock used to wait between retries, and can be replaced in tests
+var retryClock clock.Clock = clock.RealClock{}
+
+// isRetryableAWSError returns true if the call failed because it was throttled, or because of a dependency
+func isRetryableAWSError(err error) bool {
+	return awsup.AWSErrorCode(err) == "DependencyViolation" || IsThrottlingError(err)
+}
+
+// retryAWSError calls fn, retrying with exponential backoff while it fails with a retryable error,
+// until the next retry would start after the deadline.  The last error is returned.
+func retryAWSError(deadline time.Duration, fn func() error) error {
+	start := retryClock.Now()
+	delay := retryBaseDelay
+	for {
+		err := fn()
+		if err == nil || !isRetryableAWSError(err) {
+			return err
+		}
+		if retryClock.Since(start)+delay > deadline {
+			return err
+		}
+
+		klog.V(4).Infof("retryable AWS error, will retry in %v: %v", delay, err)
+		retryClock.Sleep(delay)
+
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// retryDeletes makes the deleter of the resource retry retryable AWS errors, until DeleteRetryDeadline
+func retryDeletes(r *resources.Resource) {
+	deleter := r.Deleter
+	if deleter == nil {
+		return
+	}
+	r.Deleter = func(cloud fi.Cloud, r *resources.Resource) error {
+		return retryAWSError(DeleteRetryDeadline, func() error {
+			return deleter(cloud, r)
+		})
+	}
+}
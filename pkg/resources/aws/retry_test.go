@@ -0,0 +1,175 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// useFakeRetryClock replaces the retry clock for the duration of the test
+func useFakeRetryClock(t *testing.T) *clocktesting.FakeClock {
+	clk := clocktesting.NewFakeClock(time.Now())
+	retryClock = clk
+	t.Cleanup(func() {
+		retryClock = clock.RealClock{}
+	})
+	return clk
+}
+
+func TestRetryAWSError(t *testing.T) {
+	grid := []struct {
+		name             string
+		failures         int
+		err              error
+		deadline         time.Duration
+		expectedAttempts int
+		expectedErr      bool
+		expectedElapsed  time.Duration
+	}{
+		{
+			name:             "success",
+			deadline:         time.Minute,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "dependency violation then success",
+			failures:         3,
+			err:              awserr.New("DependencyViolation", "resource has a dependent object", nil),
+			deadline:         time.Minute,
+			expectedAttempts: 4,
+			expectedElapsed:  7 * time.Second,
+		},
+		{
+			name:             "throttled then success",
+			failures:         2,
+			err:              awserr.New("RequestLimitExceeded", "request limit exceeded", nil),
+			deadline:         time.Minute,
+			expectedAttempts: 3,
+			expectedElapsed:  3 * time.Second,
+		},
+		{
+			name:             "not retryable",
+			failures:         3,
+			err:              awserr.New("InvalidParameterValue", "invalid value", nil),
+			deadline:         time.Minute,
+			expectedAttempts: 1,
+			expectedErr:      true,
+		},
+		{
+			name:             "not an AWS error",
+			failures:         3,
+			err:              errors.New("connection refused"),
+			deadline:         time.Minute,
+			expectedAttempts: 1,
+			expectedErr:      true,
+		},
+		{
+			name:             "deadline",
+			failures:         10,
+			err:              awserr.New("DependencyViolation", "resource has a dependent object", nil),
+			deadline:         10 * time.Second,
+			expectedAttempts: 4,
+			expectedErr:      true,
+			expectedElapsed:  7 * time.Second,
+		},
+		{
+			name:             "retries disabled",
+			failures:         3,
+			err:              awserr.New("DependencyViolation", "resource has a dependent object", nil),
+			expectedAttempts: 1,
+			expectedErr:      true,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			clk := useFakeRetryClock(t)
+			start := clk.Now()
+
+			attempts := 0
+			err := retryAWSError(g.deadline, func() error {
+				attempts++
+				if attempts <= g.failures {
+					return g.err
+				}
+				return nil
+			})
+			if (err != nil) != g.expectedErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if attempts != g.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", g.expectedAttempts, attempts)
+			}
+			if elapsed := clk.Since(start); elapsed != g.expectedElapsed {
+				t.Errorf("expected %v to elapse, got %v", g.expectedElapsed, elapsed)
+			}
+		})
+	}
+}
+
+// flakyDeleteRouteTableEC2 fails the first DeleteRouteTable calls with a DependencyViolation
+type flakyDeleteRouteTableEC2 struct {
+	*mockec2.MockEC2
+	failures int
+	attempts int
+}
+
+func (m *flakyDeleteRouteTableEC2) DeleteRouteTable(request *ec2.DeleteRouteTableInput) (*ec2.DeleteRouteTableOutput, error) {
+	m.attempts++
+	if m.attempts <= m.failures {
+		return nil, awserr.New("DependencyViolation", "The routeTable has dependencies and cannot be deleted.", nil)
+	}
+	return m.MockEC2.DeleteRouteTable(request)
+}
+
+func TestRetryDeletesRouteTable(t *testing.T) {
+	useFakeRetryClock(t)
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &flakyDeleteRouteTableEC2{MockEC2: &mockec2.MockEC2{}, failures: 2}
+	cloud.MockEC2 = c
+
+	rt := &ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+	}
+	c.AddRouteTable(rt)
+
+	tracker := buildTrackerForRouteTable(rt, clusterName, nil)
+	retryDeletes(tracker)
+
+	if err := tracker.Deleter(cloud, tracker); err != nil {
+		t.Fatalf("unexpected error deleting route table: %v", err)
+	}
+	if c.attempts != 3 {
+		t.Errorf("expected 3 attempts to delete the route table, got %d", c.attempts)
+	}
+	if len(c.RouteTables) != 0 {
+		t.Errorf("expected route table to be deleted, got %v", c.RouteTables)
+	}
+}
@@ -17,10 +17,14 @@ limitations under the License.
 package aws
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	"k8s.io/kops/pkg/resources"
@@ -29,54 +33,176 @@ import (
 )
 
 // DescribeRouteTables lists route-tables tagged for the cluster (shared and owned)
-func DescribeRouteTables(cloud fi.Cloud, clusterName string) (map[string]*ec2.RouteTable, error) {
+func DescribeRouteTables(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) (map[string]*ec2.RouteTable, error) {
+	return DescribeRouteTablesInVPC(context.Background(), cloud, "", clusterName, resources.ClusterTagSchemeAll, ownershipTagKeys)
+}
+
+// DescribeRouteTablesInVPC returns the route tables tagged for the cluster with the tag scheme, restricted to the VPC if vpcID is set.
+// If the VPC has been deleted (e.g. by a concurrent deletion), there are no route tables left in it, so we return none.
+func DescribeRouteTablesInVPC(ctx context.Context, cloud fi.Cloud, vpcID, clusterName string, scheme resources.ClusterTagScheme, ownershipTagKeys []string) (map[string]*ec2.RouteTable, error) {
 	c := cloud.(awsup.AWSCloud)
 
 	routeTables := make(map[string]*ec2.RouteTable)
 	klog.V(2).Info("Listing EC2 RouteTables")
-	for _, filters := range buildEC2FiltersForCluster(clusterName) {
+	for _, filters := range buildEC2FiltersForClusterWithTagScheme(clusterName, scheme, ownershipTagKeys) {
+		if vpcID != "" {
+			filters = append(filters, awsup.NewEC2Filter("vpc-id", vpcID))
+		}
 		request := &ec2.DescribeRouteTablesInput{
 			Filters: filters,
 		}
-		response, err := c.EC2().DescribeRouteTables(request)
+		err := c.EC2().DescribeRouteTablesPagesWithContext(ctx, request, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+			for _, rt := range page.RouteTables {
+				routeTables[aws.ToString(rt.RouteTableId)] = rt
+			}
+			return true
+		})
 		if err != nil {
+			if vpcID != "" && awsup.AWSErrorCode(err) == "InvalidVpcID.NotFound" {
+				klog.V(2).Infof("VPC %q not found listing RouteTables; will treat as having no RouteTables", vpcID)
+				return make(map[string]*ec2.RouteTable), nil
+			}
 			return nil, fmt.Errorf("error listing RouteTables: %v", err)
 		}
-
-		for _, rt := range response.RouteTables {
-			routeTables[aws.ToString(rt.RouteTableId)] = rt
-		}
 	}
 
 	return routeTables, nil
 }
 
 func ListRouteTables(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
-	routeTables, err := DescribeRouteTables(cloud, clusterName)
+	return ListRouteTablesMultiCluster(context.Background(), cloud, vpcID, clusterName, resources.ListOptions{})
+}
+
+// ListRouteTablesMultiCluster lists the route tables tagged for the cluster, looking them up by the tags of options.AWSClusterTagScheme.
+// Route tables that other clusters own too are handled according to options.MultiClusterPolicy,
+// so that we don't delete a route table that another live cluster still depends on.
+func ListRouteTablesMultiCluster(ctx context.Context, cloud fi.Cloud, vpcID, clusterName string, options resources.ListOptions) ([]*resources.Resource, error) {
+	switch options.AWSClusterTagScheme {
+	case "", resources.ClusterTagSchemeAll, resources.ClusterTagSchemeLegacy, resources.ClusterTagSchemeOwnership:
+	default:
+		return nil, fmt.Errorf("unknown cluster tag scheme %q", options.AWSClusterTagScheme)
+	}
+
+	routeTables, err := DescribeRouteTablesInVPC(ctx, cloud, vpcID, clusterName, options.AWSClusterTagScheme, options.OwnershipTagKeys)
 	if err != nil {
 		return nil, err
 	}
 
+	listedClusters := sets.NewString(options.ListedClusters...)
+	listedClusters.Insert(clusterName)
+
 	var resourceTrackers []*resources.Resource
 
 	for _, rt := range routeTables {
-		resourceTracker := buildTrackerForRouteTable(rt, clusterName)
+		if IsEKSManaged(rt.Tags) {
+			klog.V(2).Infof("Skipping route table %q, which is managed by EKS", aws.ToString(rt.RouteTableId))
+			continue
+		}
+
+		resourceTracker := buildTrackerForRouteTable(rt, clusterName, options.OwnershipTagKeys)
+		d := &routeTableDeleter{
+			disassociateSharedSubnets: options.AWSDisassociateSharedSubnets,
+			disassociateSubnetsInUse:  options.AWSDisassociateSubnetsInUse,
+			ownershipTagKeys:          options.OwnershipTagKeys,
+		}
+		resourceTracker.Deleter = d.Delete
+
+		owners := ownerClusters(rt.Tags, options.OwnershipTagKeys)
+		if !resourceTracker.Shared && len(owners) > 1 {
+			switch options.MultiClusterPolicy {
+			case resources.MultiClusterPolicyError, "":
+				return nil, fmt.Errorf("route table %q is owned by multiple clusters (%s); refusing to delete it", resourceTracker.ID, strings.Join(owners, ", "))
+			case resources.MultiClusterPolicySkip:
+				klog.Warningf("route table %q is owned by multiple clusters (%s); not deleting it", resourceTracker.ID, strings.Join(owners, ", "))
+				resourceTracker.Shared = true
+			case resources.MultiClusterPolicyDeleteIfAllListed:
+				if !listedClusters.HasAll(owners...) {
+					klog.Warningf("route table %q is owned by clusters that aren't listed (%s); not deleting it", resourceTracker.ID, strings.Join(sets.NewString(owners...).Difference(listedClusters).List(), ", "))
+					resourceTracker.Shared = true
+				}
+			default:
+				return nil, fmt.Errorf("unknown multi-cluster policy %q", options.MultiClusterPolicy)
+			}
+		}
+
 		resourceTrackers = append(resourceTrackers, resourceTracker)
 	}
 
 	return resourceTrackers, nil
 }
 
+// routeTableCreationTimeTags are the tags that tools commonly use to record when they created a resource
+var routeTableCreationTimeTags = []string{"CreationTimestamp", "CreationTime", "CreatedAt"}
+
+// routeTableCreationTime infers when the route table was created.
+// EC2 doesn't report the creation time of route tables, so we rely on a tag recording it in RFC3339 format.
+func routeTableCreationTime(rt *ec2.RouteTable) (time.Time, bool) {
+	for _, key := range routeTableCreationTimeTags {
+		value, found := awsup.FindEC2Tag(rt.Tags, key)
+		if !found {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			klog.Warningf("ignoring unparseable %s tag %q on route table %q: %v", key, value, aws.ToString(rt.RouteTableId), err)
+			continue
+		}
+		return created, true
+	}
+	return time.Time{}, false
+}
+
 func dumpRouteTable(op *resources.DumpOperation, r *resources.Resource) error {
-	data := make(map[string]interface{})
-	data["id"] = r.ID
-	data["type"] = r.Type
-	data["raw"] = r.Obj
+	data := &resources.RouteTableDump{
+		ID:   r.ID,
+		Type: r.Type,
+		Raw:  r.Obj,
+	}
+	if rt, ok := r.Obj.(*ec2.RouteTable); ok {
+		for _, route := range rt.Routes {
+			data.Routes = append(data.Routes, resources.RouteDump{
+				Destination: routeDestination(route),
+				Target:      routeTarget(route),
+				State:       aws.ToString(route.State),
+			})
+		}
+	}
 	op.Dump.Resources = append(op.Dump.Resources, data)
 	return nil
 }
 
-func buildTrackerForRouteTable(rt *ec2.RouteTable, clusterName string) *resources.Resource {
+// routeDestination returns the CIDR block or prefix list that the route matches
+func routeDestination(route *ec2.Route) string {
+	for _, destination := range []*string{route.DestinationCidrBlock, route.DestinationIpv6CidrBlock, route.DestinationPrefixListId} {
+		if aws.ToString(destination) != "" {
+			return aws.ToString(destination)
+		}
+	}
+	return ""
+}
+
+// routeTarget returns the id of the gateway, instance, network interface or connection that the route sends traffic to
+func routeTarget(route *ec2.Route) string {
+	for _, target := range []*string{
+		route.GatewayId,
+		route.NatGatewayId,
+		route.TransitGatewayId,
+		route.EgressOnlyInternetGatewayId,
+		route.VpcPeeringConnectionId,
+		route.CarrierGatewayId,
+		route.LocalGatewayId,
+		route.CoreNetworkArn,
+		route.InstanceId,
+		route.NetworkInterfaceId,
+	} {
+		if aws.ToString(target) != "" {
+			return aws.ToString(target)
+		}
+	}
+	return ""
+}
+
+func buildTrackerForRouteTable(rt *ec2.RouteTable, clusterName string, ownershipTagKeys []string) *resources.Resource {
 	resourceTracker := &resources.Resource{
 		Name:    FindName(rt.Tags),
 		ID:      aws.ToString(rt.RouteTableId),
@@ -84,7 +210,8 @@ func buildTrackerForRouteTable(rt *ec2.RouteTable, clusterName string) *resource
 		Obj:     rt,
 		Dumper:  dumpRouteTable,
 		Deleter: DeleteRouteTable,
-		Shared:  !HasOwnedTag(ec2.ResourceTypeRouteTable+":"+*rt.RouteTableId, rt.Tags, clusterName),
+		Actions: deleteRouteTableActions,
+		Shared:  !HasOwnedTag(ec2.ResourceTypeRouteTable+":"+*rt.RouteTableId, rt.Tags, clusterName, ownershipTagKeys),
 	}
 
 	var blocks []string
@@ -92,12 +219,102 @@ func buildTrackerForRouteTable(rt *ec2.RouteTable, clusterName string) *resource
 
 	blocks = append(blocks, "vpc:"+aws.ToString(rt.VpcId))
 
+	// Gateways can't be deleted while route tables are associated with them.
+	// VPN gateways aren't deleted with the cluster, so there are no edges to them.
+	gateways := sets.NewString()
 	for _, a := range rt.Associations {
-		blocked = append(blocked, "subnet:"+aws.ToString(a.SubnetId))
+		if a.SubnetId != nil {
+			blocked = append(blocked, "subnet:"+aws.ToString(a.SubnetId))
+		}
+		if gatewayID := aws.ToString(a.GatewayId); gatewayID != "" && !strings.HasPrefix(gatewayID, "vgw-") {
+			gateways.Insert("internet-gateway:" + gatewayID)
+		}
 	}
+	blocks = append(blocks, gateways.List()...)
 
 	resourceTracker.Blocks = blocks
 	resourceTracker.Blocked = blocked
 
 	return resourceTracker
 }
+
+// RemoveStaleRoutes removes routes from the shared route tables in resourceMap that point at deleted cluster resources.
+// We don't delete shared route tables, but routes we created to cluster NAT gateways, ENIs or instances
+// are left as blackholes once their targets are deleted, which would break routing in the shared VPC.
+// Only blackhole routes whose target was one of the (non-shared) resources in resourceMap are removed.
+func RemoveStaleRoutes(cloud fi.Cloud, resourceMap map[string]*resources.Resource) error {
+	c := cloud.(awsup.AWSCloud)
+
+	var routeTableIDs []*string
+	deletedTargets := make(map[string]bool)
+	for _, r := range resourceMap {
+		switch r.Type {
+		case ec2.ResourceTypeRouteTable:
+			if r.Shared {
+				routeTableIDs = append(routeTableIDs, aws.String(r.ID))
+			}
+		case TypeNatGateway, ec2.ResourceTypeNetworkInterface, ec2.ResourceTypeInstance:
+			if !r.Shared {
+				deletedTargets[r.ID] = true
+			}
+		}
+	}
+	if len(routeTableIDs) == 0 || len(deletedTargets) == 0 {
+		return nil
+	}
+
+	// Filtering by id, rather than asking for the ids, doesn't fail if one of the route tables is already gone
+	request := &ec2.DescribeRouteTablesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("route-table-id"), Values: routeTableIDs}},
+	}
+	var routeTables []*ec2.RouteTable
+	err := c.EC2().DescribeRouteTablesPagesWithContext(context.TODO(), request, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+		routeTables = append(routeTables, page.RouteTables...)
+		return true
+	})
+	if err != nil {
+		if isNotFoundErr(err) {
+			klog.V(2).Infof("Got %s error listing the shared RouteTables; will treat them as already-deleted", awsup.AWSErrorCode(err))
+			return nil
+		}
+		return fmt.Errorf("error listing RouteTables: %v", err)
+	}
+
+	for _, rt := range routeTables {
+		rtID := aws.ToString(rt.RouteTableId)
+		for _, route := range rt.Routes {
+			if aws.ToString(route.State) != ec2.RouteStateBlackhole {
+				continue
+			}
+			// EC2 sets both the instance and its network interface on a route to an instance, so check each target
+			var target string
+			for _, id := range []*string{route.NatGatewayId, route.InstanceId, route.NetworkInterfaceId} {
+				if deletedTargets[aws.ToString(id)] {
+					target = aws.ToString(id)
+					break
+				}
+			}
+			if target == "" {
+				continue
+			}
+
+			klog.V(2).Infof("Removing stale route to %q from RouteTable %q", target, rtID)
+			request := &ec2.DeleteRouteInput{
+				RouteTableId:             rt.RouteTableId,
+				DestinationCidrBlock:     route.DestinationCidrBlock,
+				DestinationIpv6CidrBlock: route.DestinationIpv6CidrBlock,
+				DestinationPrefixListId:  route.DestinationPrefixListId,
+			}
+			if _, err := c.EC2().DeleteRoute(request); err != nil {
+				if isNotFoundErr(err) {
+					// InvalidRoute.NotFound or InvalidRouteTableID.NotFound
+					klog.V(2).Infof("Got %s error deleting route from RouteTable %q; will treat as already-deleted", awsup.AWSErrorCode(err), rtID)
+					continue
+				}
+				return fmt.Errorf("error deleting route to %q from RouteTable %q: %v", target, rtID, err)
+			}
+		}
+	}
+
+	return nil
+}
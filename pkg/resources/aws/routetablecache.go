@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// routeTableCachingCloud is an AWSCloud whose EC2 client memoizes the responses to DescribeRouteTables.
+// Several listers describe the same route tables, so a new one is used for each listing of the cluster's resources;
+// the deleters are given the cloud by the deletion, so they always see the route tables as they are.
+type routeTableCachingCloud struct {
+	awsup.AWSCloud
+	ec2 *routeTableCache
+}
+
+var _ awsup.AWSCloud = &routeTableCachingCloud{}
+
+func newRouteTableCachingCloud(cloud awsup.AWSCloud) *routeTableCachingCloud {
+	return &routeTableCachingCloud{
+		AWSCloud: cloud,
+		ec2:      &routeTableCache{EC2API: cloud.EC2()},
+	}
+}
+
+func (c *routeTableCachingCloud) EC2() ec2iface.EC2API {
+	return c.ec2
+}
+
+// routeTableCache is an EC2 client that memoizes the responses to DescribeRouteTables, keyed by the request (i.e. the filters and ids).
+// Errors aren't cached, so a failed call is retried by the next caller.
+type routeTableCache struct {
+	ec2iface.EC2API
+
+	mutex sync.Mutex
+	// responses are the responses to DescribeRouteTables
+	responses map[string]*ec2.DescribeRouteTablesOutput
+	// pages are the pages of the responses to DescribeRouteTablesPagesWithContext
+	pages map[string][]*ec2.DescribeRouteTablesOutput
+}
+
+func (c *routeTableCache) DescribeRouteTables(input *ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := input.String()
+	if response, found := c.responses[key]; found {
+		return response, nil
+	}
+	response, err := c.EC2API.DescribeRouteTables(input)
+	if err != nil {
+		return nil, err
+	}
+	if c.responses == nil {
+		c.responses = make(map[string]*ec2.DescribeRouteTablesOutput)
+	}
+	c.responses[key] = response
+	return response, nil
+}
+
+func (c *routeTableCache) DescribeRouteTablesPagesWithContext(ctx context.Context, input *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool, opts ...request.Option) error {
+	pages, err := c.describeRouteTablesPages(ctx, input, opts...)
+	if err != nil {
+		return err
+	}
+	for i, page := range pages {
+		if !fn(page, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// describeRouteTablesPages returns all the pages of the response to the request, from the cache if possible
+func (c *routeTableCache) describeRouteTablesPages(ctx context.Context, input *ec2.DescribeRouteTablesInput, opts ...request.Option) ([]*ec2.DescribeRouteTablesOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := input.String()
+	if pages, found := c.pages[key]; found {
+		return pages, nil
+	}
+	var pages []*ec2.DescribeRouteTablesOutput
+	err := c.EC2API.DescribeRouteTablesPagesWithContext(ctx, input, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+		pages = append(pages, page)
+		return true
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if c.pages == nil {
+		c.pages = make(map[string][]*ec2.DescribeRouteTablesOutput)
+	}
+	c.pages[key] = pages
+	return pages, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// countingRouteTablesEC2 counts the calls to describe route tables
+type countingRouteTablesEC2 struct {
+	*mockec2.MockEC2
+	calls int
+}
+
+func (m *countingRouteTablesEC2) DescribeRouteTables(request *ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error) {
+	m.calls++
+	return m.MockEC2.DescribeRouteTables(request)
+}
+
+func (m *countingRouteTablesEC2) DescribeRouteTablesPagesWithContext(ctx context.Context, request *ec2.DescribeRouteTablesInput, callback func(*ec2.DescribeRouteTablesOutput, bool) bool, options ...request.Option) error {
+	m.calls++
+	return m.MockEC2.DescribeRouteTablesPagesWithContext(ctx, request, callback, options...)
+}
+
+func TestRouteTableCache(t *testing.T) {
+	mockCloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &countingRouteTablesEC2{MockEC2: &mockec2.MockEC2{}}
+	mockCloud.MockEC2 = c
+
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterName),
+				Value: aws.String("owned"),
+			},
+		},
+	})
+
+	cloud := newRouteTableCachingCloud(mockCloud)
+
+	for i := 0; i < 2; i++ {
+		routeTables, err := DescribeRouteTablesInVPC(context.TODO(), cloud, "vpc-1234", clusterName, resources.ClusterTagSchemeAll, nil)
+		if err != nil {
+			t.Fatalf("error describing route tables: %v", err)
+		}
+		if len(routeTables) != 1 || routeTables["rtb-1234"] == nil {
+			t.Errorf("unexpected route tables: %v", routeTables)
+		}
+	}
+	expected := len(buildEC2FiltersForCluster(clusterName, nil))
+	if c.calls != expected {
+		t.Errorf("expected the second description to be cached: actual=%d calls, expected=%d", c.calls, expected)
+	}
+
+	for i := 0; i < 2; i++ {
+		routeTables, err := DescribeRouteTablesIgnoreTags(cloud)
+		if err != nil {
+			t.Fatalf("error describing route tables: %v", err)
+		}
+		if len(routeTables) != 1 {
+			t.Errorf("unexpected route tables: %v", routeTables)
+		}
+	}
+	expected++
+	if c.calls != expected {
+		t.Errorf("expected the second description to be cached: actual=%d calls, expected=%d", c.calls, expected)
+	}
+
+	// Another listing doesn't see the route tables that were cached by this one
+	if _, err := DescribeRouteTablesIgnoreTags(newRouteTableCachingCloud(mockCloud)); err != nil {
+		t.Fatalf("error describing route tables: %v", err)
+	}
+	expected++
+	if c.calls != expected {
+		t.Errorf("expected a new cache to call the API: actual=%d calls, expected=%d", c.calls, expected)
+	}
+}
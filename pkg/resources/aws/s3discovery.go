@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// deleteS3ObjectActions are the AWS API actions invoked by DeleteS3Object
+var deleteS3ObjectActions = []string{
+	"s3:DeleteObject",
+}
+
+// DeleteS3Object deletes the object; the bucket that holds it is left alone
+func DeleteS3Object(cloud fi.Cloud, r *resources.Resource) error {
+	p, ok := r.Obj.(vfs.Path)
+	if !ok {
+		return fmt.Errorf("unexpected object %T for %s", r.Obj, r.ID)
+	}
+
+	klog.V(2).Infof("Deleting S3 object %q", r.ID)
+	if err := p.Remove(context.TODO()); err != nil {
+		if isNotFoundErr(err) {
+			return nil
+		}
+		return fmt.Errorf("error deleting S3 object %q: %w", r.ID, err)
+	}
+	return nil
+}
+
+func DumpS3Object(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["type"] = TypeS3Object
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// ListS3DiscoveryObjects returns the objects under the cluster's service account issuer discovery store,
+// e.g. the OIDC discovery documents that IRSA publishes in a public bucket that the user provided.
+// Only the objects are deleted, never the bucket, so the discovery store must be a prefix within the bucket.
+func ListS3DiscoveryObjects(cloud fi.Cloud, clusterName string, discoveryStore vfs.Path) ([]*resources.Resource, error) {
+	if s3Path, ok := discoveryStore.(*vfs.S3Path); ok && strings.Trim(s3Path.Key(), "/") == "" {
+		return nil, fmt.Errorf("refusing to list the discovery documents of cluster %q, because the discovery store %s is a whole bucket", clusterName, discoveryStore)
+	}
+
+	klog.V(2).Infof("Listing discovery documents under %s", discoveryStore)
+	objects, err := discoveryStore.ReadTree(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("error listing discovery documents under %s: %w", discoveryStore, err)
+	}
+
+	prefix := strings.TrimSuffix(discoveryStore.Path(), "/") + "/"
+	var resourceTrackers []*resources.Resource
+	for _, object := range objects {
+		id := object.Path()
+		resourceTrackers = append(resourceTrackers, &resources.Resource{
+			Name:    strings.TrimPrefix(id, prefix),
+			ID:      id,
+			Type:    TypeS3Object,
+			Deleter: DeleteS3Object,
+			Actions: deleteS3ObjectActions,
+			Dumper:  DumpS3Object,
+			Obj:     object,
+		})
+	}
+	return resourceTrackers, nil
+}
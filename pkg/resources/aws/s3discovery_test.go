@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func TestListS3DiscoveryObjects(t *testing.T) {
+	ctx := context.TODO()
+	clusterName := "me.example.com"
+
+	vfs.Context.ResetMemfsContext(true)
+	bucket, err := vfs.Context.BuildVfsPath("memfs://discovery-bucket")
+	if err != nil {
+		t.Fatalf("error building bucket path: %v", err)
+	}
+	for _, key := range []string{
+		clusterName + "/.well-known/openid-configuration",
+		clusterName + "/openid/v1/jwks",
+		"other.example.com/openid/v1/jwks",
+		"index.html",
+	} {
+		if err := bucket.Join(key).WriteFile(ctx, bytes.NewReader([]byte("{}")), nil); err != nil {
+			t.Fatalf("error writing %s: %v", key, err)
+		}
+	}
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	cloud.MockEC2 = &mockec2.MockEC2{}
+	clusterInfo := resources.ClusterInfo{
+		Name:           clusterName,
+		DiscoveryStore: "memfs://discovery-bucket/" + clusterName,
+	}
+	resourceMap, err := ListResourcesFiltered(cloud, clusterInfo, []string{TypeS3Object})
+	if err != nil {
+		t.Fatalf("error listing resources: %v", err)
+	}
+
+	var actual []string
+	for k, r := range resourceMap {
+		actual = append(actual, k+" "+r.Name)
+	}
+	sort.Strings(actual)
+	expected := []string{
+		"s3-object:memfs://discovery-bucket/me.example.com/.well-known/openid-configuration .well-known/openid-configuration",
+		"s3-object:memfs://discovery-bucket/me.example.com/openid/v1/jwks openid/v1/jwks",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected resources: actual=%v, expected=%v", actual, expected)
+	}
+
+	for _, r := range resourceMap {
+		if err := r.Deleter(cloud, r); err != nil {
+			t.Fatalf("error deleting %s: %v", r.ID, err)
+		}
+	}
+	for _, key := range []string{clusterName + "/.well-known/openid-configuration", clusterName + "/openid/v1/jwks"} {
+		if _, err := bucket.Join(key).ReadFile(ctx); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected %s to be deleted, got %v", key, err)
+		}
+	}
+	// The rest of the bucket survives
+	for _, key := range []string{"other.example.com/openid/v1/jwks", "index.html"} {
+		if _, err := bucket.Join(key).ReadFile(ctx); err != nil {
+			t.Errorf("expected %s to survive, got %v", key, err)
+		}
+	}
+}
+
+func TestListS3DiscoveryObjectsRefusesBucket(t *testing.T) {
+	bucket, err := vfs.Context.BuildVfsPath("s3://discovery-bucket")
+	if err != nil {
+		t.Fatalf("error building bucket path: %v", err)
+	}
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	if _, err := ListS3DiscoveryObjects(cloud, "me.example.com", bucket); err == nil {
+		t.Errorf("expected an error listing a whole bucket")
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+const (
+	TypeAutoscalingScheduledAction = "autoscaling-scheduled-action"
+
+	// describeAutoScalingGroupsMaxNames is the maximum number of names accepted by a single DescribeAutoScalingGroups call
+	describeAutoScalingGroupsMaxNames = 50
+)
+
+// deleteASGScheduledActionActions are the AWS API actions invoked by DeleteASGScheduledAction
+var deleteASGScheduledActionActions = []string{
+	"autoscaling:DeleteScheduledAction",
+}
+
+func DeleteASGScheduledAction(cloud fi.Cloud, r *resources.Resource) error {
+	ctx := context.TODO()
+
+	c := cloud.(awsup.AWSCloud)
+
+	action := r.Obj.(autoscalingtypes.ScheduledUpdateGroupAction)
+
+	klog.V(2).Infof("Deleting autoscaling scheduled action %q", r.ID)
+	request := &autoscaling.DeleteScheduledActionInput{
+		AutoScalingGroupName: action.AutoScalingGroupName,
+		ScheduledActionName:  action.ScheduledActionName,
+	}
+	_, err := c.Autoscaling().DeleteScheduledAction(ctx, request)
+	if err != nil {
+		return fmt.Errorf("error deleting autoscaling scheduled action %q: %w", r.ID, err)
+	}
+	return nil
+}
+
+// ListASGOrphans finds scheduled actions that reference autoscaling groups of the cluster that no longer exist.
+// These are normally removed along with the group, but can be left behind when the group deletion was forced.
+// Scheduled actions are not tagged, so we match them by the kops naming convention for autoscaling groups.
+// Lifecycle hooks can only be described for an existing group, so orphaned hooks can't be detected.
+func ListASGOrphans(cloud fi.Cloud, clusterName string) ([]*resources.Resource, error) {
+	ctx := context.TODO()
+
+	c := cloud.(awsup.AWSCloud)
+
+	klog.V(2).Infof("Listing autoscaling scheduled actions")
+
+	actionsByGroup := make(map[string][]autoscalingtypes.ScheduledUpdateGroupAction)
+	paginator := autoscaling.NewDescribeScheduledActionsPaginator(c.Autoscaling(), &autoscaling.DescribeScheduledActionsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing autoscaling scheduled actions: %v", err)
+		}
+		for _, action := range page.ScheduledUpdateGroupActions {
+			groupName := aws.ToString(action.AutoScalingGroupName)
+			if !isClusterAutoscalingGroupName(groupName, clusterName) {
+				continue
+			}
+			actionsByGroup[groupName] = append(actionsByGroup[groupName], action)
+		}
+	}
+
+	if len(actionsByGroup) == 0 {
+		return nil, nil
+	}
+
+	var groupNames []string
+	for groupName := range actionsByGroup {
+		groupNames = append(groupNames, groupName)
+	}
+
+	existing := make(map[string]bool)
+	for i := 0; i < len(groupNames); i += describeAutoScalingGroupsMaxNames {
+		end := i + describeAutoScalingGroupsMaxNames
+		if end > len(groupNames) {
+			end = len(groupNames)
+		}
+
+		request := &autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: groupNames[i:end],
+		}
+		response, err := c.Autoscaling().DescribeAutoScalingGroups(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("error listing autoscaling groups: %v", err)
+		}
+		for _, group := range response.AutoScalingGroups {
+			existing[aws.ToString(group.AutoScalingGroupName)] = true
+		}
+	}
+
+	var resourceTrackers []*resources.Resource
+	for groupName, actions := range actionsByGroup {
+		if existing[groupName] {
+			continue
+		}
+
+		for _, action := range actions {
+			actionName := aws.ToString(action.ScheduledActionName)
+			klog.V(2).Infof("Found scheduled action %q referencing deleted autoscaling group %q", actionName, groupName)
+
+			resourceTracker := &resources.Resource{
+				Name:    actionName,
+				ID:      groupName + "/" + actionName,
+				Type:    TypeAutoscalingScheduledAction,
+				Deleter: DeleteASGScheduledAction,
+				Actions: deleteASGScheduledActionActions,
+				Obj:     action,
+			}
+			resourceTrackers = append(resourceTrackers, resourceTracker)
+		}
+	}
+
+	return resourceTrackers, nil
+}
+
+// isClusterAutoscalingGroupName returns true if the name follows the kops naming convention for autoscaling groups of the cluster,
+// i.e. <ig>.<cluster>, <ig>.masters.<cluster> or <ig>.apiservers.<cluster>.
+// The instance group part must not contain a dot, so that groups of a cluster named e.g. "a.example.com" don't match "example.com".
+func isClusterAutoscalingGroupName(groupName, clusterName string) bool {
+	prefix, found := strings.CutSuffix(groupName, "."+clusterName)
+	if !found {
+		return false
+	}
+	for _, role := range []string{".masters", ".apiservers"} {
+		if s, found := strings.CutSuffix(prefix, role); found {
+			prefix = s
+			break
+		}
+	}
+	return prefix != "" && !strings.Contains(prefix, ".")
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"k8s.io/kops/cloudmock/aws/mockautoscaling"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListASGOrphans(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockautoscaling.MockAutoscaling{
+		Groups: map[string]*autoscalingtypes.AutoScalingGroup{
+			"nodes." + clusterName: {
+				AutoScalingGroupName: aws.String("nodes." + clusterName),
+			},
+		},
+	}
+	cloud.MockAutoscaling = c
+
+	// Group no longer exists
+	c.AddScheduledAction(&autoscalingtypes.ScheduledUpdateGroupAction{
+		AutoScalingGroupName: aws.String("spot.me.example.com"),
+		ScheduledActionName:  aws.String("scale-down"),
+	})
+	// Group still exists
+	c.AddScheduledAction(&autoscalingtypes.ScheduledUpdateGroupAction{
+		AutoScalingGroupName: aws.String("nodes.me.example.com"),
+		ScheduledActionName:  aws.String("scale-down"),
+	})
+	// Group of another cluster
+	c.AddScheduledAction(&autoscalingtypes.ScheduledUpdateGroupAction{
+		AutoScalingGroupName: aws.String("nodes.other.me.example.com"),
+		ScheduledActionName:  aws.String("scale-down"),
+	})
+
+	resourceTrackers, err := ListASGOrphans(cloud, clusterName)
+	if err != nil {
+		t.Fatalf("error listing orphaned scheduled actions: %v", err)
+	}
+	if len(resourceTrackers) != 1 {
+		t.Fatalf("expected exactly one orphaned scheduled action, got %d", len(resourceTrackers))
+	}
+
+	r := resourceTrackers[0]
+	if r.ID != "spot.me.example.com/scale-down" {
+		t.Fatalf("unexpected scheduled action %q", r.ID)
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting scheduled action: %v", err)
+	}
+	if _, found := c.ScheduledActions["spot.me.example.com::scale-down"]; found {
+		t.Errorf("expected orphaned scheduled action to be deleted")
+	}
+	if len(c.ScheduledActions) != 2 {
+		t.Errorf("expected other scheduled actions to be kept, got %v", c.ScheduledActions)
+	}
+}
+
+func TestIsClusterAutoscalingGroupName(t *testing.T) {
+	grid := map[string]bool{
+		"nodes.me.example.com":                 true,
+		"control-plane.masters.me.example.com": true,
+		"api.apiservers.me.example.com":        true,
+		"me.example.com":                       false,
+		"nodes.other.me.example.com":           false,
+		"nodes.example.com":                    false,
+	}
+	for groupName, expected := range grid {
+		actual := isClusterAutoscalingGroupName(groupName, "me.example.com")
+		if actual != expected {
+			t.Errorf("unexpected result for %q: actual=%v, expected=%v", groupName, actual, expected)
+		}
+	}
+}
@@ -18,9 +18,11 @@ package aws
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	"k8s.io/kops/pkg/resources"
@@ -28,27 +30,47 @@ import (
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 )
 
+// deleteSecurityGroupActions are the AWS API actions invoked by DeleteSecurityGroups
+var deleteSecurityGroupActions = []string{
+	"ec2:DescribeSecurityGroups",
+	"ec2:RevokeSecurityGroupIngress",
+	"ec2:RevokeSecurityGroupEgress",
+	"ec2:DeleteSecurityGroup",
+}
+
+// DeleteSecurityGroup deletes a single security group, as DeleteSecurityGroups
 func DeleteSecurityGroup(cloud fi.Cloud, t *resources.Resource) error {
+	return DeleteSecurityGroups(cloud, []*resources.Resource{t})
+}
+
+// DeleteSecurityGroups deletes the security groups in two phases, because groups whose rules reference each other can't be deleted one at a time.
+// First the ingress rules of the groups, and the egress rules that reference any of the groups, are revoked; then the groups are deleted.
+func DeleteSecurityGroups(cloud fi.Cloud, trackers []*resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
-	id := t.ID
+	ids := sets.NewString()
+	for _, t := range trackers {
+		ids.Insert(t.ID)
+	}
+
 	// First clear all inter-dependent rules
-	// TODO: Move to a "pre-execute" phase?
-	{
+	for _, id := range ids.List() {
 		request := &ec2.DescribeSecurityGroupsInput{
-			GroupIds: []*string{&id},
+			GroupIds: []*string{aws.String(id)},
 		}
 		response, err := c.EC2().DescribeSecurityGroups(request)
 		if err != nil {
-			if awsup.AWSErrorCode(err) == "InvalidGroup.NotFound" {
+			if isNotFoundErr(err) {
 				klog.V(2).Infof("Got InvalidGroup.NotFound error describing SecurityGroup %q; will treat as already-deleted", id)
-				return nil
+				ids.Delete(id)
+				continue
 			}
-			return fmt.Errorf("error describing SecurityGroup %q: %v", id, err)
+			return fmt.Errorf("error describing SecurityGroup %q: %w", id, err)
 		}
 
 		if len(response.SecurityGroups) == 0 {
-			return nil
+			ids.Delete(id)
+			continue
 		}
 		if len(response.SecurityGroups) != 1 {
 			return fmt.Errorf("found multiple SecurityGroups with ID %q", id)
@@ -57,32 +79,81 @@ func DeleteSecurityGroup(cloud fi.Cloud, t *resources.Resource) error {
 
 		if len(sg.IpPermissions) != 0 {
 			revoke := &ec2.RevokeSecurityGroupIngressInput{
-				GroupId:       &id,
+				GroupId:       aws.String(id),
 				IpPermissions: sg.IpPermissions,
 			}
 			_, err = c.EC2().RevokeSecurityGroupIngress(revoke)
 			if err != nil {
-				return fmt.Errorf("cannot revoke ingress for ID %q: %v", id, err)
+				if isNotFoundErr(err) {
+					klog.V(2).Infof("Got %s error revoking ingress of SecurityGroup %q; will treat as already-deleted", awsup.AWSErrorCode(err), id)
+					ids.Delete(id)
+					continue
+				}
+				return fmt.Errorf("cannot revoke ingress for ID %q: %w", id, err)
+			}
+		}
+
+		// The other egress rules (e.g. the default allow-all rule) go with the group
+		if egress := permissionsReferencingGroups(sg.IpPermissionsEgress, ids); len(egress) != 0 {
+			revoke := &ec2.RevokeSecurityGroupEgressInput{
+				GroupId:       aws.String(id),
+				IpPermissions: egress,
+			}
+			_, err = c.EC2().RevokeSecurityGroupEgress(revoke)
+			if err != nil {
+				if isNotFoundErr(err) {
+					klog.V(2).Infof("Got %s error revoking egress of SecurityGroup %q; will treat as already-deleted", awsup.AWSErrorCode(err), id)
+					ids.Delete(id)
+					continue
+				}
+				return fmt.Errorf("cannot revoke egress for ID %q: %w", id, err)
 			}
 		}
 	}
 
-	{
+	for _, id := range ids.List() {
 		klog.V(2).Infof("Deleting EC2 SecurityGroup %q", id)
 		request := &ec2.DeleteSecurityGroupInput{
-			GroupId: &id,
+			GroupId: aws.String(id),
 		}
 		_, err := c.EC2().DeleteSecurityGroup(request)
 		if err != nil {
+			if isNotFoundErr(err) {
+				klog.V(2).Infof("Got %s error deleting SecurityGroup %q; will treat as already-deleted", awsup.AWSErrorCode(err), id)
+				continue
+			}
 			if IsDependencyViolation(err) {
+				if deletedConcurrently("security-group:"+id, func() (bool, error) {
+					response, err := c.EC2().DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{GroupIds: []*string{aws.String(id)}})
+					if err != nil {
+						return false, err
+					}
+					return len(response.SecurityGroups) != 0, nil
+				}) {
+					continue
+				}
 				return err
 			}
-			return fmt.Errorf("error deleting SecurityGroup %q: %v", id, err)
+			return fmt.Errorf("error deleting SecurityGroup %q: %w", id, err)
 		}
 	}
 	return nil
 }
 
+// permissionsReferencingGroups returns the rules that reference any of the groups
+func permissionsReferencingGroups(permissions []*ec2.IpPermission, groupIDs sets.String) []*ec2.IpPermission {
+	var referencing []*ec2.IpPermission
+	for _, permission := range permissions {
+		for _, pair := range permission.UserIdGroupPairs {
+			if groupIDs.Has(aws.ToString(pair.GroupId)) {
+				referencing = append(referencing, permission)
+				break
+			}
+		}
+	}
+	return referencing
+}
+
 func DumpSecurityGroup(op *resources.DumpOperation, r *resources.Resource) error {
 	data := make(map[string]interface{})
 	data["id"] = r.ID
@@ -92,8 +163,8 @@ func DumpSecurityGroup(op *resources.DumpOperation, r *resources.Resource) error
 	return nil
 }
 
-func ListSecurityGroups(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
-	groups, err := DescribeSecurityGroups(cloud, clusterName)
+func ListSecurityGroups(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	groups, err := DescribeSecurityGroups(cloud, clusterName, ownershipTagKeys)
 	if err != nil {
 		return nil, err
 	}
@@ -102,13 +173,16 @@ func ListSecurityGroups(cloud fi.Cloud, vpcID, clusterName string) ([]*resources
 
 	for id, sg := range groups {
 		resourceTracker := &resources.Resource{
-			Name:    FindName(sg.Tags),
-			ID:      id,
-			Type:    ec2.ResourceTypeSecurityGroup,
-			Deleter: DeleteSecurityGroup,
-			Dumper:  DumpSecurityGroup,
-			Obj:     sg,
-			Shared:  !HasOwnedTag(ec2.ResourceTypeSecurityGroup+":"+id, sg.Tags, clusterName),
+			Name:         FindName(sg.Tags),
+			ID:           id,
+			Type:         ec2.ResourceTypeSecurityGroup,
+			GroupDeleter: DeleteSecurityGroups,
+			// The groups of a VPC are deleted together, so that the rules referencing each other can be revoked first
+			GroupKey: ec2.ResourceTypeSecurityGroup + "/" + aws.ToString(sg.VpcId),
+			Actions:  deleteSecurityGroupActions,
+			Dumper:   DumpSecurityGroup,
+			Obj:      sg,
+			Shared:   !HasOwnedTag(ec2.ResourceTypeSecurityGroup+":"+id, sg.Tags, clusterName, ownershipTagKeys),
 		}
 
 		var blocks []string
@@ -119,15 +193,31 @@ func ListSecurityGroups(cloud fi.Cloud, vpcID, clusterName string) ([]*resources
 		resourceTrackers = append(resourceTrackers, resourceTracker)
 	}
 
+	// Rules in security groups of peered VPCs can reference our groups, which blocks their deletion.
+	// We don't own those rules, so we only report them.
+	var ownedGroupIDs []string
+	for _, resourceTracker := range resourceTrackers {
+		if !resourceTracker.Shared {
+			ownedGroupIDs = append(ownedGroupIDs, resourceTracker.ID)
+		}
+	}
+	references, err := ListVPCSecurityGroupReferencesAcrossVPCs(cloud, ownedGroupIDs)
+	if err != nil {
+		klog.Warningf("unable to check for references to SecurityGroups from other VPCs: %v", err)
+	}
+	for _, reference := range references {
+		klog.Warningf("SecurityGroup %q is referenced by rules in SecurityGroups %v of VPC %q; those rules must be removed before it can be deleted", reference.GroupID, reference.ReferencingGroupIDs, reference.ReferencingVPCID)
+	}
+
 	return resourceTrackers, nil
 }
 
-func DescribeSecurityGroups(cloud fi.Cloud, clusterName string) (map[string]*ec2.SecurityGroup, error) {
+func DescribeSecurityGroups(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) (map[string]*ec2.SecurityGroup, error) {
 	c := cloud.(awsup.AWSCloud)
 
 	groups := make(map[string]*ec2.SecurityGroup)
 	klog.V(2).Infof("Listing EC2 SecurityGroups")
-	for _, filters := range buildEC2FiltersForCluster(clusterName) {
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
 		request := &ec2.DescribeSecurityGroupsInput{
 			Filters: filters,
 		}
@@ -143,3 +233,68 @@ func DescribeSecurityGroups(cloud fi.Cloud, clusterName string) (map[string]*ec2
 
 	return groups, nil
 }
+
+// CrossVPCSecurityGroupReference describes security groups in another VPC with rules referencing a security group
+type CrossVPCSecurityGroupReference struct {
+	// GroupID is the referenced security group
+	GroupID string
+	// ReferencingVPCID is the VPC containing the referencing security groups
+	ReferencingVPCID string
+	// VPCPeeringConnectionID is the peering connection through which the group is referenced, if any
+	VPCPeeringConnectionID string
+	// ReferencingGroupIDs are the security groups whose rules must be edited before the referenced group can be deleted
+	ReferencingGroupIDs []string
+}
+
+// ListVPCSecurityGroupReferencesAcrossVPCs finds security groups in other (peered) VPCs with rules referencing the given groups
+func ListVPCSecurityGroupReferencesAcrossVPCs(cloud fi.Cloud, groupIDs []string) ([]*CrossVPCSecurityGroupReference, error) {
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+
+	c := cloud.(awsup.AWSCloud)
+
+	klog.V(2).Infof("Listing EC2 SecurityGroup references from other VPCs")
+	request := &ec2.DescribeSecurityGroupReferencesInput{
+		GroupId: aws.StringSlice(groupIDs),
+	}
+	response, err := c.EC2().DescribeSecurityGroupReferences(request)
+	if err != nil {
+		return nil, fmt.Errorf("error describing SecurityGroup references: %w", err)
+	}
+
+	var references []*CrossVPCSecurityGroupReference
+	for _, ref := range response.SecurityGroupReferenceSet {
+		reference := &CrossVPCSecurityGroupReference{
+			GroupID:                aws.ToString(ref.GroupId),
+			ReferencingVPCID:       aws.ToString(ref.ReferencingVpcId),
+			VPCPeeringConnectionID: aws.ToString(ref.VpcPeeringConnectionId),
+		}
+
+		// DescribeSecurityGroupReferences only reports the VPC, so find the groups with the referencing rules
+		referencing := make(map[string]bool)
+		for _, filterName := range []string{"ip-permission.group-id", "egress.ip-permission.group-id"} {
+			request := &ec2.DescribeSecurityGroupsInput{
+				Filters: []*ec2.Filter{
+					awsup.NewEC2Filter("vpc-id", reference.ReferencingVPCID),
+					awsup.NewEC2Filter(filterName, reference.GroupID),
+				},
+			}
+			response, err := c.EC2().DescribeSecurityGroups(request)
+			if err != nil {
+				return nil, fmt.Errorf("error listing SecurityGroups in VPC %q referencing %q: %w", reference.ReferencingVPCID, reference.GroupID, err)
+			}
+			for _, sg := range response.SecurityGroups {
+				referencing[aws.ToString(sg.GroupId)] = true
+			}
+		}
+		for id := range referencing {
+			reference.ReferencingGroupIDs = append(reference.ReferencingGroupIDs, id)
+		}
+		sort.Strings(reference.ReferencingGroupIDs)
+
+		references = append(references, reference)
+	}
+
+	return references, nil
+}
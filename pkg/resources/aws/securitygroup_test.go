@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListVPCSecurityGroupReferencesAcrossVPCs(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddSecurityGroup(&ec2.SecurityGroup{
+		GroupId: aws.String("sg-cluster"),
+		VpcId:   aws.String("vpc-1234"),
+	})
+	// Same VPC; not a cross-VPC reference
+	c.AddSecurityGroup(&ec2.SecurityGroup{
+		GroupId: aws.String("sg-local"),
+		VpcId:   aws.String("vpc-1234"),
+		IpPermissions: []*ec2.IpPermission{
+			{
+				IpProtocol:       aws.String("-1"),
+				UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-cluster")}},
+			},
+		},
+	})
+	// Peered VPC
+	c.AddSecurityGroup(&ec2.SecurityGroup{
+		GroupId: aws.String("sg-peer"),
+		VpcId:   aws.String("vpc-5555"),
+		IpPermissions: []*ec2.IpPermission{
+			{
+				IpProtocol:       aws.String("-1"),
+				UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-cluster")}},
+			},
+		},
+	})
+	c.AddSecurityGroup(&ec2.SecurityGroup{
+		GroupId: aws.String("sg-peer-unrelated"),
+		VpcId:   aws.String("vpc-5555"),
+	})
+
+	references, err := ListVPCSecurityGroupReferencesAcrossVPCs(cloud, []string{"sg-cluster"})
+	if err != nil {
+		t.Fatalf("error listing cross-VPC SecurityGroup references: %v", err)
+	}
+
+	expected := []*CrossVPCSecurityGroupReference{
+		{
+			GroupID:             "sg-cluster",
+			ReferencingVPCID:    "vpc-5555",
+			ReferencingGroupIDs: []string{"sg-peer"},
+		},
+	}
+	if !reflect.DeepEqual(expected, references) {
+		t.Fatalf("unexpected references: expected=%+v, actual=%+v", expected[0], references)
+	}
+}
+
+func TestDeleteSecurityGroupsReferencingEachOther(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownedTags := []*ec2.Tag{
+		{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+	}
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddSecurityGroup(&ec2.SecurityGroup{
+		GroupId: aws.String("sg-a"),
+		VpcId:   aws.String("vpc-1234"),
+		Tags:    ownedTags,
+		IpPermissions: []*ec2.IpPermission{
+			{
+				IpProtocol:       aws.String("-1"),
+				UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-b")}},
+			},
+		},
+		IpPermissionsEgress: []*ec2.IpPermission{
+			{
+				IpProtocol:       aws.String("-1"),
+				UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-b")}},
+			},
+		},
+	})
+	c.AddSecurityGroup(&ec2.SecurityGroup{
+		GroupId: aws.String("sg-b"),
+		VpcId:   aws.String("vpc-1234"),
+		Tags:    ownedTags,
+		IpPermissions: []*ec2.IpPermission{
+			{
+				IpProtocol:       aws.String("-1"),
+				UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-a")}},
+			},
+		},
+	})
+
+	trackers, err := ListSecurityGroups(cloud, "vpc-1234", clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing security groups: %v", err)
+	}
+	if len(trackers) != 2 || trackers[0].GroupKey != trackers[1].GroupKey {
+		t.Fatalf("expected the security groups to be deleted together, got %v", trackers)
+	}
+
+	// One at a time, the group is still referenced by the rules of the other
+	if err := DeleteSecurityGroup(cloud, trackers[0]); !IsDependencyViolation(err) {
+		t.Fatalf("expected a dependency violation deleting %s alone, got %v", trackers[0].ID, err)
+	}
+
+	if err := trackers[0].GroupDeleter(cloud, trackers); err != nil {
+		t.Fatalf("error deleting security groups: %v", err)
+	}
+	if len(c.SecurityGroups) != 0 {
+		t.Errorf("expected security groups to be deleted, got %v", c.SecurityGroups)
+	}
+}
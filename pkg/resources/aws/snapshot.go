@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// deleteEBSSnapshotActions are the AWS API actions invoked by DeleteEBSSnapshot
+var deleteEBSSnapshotActions = []string{
+	"ec2:DeleteSnapshot",
+}
+
+func DeleteEBSSnapshot(cloud fi.Cloud, r *resources.Resource) error {
+	c := cloud.(awsup.AWSCloud)
+
+	id := r.ID
+
+	klog.V(2).Infof("Deleting EBS Snapshot %q", id)
+	request := &ec2.DeleteSnapshotInput{
+		SnapshotId: &id,
+	}
+	_, err := c.EC2().DeleteSnapshot(request)
+	if err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+		// InvalidSnapshot.InUse means an image was registered from the snapshot after it was listed; that image isn't ours to delete
+		return fmt.Errorf("error deleting Snapshot %q: %w", id, err)
+	}
+	return nil
+}
+
+func DumpEBSSnapshot(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["type"] = ec2.ResourceTypeSnapshot
+	data["raw"] = r.Obj
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// ListEBSSnapshots returns the EBS snapshots of the cluster, e.g. volume backups.
+// Snapshots that back an image can't be deleted (InvalidSnapshot.InUse), and the image may still be in use, so they are skipped.
+func ListEBSSnapshots(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	snapshots := make(map[string]*ec2.Snapshot)
+	klog.V(2).Infof("Listing EBS Snapshots")
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
+		request := &ec2.DescribeSnapshotsInput{
+			OwnerIds: []*string{aws.String("self")},
+			Filters:  filters,
+		}
+		err := c.EC2().DescribeSnapshotsPages(request, func(page *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+			for _, snapshot := range page.Snapshots {
+				snapshots[aws.ToString(snapshot.SnapshotId)] = snapshot
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing Snapshots: %v", err)
+		}
+	}
+
+	var resourceTrackers []*resources.Resource
+	for id, snapshot := range snapshots {
+		shared := !HasOwnedTag(ec2.ResourceTypeSnapshot+":"+id, snapshot.Tags, clusterName, ownershipTagKeys)
+		if !shared {
+			images, err := findImagesBackedBySnapshot(c, id)
+			if err != nil {
+				return nil, err
+			}
+			if len(images) != 0 {
+				klog.Warningf("Snapshot %q backs images %v; not deleting it", id, images)
+				continue
+			}
+		}
+
+		resourceTracker := &resources.Resource{
+			Name:    FindName(snapshot.Tags),
+			ID:      id,
+			Type:    ec2.ResourceTypeSnapshot,
+			Deleter: DeleteEBSSnapshot,
+			Actions: deleteEBSSnapshotActions,
+			Dumper:  DumpEBSSnapshot,
+			Obj:     snapshot,
+			Shared:  shared,
+		}
+
+		resourceTrackers = append(resourceTrackers, resourceTracker)
+	}
+
+	return resourceTrackers, nil
+}
+
+// findImagesBackedBySnapshot returns the ids of our images with the snapshot in their block device mappings
+func findImagesBackedBySnapshot(c awsup.AWSCloud, snapshotID string) ([]string, error) {
+	var imageIDs []string
+	request := &ec2.DescribeImagesInput{
+		Owners:  []*string{aws.String("self")},
+		Filters: []*ec2.Filter{awsup.NewEC2Filter("block-device-mapping.snapshot-id", snapshotID)},
+	}
+	err := c.EC2().DescribeImagesPagesWithContext(context.TODO(), request, func(page *ec2.DescribeImagesOutput, lastPage bool) bool {
+		for _, image := range page.Images {
+			imageIDs = append(imageIDs, aws.ToString(image.ImageId))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing images backed by Snapshot %q: %v", snapshotID, err)
+	}
+	return imageIDs, nil
+}
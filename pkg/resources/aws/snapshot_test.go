@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListEBSSnapshots(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	owned := []*ec2.Tag{
+		{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+	}
+
+	c.AddSnapshot(&ec2.Snapshot{
+		SnapshotId: aws.String("snap-1234"),
+		VolumeId:   aws.String("vol-1234"),
+		Tags:       owned,
+	})
+	// Backs an image
+	c.AddSnapshot(&ec2.Snapshot{
+		SnapshotId: aws.String("snap-5555"),
+		Tags:       owned,
+	})
+	c.Images = append(c.Images, &ec2.Image{
+		ImageId: aws.String("ami-5555"),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+			{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsBlockDevice{SnapshotId: aws.String("snap-5555")}},
+		},
+	})
+	// Shared with the cluster
+	c.AddSnapshot(&ec2.Snapshot{
+		SnapshotId: aws.String("snap-6666"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("shared")},
+		},
+	})
+
+	resourceTrackers, err := ListEBSSnapshots(cloud, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing snapshots: %v", err)
+	}
+	sort.Slice(resourceTrackers, func(i, j int) bool {
+		return resourceTrackers[i].ID < resourceTrackers[j].ID
+	})
+	if len(resourceTrackers) != 2 {
+		t.Fatalf("expected exactly two snapshots, got %d", len(resourceTrackers))
+	}
+	if r := resourceTrackers[0]; r.ID != "snap-1234" || r.Shared {
+		t.Errorf("expected owned snapshot snap-1234, got %q (shared=%v)", r.ID, r.Shared)
+	}
+	if r := resourceTrackers[1]; r.ID != "snap-6666" || !r.Shared {
+		t.Errorf("expected shared snapshot snap-6666, got %q (shared=%v)", r.ID, r.Shared)
+	}
+
+	r := resourceTrackers[0]
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting snapshot: %v", err)
+	}
+	if _, found := c.Snapshots["snap-1234"]; found {
+		t.Errorf("expected snapshot to be deleted")
+	}
+	if _, found := c.Snapshots["snap-5555"]; !found {
+		t.Errorf("expected snapshot backing an image to be kept")
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// cancelSpotInstanceRequestActions are the AWS API actions invoked by CancelSpotInstanceRequest
+var cancelSpotInstanceRequestActions = []string{
+	"ec2:CancelSpotInstanceRequests",
+}
+
+// CancelSpotInstanceRequest cancels the spot instance request, so that it doesn't launch another instance.
+// Cancelling the request doesn't terminate the instance it launched; that instance is deleted separately.
+func CancelSpotInstanceRequest(cloud fi.Cloud, r *resources.Resource) error {
+	c := cloud.(awsup.AWSCloud)
+
+	id := r.ID
+
+	klog.V(2).Infof("Cancelling EC2 SpotInstanceRequest %q", id)
+	request := &ec2.CancelSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: []*string{&id},
+	}
+	_, err := c.EC2().CancelSpotInstanceRequests(request)
+	if err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+		return fmt.Errorf("error cancelling SpotInstanceRequest %q: %w", id, err)
+	}
+	return nil
+}
+
+func DumpSpotInstanceRequest(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["type"] = ec2.ResourceTypeSpotInstancesRequest
+	data["raw"] = r.Obj
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// ListSpotInstanceRequests returns the spot instance requests of the cluster that could still launch instances.
+// Requests that are already closed or cancelled are skipped.
+func ListSpotInstanceRequests(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	spotRequests := make(map[string]*ec2.SpotInstanceRequest)
+	klog.V(2).Infof("Listing EC2 SpotInstanceRequests")
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
+		request := &ec2.DescribeSpotInstanceRequestsInput{
+			Filters: filters,
+		}
+		err := c.EC2().DescribeSpotInstanceRequestsPages(request, func(page *ec2.DescribeSpotInstanceRequestsOutput, lastPage bool) bool {
+			for _, spotRequest := range page.SpotInstanceRequests {
+				spotRequests[aws.ToString(spotRequest.SpotInstanceRequestId)] = spotRequest
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing SpotInstanceRequests: %v", err)
+		}
+	}
+
+	var resourceTrackers []*resources.Resource
+	for id, spotRequest := range spotRequests {
+		switch aws.ToString(spotRequest.State) {
+		case ec2.SpotInstanceStateClosed, ec2.SpotInstanceStateCancelled:
+			klog.V(4).Infof("SpotInstanceRequest %q is %s; skipping", id, aws.ToString(spotRequest.State))
+			continue
+		}
+
+		resourceTracker := &resources.Resource{
+			Name:    FindName(spotRequest.Tags),
+			ID:      id,
+			Type:    ec2.ResourceTypeSpotInstancesRequest,
+			Deleter: CancelSpotInstanceRequest,
+			Actions: cancelSpotInstanceRequestActions,
+			Dumper:  DumpSpotInstanceRequest,
+			Obj:     spotRequest,
+		}
+
+		// A persistent request would replace the instance if it were terminated first
+		if instanceID := aws.ToString(spotRequest.InstanceId); instanceID != "" {
+			resourceTracker.Blocks = []string{ec2.ResourceTypeInstance + ":" + instanceID}
+		}
+
+		resourceTrackers = append(resourceTrackers, resourceTracker)
+	}
+
+	return resourceTrackers, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListSpotInstanceRequests(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	tags := []*ec2.Tag{
+		{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+	}
+
+	// Active, with a running instance
+	c.AddSpotInstanceRequest(&ec2.SpotInstanceRequest{
+		SpotInstanceRequestId: aws.String("sir-1234"),
+		InstanceId:            aws.String("i-1234"),
+		State:                 aws.String(ec2.SpotInstanceStateActive),
+		Tags:                  tags,
+	})
+	// Already closed or cancelled
+	c.AddSpotInstanceRequest(&ec2.SpotInstanceRequest{
+		SpotInstanceRequestId: aws.String("sir-5555"),
+		State:                 aws.String(ec2.SpotInstanceStateClosed),
+		Tags:                  tags,
+	})
+	c.AddSpotInstanceRequest(&ec2.SpotInstanceRequest{
+		SpotInstanceRequestId: aws.String("sir-6666"),
+		State:                 aws.String(ec2.SpotInstanceStateCancelled),
+		Tags:                  tags,
+	})
+	// Tagged for another cluster
+	c.AddSpotInstanceRequest(&ec2.SpotInstanceRequest{
+		SpotInstanceRequestId: aws.String("sir-7777"),
+		State:                 aws.String(ec2.SpotInstanceStateOpen),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/other.example.com"), Value: aws.String("owned")},
+		},
+	})
+
+	resourceTrackers, err := ListSpotInstanceRequests(cloud, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing spot instance requests: %v", err)
+	}
+	if len(resourceTrackers) != 1 {
+		t.Fatalf("expected exactly one spot instance request, got %d", len(resourceTrackers))
+	}
+
+	r := resourceTrackers[0]
+	if r.ID != "sir-1234" {
+		t.Fatalf("unexpected spot instance request %q", r.ID)
+	}
+	expectedBlocks := []string{"instance:i-1234"}
+	if !reflect.DeepEqual(expectedBlocks, r.Blocks) {
+		t.Errorf("unexpected blocks: expected=%q, actual=%q", expectedBlocks, r.Blocks)
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error cancelling spot instance request: %v", err)
+	}
+	if state := aws.ToString(c.SpotInstanceRequests["sir-1234"].State); state != ec2.SpotInstanceStateCancelled {
+		t.Errorf("expected spot instance request to be cancelled, was %q", state)
+	}
+	if state := aws.ToString(c.SpotInstanceRequests["sir-7777"].State); state != ec2.SpotInstanceStateOpen {
+		t.Errorf("expected spot instance request of other cluster to be kept, was %q", state)
+	}
+}
@@ -18,10 +18,14 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/klog/v2"
 	"k8s.io/kops/pkg/resources"
 	"k8s.io/kops/upup/pkg/fi"
@@ -39,6 +43,11 @@ func DumpSQSQueue(op *resources.DumpOperation, r *resources.Resource) error {
 	return nil
 }
 
+// deleteSQSQueueActions are the AWS API actions invoked by DeleteSQSQueue
+var deleteSQSQueueActions = []string{
+	"sqs:DeleteQueue",
+}
+
 func DeleteSQSQueue(cloud fi.Cloud, r *resources.Resource) error {
 	ctx := context.TODO()
 	c := cloud.(awsup.AWSCloud)
@@ -50,7 +59,7 @@ func DeleteSQSQueue(cloud fi.Cloud, r *resources.Resource) error {
 		QueueUrl: &url,
 	}
 	if _, err := c.SQS().DeleteQueue(ctx, request); err != nil {
-		if awsup.AWSErrorCode(err) == "AWS.SimpleQueueService.NonExistentQueue" {
+		if isSQSQueueNotFoundErr(err) {
 			// Concurrently deleted
 			return nil
 		}
@@ -59,33 +68,55 @@ func DeleteSQSQueue(cloud fi.Cloud, r *resources.Resource) error {
 	return nil
 }
 
-func ListSQSQueues(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Resource, error) {
+// ListSQSQueues lists the SQS queues that kops creates for instance lifecycle events, e.g. for the node termination handler.
+// The queue names start with the cluster name, and the queues are tagged for the cluster;
+// queues that are tagged as shared, or as owned by another cluster with a similar name, are preserved.
+func ListSQSQueues(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	ctx := context.TODO()
 	c := cloud.(awsup.AWSCloud)
 
 	klog.V(2).Infof("Listing SQS queues")
 	queuePrefix := strings.ReplaceAll(clusterName, ".", "-")
 
-	request := &sqs.ListQueuesInput{
+	var queueURLs []string
+	paginator := sqs.NewListQueuesPaginator(c.SQS(), &sqs.ListQueuesInput{
 		QueueNamePrefix: &queuePrefix,
-	}
-	response, err := c.SQS().ListQueues(context.TODO(), request)
-	if err != nil {
-		return nil, fmt.Errorf("error listing SQS queues: %v", err)
-	}
-	if response == nil || len(response.QueueUrls) == 0 {
-		return nil, nil
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing SQS queues: %v", err)
+		}
+		queueURLs = append(queueURLs, page.QueueUrls...)
 	}
 
 	var resourceTrackers []*resources.Resource
 
-	for _, queueUrl := range response.QueueUrls {
+	for _, queueUrl := range queueURLs {
+		tagsResponse, err := c.SQS().ListQueueTags(ctx, &sqs.ListQueueTagsInput{
+			QueueUrl: aws.String(queueUrl),
+		})
+		if err != nil {
+			if isSQSQueueNotFoundErr(err) {
+				// Concurrently deleted
+				continue
+			}
+			return nil, fmt.Errorf("error listing tags of SQS queue %q: %w", queueUrl, err)
+		}
+		var tags []*ec2.Tag
+		for k, v := range tagsResponse.Tags {
+			tags = append(tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+
 		resourceTracker := &resources.Resource{
 			Name:    queueUrl,
 			ID:      queueUrl,
 			Type:    "sqs",
 			Deleter: DeleteSQSQueue,
+			Actions: deleteSQSQueueActions,
 			Dumper:  DumpSQSQueue,
 			Obj:     queueUrl,
+			Shared:  sharedWithCluster("sqs:"+queueUrl, tags, clusterName, ownershipTagKeys),
 		}
 
 		resourceTrackers = append(resourceTrackers, resourceTracker)
@@ -93,3 +124,9 @@ func ListSQSQueues(cloud fi.Cloud, vpcID, clusterName string) ([]*resources.Reso
 
 	return resourceTrackers, nil
 }
+
+// isSQSQueueNotFoundErr returns true if the error reports that the queue doesn't exist
+func isSQSQueueNotFoundErr(err error) bool {
+	var queueDoesNotExist *sqstypes.QueueDoesNotExist
+	return awsup.AWSErrorCode(err) == "AWS.SimpleQueueService.NonExistentQueue" || errors.As(err, &queueDoesNotExist)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"k8s.io/kops/cloudmock/aws/mocksqs"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListSQSQueues(t *testing.T) {
+	ctx := context.TODO()
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTag := "kubernetes.io/cluster/" + clusterName
+
+	queues := &mocksqs.MockSQS{}
+	cloud.MockSQS = queues
+
+	queues.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("me-example-com-nth"),
+		Tags:      map[string]string{ownershipTag: "owned"},
+	})
+	queues.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("me-example-com-events"),
+		Tags:      map[string]string{ownershipTag: "shared"},
+	})
+	// Found by the name prefix, but owned by another cluster
+	queues.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("me-example-com-staging-nth"),
+		Tags:      map[string]string{"kubernetes.io/cluster/me.example.com-staging": "owned"},
+	})
+
+	resourceTrackers, err := ListSQSQueues(cloud, "", clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing SQS queues: %v", err)
+	}
+
+	shared := make(map[string]bool)
+	for _, r := range resourceTrackers {
+		shared[r.ID] = r.Shared
+	}
+	ownedURL := "https://sqs.us-east-1.amazonaws.com/123456789123/me-example-com-nth"
+	expected := map[string]bool{
+		ownedURL: false,
+		"https://sqs.us-east-1.amazonaws.com/123456789123/me-example-com-events":      true,
+		"https://sqs.us-east-1.amazonaws.com/123456789123/me-example-com-staging-nth": true,
+	}
+	if len(shared) != len(expected) {
+		t.Fatalf("unexpected SQS queues: actual=%v, expected=%v", shared, expected)
+	}
+	for url, s := range expected {
+		if actual, found := shared[url]; !found || actual != s {
+			t.Errorf("unexpected SQS queues: actual=%v, expected=%v", shared, expected)
+		}
+	}
+
+	for _, r := range resourceTrackers {
+		if r.Shared {
+			continue
+		}
+		if err := r.Deleter(cloud, r); err != nil {
+			t.Fatalf("error deleting %s: %v", r.ID, err)
+		}
+		// Deleting it again, as a concurrent deletion would, is not an error
+		if err := r.Deleter(cloud, r); err != nil {
+			t.Fatalf("error deleting %s again: %v", r.ID, err)
+		}
+	}
+	if _, found := queues.Queues["me-example-com-nth"]; found {
+		t.Errorf("expected the owned queue to be deleted")
+	}
+	if len(queues.Queues) != 2 {
+		t.Errorf("expected the other queues to be kept, got %v", queues.Queues)
+	}
+}
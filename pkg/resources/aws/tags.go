@@ -17,14 +17,43 @@ limitations under the License.
 package aws
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
+	"k8s.io/kops/pkg/resources"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 )
 
-// HasOwnedTag looks for the new tag indicating that the cluster does owns the resource, or the legacy tag
-func HasOwnedTag(description string, tags []*ec2.Tag, clusterName string) bool {
+// isOwnershipTagKey returns true if the key is one of the additional ownership tag keys.
+// These are tag keys whose value names the cluster that owns a resource, e.g. "company.com/cluster",
+// for environments that already tag resources with their own ownership tag (see resources.ListOptions).
+// A resource tagged with one of them for the cluster is found and owned like one with the legacy KubernetesCluster tag.
+func isOwnershipTagKey(key string, ownershipTagKeys []string) bool {
+	for _, k := range ownershipTagKeys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// HasOwnedTag returns true if the tags mark the resource as owned by the cluster, so that it is deleted with the cluster.
+// Listers use it to decide whether a resource is Shared. The description (e.g. "route-table:rtb-1234") is only used in warnings.
+//
+// The ownership tag "kubernetes.io/cluster/<clusterName>" takes precedence:
+//   - "owned" means the cluster owns the resource
+//   - "shared" means the resource is used by the cluster, but was created by the user or is shared with other clusters
+//   - any other value is unexpected and isn't treated as ownership
+//
+// Without the ownership tag, the legacy tag "KubernetesCluster=<clusterName>" (or one of the ownershipTagKeys
+// with the value <clusterName>) means the cluster owns the resource.
+// A resource that is only tagged for another cluster, or not tagged at all, isn't owned.
+func HasOwnedTag(description string, tags []*ec2.Tag, clusterName string, ownershipTagKeys []string) bool {
 	tagKey := "kubernetes.io/cluster/" + clusterName
 
 	var found *ec2.Tag
@@ -52,7 +81,11 @@ func HasOwnedTag(description string, tags []*ec2.Tag, clusterName string) bool {
 
 	// Look for legacy tag - we assume that implies ownership
 	for _, tag := range tags {
-		if aws.ToString(tag.Key) != awsup.TagClusterName {
+		key := aws.ToString(tag.Key)
+		if key != awsup.TagClusterName && !isOwnershipTagKey(key, ownershipTagKeys) {
+			continue
+		}
+		if aws.ToString(tag.Value) != clusterName {
 			continue
 		}
 
@@ -67,3 +100,92 @@ func HasOwnedTag(description string, tags []*ec2.Tag, clusterName string) bool {
 	klog.Warningf("cluster tag not found on %s", description)
 	return false
 }
+
+// ownerClusters returns the names of the clusters that the tags mark as owning the resource, in sorted order.
+func ownerClusters(tags []*ec2.Tag, ownershipTagKeys []string) []string {
+	// As in HasOwnedTag, the legacy tag implies ownership unless the new tag says otherwise
+	values := make(map[string]string)
+	legacyOwners := sets.NewString()
+	for _, tag := range tags {
+		key := aws.ToString(tag.Key)
+		if key == awsup.TagClusterName || isOwnershipTagKey(key, ownershipTagKeys) {
+			legacyOwners.Insert(aws.ToString(tag.Value))
+		} else if strings.HasPrefix(key, "kubernetes.io/cluster/") {
+			values[strings.TrimPrefix(key, "kubernetes.io/cluster/")] = aws.ToString(tag.Value)
+		}
+	}
+
+	owners := sets.NewString()
+	for clusterName, value := range values {
+		if value == "owned" {
+			owners.Insert(clusterName)
+		}
+	}
+	for _, legacyOwner := range legacyOwners.List() {
+		if _, found := values[legacyOwner]; legacyOwner != "" && !found {
+			owners.Insert(legacyOwner)
+		}
+	}
+	return owners.List()
+}
+
+// hasClusterTag returns true if the tags mark the resource as belonging to the cluster, either owned or shared.
+// It is used for resource types that can't be filtered by tag server-side.
+func hasClusterTag(tags []*ec2.Tag, clusterName string, ownershipTagKeys []string) bool {
+	for _, tag := range tags {
+		key := aws.ToString(tag.Key)
+		if key == "kubernetes.io/cluster/"+clusterName {
+			return true
+		}
+		if (key == awsup.TagClusterName || isOwnershipTagKey(key, ownershipTagKeys)) && aws.ToString(tag.Value) == clusterName {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedWithCluster returns true if a resource that was found by its name, or by its cluster tag, must be left alone:
+// it is tagged as shared with the cluster, or it isn't tagged for the cluster but is owned by another cluster.
+// A resource with the exact name that kops gives it, without any ownership tags, is owned by the cluster, as kops didn't always tag it.
+func sharedWithCluster(description string, tags []*ec2.Tag, clusterName string, ownershipTagKeys []string) bool {
+	if hasClusterTag(tags, clusterName, ownershipTagKeys) {
+		return !HasOwnedTag(description, tags, clusterName, ownershipTagKeys)
+	}
+	return len(ownerClusters(tags, ownershipTagKeys)) != 0
+}
+
+// TagLastDeleteRun is the tag we set on the shared resources that a deletion leaves behind, to identify the deletion run
+const TagLastDeleteRun = "kops.k8s.io/last-delete-run"
+
+// lastDeleteRunTaggableTypes are the types of resource that TagSharedResources can tag
+var lastDeleteRunTaggableTypes = sets.NewString(
+	ec2.ResourceTypeVpc,
+	ec2.ResourceTypeSubnet,
+	ec2.ResourceTypeRouteTable,
+	ec2.ResourceTypeSecurityGroup,
+	"internet-gateway",
+	"dhcp-options",
+	TypeNatGateway,
+	TypeElasticIp,
+)
+
+// TagSharedResources sets the TagLastDeleteRun tag on the shared resources, which the deletion left behind.
+// Resources that are not EC2 resources are skipped.
+func TagSharedResources(cloud awsup.AWSCloud, resourceMap map[string]*resources.Resource, runID string) error {
+	var keys []string
+	for k, r := range resourceMap {
+		if r.Shared && lastDeleteRunTaggableTypes.Has(r.Type) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		r := resourceMap[k]
+		klog.V(2).Infof("Tagging shared %s %q with delete run %q", r.Type, r.ID, runID)
+		if err := cloud.CreateTags(r.ID, map[string]string{TagLastDeleteRun: runID}); err != nil {
+			return fmt.Errorf("error tagging shared %s %q: %w", r.Type, r.ID, err)
+		}
+	}
+	return nil
+}
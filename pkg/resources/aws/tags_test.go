@@ -0,0 +1,143 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestHasOwnedTag(t *testing.T) {
+	clusterName := "me.example.com"
+	tag := func(key, value string) *ec2.Tag {
+		return &ec2.Tag{Key: aws.String(key), Value: aws.String(value)}
+	}
+
+	grid := []struct {
+		name     string
+		tags     []*ec2.Tag
+		expected bool
+	}{
+		{
+			name:     "owned",
+			tags:     []*ec2.Tag{tag("kubernetes.io/cluster/"+clusterName, "owned")},
+			expected: true,
+		},
+		{
+			name:     "shared",
+			tags:     []*ec2.Tag{tag("kubernetes.io/cluster/"+clusterName, "shared")},
+			expected: false,
+		},
+		{
+			name:     "unknown value",
+			tags:     []*ec2.Tag{tag("kubernetes.io/cluster/"+clusterName, "yes")},
+			expected: false,
+		},
+		{
+			name:     "owned by other cluster",
+			tags:     []*ec2.Tag{tag("kubernetes.io/cluster/other.example.com", "owned")},
+			expected: false,
+		},
+		{
+			name:     "legacy tag",
+			tags:     []*ec2.Tag{tag(awsup.TagClusterName, clusterName)},
+			expected: true,
+		},
+		{
+			name:     "legacy tag of other cluster",
+			tags:     []*ec2.Tag{tag(awsup.TagClusterName, "other.example.com")},
+			expected: false,
+		},
+		{
+			name: "shared overrides legacy tag",
+			tags: []*ec2.Tag{
+				tag(awsup.TagClusterName, clusterName),
+				tag("kubernetes.io/cluster/"+clusterName, "shared"),
+			},
+			expected: false,
+		},
+		{
+			name:     "other tags",
+			tags:     []*ec2.Tag{tag("Name", clusterName)},
+			expected: false,
+		},
+		{
+			name:     "no tags",
+			expected: false,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			if actual := HasOwnedTag("vpc:vpc-1234", g.tags, clusterName, nil); actual != g.expected {
+				t.Errorf("expected %v, got %v", g.expected, actual)
+			}
+		})
+	}
+}
+
+func TestHasOwnedTagWithOwnershipTagKeys(t *testing.T) {
+	ownershipTagKeys := []string{"company.com/cluster"}
+
+	clusterName := "me.example.com"
+	tag := func(key, value string) *ec2.Tag {
+		return &ec2.Tag{Key: aws.String(key), Value: aws.String(value)}
+	}
+
+	grid := []struct {
+		name     string
+		tags     []*ec2.Tag
+		expected bool
+	}{
+		{
+			name:     "custom tag",
+			tags:     []*ec2.Tag{tag("company.com/cluster", clusterName)},
+			expected: true,
+		},
+		{
+			name:     "custom tag of other cluster",
+			tags:     []*ec2.Tag{tag("company.com/cluster", "other.example.com")},
+			expected: false,
+		},
+		{
+			name:     "unconfigured custom tag",
+			tags:     []*ec2.Tag{tag("other.com/cluster", clusterName)},
+			expected: false,
+		},
+		{
+			name: "shared overrides custom tag",
+			tags: []*ec2.Tag{
+				tag("company.com/cluster", clusterName),
+				tag("kubernetes.io/cluster/"+clusterName, "shared"),
+			},
+			expected: false,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			if actual := HasOwnedTag("vpc:vpc-1234", g.tags, clusterName, ownershipTagKeys); actual != g.expected {
+				t.Errorf("expected %v, got %v", g.expected, actual)
+			}
+		})
+	}
+
+	if owners := ownerClusters([]*ec2.Tag{tag("company.com/cluster", clusterName)}, ownershipTagKeys); len(owners) != 1 || owners[0] != clusterName {
+		t.Errorf("unexpected owners: %v", owners)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// deleteTransitGatewayAttachmentActions are the AWS API actions invoked by DeleteTransitGatewayAttachment
+var deleteTransitGatewayAttachmentActions = []string{
+	"ec2:DeleteTransitGatewayVpcAttachment",
+}
+
+func DeleteTransitGatewayAttachment(cloud fi.Cloud, r *resources.Resource) error {
+	c := cloud.(awsup.AWSCloud)
+
+	id := r.ID
+
+	klog.V(2).Infof("Deleting EC2 TransitGatewayVpcAttachment %q", id)
+	request := &ec2.DeleteTransitGatewayVpcAttachmentInput{
+		TransitGatewayAttachmentId: &id,
+	}
+	_, err := c.EC2().DeleteTransitGatewayVpcAttachment(request)
+	if err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+		return fmt.Errorf("error deleting TransitGatewayVpcAttachment %q: %w", id, err)
+	}
+	return nil
+}
+
+func DumpTransitGatewayAttachment(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["type"] = ec2.ResourceTypeTransitGatewayAttachment
+	data["raw"] = r.Obj
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// ListTransitGatewayAttachments returns the transit gateway VPC attachments owned by the cluster.
+// Only the attachments are returned: the transit gateway itself is usually shared between VPCs, and is never deleted.
+func ListTransitGatewayAttachments(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	attachments := make(map[string]*ec2.TransitGatewayVpcAttachment)
+	klog.V(2).Infof("Listing EC2 TransitGatewayVpcAttachments")
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
+		request := &ec2.DescribeTransitGatewayVpcAttachmentsInput{
+			Filters: filters,
+		}
+		err := c.EC2().DescribeTransitGatewayVpcAttachmentsPages(request, func(page *ec2.DescribeTransitGatewayVpcAttachmentsOutput, lastPage bool) bool {
+			for _, attachment := range page.TransitGatewayVpcAttachments {
+				attachments[aws.ToString(attachment.TransitGatewayAttachmentId)] = attachment
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing TransitGatewayVpcAttachments: %v", err)
+		}
+	}
+
+	var resourceTrackers []*resources.Resource
+	for id, attachment := range attachments {
+		if !HasOwnedTag(ec2.ResourceTypeTransitGatewayAttachment+":"+id, attachment.Tags, clusterName, ownershipTagKeys) {
+			continue
+		}
+
+		switch aws.ToString(attachment.State) {
+		case ec2.TransitGatewayAttachmentStateDeleted, ec2.TransitGatewayAttachmentStateDeleting,
+			ec2.TransitGatewayAttachmentStateFailed, ec2.TransitGatewayAttachmentStateRejected:
+			klog.V(2).Infof("TransitGatewayVpcAttachment %q is %s; skipping", id, aws.ToString(attachment.State))
+			continue
+		}
+
+		// The VPC can only be deleted once it is detached from the transit gateway
+		var blocks []string
+		if vpcID := aws.ToString(attachment.VpcId); vpcID != "" {
+			blocks = append(blocks, ec2.ResourceTypeVpc+":"+vpcID)
+		}
+
+		resourceTracker := &resources.Resource{
+			Name:    FindName(attachment.Tags),
+			ID:      id,
+			Type:    ec2.ResourceTypeTransitGatewayAttachment,
+			Deleter: DeleteTransitGatewayAttachment,
+			Actions: deleteTransitGatewayAttachmentActions,
+			Dumper:  DumpTransitGatewayAttachment,
+			Obj:     attachment,
+			Blocks:  blocks,
+		}
+
+		resourceTrackers = append(resourceTrackers, resourceTracker)
+	}
+
+	return resourceTrackers, nil
+}
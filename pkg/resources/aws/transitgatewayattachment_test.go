@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListTransitGatewayAttachments(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	// The transit gateway is shared with other VPCs, and must be kept
+	c.AddTransitGateway(&ec2.TransitGateway{
+		TransitGatewayId: aws.String("tgw-1234"),
+		State:            aws.String(ec2.TransitGatewayStateAvailable),
+	})
+
+	// Owned by the cluster
+	c.AddTransitGatewayVpcAttachment(&ec2.TransitGatewayVpcAttachment{
+		TransitGatewayAttachmentId: aws.String("tgw-attach-1234"),
+		TransitGatewayId:           aws.String("tgw-1234"),
+		VpcId:                      aws.String("vpc-1234"),
+		State:                      aws.String(ec2.TransitGatewayAttachmentStateAvailable),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+		},
+	})
+
+	// Owned by another cluster
+	c.AddTransitGatewayVpcAttachment(&ec2.TransitGatewayVpcAttachment{
+		TransitGatewayAttachmentId: aws.String("tgw-attach-5555"),
+		TransitGatewayId:           aws.String("tgw-1234"),
+		VpcId:                      aws.String("vpc-other"),
+		State:                      aws.String(ec2.TransitGatewayAttachmentStateAvailable),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/other.example.com"), Value: aws.String("owned")},
+		},
+	})
+
+	// Already being deleted
+	c.AddTransitGatewayVpcAttachment(&ec2.TransitGatewayVpcAttachment{
+		TransitGatewayAttachmentId: aws.String("tgw-attach-6666"),
+		TransitGatewayId:           aws.String("tgw-1234"),
+		VpcId:                      aws.String("vpc-1234"),
+		State:                      aws.String(ec2.TransitGatewayAttachmentStateDeleting),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+		},
+	})
+
+	resourceTrackers, err := ListTransitGatewayAttachments(cloud, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing transit gateway attachments: %v", err)
+	}
+	if len(resourceTrackers) != 1 {
+		t.Fatalf("expected exactly one transit gateway attachment, got %d", len(resourceTrackers))
+	}
+
+	r := resourceTrackers[0]
+	if r.ID != "tgw-attach-1234" || r.Type != ec2.ResourceTypeTransitGatewayAttachment {
+		t.Fatalf("unexpected resource %s:%s", r.Type, r.ID)
+	}
+	expectedBlocks := []string{"vpc:vpc-1234"}
+	if !reflect.DeepEqual(expectedBlocks, r.Blocks) {
+		t.Errorf("unexpected blocks: expected=%q, actual=%q", expectedBlocks, r.Blocks)
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting transit gateway attachment: %v", err)
+	}
+	if _, found := c.TransitGatewayVpcAttachments["tgw-attach-1234"]; found {
+		t.Errorf("expected transit gateway attachment to be deleted")
+	}
+	if _, found := c.TransitGatewayVpcAttachments["tgw-attach-5555"]; !found {
+		t.Errorf("expected transit gateway attachment of other cluster to be kept")
+	}
+	if _, found := c.TransitGateways["tgw-1234"]; !found {
+		t.Errorf("expected shared transit gateway to be kept")
+	}
+
+	// Deleting again is a no-op
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Errorf("unexpected error deleting transit gateway attachment twice: %v", err)
+	}
+}
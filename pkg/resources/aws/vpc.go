@@ -28,6 +28,11 @@ import (
 	"k8s.io/kops/util/pkg/maps"
 )
 
+// deleteVPCActions are the AWS API actions invoked by DeleteVPC
+var deleteVPCActions = []string{
+	"ec2:DeleteVpc",
+}
+
 func DeleteVPC(cloud fi.Cloud, r *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
@@ -39,15 +44,24 @@ func DeleteVPC(cloud fi.Cloud, r *resources.Resource) error {
 	}
 	_, err := c.EC2().DeleteVpc(request)
 	if err != nil {
-		if awsup.AWSErrorCode(err) == "InvalidVpcID.NotFound" {
+		if isNotFoundErr(err) {
 			// Concurrently deleted
 			return nil
 		}
 
 		if IsDependencyViolation(err) {
+			if deletedConcurrently("vpc:"+id, func() (bool, error) {
+				response, err := c.EC2().DescribeVpcs(&ec2.DescribeVpcsInput{VpcIds: []*string{&id}})
+				if err != nil {
+					return false, err
+				}
+				return len(response.Vpcs) != 0, nil
+			}) {
+				return nil
+			}
 			return err
 		}
-		return fmt.Errorf("error deleting VPC %q: %v", id, err)
+		return fmt.Errorf("error deleting VPC %q: %w", id, err)
 	}
 	return nil
 }
@@ -68,12 +82,12 @@ func DumpVPC(op *resources.DumpOperation, r *resources.Resource) error {
 	return nil
 }
 
-func DescribeVPC(cloud fi.Cloud, clusterName string) (*ec2.Vpc, error) {
+func DescribeVPC(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) (*ec2.Vpc, error) {
 	c := cloud.(awsup.AWSCloud)
 
 	vpcs := make(map[string]*ec2.Vpc)
 	klog.V(2).Info("Listing EC2 VPC")
-	for _, filters := range buildEC2FiltersForCluster(clusterName) {
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
 		request := &ec2.DescribeVpcsInput{
 			Filters: filters,
 		}
@@ -97,8 +111,8 @@ func DescribeVPC(cloud fi.Cloud, clusterName string) (*ec2.Vpc, error) {
 	}
 }
 
-func ListVPCs(cloud fi.Cloud, clusterName string) ([]*resources.Resource, error) {
-	vpc, err := DescribeVPC(cloud, clusterName)
+func ListVPCs(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	vpc, err := DescribeVPC(cloud, clusterName, ownershipTagKeys)
 	if err != nil {
 		return nil, err
 	}
@@ -107,18 +121,29 @@ func ListVPCs(cloud fi.Cloud, clusterName string) ([]*resources.Resource, error)
 	if vpc != nil {
 		vpcID := aws.ToString(vpc.VpcId)
 
+		foreign, err := (&foreignOwnerChecker{cloud: cloud.(awsup.AWSCloud)}).IsForeign(vpc.OwnerId)
+		if err != nil {
+			return nil, err
+		}
+
 		resourceTracker := &resources.Resource{
 			Name:    FindName(vpc.Tags),
 			ID:      vpcID,
 			Type:    ec2.ResourceTypeVpc,
 			Deleter: DeleteVPC,
+			Actions: deleteVPCActions,
 			Dumper:  DumpVPC,
 			Obj:     vpc,
-			Shared:  !HasOwnedTag(ec2.ResourceTypeVpc+":"+vpcID, vpc.Tags, clusterName),
+			// A VPC shared in from another account can't be ours to delete
+			Shared: foreign || !HasOwnedTag(ec2.ResourceTypeVpc+":"+vpcID, vpc.Tags, clusterName, ownershipTagKeys),
 		}
 
 		var blocks []string
-		blocks = append(blocks, "dhcp-options:"+aws.ToString(vpc.DhcpOptionsId))
+		// The DHCP options set can only be deleted once it is no longer associated with the VPC.
+		// A VPC without a set of its own is associated with "default".
+		if dhcpOptionsID := aws.ToString(vpc.DhcpOptionsId); dhcpOptionsID != "" && dhcpOptionsID != "default" {
+			blocks = append(blocks, "dhcp-options:"+dhcpOptionsID)
+		}
 
 		resourceTracker.Blocks = blocks
 
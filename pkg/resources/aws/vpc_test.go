@@ -17,11 +17,13 @@ limitations under the License.
 package aws
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/pkg/resources"
 	"k8s.io/kops/pkg/testutils"
 	"k8s.io/kops/upup/pkg/fi/utils"
 )
@@ -111,7 +113,7 @@ func TestListVPCs(t *testing.T) {
 	}
 
 	for _, g := range grid {
-		resources, err := ListVPCs(awsCloud, g.ClusterName)
+		resources, err := ListVPCs(awsCloud, g.ClusterName, nil)
 		if err != nil {
 			t.Errorf("unexpected error listing VPCs: %v", err)
 			continue
@@ -131,3 +133,144 @@ func TestListVPCs(t *testing.T) {
 		}
 	}
 }
+
+func TestListVPCsWithOwnershipTagKeys(t *testing.T) {
+	h := testutils.NewIntegrationTestHarness(t)
+	defer h.Close()
+
+	h.MockKopsVersion("1.21.0-alpha.1")
+	awsCloud := h.SetupMockAWS()
+
+	mockEC2 := awsCloud.EC2().(*mockec2.MockEC2)
+
+	mockEC2.CreateVpcWithId(&ec2.CreateVpcInput{
+		CidrBlock: aws.String("10.0.0.0/12"),
+	}, "vpc-custom")
+	mockEC2.CreateTags(&ec2.CreateTagsInput{
+		Resources: aws.StringSlice([]string{"vpc-custom"}),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("company.com/cluster"), Value: aws.String("custom.example.com")},
+		},
+	})
+
+	vpcs, err := ListVPCs(awsCloud, "custom.example.com", []string{"company.com/cluster"})
+	if err != nil {
+		t.Fatalf("unexpected error listing VPCs: %v", err)
+	}
+	if len(vpcs) != 1 || vpcs[0].ID != "vpc-custom" {
+		t.Fatalf("expected to find vpc-custom, got %v", vpcs)
+	}
+	if vpcs[0].Shared {
+		t.Errorf("expected vpc-custom to be owned by the cluster")
+	}
+
+	// Without the key configured, the VPC isn't found
+	vpcs, err = ListVPCs(awsCloud, "custom.example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error listing VPCs: %v", err)
+	}
+	if len(vpcs) != 0 {
+		t.Errorf("expected no VPCs, got %v", vpcs)
+	}
+
+	// The keys are passed down from the list options
+	clusterInfo := resources.ClusterInfo{
+		Name: "custom.example.com",
+		ListOptions: resources.ListOptions{
+			AWSResourceTypes: []string{ec2.ResourceTypeVpc},
+			OwnershipTagKeys: []string{"company.com/cluster"},
+		},
+	}
+	listed, err := ListResourcesAWS(awsCloud, clusterInfo)
+	if err != nil {
+		t.Fatalf("unexpected error listing vpcs: %v", err)
+	}
+	if r := listed["vpc:vpc-custom"]; r == nil || r.Shared {
+		t.Errorf("expected vpc-custom to be listed as owned by the cluster, got %v", r)
+	}
+}
+
+func TestListVPCsBlocksDhcpOptions(t *testing.T) {
+	h := testutils.NewIntegrationTestHarness(t)
+	defer h.Close()
+
+	h.MockKopsVersion("1.21.0-alpha.1")
+	awsCloud := h.SetupMockAWS()
+
+	mockEC2 := awsCloud.EC2().(*mockec2.MockEC2)
+
+	clusterName := "owned.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+
+	mockEC2.CreateVpcWithId(&ec2.CreateVpcInput{
+		CidrBlock: aws.String("10.0.0.0/12"),
+	}, "vpc-owned")
+	mockEC2.CreateTags(&ec2.CreateTagsInput{
+		Resources: aws.StringSlice([]string{"vpc-owned"}),
+		Tags: []*ec2.Tag{
+			{Key: aws.String(ownershipTagKey), Value: aws.String("owned")},
+		},
+	})
+
+	createDhcpOptions := func(tags ...*ec2.Tag) string {
+		request := &ec2.CreateDhcpOptionsInput{}
+		if len(tags) != 0 {
+			request.TagSpecifications = []*ec2.TagSpecification{
+				{ResourceType: aws.String(ec2.ResourceTypeDhcpOptions), Tags: tags},
+			}
+		}
+		response, err := mockEC2.CreateDhcpOptions(request)
+		if err != nil {
+			t.Fatalf("error creating DHCP options: %v", err)
+		}
+		return aws.ToString(response.DhcpOptions.DhcpOptionsId)
+	}
+
+	owned := createDhcpOptions(&ec2.Tag{Key: aws.String(ownershipTagKey), Value: aws.String("owned")})
+	legacy := createDhcpOptions(&ec2.Tag{Key: aws.String("KubernetesCluster"), Value: aws.String(clusterName)})
+	shared := createDhcpOptions(&ec2.Tag{Key: aws.String(ownershipTagKey), Value: aws.String("shared")})
+	createDhcpOptions()
+
+	if _, err := mockEC2.AssociateDhcpOptions(&ec2.AssociateDhcpOptionsInput{
+		DhcpOptionsId: aws.String(owned),
+		VpcId:         aws.String("vpc-owned"),
+	}); err != nil {
+		t.Fatalf("error associating DHCP options: %v", err)
+	}
+
+	vpcs, err := ListVPCs(awsCloud, clusterName, nil)
+	if err != nil {
+		t.Fatalf("unexpected error listing VPCs: %v", err)
+	}
+	if len(vpcs) != 1 {
+		t.Fatalf("expected 1 VPC, got %d", len(vpcs))
+	}
+	// The VPC is deleted first, and the DHCP options set once it is no longer associated
+	if expected := []string{"dhcp-options:" + owned}; !utils.StringSlicesEqualIgnoreOrder(vpcs[0].Blocks, expected) {
+		t.Errorf("unexpected blocks for VPC: actual=%v, expected=%v", vpcs[0].Blocks, expected)
+	}
+	if len(vpcs[0].Blocked) != 0 {
+		t.Errorf("unexpected blocked for VPC: %v", vpcs[0].Blocked)
+	}
+
+	dhcpOptions, err := ListDhcpOptions(awsCloud, "vpc-owned", clusterName, nil)
+	if err != nil {
+		t.Fatalf("unexpected error listing DHCP options: %v", err)
+	}
+
+	expectedShared := map[string]bool{
+		owned:  false,
+		legacy: false,
+		shared: true,
+	}
+	actualShared := make(map[string]bool)
+	for _, r := range dhcpOptions {
+		actualShared[r.ID] = r.Shared
+		if r.Type+":"+r.ID == vpcs[0].Blocks[0] && len(r.Blocked) != 0 {
+			t.Errorf("unexpected blocked for DHCP options %s: %v", r.ID, r.Blocked)
+		}
+	}
+	if !reflect.DeepEqual(actualShared, expectedShared) {
+		t.Errorf("unexpected DHCP options: actual=%v, expected=%v", actualShared, expectedShared)
+	}
+}
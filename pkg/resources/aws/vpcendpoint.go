@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// deleteVPCEndpointActions are the AWS API actions invoked by DeleteVPCEndpoints
+var deleteVPCEndpointActions = []string{
+	"ec2:DeleteVpcEndpoints",
+}
+
+// DeleteVPCEndpoints deletes the VPC endpoints in a single call.
+// The call reports the endpoints it failed to delete individually, rather than failing as a whole.
+func DeleteVPCEndpoints(cloud fi.Cloud, trackers []*resources.Resource) error {
+	c := cloud.(awsup.AWSCloud)
+
+	var ids []string
+	for _, t := range trackers {
+		ids = append(ids, t.ID)
+	}
+
+	klog.V(2).Infof("Deleting EC2 VpcEndpoints %v", ids)
+	request := &ec2.DeleteVpcEndpointsInput{
+		VpcEndpointIds: aws.StringSlice(ids),
+	}
+	response, err := c.EC2().DeleteVpcEndpoints(request)
+	if err != nil {
+		return fmt.Errorf("error deleting VpcEndpoints %v: %w", ids, err)
+	}
+	for _, item := range response.Unsuccessful {
+		if item.Error == nil {
+			continue
+		}
+		err := awserr.New(aws.ToString(item.Error.Code), aws.ToString(item.Error.Message), nil)
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			continue
+		}
+		return fmt.Errorf("error deleting VpcEndpoint %q: %w", aws.ToString(item.ResourceId), err)
+	}
+	return nil
+}
+
+func DumpVPCEndpoint(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["type"] = ec2.ResourceTypeVpcEndpoint
+	data["raw"] = r.Obj
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// ListVPCEndpoints returns the VPC endpoints in the cluster VPC that are tagged for the cluster.
+// Gateway endpoints are associated with route tables, and interface endpoints with subnets and security groups;
+// those can only be deleted once the endpoints are gone. Endpoints that are shared with the cluster are kept.
+func ListVPCEndpoints(cloud fi.Cloud, vpcID, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	if vpcID == "" {
+		return nil, nil
+	}
+
+	c := cloud.(awsup.AWSCloud)
+
+	endpoints := make(map[string]*ec2.VpcEndpoint)
+	klog.V(2).Infof("Listing EC2 VpcEndpoints")
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
+		request := &ec2.DescribeVpcEndpointsInput{
+			Filters: append(filters, awsup.NewEC2Filter("vpc-id", vpcID)),
+		}
+		err := c.EC2().DescribeVpcEndpointsPages(request, func(page *ec2.DescribeVpcEndpointsOutput, lastPage bool) bool {
+			for _, endpoint := range page.VpcEndpoints {
+				endpoints[aws.ToString(endpoint.VpcEndpointId)] = endpoint
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing VpcEndpoints: %v", err)
+		}
+	}
+
+	var resourceTrackers []*resources.Resource
+	for id, endpoint := range endpoints {
+		// The API documents the states capitalized, but reports them in lower case
+		state := aws.ToString(endpoint.State)
+		if strings.EqualFold(state, ec2.StateDeleted) || strings.EqualFold(state, ec2.StateDeleting) {
+			klog.V(2).Infof("VpcEndpoint %q is %s; skipping", id, state)
+			continue
+		}
+
+		blocks := []string{ec2.ResourceTypeVpc + ":" + vpcID}
+		for _, routeTableID := range endpoint.RouteTableIds {
+			blocks = append(blocks, ec2.ResourceTypeRouteTable+":"+aws.ToString(routeTableID))
+		}
+		for _, subnetID := range endpoint.SubnetIds {
+			blocks = append(blocks, ec2.ResourceTypeSubnet+":"+aws.ToString(subnetID))
+		}
+		for _, group := range endpoint.Groups {
+			blocks = append(blocks, ec2.ResourceTypeSecurityGroup+":"+aws.ToString(group.GroupId))
+		}
+
+		resourceTracker := &resources.Resource{
+			Name:         FindName(endpoint.Tags),
+			ID:           id,
+			Type:         ec2.ResourceTypeVpcEndpoint,
+			GroupDeleter: DeleteVPCEndpoints,
+			// The endpoints are deleted together, as DeleteVpcEndpoints accepts many ids
+			GroupKey: ec2.ResourceTypeVpcEndpoint,
+			Actions:  deleteVPCEndpointActions,
+			Dumper:   DumpVPCEndpoint,
+			Obj:      endpoint,
+			Shared:   !HasOwnedTag(ec2.ResourceTypeVpcEndpoint+":"+id, endpoint.Tags, clusterName, ownershipTagKeys),
+			Blocks:   blocks,
+		}
+
+		resourceTrackers = append(resourceTrackers, resourceTracker)
+	}
+
+	return resourceTrackers, nil
+}
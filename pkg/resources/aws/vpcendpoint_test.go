@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListVPCEndpoints(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddRouteTable(&ec2.RouteTable{
+		RouteTableId: aws.String("rtb-1234"),
+		VpcId:        aws.String("vpc-1234"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+		},
+	})
+
+	// Owned by the cluster, and associated with the cluster route table
+	c.AddVpcEndpoint(&ec2.VpcEndpoint{
+		VpcEndpointId:   aws.String("vpce-1234"),
+		VpcEndpointType: aws.String(ec2.VpcEndpointTypeGateway),
+		ServiceName:     aws.String("com.amazonaws.us-east-1.s3"),
+		VpcId:           aws.String("vpc-1234"),
+		RouteTableIds:   []*string{aws.String("rtb-1234")},
+		State:           aws.String("available"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+		},
+	})
+
+	// Shared with the cluster
+	c.AddVpcEndpoint(&ec2.VpcEndpoint{
+		VpcEndpointId:   aws.String("vpce-5555"),
+		VpcEndpointType: aws.String(ec2.VpcEndpointTypeInterface),
+		ServiceName:     aws.String("com.amazonaws.us-east-1.ecr.api"),
+		VpcId:           aws.String("vpc-1234"),
+		SubnetIds:       []*string{aws.String("subnet-1234")},
+		State:           aws.String("available"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("shared")},
+		},
+	})
+
+	// In another VPC
+	c.AddVpcEndpoint(&ec2.VpcEndpoint{
+		VpcEndpointId:   aws.String("vpce-6666"),
+		VpcEndpointType: aws.String(ec2.VpcEndpointTypeGateway),
+		VpcId:           aws.String("vpc-other"),
+		State:           aws.String("available"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+		},
+	})
+
+	resourceTrackers, err := ListVPCEndpoints(cloud, "vpc-1234", clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing VPC endpoints: %v", err)
+	}
+	sort.Slice(resourceTrackers, func(i, j int) bool {
+		return resourceTrackers[i].ID < resourceTrackers[j].ID
+	})
+	if len(resourceTrackers) != 2 {
+		t.Fatalf("expected two VPC endpoints, got %d", len(resourceTrackers))
+	}
+
+	owned, shared := resourceTrackers[0], resourceTrackers[1]
+	if owned.ID != "vpce-1234" || owned.Shared {
+		t.Errorf("expected owned VPC endpoint vpce-1234, got %q (shared=%v)", owned.ID, owned.Shared)
+	}
+	if shared.ID != "vpce-5555" || !shared.Shared {
+		t.Errorf("expected shared VPC endpoint vpce-5555, got %q (shared=%v)", shared.ID, shared.Shared)
+	}
+	expectedBlocks := []string{"vpc:vpc-1234", "route-table:rtb-1234"}
+	if !reflect.DeepEqual(expectedBlocks, owned.Blocks) {
+		t.Errorf("unexpected blocks: expected=%q, actual=%q", expectedBlocks, owned.Blocks)
+	}
+
+	// The route table can't be deleted until the endpoint is gone
+	if _, err := c.DeleteRouteTable(&ec2.DeleteRouteTableInput{RouteTableId: aws.String("rtb-1234")}); !IsDependencyViolation(err) {
+		t.Fatalf("expected dependency violation deleting route table, got %v", err)
+	}
+	if err := owned.GroupDeleter(cloud, []*resources.Resource{owned}); err != nil {
+		t.Fatalf("error deleting VPC endpoint: %v", err)
+	}
+	if _, found := c.VpcEndpoints["vpce-1234"]; found {
+		t.Errorf("expected VPC endpoint to be deleted")
+	}
+	if _, found := c.VpcEndpoints["vpce-5555"]; !found {
+		t.Errorf("expected shared VPC endpoint to be kept")
+	}
+	if _, err := c.DeleteRouteTable(&ec2.DeleteRouteTableInput{RouteTableId: aws.String("rtb-1234")}); err != nil {
+		t.Errorf("error deleting route table after VPC endpoint: %v", err)
+	}
+
+	// Deleting again is a no-op
+	if err := owned.GroupDeleter(cloud, []*resources.Resource{owned}); err != nil {
+		t.Errorf("unexpected error deleting VPC endpoint twice: %v", err)
+	}
+}
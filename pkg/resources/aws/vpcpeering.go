@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog/v2"
+
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// deleteVPCPeeringConnectionActions are the AWS API actions invoked by DeleteVPCPeeringConnection
+var deleteVPCPeeringConnectionActions = []string{
+	"ec2:DeleteVpcPeeringConnection",
+}
+
+func DeleteVPCPeeringConnection(cloud fi.Cloud, r *resources.Resource) error {
+	c := cloud.(awsup.AWSCloud)
+
+	id := r.ID
+
+	klog.V(2).Infof("Deleting EC2 VpcPeeringConnection %q", id)
+	request := &ec2.DeleteVpcPeeringConnectionInput{
+		VpcPeeringConnectionId: &id,
+	}
+	_, err := c.EC2().DeleteVpcPeeringConnection(request)
+	if err != nil {
+		if isNotFoundErr(err) {
+			// Concurrently deleted
+			return nil
+		}
+		return fmt.Errorf("error deleting VpcPeeringConnection %q: %w", id, err)
+	}
+	return nil
+}
+
+func DumpVPCPeeringConnection(op *resources.DumpOperation, r *resources.Resource) error {
+	data := make(map[string]interface{})
+	data["id"] = r.ID
+	data["type"] = ec2.ResourceTypeVpcPeeringConnection
+	data["raw"] = r.Obj
+	op.Dump.Resources = append(op.Dump.Resources, data)
+	return nil
+}
+
+// ListVPCPeeringConnections returns the VPC peering connections owned by the cluster.
+// The cluster VPC may be either the requester or the accepter of the connection; connections owned by another cluster are left alone.
+func ListVPCPeeringConnections(cloud fi.Cloud, clusterName string, ownershipTagKeys []string) ([]*resources.Resource, error) {
+	c := cloud.(awsup.AWSCloud)
+
+	connections := make(map[string]*ec2.VpcPeeringConnection)
+	klog.V(2).Infof("Listing EC2 VpcPeeringConnections")
+	for _, filters := range buildEC2FiltersForCluster(clusterName, ownershipTagKeys) {
+		request := &ec2.DescribeVpcPeeringConnectionsInput{
+			Filters: filters,
+		}
+		err := c.EC2().DescribeVpcPeeringConnectionsPages(request, func(page *ec2.DescribeVpcPeeringConnectionsOutput, lastPage bool) bool {
+			for _, connection := range page.VpcPeeringConnections {
+				connections[aws.ToString(connection.VpcPeeringConnectionId)] = connection
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing VpcPeeringConnections: %v", err)
+		}
+	}
+	if len(connections) == 0 {
+		return nil, nil
+	}
+
+	// The VPC can only be deleted once its peering connections are gone
+	vpc, err := DescribeVPC(cloud, clusterName, ownershipTagKeys)
+	if err != nil {
+		return nil, err
+	}
+	vpcID := ""
+	if vpc != nil {
+		vpcID = aws.ToString(vpc.VpcId)
+	}
+
+	var resourceTrackers []*resources.Resource
+	for id, connection := range connections {
+		if !HasOwnedTag(ec2.ResourceTypeVpcPeeringConnection+":"+id, connection.Tags, clusterName, ownershipTagKeys) {
+			continue
+		}
+
+		if connection.Status != nil {
+			switch aws.ToString(connection.Status.Code) {
+			case ec2.VpcPeeringConnectionStateReasonCodeDeleted, ec2.VpcPeeringConnectionStateReasonCodeDeleting,
+				ec2.VpcPeeringConnectionStateReasonCodeRejected, ec2.VpcPeeringConnectionStateReasonCodeFailed,
+				ec2.VpcPeeringConnectionStateReasonCodeExpired:
+				klog.V(2).Infof("VpcPeeringConnection %q is %s; skipping", id, aws.ToString(connection.Status.Code))
+				continue
+			}
+		}
+
+		var blocks []string
+		for _, side := range []*ec2.VpcPeeringConnectionVpcInfo{connection.RequesterVpcInfo, connection.AccepterVpcInfo} {
+			if side != nil && vpcID != "" && aws.ToString(side.VpcId) == vpcID {
+				blocks = append(blocks, ec2.ResourceTypeVpc+":"+vpcID)
+			}
+		}
+
+		resourceTracker := &resources.Resource{
+			Name:    FindName(connection.Tags),
+			ID:      id,
+			Type:    ec2.ResourceTypeVpcPeeringConnection,
+			Deleter: DeleteVPCPeeringConnection,
+			Actions: deleteVPCPeeringConnectionActions,
+			Dumper:  DumpVPCPeeringConnection,
+			Obj:     connection,
+			Blocks:  blocks,
+		}
+
+		resourceTrackers = append(resourceTrackers, resourceTracker)
+	}
+
+	return resourceTrackers, nil
+}
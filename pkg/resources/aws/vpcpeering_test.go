@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListVPCPeeringConnections(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.CreateVpcWithId(&ec2.CreateVpcInput{
+		CidrBlock: aws.String("10.0.0.0/16"),
+	}, "vpc-1234")
+	c.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{aws.String("vpc-1234")},
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+		},
+	})
+
+	// Owned by the cluster, which accepted the connection
+	c.AddVpcPeeringConnection(&ec2.VpcPeeringConnection{
+		VpcPeeringConnectionId: aws.String("pcx-1234"),
+		RequesterVpcInfo:       &ec2.VpcPeeringConnectionVpcInfo{VpcId: aws.String("vpc-peer")},
+		AccepterVpcInfo:        &ec2.VpcPeeringConnectionVpcInfo{VpcId: aws.String("vpc-1234")},
+		Status:                 &ec2.VpcPeeringConnectionStateReason{Code: aws.String(ec2.VpcPeeringConnectionStateReasonCodeActive)},
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+		},
+	})
+
+	// Owned by another cluster, and only shared with ours
+	c.AddVpcPeeringConnection(&ec2.VpcPeeringConnection{
+		VpcPeeringConnectionId: aws.String("pcx-5555"),
+		RequesterVpcInfo:       &ec2.VpcPeeringConnectionVpcInfo{VpcId: aws.String("vpc-1234")},
+		AccepterVpcInfo:        &ec2.VpcPeeringConnectionVpcInfo{VpcId: aws.String("vpc-other")},
+		Status:                 &ec2.VpcPeeringConnectionStateReason{Code: aws.String(ec2.VpcPeeringConnectionStateReasonCodeActive)},
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("shared")},
+			{Key: aws.String("kubernetes.io/cluster/other.example.com"), Value: aws.String("owned")},
+		},
+	})
+
+	// Already deleted
+	c.AddVpcPeeringConnection(&ec2.VpcPeeringConnection{
+		VpcPeeringConnectionId: aws.String("pcx-6666"),
+		RequesterVpcInfo:       &ec2.VpcPeeringConnectionVpcInfo{VpcId: aws.String("vpc-1234")},
+		AccepterVpcInfo:        &ec2.VpcPeeringConnectionVpcInfo{VpcId: aws.String("vpc-peer")},
+		Status:                 &ec2.VpcPeeringConnectionStateReason{Code: aws.String(ec2.VpcPeeringConnectionStateReasonCodeDeleted)},
+		Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+		},
+	})
+
+	resourceTrackers, err := ListVPCPeeringConnections(cloud, clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing VPC peering connections: %v", err)
+	}
+	if len(resourceTrackers) != 1 {
+		t.Fatalf("expected exactly one VPC peering connection, got %d", len(resourceTrackers))
+	}
+
+	r := resourceTrackers[0]
+	if r.ID != "pcx-1234" {
+		t.Fatalf("unexpected VPC peering connection %q", r.ID)
+	}
+	expectedBlocks := []string{"vpc:vpc-1234"}
+	if !reflect.DeepEqual(expectedBlocks, r.Blocks) {
+		t.Errorf("unexpected blocks: expected=%q, actual=%q", expectedBlocks, r.Blocks)
+	}
+
+	if err := r.Deleter(cloud, r); err != nil {
+		t.Fatalf("error deleting VPC peering connection: %v", err)
+	}
+	if _, found := c.VpcPeeringConnections["pcx-1234"]; found {
+		t.Errorf("expected VPC peering connection to be deleted")
+	}
+	if _, found := c.VpcPeeringConnections["pcx-5555"]; !found {
+		t.Errorf("expected VPC peering connection of other cluster to be kept")
+	}
+}
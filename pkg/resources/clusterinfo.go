@@ -16,12 +16,95 @@ limitations under the License.
 
 package resources
 
+import "time"
+
 type ClusterInfo struct {
 	Name        string
 	UsesNoneDNS bool
+	// ServiceAccountIssuer is the issuer URL trusted by the cluster's AWS OIDC provider, if the cluster has one
+	ServiceAccountIssuer string
+	// DiscoveryStore is the location under which the cluster publishes its service account issuer discovery documents, if it does
+	DiscoveryStore string
+	// ListOptions narrow down which resources are collected
+	ListOptions
 	// Azure specific
 	AzureResourceGroupName   string
 	AzureResourceGroupShared bool
 	AzureNetworkShared       bool
 	AzureRouteTableShared    bool
 }
+
+// ListOptions are optional settings that narrow down which cloud resources are collected
+type ListOptions struct {
+	// AWSOutpostARN, if set, restricts the collected AWS resources that are placed on an Outpost to those on this Outpost
+	AWSOutpostARN string
+	// AWSIAMPathPrefix, if set, restricts the collected AWS IAM resources to those under this path (e.g. "/kops/")
+	AWSIAMPathPrefix string
+	// MultiClusterPolicy decides what happens to resources that are owned by other clusters as well as this one
+	MultiClusterPolicy MultiClusterPolicy
+	// ListedClusters are the other clusters whose co-owned resources may be deleted under MultiClusterPolicyDeleteIfAllListed
+	ListedClusters []string
+	// AWSUntaggedRouteTableMinAge, if set, only adopts untagged route tables in the cluster VPC that are at least this old.
+	// Route tables whose creation time can't be inferred are adopted regardless.
+	AWSUntaggedRouteTableMinAge time.Duration
+	// AWSUntaggedRouteTableVPCs, if set, only adopts untagged route tables in these VPCs
+	AWSUntaggedRouteTableVPCs []string
+	// AWSDisassociateSharedSubnets allows owned route tables to be disassociated from shared subnets, so that they can be deleted
+	AWSDisassociateSharedSubnets bool
+	// AWSDisassociateSubnetsInUse allows route tables to be disassociated from subnets in which instances are running
+	AWSDisassociateSubnetsInUse bool
+	// AWSSkipIAM, if set, doesn't collect the AWS IAM roles and instance profiles, e.g. because they are managed outside kops
+	AWSSkipIAM bool
+	// AWSResourceTypes, if set, restricts the collected AWS resources to those of these types (e.g. "route-table", "iam-role")
+	AWSResourceTypes []string
+	// AllowedRegions, if set, are the only cloud regions that resources may be collected from
+	AllowedRegions []string
+	// OwnershipTagKeys are additional AWS tag keys whose value names the cluster that owns a resource, e.g. "company.com/cluster",
+	// for environments that already tag resources with their own ownership tag
+	OwnershipTagKeys []string
+	// AWSELBTags, if set, are the tags that the cluster's classic load balancers are matched by, instead of the cloud's tags.
+	// A tag with an empty value only requires the key to exist, and a value can be a pattern in path.Match syntax, e.g. "owned-*".
+	AWSELBTags map[string]string
+	// AWSClusterTagScheme, if set, restricts the cluster tags that the AWS route tables are looked up by.
+	// Each tag costs a round-trip, so restricting them saves calls when the other tags are known to be absent.
+	AWSClusterTagScheme ClusterTagScheme
+}
+
+// MultiClusterPolicy is the policy for a resource that is owned by more than one cluster
+type MultiClusterPolicy string
+
+const (
+	// MultiClusterPolicyError fails the listing, so that nothing is deleted. This is the default.
+	MultiClusterPolicyError MultiClusterPolicy = "error"
+	// MultiClusterPolicySkip treats the resource as shared, so that it is not deleted
+	MultiClusterPolicySkip MultiClusterPolicy = "skip"
+	// MultiClusterPolicyDeleteIfAllListed deletes the resource only if all its other owners are in ListedClusters,
+	// and otherwise treats it as shared
+	MultiClusterPolicyDeleteIfAllListed MultiClusterPolicy = "delete-if-all-listed"
+)
+
+// MultiClusterPolicies are the supported values of MultiClusterPolicy
+var MultiClusterPolicies = []MultiClusterPolicy{
+	MultiClusterPolicyError,
+	MultiClusterPolicySkip,
+	MultiClusterPolicyDeleteIfAllListed,
+}
+
+// ClusterTagScheme is the set of cluster tags that cloud resources are looked up by
+type ClusterTagScheme string
+
+const (
+	// ClusterTagSchemeAll looks resources up by both the legacy and the ownership tags, and by the OwnershipTagKeys. This is the default.
+	ClusterTagSchemeAll ClusterTagScheme = "all"
+	// ClusterTagSchemeLegacy only looks resources up by the legacy KubernetesCluster tag, which is all that very old clusters have
+	ClusterTagSchemeLegacy ClusterTagScheme = "legacy"
+	// ClusterTagSchemeOwnership only looks resources up by the kubernetes.io/cluster/<name> tag
+	ClusterTagSchemeOwnership ClusterTagScheme = "ownership"
+)
+
+// ClusterTagSchemes are the supported values of ClusterTagScheme
+var ClusterTagSchemes = []ClusterTagScheme{
+	ClusterTagSchemeAll,
+	ClusterTagSchemeLegacy,
+	ClusterTagSchemeOwnership,
+}
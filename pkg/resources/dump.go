@@ -17,9 +17,14 @@ limitations under the License.
 package resources
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"k8s.io/klog/v2"
 	"k8s.io/kops/upup/pkg/fi"
@@ -40,8 +45,23 @@ type DumpOperation struct {
 	Dump *Dump
 }
 
-// BuildDump gathers information about the cluster and returns an object for dumping
+// RedactedMarker replaces the values of the fields that are redacted from a dump
+const RedactedMarker = "[redacted]"
+
+// DumpOptions controls how BuildDumpWithOptions dumps the resources
+type DumpOptions struct {
+	// IncludeSecrets keeps the fields of the raw cloud objects that may hold secrets, such as the user-data of instances and launch templates.
+	// By default they are replaced with RedactedMarker, as the user-data of the nodes can include bootstrap tokens and kubeconfigs.
+	IncludeSecrets bool
+}
+
+// BuildDump gathers information about the cluster and returns an object for dumping, with the secrets redacted
 func BuildDump(ctx context.Context, cloud fi.Cloud, resources map[string]*Resource) (*Dump, error) {
+	return BuildDumpWithOptions(ctx, cloud, resources, DumpOptions{})
+}
+
+// BuildDumpWithOptions gathers information about the cluster and returns an object for dumping
+func BuildDumpWithOptions(ctx context.Context, cloud fi.Cloud, resources map[string]*Resource, options DumpOptions) (*Dump, error) {
 	dump := &Dump{}
 	op := &DumpOperation{
 		Context: ctx,
@@ -63,5 +83,200 @@ func BuildDump(ctx context.Context, cloud fi.Cloud, resources map[string]*Resour
 
 	sort.SliceStable(dump.Instances, func(i, j int) bool { return dump.Instances[i].Name < dump.Instances[j].Name })
 
+	if !options.IncludeSecrets {
+		if err := redactDump(dump); err != nil {
+			return nil, err
+		}
+	}
+
 	return dump, nil
 }
+
+// redactDump replaces the secrets in the raw cloud objects of the dumped resources with RedactedMarker
+func redactDump(dump *Dump) error {
+	for _, d := range dump.Resources {
+		switch data := d.(type) {
+		case map[string]interface{}:
+			if raw, found := data["raw"]; found {
+				redacted, err := redactSecrets(raw)
+				if err != nil {
+					return err
+				}
+				data["raw"] = redacted
+			}
+		case *RouteTableDump:
+			redacted, err := redactSecrets(data.Raw)
+			if err != nil {
+				return err
+			}
+			data.Raw = redacted
+		}
+	}
+	return nil
+}
+
+// redactSecrets returns the object with its user-data fields, at any depth, replaced with RedactedMarker.
+// The objects are of many cloud-specific types, so they are redacted in their JSON form;
+// an object that has no user-data is returned unchanged.
+func redactSecrets(raw interface{}) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling %T: %v", raw, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	// Keep large numbers (e.g. account ids) as they are
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("error unmarshaling %T: %v", raw, err)
+	}
+	if !redactUserData(generic) {
+		return raw, nil
+	}
+	return generic, nil
+}
+
+// redactUserData replaces the non-empty values of the user-data fields (e.g. "UserData", "userData", "user_data") in place,
+// returning true if any were replaced
+func redactUserData(v interface{}) bool {
+	redacted := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if strings.EqualFold(strings.ReplaceAll(k, "_", ""), "userdata") {
+				if child != nil && child != "" {
+					v[k] = RedactedMarker
+					redacted = true
+				}
+				continue
+			}
+			if redactUserData(child) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if redactUserData(child) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+// GroupDumpByVPC moves the resources of the dump that belong to a VPC from Resources to ResourcesByVPC.
+// A resource belongs to the VPC that it blocks ("vpc:<id>"), or to itself if it is a VPC;
+// resources are matched to the dumped data by their "type" and "id", and the others stay in Resources.
+func GroupDumpByVPC(dump *Dump, resources map[string]*Resource) {
+	var ungrouped []interface{}
+	for _, d := range dump.Resources {
+		vpcID := ""
+		if resourceType, id := dumpedResourceKey(d); resourceType != "" {
+			if r := resources[resourceType+":"+id]; r != nil {
+				vpcID = resourceVPC(r)
+			}
+		}
+
+		if vpcID == "" {
+			ungrouped = append(ungrouped, d)
+			continue
+		}
+		if dump.ResourcesByVPC == nil {
+			dump.ResourcesByVPC = make(map[string][]interface{})
+		}
+		dump.ResourcesByVPC[vpcID] = append(dump.ResourcesByVPC[vpcID], d)
+	}
+	dump.Resources = ungrouped
+}
+
+// dumpedResourceKey returns the type and id of a resource in a dump, or "" if the resource doesn't record its type
+func dumpedResourceKey(d interface{}) (string, string) {
+	switch data := d.(type) {
+	case map[string]interface{}:
+		resourceType, _ := data["type"].(string)
+		id, _ := data["id"].(string)
+		return resourceType, id
+	case *RouteTableDump:
+		return data.Type, data.ID
+	}
+	return "", ""
+}
+
+// resourceVPC returns the id of the VPC that the resource belongs to, or "" if it doesn't belong to one
+func resourceVPC(r *Resource) string {
+	if r.Type == "vpc" {
+		return r.ID
+	}
+	for _, block := range r.Blocks {
+		if strings.HasPrefix(block, "vpc:") {
+			return strings.TrimPrefix(block, "vpc:")
+		}
+	}
+	return ""
+}
+
+// WriteShardedDump writes the dump to dir as one JSON file per resource type (e.g. route-tables.json, volumes.json),
+// so that tooling can process each type independently.  The instances, subnets and VPC summaries are written to
+// summary.json, and resources that don't record their type go to resources.json.
+// Each shard is written as soon as it is complete, so a large dump never has to be marshaled in one piece.
+func WriteShardedDump(dump *Dump, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating directory %q: %v", dir, err)
+	}
+
+	shards := make(map[string][]interface{})
+	for _, r := range dump.Resources {
+		name := "resources"
+		if t, _ := dumpedResourceKey(r); t != "" {
+			name = shardName(t)
+		}
+		shards[name] = append(shards[name], r)
+	}
+
+	var names []string
+	for name := range shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeShard(dir, name, shards[name]); err != nil {
+			return err
+		}
+	}
+
+	if len(dump.Instances) != 0 || len(dump.Subnets) != 0 || dump.VPC != nil {
+		summary := &Dump{
+			Instances: dump.Instances,
+			Subnets:   dump.Subnets,
+			VPC:       dump.VPC,
+		}
+		if err := writeShard(dir, "summary", summary); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shardName returns the name of the shard for a resource type, e.g. "route-tables" for "route-table"
+func shardName(resourceType string) string {
+	if strings.HasSuffix(resourceType, "s") {
+		return resourceType
+	}
+	return resourceType + "s"
+}
+
+func writeShard(dir, name string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling %s: %v", name, err)
+	}
+	p := filepath.Join(dir, name+".json")
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		return fmt.Errorf("error writing %q: %v", p, err)
+	}
+	return nil
+}
@@ -16,6 +16,12 @@ limitations under the License.
 
 package resources
 
+import "encoding/json"
+
+// DumpSchemaVersion is the version of the JSON document written by Dump.ToJSON.
+// It should be bumped whenever a field is renamed or removed, so that consumers of a dump can tell the formats apart.
+const DumpSchemaVersion = "v1"
+
 // Instance is the type for an instance in a dump
 type Instance struct {
 	Name             string   `json:"name,omitempty"`
@@ -36,10 +42,42 @@ type VPC struct {
 	ID string `json:"id,omitempty"`
 }
 
+// RouteTableDump is the type for a route table in a dump
+type RouteTableDump struct {
+	ID     string      `json:"id"`
+	Type   string      `json:"type"`
+	Routes []RouteDump `json:"routes,omitempty"`
+	// Raw is the route table as returned by the cloud
+	Raw interface{} `json:"raw"`
+}
+
+// RouteDump is the type for a route of a route table in a dump
+type RouteDump struct {
+	// Destination is the CIDR or prefix list that the route matches
+	Destination string `json:"destination,omitempty"`
+	// Target is the id of the gateway, instance or network interface that the traffic is routed to
+	Target string `json:"target,omitempty"`
+	// State is the state of the route, e.g. "blackhole" if its target has been deleted
+	State string `json:"state,omitempty"`
+}
+
 // Dump is the type for a dump result
 type Dump struct {
 	Resources []interface{} `json:"resources,omitempty"`
 	Instances []*Instance   `json:"instances,omitempty"`
 	Subnets   []*Subnet     `json:"subnets,omitempty"`
 	VPC       *VPC          `json:"vpc,omitempty"`
+	// ResourcesByVPC holds the resources that belong to a VPC, by VPC id, when the dump is grouped by VPC
+	ResourcesByVPC map[string][]interface{} `json:"resourcesByVPC,omitempty"`
+}
+
+// dumpEnvelope is the versioned JSON document for a dump
+type dumpEnvelope struct {
+	SchemaVersion string `json:"schemaVersion"`
+	*Dump
+}
+
+// ToJSON serializes the dump as an indented JSON document, versioned with DumpSchemaVersion
+func (d *Dump) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(&dumpEnvelope{SchemaVersion: DumpSchemaVersion, Dump: d}, "", "  ")
 }
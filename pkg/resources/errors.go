@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ServiceFailuresError is returned alongside the resources that could be listed
+// when some cloud services (e.g. "iam") could not be listed.
+// The resources of the failed services are not included.
+type ServiceFailuresError struct {
+	// Failures maps the name of each failed service to its error
+	Failures map[string]error
+}
+
+func (e *ServiceFailuresError) Error() string {
+	var services []string
+	for service := range e.Failures {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	var messages []string
+	for _, service := range services {
+		messages = append(messages, fmt.Sprintf("%s: %v", service, e.Failures[service]))
+	}
+	return fmt.Sprintf("error listing resources of services %s: %s", strings.Join(services, ", "), strings.Join(messages, "; "))
+}
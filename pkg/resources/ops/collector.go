@@ -18,6 +18,7 @@ package ops
 
 import (
 	"fmt"
+	"slices"
 
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/resources"
@@ -38,12 +39,93 @@ import (
 	cloudscaleway "k8s.io/kops/upup/pkg/fi/cloudup/scaleway"
 )
 
+// PreflightCheck verifies that the credentials are permitted to list the cluster resources on the specified cloud,
+// as ListResourcesWithOptions would list them with the same options.
+// It is currently only implemented for AWS; it is a no-op on other clouds.
+func PreflightCheck(cloud fi.Cloud, cluster *kops.Cluster, options resources.ListOptions) error {
+	switch cloud.ProviderID() {
+	case kops.CloudProviderAWS:
+		return aws.PreflightCheck(cloud.(awsup.AWSCloud), buildClusterInfo(cluster, options))
+	default:
+		return nil
+	}
+}
+
+// RemoveStaleRoutes removes routes in shared route tables that point at deleted cluster resources.
+// It is currently only implemented for AWS; it is a no-op on other clouds.
+func RemoveStaleRoutes(cloud fi.Cloud, resourceMap map[string]*resources.Resource) error {
+	switch cloud.ProviderID() {
+	case kops.CloudProviderAWS:
+		return aws.RemoveStaleRoutes(cloud, resourceMap)
+	default:
+		return nil
+	}
+}
+
+// TagSharedResources tags the shared resources that a deletion left behind with the id of the deletion run.
+// It is currently only implemented for AWS; it is a no-op on other clouds.
+func TagSharedResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource, runID string) error {
+	switch cloud.ProviderID() {
+	case kops.CloudProviderAWS:
+		return aws.TagSharedResources(cloud.(awsup.AWSCloud), resourceMap, runID)
+	default:
+		return nil
+	}
+}
+
+// AuditUntaggedResources returns the resources that are named for the cluster but not tagged as belonging to it.
+// They are reported only; they are not part of the resources that ListResources collects.
+// It is currently only implemented for AWS; it returns nothing on other clouds.
+func AuditUntaggedResources(cloud fi.Cloud, clusterName string) ([]*resources.Resource, error) {
+	switch cloud.ProviderID() {
+	case kops.CloudProviderAWS:
+		return aws.AuditUntaggedResources(cloud.(awsup.AWSCloud), clusterName)
+	default:
+		return nil, nil
+	}
+}
+
+// CheckRegionAllowed returns an error if allowedRegions is set and doesn't include the cloud's region.
+// It protects against collecting, and so deleting, resources in an unexpected region.
+func CheckRegionAllowed(cloud fi.Cloud, allowedRegions []string) error {
+	if len(allowedRegions) == 0 {
+		return nil
+	}
+	region := cloud.Region()
+	if !slices.Contains(allowedRegions, region) {
+		return fmt.Errorf("region %q is not in the allowed regions %v", region, allowedRegions)
+	}
+	return nil
+}
+
 // ListResources collects the resources from the specified cloud
 func ListResources(cloud fi.Cloud, cluster *kops.Cluster) (map[string]*resources.Resource, error) {
+	return ListResourcesWithOptions(cloud, cluster, resources.ListOptions{})
+}
+
+// buildClusterInfo returns the cluster info that the listers use to find the resources of the cluster
+func buildClusterInfo(cluster *kops.Cluster, options resources.ListOptions) resources.ClusterInfo {
 	clusterInfo := resources.ClusterInfo{
 		Name:        cluster.Name,
 		UsesNoneDNS: cluster.UsesNoneDNS(),
+		ListOptions: options,
+	}
+	if discovery := cluster.Spec.ServiceAccountIssuerDiscovery; discovery != nil && discovery.EnableAWSOIDCProvider && cluster.Spec.KubeAPIServer != nil {
+		clusterInfo.ServiceAccountIssuer = fi.ValueOf(cluster.Spec.KubeAPIServer.ServiceAccountIssuer)
+	}
+	if discovery := cluster.Spec.ServiceAccountIssuerDiscovery; discovery != nil {
+		clusterInfo.DiscoveryStore = discovery.DiscoveryStore
 	}
+	return clusterInfo
+}
+
+// ListResourcesWithOptions collects the resources from the specified cloud, narrowed down by the options
+func ListResourcesWithOptions(cloud fi.Cloud, cluster *kops.Cluster, options resources.ListOptions) (map[string]*resources.Resource, error) {
+	if err := CheckRegionAllowed(cloud, options.AllowedRegions); err != nil {
+		return nil, err
+	}
+
+	clusterInfo := buildClusterInfo(cluster, options)
 
 	switch cloud.ProviderID() {
 	case kops.CloudProviderAWS:
@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func TestListResourcesDisallowedRegion(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-west-2", "a")
+	cluster := &kops.Cluster{}
+	cluster.Name = "me.example.com"
+
+	options := resources.ListOptions{
+		AllowedRegions: []string{"us-east-1", "us-east-2"},
+	}
+	if _, err := ListResourcesWithOptions(cloud, cluster, options); err == nil {
+		t.Fatalf("expected error listing resources in a region that isn't allowed")
+	}
+
+	deleteOptions := &DeleteOptions{
+		AllowedRegions: []string{"us-east-1", "us-east-2"},
+	}
+	if err := DeleteResourcesWithOptions(cloud, map[string]*resources.Resource{}, deleteOptions); err == nil {
+		t.Fatalf("expected error deleting resources in a region that isn't allowed")
+	}
+}
+
+func TestCheckRegionAllowed(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "a")
+
+	if err := CheckRegionAllowed(cloud, nil); err != nil {
+		t.Errorf("unexpected error with no allowed regions: %v", err)
+	}
+	if err := CheckRegionAllowed(cloud, []string{"us-west-2", "us-east-1"}); err != nil {
+		t.Errorf("unexpected error for allowed region: %v", err)
+	}
+	if err := CheckRegionAllowed(cloud, []string{"us-west-2"}); err == nil {
+		t.Errorf("expected error for region that isn't allowed")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DeleteConditionStatus is the status of the deletion of a type of resource
+type DeleteConditionStatus string
+
+const (
+	// DeleteConditionReady means that all the resources of the type were deleted, or deliberately left alone (e.g. shared resources)
+	DeleteConditionReady DeleteConditionStatus = "Ready"
+	// DeleteConditionFailed means that some resources of the type were not deleted
+	DeleteConditionFailed DeleteConditionStatus = "Failed"
+)
+
+// DeleteCondition is the outcome of deleting all the resources of a type
+type DeleteCondition struct {
+	// ResourceType is the type of the resources, e.g. "route-table"
+	ResourceType string
+	Status       DeleteConditionStatus
+	// Message lists the resources that were not deleted, and why; for a Ready condition, the resources that were left alone
+	Message string
+}
+
+// DeleteConditionWriter receives the outcome of a deletion, for example to set it on the status of a custom resource
+type DeleteConditionWriter interface {
+	SetDeleteCondition(condition DeleteCondition) error
+}
+
+// DeleteConditions converts a DeleteReport into one condition per resource type, sorted by type
+func DeleteConditions(report *DeleteReport) []DeleteCondition {
+	byType := make(map[string][]*DeleteResult)
+	for _, result := range report.Results {
+		byType[result.Type] = append(byType[result.Type], result)
+	}
+
+	var conditions []DeleteCondition
+	for resourceType, results := range byType {
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].ID < results[j].ID
+		})
+
+		var failures, skipped []string
+		for _, result := range results {
+			if result.Deleted {
+				continue
+			}
+			if result.Skipped != "" {
+				skipped = append(skipped, fmt.Sprintf("%s: skipped, %s", result.ID, result.Skipped))
+				continue
+			}
+			if result.Error != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", result.ID, result.Error))
+			} else {
+				failures = append(failures, fmt.Sprintf("%s: not attempted", result.ID))
+			}
+		}
+
+		condition := DeleteCondition{
+			ResourceType: resourceType,
+			Status:       DeleteConditionReady,
+			Message:      strings.Join(skipped, "; "),
+		}
+		if len(failures) != 0 {
+			condition.Status = DeleteConditionFailed
+			condition.Message = strings.Join(append(failures, skipped...), "; ")
+		}
+		conditions = append(conditions, condition)
+	}
+
+	sort.Slice(conditions, func(i, j int) bool {
+		return conditions[i].ResourceType < conditions[j].ResourceType
+	})
+	return conditions
+}
+
+// WriteDeleteConditions sets the conditions for a DeleteReport on the writer
+func WriteDeleteConditions(report *DeleteReport, writer DeleteConditionWriter) error {
+	for _, condition := range DeleteConditions(report) {
+		if err := writer.SetDeleteCondition(condition); err != nil {
+			return fmt.Errorf("error setting condition for %q: %w", condition.ResourceType, err)
+		}
+	}
+	return nil
+}
@@ -17,36 +17,304 @@ limitations under the License.
 package ops
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	"k8s.io/kops/pkg/resources"
 	awsresources "k8s.io/kops/pkg/resources/aws"
 	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/util/pkg/maps"
+	"k8s.io/utils/clock"
 )
 
+// RequiredActions returns the distinct cloud API actions that deleting the resources would invoke, sorted.
+// It does not call the cloud; it is built from the actions declared on each resource.
+func RequiredActions(resourceMap map[string]*resources.Resource) []string {
+	actions := make(map[string]bool)
+	for _, r := range resourceMap {
+		for _, action := range r.Actions {
+			actions[action] = true
+		}
+	}
+
+	var l []string
+	for action := range actions {
+		l = append(l, action)
+	}
+	sort.Strings(l)
+	return l
+}
+
+// DeleteOptions controls how DeleteResourcesWithOptions deletes resources
+type DeleteOptions struct {
+	// Count is the number of consecutive passes without progress after which we give up; 0 means never give up
+	Count int
+	// Interval is the time to wait between passes
+	Interval time.Duration
+	// Wait is the total time to wait for the resources to be deleted; 0 means no limit
+	Wait time.Duration
+	// Seed, if non-zero, shuffles the order in which independent resources are deleted.
+	// The same seed produces the same order for the same set of resources, which helps when debugging.
+	Seed int64
+	// MaxConcurrentDeletes is the maximum number of independent resources that are deleted at the same time; 0 means no limit.
+	// A lower limit avoids cloud API throttling (e.g. RequestLimitExceeded) when there are many resources.
+	MaxConcurrentDeletes int
+	// AllowedRegions, if set, are the only cloud regions that resources may be deleted in
+	AllowedRegions []string
+	// ProtectedIDs are the ids of resources that are never deleted, whatever their tags say, e.g. a VPC shared with other clusters.
+	// The resources that they block are still deleted, as if the protected resources were shared.
+	ProtectedIDs []string
+	// Report, if set, is filled in with the outcome for each resource
+	Report *DeleteReport
+	// RunID identifies this deletion in the output and the report, so they can be correlated; a UUID is generated if not set
+	RunID string
+	// Out is where the progress of the deletion is written; defaults to os.Stdout
+	Out io.Writer
+	// Clock is used to wait between the retries of RetryPolicies; defaults to the real clock
+	Clock clock.Clock
+	// Observer, if set, is notified of the progress of the deletion of each resource
+	Observer DeletionObserver
+	// Progress, if set, is called with the overall progress once the skipped resources are known, and after each resource is deleted.
+	// The calls are serialized, and hold up the deletion, so they should return quickly.
+	Progress func(progress DeleteProgress)
+	// BestEffort gives up on a resource whose deletion fails with an error other than a dependency violation,
+	// rather than retrying it in each pass. The resources that it blocks are skipped, but the independent resources are
+	// still deleted, and the deletion then returns a *DeleteFailuresError listing every resource that it gave up on.
+	// Deletions that fail terminally (e.g. because the deleter refuses to delete the resource) are given up on even without BestEffort.
+	BestEffort bool
+}
+
+// DeleteFailuresError is returned by a deletion that gave up on some of the resources
+type DeleteFailuresError struct {
+	// Failures maps the key ("<type>:<id>") of each resource that could not be deleted to its error
+	Failures map[string]error
+}
+
+func (e *DeleteFailuresError) Error() string {
+	var messages []string
+	for _, k := range maps.SortedKeys(e.Failures) {
+		messages = append(messages, fmt.Sprintf("%s: %v", k, e.Failures[k]))
+	}
+	return fmt.Sprintf("error deleting %d resources: %s", len(e.Failures), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the errors of the resources, so that errors.Is and errors.As can match them
+func (e *DeleteFailuresError) Unwrap() []error {
+	var errs []error
+	for _, k := range maps.SortedKeys(e.Failures) {
+		errs = append(errs, e.Failures[k])
+	}
+	return errs
+}
+
+// DeleteReport records the outcome of deleting each resource
+type DeleteReport struct {
+	// RunID identifies the deletion that produced the report
+	RunID string
+	// Results are the outcomes, by resource key ("<type>:<id>")
+	Results map[string]*DeleteResult
+
+	// mutex guards Results while the deletion is running
+	mutex sync.Mutex
+}
+
+// DeleteResult is the outcome of deleting a resource
+type DeleteResult struct {
+	Type string
+	ID   string
+	// Deleted is true if the resource was deleted
+	Deleted bool
+	// Skipped is the reason the resource was deliberately left alone, e.g. "shared" or "protected"; empty if it wasn't
+	Skipped string
+	// Error is the last error deleting the resource, if any; a resource that was never attempted has no error
+	Error error
+}
+
+// Dangling returns the keys of the resources that are not yet deleted, but all of whose dependencies have been.
+// These are the resources that would be left dangling if the deletion stopped now, e.g. a subnet whose instances are gone.
+// It can be called while the deletion is running.
+func (r *DeleteReport) Dangling(resourceMap map[string]*resources.Resource) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	deleted := func(k string) bool {
+		result := r.Results[k]
+		return result != nil && result.Deleted
+	}
+
+	var dangling []string
+	for k, deps := range buildDependencyMap(resourceMap) {
+		if _, found := resourceMap[k]; !found || deleted(k) || len(deps) == 0 {
+			continue
+		}
+		allDeleted := true
+		for _, dep := range deps {
+			if !deleted(dep) {
+				allDeleted = false
+			}
+		}
+		if allDeleted {
+			dangling = append(dangling, k)
+		}
+	}
+	sort.Strings(dangling)
+	return dangling
+}
+
+// record sets the outcome of deleting the resources
+func (r *DeleteReport) record(trackers []*resources.Resource, err error) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, t := range trackers {
+		r.Results[t.Type+":"+t.ID] = &DeleteResult{
+			Type:    t.Type,
+			ID:      t.ID,
+			Deleted: err == nil,
+			Error:   err,
+		}
+	}
+}
+
+// recordSkipped records that the resource was deliberately not deleted, for the reason
+func (r *DeleteReport) recordSkipped(t *resources.Resource, reason string) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Results[t.Type+":"+t.ID] = &DeleteResult{
+		Type:    t.Type,
+		ID:      t.ID,
+		Skipped: reason,
+	}
+}
+
 // DeleteResources deletes the resources, as previously collected by ListResources
 func DeleteResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource, count int, interval, wait time.Duration) error {
-	depMap := make(map[string][]string)
+	options := &DeleteOptions{
+		Count:    count,
+		Interval: interval,
+		Wait:     wait,
+	}
+	return DeleteResourcesWithOptions(cloud, resourceMap, options)
+}
+
+// DeleteResourcesWithOptions deletes the resources, as previously collected by ListResources
+func DeleteResourcesWithOptions(cloud fi.Cloud, resourceMap map[string]*resources.Resource, options *DeleteOptions) error {
+	if err := CheckRegionAllowed(cloud, options.AllowedRegions); err != nil {
+		return err
+	}
+
+	count := options.Count
+	interval := options.Interval
+	wait := options.Wait
+
+	runID := options.RunID
+	if runID == "" {
+		runID = uuid.New().String()
+	}
+	out := options.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	clk := options.Clock
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+	observer := options.Observer
+	if observer == nil {
+		observer = NoopDeletionObserver{}
+	}
+	// printf writes a line of progress, prefixed with the run id
+	printf := func(format string, args ...interface{}) {
+		fmt.Fprintf(out, "[%s] "+format, append([]interface{}{runID}, args...)...)
+	}
+
+	// slots bounds the number of concurrent deletions, if there is a limit
+	var slots chan struct{}
+	if options.MaxConcurrentDeletes > 0 {
+		slots = make(chan struct{}, options.MaxConcurrentDeletes)
+	}
+
+	protected := sets.NewString(options.ProtectedIDs...)
+
+	depMap := buildDependencyMap(resourceMap)
 
 	done := make(map[string]*resources.Resource)
+	// gaveUp are the errors of the resources that are no longer retried: those that failed terminally,
+	// and in a BestEffort deletion those that failed with an error other than a dependency violation
+	gaveUp := make(map[string]error)
 
 	var mutex sync.Mutex
 
-	for k, t := range resourceMap {
-		for _, block := range t.Blocks {
-			depMap[block] = append(depMap[block], k)
+	if options.Report != nil {
+		options.Report.mutex.Lock()
+		options.Report.RunID = runID
+		options.Report.Results = make(map[string]*DeleteResult)
+		for k, t := range resourceMap {
+			// Resources we never get to attempt are reported as not deleted
+			options.Report.Results[k] = &DeleteResult{Type: t.Type, ID: t.ID, Deleted: t.Done}
 		}
+		options.Report.mutex.Unlock()
+	}
 
-		depMap[k] = append(depMap[k], t.Blocked...)
-
+	for _, k := range maps.SortedKeys(resourceMap) {
+		t := resourceMap[k]
 		if t.Done {
 			done[k] = t
+		} else if t.Shared {
+			// A shared resource isn't ours to delete, but the resources it blocks can go ahead
+			klog.V(2).Infof("[%s] not deleting shared resource %q", runID, k)
+			observer.OnSkip(t, "shared")
+			options.Report.recordSkipped(t, "shared")
+			done[k] = t
+		} else if protected.Has(t.ID) {
+			klog.Warningf("[%s] not deleting protected resource %q", runID, k)
+			printf("not deleting protected resource %s\n", k)
+			observer.OnSkip(t, "protected")
+			options.Report.recordSkipped(t, "protected")
+			done[k] = t
+		}
+	}
+
+	// reportProgress calls options.Progress, if set; the caller must hold mutex once deletions have started
+	reportProgress := func() {
+		if options.Progress == nil {
+			return
+		}
+		progress := DeleteProgress{
+			Total:     len(resourceMap),
+			Completed: len(done),
+		}
+		for k := range resourceMap {
+			if _, d := done[k]; !d && dependenciesDone(depMap[k], done) {
+				progress.Ready++
+			}
 		}
+		options.Progress(progress)
+	}
+	// A cycle would never make progress, so we fail now rather than when the wait time is exceeded
+	if cycle := findDependencyCycle(resourceMap, depMap, done); cycle != nil {
+		return dependencyCycleError(cycle)
 	}
 
+	reportProgress()
+
 	klog.V(2).Info("Dependencies")
 	for k, v := range depMap {
 		klog.V(2).Infof("\t%s\t%v", k, v)
@@ -56,13 +324,22 @@ func DeleteResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource,
 	iterationsWithNoProgress := 0
 	for {
 		if wait > 0 && time.Now().After(timeout) {
-			return fmt.Errorf("wait time exceeded during resources deletion")
+			err := fmt.Errorf("wait time exceeded during resources deletion")
+			if len(gaveUp) != 0 {
+				return errors.Join(err, &DeleteFailuresError{Failures: gaveUp})
+			}
+			return err
 		}
 
 		failed := make(map[string]*resources.Resource)
+		// attempts counts the attempts to delete each group in this pass, and retryAt is when the groups that were
+		// throttled or blocked are attempted again, so that they don't hold up the other deletions while they wait
+		attempts := make(map[string]int)
+		retryAt := make(map[string]time.Time)
 
 		for {
 			phase := make(map[string]*resources.Resource)
+			var nextRetry time.Time
 
 			for k, r := range resourceMap {
 				if _, d := done[k]; d {
@@ -74,6 +351,10 @@ func DeleteResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource,
 					continue
 				}
 
+				if _, g := gaveUp[k]; g {
+					continue
+				}
+
 				ready := true
 				for _, dep := range depMap[k] {
 					if _, d := done[dep]; !d {
@@ -85,11 +366,23 @@ func DeleteResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource,
 					continue
 				}
 
+				if at, found := retryAt[k]; found && clk.Now().Before(at) {
+					if nextRetry.IsZero() || at.Before(nextRetry) {
+						nextRetry = at
+					}
+					continue
+				}
+
 				phase[k] = r
 			}
 
 			if len(phase) == 0 {
-				break
+				if nextRetry.IsZero() {
+					break
+				}
+				// Nothing else can be deleted until then
+				clk.Sleep(nextRetry.Sub(clk.Now()))
+				continue
 			}
 
 			groups := make(map[string][]*resources.Resource)
@@ -102,7 +395,12 @@ func DeleteResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource,
 			}
 
 			var wg sync.WaitGroup
-			for _, trackers := range groups {
+			for _, groupKey := range orderGroups(groups, options.Seed) {
+				trackers := groups[groupKey]
+				if slots != nil {
+					// Taking the slot before starting the goroutine keeps the deletions in order
+					slots <- struct{}{}
+				}
 				wg.Add(1)
 
 				go func(trackers []*resources.Resource) {
@@ -115,8 +413,15 @@ func DeleteResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource,
 
 					defer wg.Done()
 
+					if slots != nil {
+						defer func() { <-slots }()
+					}
+
 					human := trackers[0].Type + ":" + trackers[0].ID
 
+					for _, t := range trackers {
+						observer.OnStart(t)
+					}
 					var err error
 					if trackers[0].GroupDeleter != nil {
 						err = trackers[0].GroupDeleter(cloud, trackers)
@@ -126,13 +431,42 @@ func DeleteResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource,
 						}
 						err = trackers[0].Deleter(cloud, trackers[0])
 					}
+					mutex.Lock()
+					options.Report.record(trackers, err)
+					mutex.Unlock()
+
+					for _, t := range trackers {
+						if err != nil {
+							observer.OnError(t, err)
+						} else {
+							observer.OnDelete(t)
+						}
+					}
+
 					if err != nil {
 						mutex.Lock()
-						if awsresources.IsDependencyViolation(err) {
-							fmt.Printf("%s\tstill has dependencies, will retry\n", human)
-							klog.V(4).Infof("resource %q generated a dependency error: %v", human, err)
+						attempts[human]++
+						if delay, retry := retryDelay(trackers[0].Type, attempts[human], err); retry && !awsresources.IsTerminal(err) {
+							// Re-queue the resources, rather than wait here and hold up the other deletions of the phase
+							klog.V(4).Infof("[%s] error deleting %q, will retry in %v: %v", runID, human, delay, err)
+							for _, t := range trackers {
+								k := t.Type + ":" + t.ID
+								delete(failed, k)
+								retryAt[k] = clk.Now().Add(delay)
+							}
+							mutex.Unlock()
+							return
+						}
+						if awsresources.IsTerminal(err) || (options.BestEffort && !awsresources.IsDependencyViolation(err)) {
+							printf("%s\terror deleting resources, giving up: %v\n", human, err)
+							for _, t := range trackers {
+								gaveUp[t.Type+":"+t.ID] = err
+							}
+						} else if awsresources.IsDependencyViolation(err) {
+							printf("%s\tstill has dependencies, will retry\n", human)
+							klog.V(4).Infof("[%s] resource %q generated a dependency error: %v", runID, human, err)
 						} else {
-							fmt.Printf("%s\terror deleting resources, will retry: %v\n", human, err)
+							printf("%s\terror deleting resources, will retry: %v\n", human, err)
 						}
 						for _, t := range trackers {
 							k := t.Type + ":" + t.ID
@@ -141,7 +475,7 @@ func DeleteResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource,
 						mutex.Unlock()
 					} else {
 						mutex.Lock()
-						fmt.Printf("%s\tok\n", human)
+						printf("%s\tok\n", human)
 
 						iterationsWithNoProgress = 0
 						for _, t := range trackers {
@@ -149,6 +483,7 @@ func DeleteResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource,
 							delete(failed, k)
 							done[k] = t
 						}
+						reportProgress()
 						mutex.Unlock()
 					}
 				}(trackers)
@@ -160,20 +495,219 @@ func DeleteResources(cloud fi.Cloud, resourceMap map[string]*resources.Resource,
 			return nil
 		}
 
-		fmt.Printf("Not all resources deleted; waiting before reattempting deletion\n")
+		if len(gaveUp) != 0 {
+			// Once the only resources left are those we gave up on, and those that wait on them, there is nothing more to do
+			blocked := blockedByFailures(resourceMap, depMap, done, gaveUp)
+			if len(done)+len(blocked) == len(resourceMap) {
+				for _, k := range maps.SortedKeys(blocked) {
+					if _, g := gaveUp[k]; g {
+						continue
+					}
+					printf("%s\tnot deleting, because a resource it depends on could not be deleted\n", k)
+					observer.OnSkip(resourceMap[k], "dependency failed")
+				}
+				return &DeleteFailuresError{Failures: gaveUp}
+			}
+		}
+
+		printf("Not all resources deleted; waiting before reattempting deletion\n")
 		for k := range resourceMap {
 			if _, d := done[k]; d {
 				continue
 			}
 
-			fmt.Printf("\t%s\n", k)
+			printf("\t%s\n", k)
 		}
 
 		iterationsWithNoProgress++
 		if iterationsWithNoProgress > count && count != 0 {
-			return fmt.Errorf("not making progress deleting resources; giving up")
+			err := fmt.Errorf("not making progress deleting resources; giving up")
+			if len(gaveUp) != 0 {
+				return errors.Join(err, &DeleteFailuresError{Failures: gaveUp})
+			}
+			return err
 		}
 
 		time.Sleep(interval)
 	}
 }
+
+// dependenciesDone returns true if all the dependencies have been deleted or skipped
+func dependenciesDone(deps []string, done map[string]*resources.Resource) bool {
+	for _, dep := range deps {
+		if _, d := done[dep]; !d {
+			return false
+		}
+	}
+	return true
+}
+
+// blockedByFailures returns the keys of the resources that are not done, and either failed or wait (directly or not) on one that failed
+func blockedByFailures(resourceMap map[string]*resources.Resource, depMap map[string][]string, done map[string]*resources.Resource, failures map[string]error) map[string]bool {
+	blocked := make(map[string]bool)
+	for k := range failures {
+		blocked[k] = true
+	}
+	for changed := true; changed; {
+		changed = false
+		for k := range resourceMap {
+			if _, d := done[k]; d || blocked[k] {
+				continue
+			}
+			for _, dep := range depMap[k] {
+				if blocked[dep] {
+					blocked[k] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return blocked
+}
+
+// buildDependencyMap returns, for each resource key, the keys of the resources that must be deleted before it
+func buildDependencyMap(resourceMap map[string]*resources.Resource) map[string][]string {
+	depMap := make(map[string][]string)
+	for k, t := range resourceMap {
+		for _, block := range t.Blocks {
+			depMap[block] = append(depMap[block], k)
+		}
+
+		depMap[k] = append(depMap[k], t.Blocked...)
+	}
+	return depMap
+}
+
+// ValidateDependencies returns an error naming the resources whose Blocks or Blocked refer to resources that aren't in resourceMap.
+// A resource blocked by a missing resource is never deleted, and a resource blocking a missing resource
+// may be deleted too early if the reference is mistyped, so such references usually point to a bug in a lister.
+// References to shared resources resolve only if resourceMap still includes them.
+func ValidateDependencies(resourceMap map[string]*resources.Resource) error {
+	var problems []string
+	for k, t := range resourceMap {
+		for _, block := range t.Blocks {
+			if _, found := resourceMap[block]; !found {
+				problems = append(problems, fmt.Sprintf("%s blocks %s, which was not found", k, block))
+			}
+		}
+		for _, blocked := range t.Blocked {
+			if _, found := resourceMap[blocked]; !found {
+				problems = append(problems, fmt.Sprintf("%s is blocked by %s, which was not found", k, blocked))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("unresolved resource dependencies: %s", strings.Join(problems, "; "))
+}
+
+// CheckDependencyCycles returns an error naming the resources in a cycle of Blocks and Blocked dependencies, if there is one.
+// The resources in a cycle would each wait for another to be deleted first, so none of them could be deleted.
+// Resources that are already deleted or shared don't wait for anything, so they don't complete a cycle.
+func CheckDependencyCycles(resourceMap map[string]*resources.Resource) error {
+	done := make(map[string]*resources.Resource)
+	for k, t := range resourceMap {
+		if t.Done || t.Shared {
+			done[k] = t
+		}
+	}
+	if cycle := findDependencyCycle(resourceMap, buildDependencyMap(resourceMap), done); cycle != nil {
+		return dependencyCycleError(cycle)
+	}
+	return nil
+}
+
+func dependencyCycleError(cycle []string) error {
+	return fmt.Errorf("resource dependencies form a cycle, so none of its resources can be deleted: %s", strings.Join(cycle, " -> "))
+}
+
+// findDependencyCycle returns the keys of the resources in a dependency cycle, starting and ending with the same key,
+// or nil if the resources can be deleted in some order.
+// The resources in done don't wait for anything, and dependencies on resources not in resourceMap are ignored,
+// because ValidateDependencies reports them.
+func findDependencyCycle(resourceMap map[string]*resources.Resource, depMap map[string][]string, done map[string]*resources.Resource) []string {
+	// Sort topologically (Kahn's algorithm); the resources that can't be sorted are in, or wait on, a cycle
+	waiting := make(map[string]int)
+	dependents := make(map[string][]string)
+	var ready []string
+	for _, k := range maps.SortedKeys(resourceMap) {
+		if _, d := done[k]; !d {
+			for _, dep := range sets.NewString(depMap[k]...).List() {
+				if _, found := resourceMap[dep]; !found {
+					continue
+				}
+				if _, d := done[dep]; d {
+					continue
+				}
+				waiting[k]++
+				dependents[dep] = append(dependents[dep], k)
+			}
+		}
+		if waiting[k] == 0 {
+			ready = append(ready, k)
+		}
+	}
+	for len(ready) != 0 {
+		k := ready[0]
+		ready = ready[1:]
+		for _, dependent := range dependents[k] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	// Each unsorted resource waits on another unsorted resource, so following those dependencies must come back round
+	var start string
+	for _, k := range maps.SortedKeys(resourceMap) {
+		if waiting[k] > 0 {
+			start = k
+			break
+		}
+	}
+	if start == "" {
+		return nil
+	}
+	position := make(map[string]int)
+	var path []string
+	for k := start; ; {
+		if i, visited := position[k]; visited {
+			return append(path[i:], k)
+		}
+		position[k] = len(path)
+		path = append(path, k)
+		for _, dep := range sets.NewString(depMap[k]...).List() {
+			if waiting[dep] > 0 {
+				k = dep
+				break
+			}
+		}
+	}
+}
+
+// orderGroups returns the keys of the groups in the order they should be started.
+// Groups are sorted by key, and the trackers in each group by ID, so that the order doesn't depend on map iteration.
+// If seed is non-zero, the groups are then shuffled using the seed.
+func orderGroups(groups map[string][]*resources.Resource, seed int64) []string {
+	var keys []string
+	for k, trackers := range groups {
+		sort.SliceStable(trackers, func(i, j int) bool {
+			return trackers[i].ID < trackers[j].ID
+		})
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if seed != 0 {
+		r := rand.New(rand.NewSource(seed))
+		r.Shuffle(len(keys), func(i, j int) {
+			keys[i], keys[j] = keys[j], keys[i]
+		})
+	}
+
+	return keys
+}
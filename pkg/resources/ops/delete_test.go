@@ -0,0 +1,1118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/pkg/resources"
+	awsresources "k8s.io/kops/pkg/resources/aws"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func buildGroups() map[string][]*resources.Resource {
+	groups := make(map[string][]*resources.Resource)
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("subnet-%d", i)
+		groups["_subnet:"+id] = []*resources.Resource{{Type: "subnet", ID: id}}
+	}
+	groups["subnet-1"] = []*resources.Resource{
+		{Type: "instance", ID: "i-2"},
+		{Type: "instance", ID: "i-1"},
+	}
+	return groups
+}
+
+func TestOrderGroupsSameSeed(t *testing.T) {
+	for _, seed := range []int64{0, 1, 42} {
+		first := orderGroups(buildGroups(), seed)
+		second := orderGroups(buildGroups(), seed)
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("expected the same order for seed %d, got %v and %v", seed, first, second)
+		}
+	}
+}
+
+func TestOrderGroupsSortsTrackers(t *testing.T) {
+	groups := buildGroups()
+	orderGroups(groups, 1)
+
+	var ids []string
+	for _, t := range groups["subnet-1"] {
+		ids = append(ids, t.ID)
+	}
+	expected := []string{"i-1", "i-2"}
+	if !reflect.DeepEqual(expected, ids) {
+		t.Errorf("expected trackers in group to be sorted by ID: expected=%v, actual=%v", expected, ids)
+	}
+}
+
+func TestEstimateDeletionTimeSerialNatGateways(t *testing.T) {
+	natDuration := DeletionDurations["nat-gateway"]
+	resourceMap := map[string]*resources.Resource{
+		"nat-gateway:nat-1": {Type: "nat-gateway", ID: "nat-1", Blocks: []string{"nat-gateway:nat-2"}},
+		"nat-gateway:nat-2": {Type: "nat-gateway", ID: "nat-2"},
+		"subnet:subnet-1":   {Type: "subnet", ID: "subnet-1", Blocked: []string{"nat-gateway:nat-2"}},
+	}
+
+	actual := EstimateDeletionTime(resourceMap)
+	expected := 2*natDuration + DefaultDeletionDuration
+	if actual != expected {
+		t.Errorf("unexpected estimate: actual=%v, expected=%v", actual, expected)
+	}
+}
+
+// recordingConditionWriter is a DeleteConditionWriter that records the conditions it is given
+type recordingConditionWriter struct {
+	conditions []DeleteCondition
+}
+
+func (w *recordingConditionWriter) SetDeleteCondition(condition DeleteCondition) error {
+	w.conditions = append(w.conditions, condition)
+	return nil
+}
+
+func TestSummarizeShared(t *testing.T) {
+	trackers := []*resources.Resource{
+		{Type: "route-table", ID: "rtb-owned"},
+		{Type: "route-table", ID: "rtb-shared2", Shared: true},
+		{Type: "route-table", ID: "rtb-shared1", Shared: true},
+		{Type: "volume", ID: "vol-owned"},
+		{Type: "volume", ID: "vol-shared", Shared: true},
+		{Type: "subnet", ID: "subnet-owned"},
+	}
+
+	actual := SummarizeShared(trackers)
+	expected := map[string][]string{
+		"route-table": {"rtb-shared1", "rtb-shared2"},
+		"volume":      {"vol-shared"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected summary: actual=%v, expected=%v", actual, expected)
+	}
+
+	if actual := SummarizeShared(trackers[:1]); len(actual) != 0 {
+		t.Errorf("expected no shared resources, got %v", actual)
+	}
+}
+
+func TestCountByType(t *testing.T) {
+	trackers := []*resources.Resource{
+		{Type: "route-table", ID: "rtb-owned"},
+		{Type: "route-table", ID: "rtb-shared", Shared: true},
+		{Type: "vpc", ID: "vpc-shared", Shared: true},
+		{Type: "iam-role", ID: "role-1"},
+		{Type: "iam-role", ID: "role-2"},
+		{Type: "iam-role", ID: "role-3", Done: true},
+	}
+
+	actual := CountByType(trackers)
+	expected := map[string]int{
+		"route-table": 2,
+		"vpc":         1,
+		"iam-role":    3,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected counts: actual=%v, expected=%v", actual, expected)
+	}
+
+	if actual := CountByType(nil); len(actual) != 0 {
+		t.Errorf("expected no counts, got %v", actual)
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	resourceMap := map[string]*resources.Resource{
+		"instance:i-1":    {Type: "instance", ID: "i-1", Blocks: []string{"subnet:subnet-1"}},
+		"subnet:subnet-1": {Type: "subnet", ID: "subnet-1", Blocks: []string{"vpc:vpc-1"}},
+		"vpc:vpc-1":       {Type: "vpc", ID: "vpc-1"},
+		"volume:vol-1":    {Type: "volume", ID: "vol-1", Blocked: []string{"instance:i-1"}},
+	}
+	if err := ValidateDependencies(resourceMap); err != nil {
+		t.Fatalf("unexpected error validating dependencies: %v", err)
+	}
+
+	resourceMap["route-table:rtb-1"] = &resources.Resource{Type: "route-table", ID: "rtb-1", Blocks: []string{"vcp:vpc-1"}}
+	resourceMap["volume:vol-2"] = &resources.Resource{Type: "volume", ID: "vol-2", Blocked: []string{"instance:i-2"}}
+	err := ValidateDependencies(resourceMap)
+	if err == nil {
+		t.Fatalf("expected an error for the dangling references")
+	}
+	expected := "unresolved resource dependencies: route-table:rtb-1 blocks vcp:vpc-1, which was not found; volume:vol-2 is blocked by instance:i-2, which was not found"
+	if err.Error() != expected {
+		t.Errorf("unexpected error: actual=%q, expected=%q", err.Error(), expected)
+	}
+}
+
+func TestCheckDependencyCycles(t *testing.T) {
+	resourceMap := map[string]*resources.Resource{
+		"instance:i-1":    {Type: "instance", ID: "i-1", Blocks: []string{"subnet:subnet-1"}},
+		"subnet:subnet-1": {Type: "subnet", ID: "subnet-1", Blocks: []string{"vpc:vpc-1"}},
+		"vpc:vpc-1":       {Type: "vpc", ID: "vpc-1", Shared: true, Blocks: []string{"instance:i-1"}},
+	}
+	// The cycle goes through a shared resource, which isn't waited for
+	if err := CheckDependencyCycles(resourceMap); err != nil {
+		t.Fatalf("unexpected error checking dependency cycles: %v", err)
+	}
+
+	// a -> b -> a, with a resource waiting on the cycle
+	resourceMap["route-table:rtb-a"] = &resources.Resource{Type: "route-table", ID: "rtb-a", Blocks: []string{"route-table:rtb-b"}}
+	resourceMap["route-table:rtb-b"] = &resources.Resource{Type: "route-table", ID: "rtb-b", Blocks: []string{"route-table:rtb-a"}}
+	resourceMap["route-table:rtb-c"] = &resources.Resource{Type: "route-table", ID: "rtb-c", Blocked: []string{"route-table:rtb-b"}}
+	err := CheckDependencyCycles(resourceMap)
+	if err == nil {
+		t.Fatalf("expected an error for the dependency cycle")
+	}
+	if !strings.Contains(err.Error(), "route-table:rtb-a -> route-table:rtb-b -> route-table:rtb-a") {
+		t.Errorf("expected the error to name the resources in the cycle, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "rtb-c") {
+		t.Errorf("expected the error to name only the resources in the cycle, got: %v", err)
+	}
+
+	// The deletion fails up front, rather than waiting for the cycle to make progress
+	deleted := 0
+	for _, r := range resourceMap {
+		r.Deleter = func(cloud fi.Cloud, r *resources.Resource) error {
+			deleted++
+			return nil
+		}
+	}
+	options := &DeleteOptions{Out: &bytes.Buffer{}}
+	if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected deleting the resources to fail on the cycle, got: %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("expected no resources to be deleted, got %d", deleted)
+	}
+}
+
+func TestWriteDeleteConditions(t *testing.T) {
+	report := &DeleteReport{
+		Results: map[string]*DeleteResult{
+			"route-table:rtb-1": {Type: "route-table", ID: "rtb-1", Deleted: true},
+			"route-table:rtb-2": {Type: "route-table", ID: "rtb-2", Deleted: true},
+			"subnet:subnet-1":   {Type: "subnet", ID: "subnet-1", Error: fmt.Errorf("DependencyViolation")},
+			"vpc:vpc-1":         {Type: "vpc", ID: "vpc-1"},
+		},
+	}
+
+	w := &recordingConditionWriter{}
+	if err := WriteDeleteConditions(report, w); err != nil {
+		t.Fatalf("error writing conditions: %v", err)
+	}
+
+	expected := []DeleteCondition{
+		{ResourceType: "route-table", Status: DeleteConditionReady},
+		{ResourceType: "subnet", Status: DeleteConditionFailed, Message: "subnet-1: DependencyViolation"},
+		{ResourceType: "vpc", Status: DeleteConditionFailed, Message: "vpc-1: not attempted"},
+	}
+	if !reflect.DeepEqual(w.conditions, expected) {
+		t.Errorf("unexpected conditions: actual=%v, expected=%v", w.conditions, expected)
+	}
+}
+
+func TestDeleteConditionsSkippedResources(t *testing.T) {
+	deleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		return nil
+	}
+	resourceMap := map[string]*resources.Resource{
+		"vpc:vpc-1":       {Type: "vpc", ID: "vpc-1", Deleter: deleter, Shared: true},
+		"subnet:subnet-1": {Type: "subnet", ID: "subnet-1", Deleter: deleter, Blocks: []string{"vpc:vpc-1"}},
+		"subnet:subnet-2": {Type: "subnet", ID: "subnet-2", Deleter: deleter, Blocks: []string{"vpc:vpc-1"}},
+	}
+
+	report := &DeleteReport{}
+	options := &DeleteOptions{
+		Out:          &bytes.Buffer{},
+		Report:       report,
+		ProtectedIDs: []string{"subnet-2"},
+	}
+	if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+
+	// The shared and protected resources were left alone on purpose, so they don't fail the deletion
+	expected := []DeleteCondition{
+		{ResourceType: "subnet", Status: DeleteConditionReady, Message: "subnet-2: skipped, protected"},
+		{ResourceType: "vpc", Status: DeleteConditionReady, Message: "vpc-1: skipped, shared"},
+	}
+	if actual := DeleteConditions(report); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected conditions: actual=%v, expected=%v", actual, expected)
+	}
+}
+
+func TestDeleteResourcesRunID(t *testing.T) {
+	deleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		return nil
+	}
+	resourceMap := map[string]*resources.Resource{
+		"route-table:rtb-1": {Type: "route-table", ID: "rtb-1", Deleter: deleter},
+		"subnet:subnet-1":   {Type: "subnet", ID: "subnet-1", Deleter: deleter, Blocks: []string{"route-table:rtb-1"}},
+	}
+
+	var out bytes.Buffer
+	report := &DeleteReport{}
+	options := &DeleteOptions{
+		Out:    &out,
+		Report: report,
+	}
+	if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+
+	if report.RunID == "" {
+		t.Fatalf("expected a run id to be generated")
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(resourceMap) {
+		t.Fatalf("expected a line per resource, got: %q", out.String())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "["+report.RunID+"] ") {
+			t.Errorf("expected line to be prefixed with run id %q, got: %q", report.RunID, line)
+		}
+	}
+}
+
+func TestDeleteReportDangling(t *testing.T) {
+	deleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		return nil
+	}
+	failingDeleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		return fmt.Errorf("DependencyViolation")
+	}
+	resourceMap := map[string]*resources.Resource{
+		"instance:i-1":      {Type: "instance", ID: "i-1", Deleter: deleter, Blocks: []string{"subnet:subnet-1"}},
+		"subnet:subnet-1":   {Type: "subnet", ID: "subnet-1", Deleter: failingDeleter, Blocks: []string{"vpc:vpc-1"}},
+		"vpc:vpc-1":         {Type: "vpc", ID: "vpc-1", Deleter: deleter},
+		"instance:i-2":      {Type: "instance", ID: "i-2", Deleter: failingDeleter, Blocks: []string{"subnet:subnet-2"}},
+		"subnet:subnet-2":   {Type: "subnet", ID: "subnet-2", Deleter: deleter},
+		"keypair:keypair-1": {Type: "keypair", ID: "keypair-1", Deleter: deleter},
+	}
+
+	report := &DeleteReport{}
+	options := &DeleteOptions{
+		Count:  1,
+		Out:    &bytes.Buffer{},
+		Report: report,
+	}
+	if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err == nil {
+		t.Fatalf("expected deletion to give up")
+	}
+
+	expected := []string{"subnet:subnet-1"}
+	if actual := report.Dangling(resourceMap); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected dangling resources: actual=%v, expected=%v", actual, expected)
+	}
+}
+
+func TestDeleteResourcesRetryPolicies(t *testing.T) {
+	dependencyViolation := awserr.New("DependencyViolation", "resource has a dependent object", nil)
+	grid := []struct {
+		name             string
+		resourceType     string
+		err              error
+		expectedAttempts int
+		expectedElapsed  time.Duration
+	}{
+		{
+			name:             "route table",
+			resourceType:     "route-table",
+			err:              dependencyViolation,
+			expectedAttempts: 3,
+			expectedElapsed:  time.Second + 2*time.Second,
+		},
+		{
+			name:             "nat gateway",
+			resourceType:     awsresources.TypeNatGateway,
+			err:              dependencyViolation,
+			expectedAttempts: 5,
+			expectedElapsed:  15*time.Second + 30*time.Second + time.Minute + 2*time.Minute,
+		},
+		{
+			name:             "default",
+			resourceType:     "volume",
+			err:              dependencyViolation,
+			expectedAttempts: 4,
+			expectedElapsed:  time.Second + 2*time.Second + 4*time.Second,
+		},
+		{
+			name:             "throttled",
+			resourceType:     "volume",
+			err:              awserr.New("RequestLimitExceeded", "request limit exceeded", nil),
+			expectedAttempts: 4,
+			expectedElapsed:  time.Second + 2*time.Second + 4*time.Second,
+		},
+		{
+			name:             "not retryable",
+			resourceType:     awsresources.TypeNatGateway,
+			err:              awserr.New("InvalidParameterValue", "invalid value", nil),
+			expectedAttempts: 1,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			attempts := 0
+			deleter := func(cloud fi.Cloud, r *resources.Resource) error {
+				attempts++
+				return g.err
+			}
+			resourceMap := map[string]*resources.Resource{
+				g.resourceType + ":id-1": {Type: g.resourceType, ID: "id-1", Deleter: deleter},
+			}
+
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			clk := clocktesting.NewFakeClock(start)
+			options := &DeleteOptions{
+				Count: 1,
+				Out:   &bytes.Buffer{},
+				Clock: clk,
+			}
+			if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err == nil {
+				t.Fatalf("expected deletion to give up")
+			}
+
+			// Giving up after one pass with no progress means the resource is deleted in two passes
+			if attempts != 2*g.expectedAttempts {
+				t.Errorf("unexpected number of attempts: actual=%d, expected=%d", attempts, 2*g.expectedAttempts)
+			}
+			if elapsed := clk.Since(start); elapsed != 2*g.expectedElapsed {
+				t.Errorf("unexpected time between attempts: actual=%v, expected=%v", elapsed, 2*g.expectedElapsed)
+			}
+		})
+	}
+}
+
+func TestDeleteResourcesRetryDoesNotHoldUpOthers(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clocktesting.NewFakeClock(start)
+
+	var mutex sync.Mutex
+	deletedAt := make(map[string]time.Duration)
+	natAttempts := 0
+	natDeleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		natAttempts++
+		if natAttempts == 1 {
+			return awserr.New("DependencyViolation", "resource has a dependent object", nil)
+		}
+		deletedAt[r.Type+":"+r.ID] = clk.Since(start)
+		return nil
+	}
+	deleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		deletedAt[r.Type+":"+r.ID] = clk.Since(start)
+		return nil
+	}
+	resourceMap := map[string]*resources.Resource{
+		"nat-gateway:nat-a": {Type: awsresources.TypeNatGateway, ID: "nat-a", Deleter: natDeleter},
+		"volume:vol-b":      {Type: "volume", ID: "vol-b", Deleter: deleter, Blocks: []string{"subnet:subnet-c"}},
+		"subnet:subnet-c":   {Type: "subnet", ID: "subnet-c", Deleter: deleter, Blocked: []string{"volume:vol-b"}},
+	}
+
+	options := &DeleteOptions{
+		Out:   &bytes.Buffer{},
+		Clock: clk,
+	}
+	if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err != nil {
+		t.Fatalf("unexpected error deleting resources: %v", err)
+	}
+
+	// The subnet became ready while the NAT gateway was waiting to be retried, and didn't wait for it
+	expected := map[string]time.Duration{
+		"nat-gateway:nat-a": 15 * time.Second,
+		"volume:vol-b":      0,
+		"subnet:subnet-c":   0,
+	}
+	if !reflect.DeepEqual(deletedAt, expected) {
+		t.Errorf("unexpected deletion times: actual=%v, expected=%v", deletedAt, expected)
+	}
+}
+
+// recordingObserver is a DeletionObserver that records the events
+type recordingObserver struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (o *recordingObserver) record(event string, r *resources.Resource) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.events = append(o.events, event+" "+r.Type+":"+r.ID)
+}
+
+func (o *recordingObserver) OnStart(r *resources.Resource)  { o.record("start", r) }
+func (o *recordingObserver) OnDelete(r *resources.Resource) { o.record("delete", r) }
+func (o *recordingObserver) OnSkip(r *resources.Resource, reason string) {
+	o.record("skip("+reason+")", r)
+}
+func (o *recordingObserver) OnError(r *resources.Resource, err error) { o.record("error", r) }
+
+func TestDeleteResourcesObserver(t *testing.T) {
+	deleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		return nil
+	}
+	attempts := 0
+	failOnceDeleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		attempts++
+		if attempts == 1 {
+			return fmt.Errorf("DependencyViolation")
+		}
+		return nil
+	}
+	resourceMap := map[string]*resources.Resource{
+		"instance:i-1":    {Type: "instance", ID: "i-1", Deleter: deleter, Blocks: []string{"subnet:subnet-1"}},
+		"subnet:subnet-1": {Type: "subnet", ID: "subnet-1", Deleter: failOnceDeleter, Blocks: []string{"vpc:vpc-1"}},
+		"vpc:vpc-1":       {Type: "vpc", ID: "vpc-1", Deleter: deleter, Shared: true},
+	}
+
+	observer := &recordingObserver{}
+	options := &DeleteOptions{
+		Out:      &bytes.Buffer{},
+		Observer: observer,
+	}
+	if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+
+	expected := []string{
+		"skip(shared) vpc:vpc-1",
+		"start instance:i-1",
+		"delete instance:i-1",
+		"start subnet:subnet-1",
+		"error subnet:subnet-1",
+		"start subnet:subnet-1",
+		"delete subnet:subnet-1",
+	}
+	if !reflect.DeepEqual(observer.events, expected) {
+		t.Errorf("unexpected events: actual=%v, expected=%v", observer.events, expected)
+	}
+}
+
+func TestDeleteResourcesBestEffort(t *testing.T) {
+	var mutex sync.Mutex
+	deleted := make(map[string]bool)
+	deleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		deleted[r.Type+":"+r.ID] = true
+		return nil
+	}
+	errAccessDenied := fmt.Errorf("access denied")
+	failingDeleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		return errAccessDenied
+	}
+	resourceMap := map[string]*resources.Resource{
+		"instance:i-a":    {Type: "instance", ID: "i-a", Deleter: failingDeleter, Blocks: []string{"subnet:subnet-b"}},
+		"subnet:subnet-b": {Type: "subnet", ID: "subnet-b", Deleter: deleter, Blocked: []string{"instance:i-a"}},
+		"volume:vol-c":    {Type: "volume", ID: "vol-c", Deleter: deleter},
+		"volume:vol-d":    {Type: "volume", ID: "vol-d", Deleter: deleter},
+	}
+
+	observer := &recordingObserver{}
+	options := &DeleteOptions{
+		Out:        &bytes.Buffer{},
+		Observer:   observer,
+		BestEffort: true,
+	}
+	err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options)
+
+	var failures *DeleteFailuresError
+	if !errors.As(err, &failures) {
+		t.Fatalf("expected a DeleteFailuresError, got %v", err)
+	}
+	if len(failures.Failures) != 1 || failures.Failures["instance:i-a"] == nil {
+		t.Errorf("unexpected failures: %v", failures.Failures)
+	}
+	if !errors.Is(err, errAccessDenied) {
+		t.Errorf("expected the error to wrap the deleter's error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "instance:i-a: access denied") {
+		t.Errorf("expected the error to list the failed resource, got %q", err.Error())
+	}
+
+	for _, k := range []string{"volume:vol-c", "volume:vol-d"} {
+		if !deleted[k] {
+			t.Errorf("expected %s to be deleted", k)
+		}
+	}
+	if deleted["subnet:subnet-b"] {
+		t.Errorf("did not expect subnet:subnet-b to be deleted")
+	}
+
+	found := false
+	for _, event := range observer.events {
+		if event == "start subnet:subnet-b" {
+			t.Errorf("did not expect an attempt to delete subnet:subnet-b")
+		}
+		if event == "skip(dependency failed) subnet:subnet-b" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected subnet:subnet-b to be skipped, events=%v", observer.events)
+	}
+}
+
+func TestDeleteResourcesTerminalError(t *testing.T) {
+	var mutex sync.Mutex
+	attempts := make(map[string]int)
+	deleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		attempts[r.Type+":"+r.ID]++
+		return nil
+	}
+	refusingDeleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		attempts[r.Type+":"+r.ID]++
+		return &awsresources.DeletionError{Type: r.Type, ID: r.ID, Err: fmt.Errorf("refusing to disassociate"), Terminal: true}
+	}
+	resourceMap := map[string]*resources.Resource{
+		"route-table:rtb-a": {Type: "route-table", ID: "rtb-a", Deleter: refusingDeleter, Blocks: []string{"vpc:vpc-b"}},
+		"vpc:vpc-b":         {Type: "vpc", ID: "vpc-b", Deleter: deleter, Blocked: []string{"route-table:rtb-a"}},
+		"volume:vol-c":      {Type: "volume", ID: "vol-c", Deleter: deleter},
+	}
+
+	// Even without BestEffort, a terminal error is not retried
+	options := &DeleteOptions{
+		Out: &bytes.Buffer{},
+	}
+	err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options)
+
+	var failures *DeleteFailuresError
+	if !errors.As(err, &failures) {
+		t.Fatalf("expected a DeleteFailuresError, got %v", err)
+	}
+	if len(failures.Failures) != 1 || failures.Failures["route-table:rtb-a"] == nil {
+		t.Errorf("unexpected failures: %v", failures.Failures)
+	}
+	if attempts["route-table:rtb-a"] != 1 {
+		t.Errorf("expected the route table to be attempted once, got %d", attempts["route-table:rtb-a"])
+	}
+	if attempts["volume:vol-c"] != 1 {
+		t.Errorf("expected the volume to be deleted")
+	}
+	if attempts["vpc:vpc-b"] != 0 {
+		t.Errorf("did not expect the vpc to be deleted")
+	}
+}
+
+func TestDeleteResourcesProtectedIDs(t *testing.T) {
+	deleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		return nil
+	}
+	protectedDeleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		t.Errorf("unexpected deletion of protected resource %s:%s", r.Type, r.ID)
+		return nil
+	}
+	resourceMap := map[string]*resources.Resource{
+		"subnet:subnet-1": {Type: "subnet", ID: "subnet-1", Deleter: deleter, Blocks: []string{"vpc:vpc-1"}},
+		"vpc:vpc-1":       {Type: "vpc", ID: "vpc-1", Deleter: protectedDeleter},
+	}
+
+	observer := &recordingObserver{}
+	out := &bytes.Buffer{}
+	report := &DeleteReport{}
+	options := &DeleteOptions{
+		Out:          out,
+		Observer:     observer,
+		Report:       report,
+		ProtectedIDs: []string{"vpc-1"},
+	}
+	if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+
+	expected := []string{
+		"skip(protected) vpc:vpc-1",
+		"start subnet:subnet-1",
+		"delete subnet:subnet-1",
+	}
+	if !reflect.DeepEqual(observer.events, expected) {
+		t.Errorf("unexpected events: actual=%v, expected=%v", observer.events, expected)
+	}
+	if report.Results["vpc:vpc-1"].Deleted {
+		t.Errorf("expected protected vpc to be reported as not deleted")
+	}
+	if !strings.Contains(out.String(), "not deleting protected resource vpc:vpc-1") {
+		t.Errorf("expected protected vpc in output, got %q", out.String())
+	}
+}
+
+func TestDeleteResourcesProgress(t *testing.T) {
+	deleter := func(cloud fi.Cloud, r *resources.Resource) error {
+		return nil
+	}
+	resourceMap := map[string]*resources.Resource{
+		"instance:i-1":    {Type: "instance", ID: "i-1", Deleter: deleter, Blocks: []string{"subnet:subnet-1"}},
+		"instance:i-2":    {Type: "instance", ID: "i-2", Deleter: deleter, Blocks: []string{"subnet:subnet-1"}},
+		"subnet:subnet-1": {Type: "subnet", ID: "subnet-1", Deleter: deleter, Blocks: []string{"vpc:vpc-1"}},
+		"vpc:vpc-1":       {Type: "vpc", ID: "vpc-1", Deleter: deleter},
+		"dhcp:dopt-1":     {Type: "dhcp", ID: "dopt-1", Deleter: deleter, Shared: true},
+	}
+
+	var progress []DeleteProgress
+	options := &DeleteOptions{
+		Out: &bytes.Buffer{},
+		Progress: func(p DeleteProgress) {
+			progress = append(progress, p)
+		},
+	}
+	if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+
+	// One report for the skipped resources, then one per deletion
+	if len(progress) != 5 {
+		t.Fatalf("unexpected number of progress reports: %v", progress)
+	}
+	if expected := (DeleteProgress{Total: 5, Completed: 1, Ready: 2}); progress[0] != expected {
+		t.Errorf("unexpected initial progress: actual=%+v, expected=%+v", progress[0], expected)
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i].Total != 5 || progress[i].Completed != progress[i-1].Completed+1 {
+			t.Errorf("unexpected progress after %d deletions: %+v", i, progress[i])
+		}
+	}
+	last := progress[len(progress)-1]
+	if last.Completed != last.Total || last.Ready != 0 || last.Percent() != 100 {
+		t.Errorf("unexpected final progress: %+v", last)
+	}
+}
+
+// concurrencyRecorder is a deleter that records the largest number of deletions running at the same time
+type concurrencyRecorder struct {
+	mutex       sync.Mutex
+	inFlight    int
+	maxInFlight int
+	// waitFor, if set, holds each deletion until that many deletions are running, or until a timeout
+	waitFor int
+}
+
+func (c *concurrencyRecorder) Delete(cloud fi.Cloud, r *resources.Resource) error {
+	c.mutex.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.mutex.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mutex.Lock()
+		reached := c.waitFor == 0 || c.maxInFlight >= c.waitFor
+		c.mutex.Unlock()
+		if reached {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(time.Millisecond)
+
+	c.mutex.Lock()
+	c.inFlight--
+	c.mutex.Unlock()
+	return nil
+}
+
+func buildIndependentResources(deleter func(cloud fi.Cloud, r *resources.Resource) error, n int) map[string]*resources.Resource {
+	resourceMap := make(map[string]*resources.Resource)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("subnet-%d", i)
+		resourceMap["subnet:"+id] = &resources.Resource{Type: "subnet", ID: id, Deleter: deleter}
+	}
+	return resourceMap
+}
+
+func TestDeleteResourcesMaxConcurrentDeletesSequential(t *testing.T) {
+	recorder := &concurrencyRecorder{}
+	resourceMap := buildIndependentResources(recorder.Delete, 10)
+
+	options := &DeleteOptions{
+		Out:                  &bytes.Buffer{},
+		MaxConcurrentDeletes: 1,
+	}
+	if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+
+	if recorder.maxInFlight != 1 {
+		t.Errorf("expected deletions to run one at a time, got %d at the same time", recorder.maxInFlight)
+	}
+}
+
+func TestDeleteResourcesMaxConcurrentDeletes(t *testing.T) {
+	recorder := &concurrencyRecorder{waitFor: 3}
+	resourceMap := buildIndependentResources(recorder.Delete, 10)
+
+	options := &DeleteOptions{
+		Out:                  &bytes.Buffer{},
+		MaxConcurrentDeletes: 3,
+	}
+	if err := DeleteResourcesWithOptions(awsup.BuildMockAWSCloud("us-east-1", "a"), resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+
+	if recorder.maxInFlight != 3 {
+		t.Errorf("expected 3 deletions to run at the same time, got %d", recorder.maxInFlight)
+	}
+}
+
+func TestDeleteResourcesNatGatewayBeforeSubnet(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddSubnet(&ec2.Subnet{
+		SubnetId: aws.String("subnet-1234"),
+		VpcId:    aws.String("vpc-1234"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String(ownershipTagKey), Value: aws.String("owned")},
+		},
+	})
+	// The NAT gateway is found through the cluster's route tables, not the subnet
+	if _, err := c.CreateNatGatewayWithId(&ec2.CreateNatGatewayInput{SubnetId: aws.String("subnet-1234")}, "nat-1234"); err != nil {
+		t.Fatalf("error creating NAT gateway: %v", err)
+	}
+
+	subnets, err := awsresources.ListSubnets(cloud, "vpc-1234", clusterName)
+	if err != nil {
+		t.Fatalf("error listing subnets: %v", err)
+	}
+	resourceMap := map[string]*resources.Resource{
+		"vpc:vpc-1234": {Type: "vpc", ID: "vpc-1234", Shared: true},
+		awsresources.TypeNatGateway + ":nat-1234": {
+			Type:    awsresources.TypeNatGateway,
+			ID:      "nat-1234",
+			Deleter: awsresources.DeleteNatGateway,
+		},
+	}
+	for _, r := range subnets {
+		resourceMap[r.Type+":"+r.ID] = r
+	}
+
+	observer := &recordingObserver{}
+	options := &DeleteOptions{
+		Out:      &bytes.Buffer{},
+		Observer: observer,
+	}
+	if err := DeleteResourcesWithOptions(cloud, resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+
+	// The subnet would fail with a dependency violation if it was deleted first
+	expected := []string{
+		"skip(shared) vpc:vpc-1234",
+		"start nat-gateway:nat-1234",
+		"delete nat-gateway:nat-1234",
+		"start subnet:subnet-1234",
+		"delete subnet:subnet-1234",
+	}
+	if !reflect.DeepEqual(observer.events, expected) {
+		t.Errorf("unexpected events: actual=%v, expected=%v", observer.events, expected)
+	}
+}
+
+func TestDeleteResourcesElasticIPAfterNatGateway(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddSubnet(&ec2.Subnet{
+		SubnetId: aws.String("subnet-1234"),
+		VpcId:    aws.String("vpc-1234"),
+	})
+	if _, err := c.AllocateAddressWithId(&ec2.AllocateAddressInput{
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeElasticIp),
+				Tags: []*ec2.Tag{
+					{Key: aws.String(ownershipTagKey), Value: aws.String("owned")},
+				},
+			},
+		},
+	}, "eipalloc-1234"); err != nil {
+		t.Fatalf("error allocating address: %v", err)
+	}
+	if _, err := c.CreateNatGatewayWithId(&ec2.CreateNatGatewayInput{
+		SubnetId:     aws.String("subnet-1234"),
+		AllocationId: aws.String("eipalloc-1234"),
+	}, "nat-1234"); err != nil {
+		t.Fatalf("error creating NAT gateway: %v", err)
+	}
+
+	eips, err := awsresources.ListElasticIPs(cloud, "vpc-1234", clusterName, nil)
+	if err != nil {
+		t.Fatalf("error listing elastic IPs: %v", err)
+	}
+	if len(eips) != 1 {
+		t.Fatalf("expected exactly one elastic IP, got %d", len(eips))
+	}
+	if expected := []string{awsresources.TypeNatGateway + ":nat-1234"}; !reflect.DeepEqual(eips[0].Blocked, expected) {
+		t.Errorf("unexpected blocked: actual=%v, expected=%v", eips[0].Blocked, expected)
+	}
+
+	// The NAT gateway doesn't block the address itself, so only the address's dependency orders them
+	resourceMap := map[string]*resources.Resource{
+		awsresources.TypeNatGateway + ":nat-1234": {
+			Type:    awsresources.TypeNatGateway,
+			ID:      "nat-1234",
+			Deleter: awsresources.DeleteNatGateway,
+		},
+	}
+	for _, r := range eips {
+		resourceMap[r.Type+":"+r.ID] = r
+	}
+
+	observer := &recordingObserver{}
+	options := &DeleteOptions{
+		Out:      &bytes.Buffer{},
+		Observer: observer,
+	}
+	if err := DeleteResourcesWithOptions(cloud, resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+
+	// Releasing the address would fail with InvalidIPAddress.InUse if it was attempted first
+	expected := []string{
+		"start nat-gateway:nat-1234",
+		"delete nat-gateway:nat-1234",
+		"start elastic-ip:eipalloc-1234",
+		"delete elastic-ip:eipalloc-1234",
+	}
+	if !reflect.DeepEqual(observer.events, expected) {
+		t.Errorf("unexpected events: actual=%v, expected=%v", observer.events, expected)
+	}
+	if len(c.Addresses) != 0 {
+		t.Errorf("expected the address to be released, got %v", c.Addresses)
+	}
+}
+
+func TestBuildDeletionPlan(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	ownershipTagKey := "kubernetes.io/cluster/" + clusterName
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	c.AddSubnet(&ec2.Subnet{
+		SubnetId: aws.String("subnet-1234"),
+		VpcId:    aws.String("vpc-1234"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String(ownershipTagKey), Value: aws.String("owned")},
+		},
+	})
+	c.AddRouteTable(&ec2.RouteTable{
+		RouteTableId: aws.String("rtb-1234"),
+		VpcId:        aws.String("vpc-1234"),
+		Associations: []*ec2.RouteTableAssociation{
+			{RouteTableAssociationId: aws.String("rtbassoc-1234"), RouteTableId: aws.String("rtb-1234"), SubnetId: aws.String("subnet-1234")},
+		},
+		Tags: []*ec2.Tag{
+			{Key: aws.String(ownershipTagKey), Value: aws.String("owned")},
+		},
+	})
+
+	resourceMap := map[string]*resources.Resource{
+		"vpc:vpc-1234": {Type: "vpc", ID: "vpc-1234", Shared: true},
+	}
+	subnets, err := awsresources.ListSubnets(cloud, "vpc-1234", clusterName)
+	if err != nil {
+		t.Fatalf("error listing subnets: %v", err)
+	}
+	routeTables, err := awsresources.ListRouteTables(cloud, "vpc-1234", clusterName)
+	if err != nil {
+		t.Fatalf("error listing route tables: %v", err)
+	}
+	for _, r := range append(subnets, routeTables...) {
+		resourceMap[r.Type+":"+r.ID] = r
+	}
+
+	plan := BuildDeletionPlan(resourceMap)
+
+	planned := make(map[string]*PlannedResource)
+	var keys []string
+	for _, r := range plan.Resources {
+		k := r.Type + ":" + r.ID
+		planned[k] = r
+		keys = append(keys, k)
+	}
+	if expected := []string{"route-table:rtb-1234", "subnet:subnet-1234", "vpc:vpc-1234"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("unexpected resources in plan: actual=%v, expected=%v", keys, expected)
+	}
+
+	// The route table can only be deleted once it is disassociated from the subnet, which happens when the subnet is deleted
+	if expected := []string{"subnet:subnet-1234"}; !reflect.DeepEqual(planned["route-table:rtb-1234"].DependsOn, expected) {
+		t.Errorf("unexpected dependencies of route table: actual=%v, expected=%v", planned["route-table:rtb-1234"].DependsOn, expected)
+	}
+	if expected := []string{"route-table:rtb-1234", "subnet:subnet-1234"}; !reflect.DeepEqual(planned["vpc:vpc-1234"].DependsOn, expected) {
+		t.Errorf("unexpected dependencies of vpc: actual=%v, expected=%v", planned["vpc:vpc-1234"].DependsOn, expected)
+	}
+	if !planned["vpc:vpc-1234"].Shared || planned["subnet:subnet-1234"].Shared {
+		t.Errorf("unexpected shared status: %+v, %+v", planned["vpc:vpc-1234"], planned["subnet:subnet-1234"])
+	}
+	if len(planned["subnet:subnet-1234"].Actions) == 0 {
+		t.Errorf("expected the actions to delete the subnet in the plan")
+	}
+
+	plan.MarkProtected([]string{"subnet-1234"})
+	if !planned["subnet:subnet-1234"].Protected || planned["route-table:rtb-1234"].Protected {
+		t.Errorf("unexpected protected status: %+v, %+v", planned["subnet:subnet-1234"], planned["route-table:rtb-1234"])
+	}
+}
+
+func TestDeleteResourcesFilteredToRouteTables(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+	owned := []*ec2.Tag{
+		{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+	}
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	if _, err := c.CreateVpcWithId(&ec2.CreateVpcInput{
+		CidrBlock: aws.String("10.0.0.0/16"),
+		TagSpecifications: []*ec2.TagSpecification{
+			{ResourceType: aws.String(ec2.ResourceTypeVpc), Tags: owned},
+		},
+	}, "vpc-1234"); err != nil {
+		t.Fatalf("error creating VPC: %v", err)
+	}
+	c.AddSubnet(&ec2.Subnet{
+		SubnetId: aws.String("subnet-1234"),
+		VpcId:    aws.String("vpc-1234"),
+		Tags:     owned,
+	})
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        aws.String("vpc-1234"),
+		RouteTableId: aws.String("rtb-1234"),
+		Associations: []*ec2.RouteTableAssociation{
+			{
+				RouteTableAssociationId: aws.String("rtbassoc-1234"),
+				RouteTableId:            aws.String("rtb-1234"),
+				SubnetId:                aws.String("subnet-1234"),
+			},
+		},
+		Tags: owned,
+	})
+
+	resourceMap, err := awsresources.ListResourcesFiltered(cloud, resources.ClusterInfo{Name: clusterName}, []string{ec2.ResourceTypeRouteTable})
+	if err != nil {
+		t.Fatalf("error listing resources: %v", err)
+	}
+	if _, found := resourceMap["route-table:rtb-1234"]; !found || len(resourceMap) != 1 {
+		t.Fatalf("expected only the route table to be listed, got %v", resourceMap)
+	}
+
+	// The subnet and the VPC aren't being deleted, so the route table mustn't wait for them
+	if err := ValidateDependencies(resourceMap); err != nil {
+		t.Errorf("unexpected unresolved dependencies: %v", err)
+	}
+
+	observer := &recordingObserver{}
+	options := &DeleteOptions{
+		Out:      &bytes.Buffer{},
+		Observer: observer,
+	}
+	if err := DeleteResourcesWithOptions(cloud, resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+
+	expected := []string{
+		"start route-table:rtb-1234",
+		"delete route-table:rtb-1234",
+	}
+	if !reflect.DeepEqual(observer.events, expected) {
+		t.Errorf("unexpected events: actual=%v, expected=%v", observer.events, expected)
+	}
+	if _, found := c.RouteTables["rtb-1234"]; found {
+		t.Errorf("expected route table to be deleted")
+	}
+	subnets, err := c.DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice([]string{"subnet-1234"})})
+	if err != nil || len(subnets.Subnets) != 1 {
+		t.Errorf("expected subnet to be left alone, got %v (err=%v)", subnets, err)
+	}
+}
+
+func TestDeleteResourcesUntaggedENIInOwnedVPC(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	clusterName := "me.example.com"
+
+	c := &mockec2.MockEC2{}
+	cloud.MockEC2 = c
+
+	if _, err := c.CreateVpcWithId(&ec2.CreateVpcInput{
+		CidrBlock: aws.String("10.0.0.0/16"),
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeVpc),
+				Tags:         []*ec2.Tag{{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")}},
+			},
+		},
+	}, "vpc-1234"); err != nil {
+		t.Fatalf("error creating VPC: %v", err)
+	}
+	// e.g. left behind by the CNI, which doesn't tag its ENIs
+	c.AddNetworkInterface(&ec2.NetworkInterface{
+		NetworkInterfaceId: aws.String("eni-untagged"),
+		VpcId:              aws.String("vpc-1234"),
+		SubnetId:           aws.String("subnet-1234"),
+		Status:             aws.String(ec2.NetworkInterfaceStatusAvailable),
+	})
+
+	resourceMap, err := awsresources.ListResourcesFiltered(cloud, resources.ClusterInfo{Name: clusterName}, []string{ec2.ResourceTypeNetworkInterface})
+	if err != nil {
+		t.Fatalf("error listing resources: %v", err)
+	}
+	if r := resourceMap["network-interface:eni-untagged"]; r == nil || r.Shared {
+		t.Fatalf("expected the untagged ENI to be listed as owned, got %v", resourceMap)
+	}
+
+	options := &DeleteOptions{Out: &bytes.Buffer{}}
+	if err := DeleteResourcesWithOptions(cloud, resourceMap, options); err != nil {
+		t.Fatalf("error deleting resources: %v", err)
+	}
+	if c.FindNetworkInterface("eni-untagged") != nil {
+		t.Errorf("expected the untagged ENI to be deleted")
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"time"
+
+	"k8s.io/kops/pkg/resources"
+	awsresources "k8s.io/kops/pkg/resources/aws"
+)
+
+// DefaultDeletionDuration is the time assumed to delete a resource whose type isn't in DeletionDurations
+var DefaultDeletionDuration = 5 * time.Second
+
+// DeletionDurations are the typical times taken to delete a resource, by resource type.
+// They are used by EstimateDeletionTime, and can be adjusted by callers.
+var DeletionDurations = map[string]time.Duration{
+	awsresources.TypeNatGateway:              2 * time.Minute,
+	awsresources.TypeLoadBalancer:            30 * time.Second,
+	"autoscaling-group":                      time.Minute,
+	"instance":                               time.Minute,
+	"network-interface":                      20 * time.Second,
+	"vpc":                                    10 * time.Second,
+	awsresources.TypeAutoscalingLaunchConfig: 5 * time.Second,
+}
+
+// EstimateDeletionTime returns roughly how long DeleteResources will take to delete the resources.
+// Resources are deleted in tiers: a resource is deleted once everything it depends on has been deleted,
+// and the resources in a tier are deleted in parallel, so each tier takes as long as its slowest resource.
+// Dependencies on resources that aren't in resourceMap are ignored.
+func EstimateDeletionTime(resourceMap map[string]*resources.Resource) time.Duration {
+	depMap := make(map[string][]string)
+	for k, t := range resourceMap {
+		for _, block := range t.Blocks {
+			depMap[block] = append(depMap[block], k)
+		}
+		depMap[k] = append(depMap[k], t.Blocked...)
+	}
+
+	done := make(map[string]bool)
+	for k, t := range resourceMap {
+		if t.Done {
+			done[k] = true
+		}
+	}
+
+	var total time.Duration
+	for len(done) < len(resourceMap) {
+		var tier []string
+		for k := range resourceMap {
+			if done[k] {
+				continue
+			}
+			ready := true
+			for _, dep := range depMap[k] {
+				if _, found := resourceMap[dep]; found && !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				tier = append(tier, k)
+			}
+		}
+
+		if len(tier) == 0 {
+			// A dependency cycle; the remaining resources can't be ordered, so assume they go in one tier
+			for k := range resourceMap {
+				if !done[k] {
+					tier = append(tier, k)
+				}
+			}
+		}
+
+		var slowest time.Duration
+		for _, k := range tier {
+			done[k] = true
+			if d := deletionDuration(resourceMap[k]); d > slowest {
+				slowest = d
+			}
+		}
+		total += slowest
+	}
+
+	return total
+}
+
+func deletionDuration(r *resources.Resource) time.Duration {
+	if d, found := DeletionDurations[r.Type]; found {
+		return d
+	}
+	return DefaultDeletionDuration
+}
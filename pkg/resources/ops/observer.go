@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"k8s.io/kops/pkg/resources"
+)
+
+// DeletionObserver is notified of the progress of DeleteResourcesWithOptions, for each resource,
+// so that callers embedding kops can follow the deletion without parsing its output.
+// The methods may be called concurrently, as independent resources are deleted in parallel.
+type DeletionObserver interface {
+	// OnStart is called when the deletion of the resource is attempted, in each pass
+	OnStart(r *resources.Resource)
+	// OnDelete is called once the resource has been deleted
+	OnDelete(r *resources.Resource)
+	// OnSkip is called for a resource that won't be deleted, e.g. because it is shared
+	OnSkip(r *resources.Resource, reason string)
+	// OnError is called when an attempt to delete the resource fails; it will be retried in the next pass,
+	// unless a BestEffort deletion gives up on it
+	OnError(r *resources.Resource, err error)
+}
+
+// NoopDeletionObserver is a DeletionObserver that ignores the events
+type NoopDeletionObserver struct{}
+
+var _ DeletionObserver = NoopDeletionObserver{}
+
+func (NoopDeletionObserver) OnStart(r *resources.Resource)               {}
+func (NoopDeletionObserver) OnDelete(r *resources.Resource)              {}
+func (NoopDeletionObserver) OnSkip(r *resources.Resource, reason string) {}
+func (NoopDeletionObserver) OnError(r *resources.Resource, err error)    {}
+
+// DeleteProgress is a snapshot of the progress of DeleteResourcesWithOptions, across all the resources
+type DeleteProgress struct {
+	// Total is the number of resources, including those that are skipped
+	Total int
+	// Completed is the number of resources that have been deleted or skipped
+	Completed int
+	// Ready is the number of resources that are not yet completed, but whose dependencies all are; these are the resources being deleted
+	Ready int
+}
+
+// Percent returns the percentage of the resources that have been completed
+func (p DeleteProgress) Percent() int {
+	if p.Total == 0 {
+		return 100
+	}
+	return p.Completed * 100 / p.Total
+}
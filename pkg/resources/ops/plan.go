@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"errors"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/resources"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/util/pkg/maps"
+)
+
+// DeletionPlan describes what DeleteResources would do with the cluster resources, for previewing a deletion.
+// Unlike a dump it doesn't include the raw cloud objects, only what is needed to follow the deletion.
+type DeletionPlan struct {
+	Resources []*PlannedResource `json:"resources"`
+}
+
+// PlannedResource is a resource in a DeletionPlan
+type PlannedResource struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// Shared is true if the resource isn't owned by the cluster, so will be left alone
+	Shared bool `json:"shared"`
+	// Protected is true if the resource is one of the DeleteOptions.ProtectedIDs, so will be left alone
+	Protected bool `json:"protected,omitempty"`
+	// DependsOn are the keys ("<type>:<id>") of the resources that must be deleted before this one
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// Actions are the cloud API actions that deleting the resource invokes
+	Actions []string `json:"actions,omitempty"`
+}
+
+// PlanDeletion lists the cluster resources, as ListResourcesWithOptions does, and returns the plan for deleting them.
+// If only some cloud services could not be listed, the plan for the others is returned along with the *resources.ServiceFailuresError.
+func PlanDeletion(cloud fi.Cloud, cluster *kops.Cluster, options resources.ListOptions) (*DeletionPlan, error) {
+	resourceMap, err := ListResourcesWithOptions(cloud, cluster, options)
+	if err != nil {
+		var serviceFailures *resources.ServiceFailuresError
+		if !errors.As(err, &serviceFailures) {
+			return nil, err
+		}
+	}
+	return BuildDeletionPlan(resourceMap), err
+}
+
+// BuildDeletionPlan returns the plan for deleting the resources, sorted by key, with the dependencies between them
+func BuildDeletionPlan(resourceMap map[string]*resources.Resource) *DeletionPlan {
+	depMap := buildDependencyMap(resourceMap)
+
+	plan := &DeletionPlan{}
+	for _, k := range maps.SortedKeys(resourceMap) {
+		r := resourceMap[k]
+		// A resource can be both Blocked by another and in its Blocks, so the edges are deduplicated
+		dependsOn := sets.NewString(depMap[k]...).List()
+		plan.Resources = append(plan.Resources, &PlannedResource{
+			Type:      r.Type,
+			ID:        r.ID,
+			Name:      r.Name,
+			Shared:    r.Shared,
+			DependsOn: dependsOn,
+			Actions:   r.Actions,
+		})
+	}
+	return plan
+}
+
+// MarkProtected marks the resources whose ids are protectedIDs, which DeleteResources never deletes
+func (p *DeletionPlan) MarkProtected(protectedIDs []string) {
+	protected := sets.NewString(protectedIDs...)
+	for _, r := range p.Resources {
+		if protected.Has(r.ID) {
+			r.Protected = true
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	awsresources "k8s.io/kops/pkg/resources/aws"
+)
+
+// RetryPolicy is how often, and how quickly, a deletion that is throttled or blocked by a dependency is retried
+// before moving on to the next pass. Other errors are not retried until the next pass.
+// A resource that is waiting to be retried doesn't hold up the deletion of the other resources.
+type RetryPolicy struct {
+	// Attempts is the number of times the deletion is attempted in each pass
+	Attempts int
+	// BaseDelay is the time to wait before the first retry; it doubles for each retry after that
+	BaseDelay time.Duration
+	// MaxDelay is the longest time to wait between retries
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy for resources whose type isn't in RetryPolicies.
+// EC2 is eventually consistent, so a dependency is often still being deleted when we try to delete the resource;
+// a few quick retries save waiting for the next pass.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 4, BaseDelay: time.Second, MaxDelay: 15 * time.Second}
+
+// RetryPolicies are the retry policies, by resource type, and can be adjusted by callers.
+// NAT gateways take minutes to delete, so we wait for them; route tables are usually only blocked briefly.
+var RetryPolicies = map[string]RetryPolicy{
+	awsresources.TypeNatGateway: {Attempts: 5, BaseDelay: 15 * time.Second, MaxDelay: 2 * time.Minute},
+	ec2.ResourceTypeRouteTable:  {Attempts: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Second},
+}
+
+// retryPolicyFor returns the retry policy for the resource type
+func retryPolicyFor(resourceType string) RetryPolicy {
+	if policy, found := RetryPolicies[resourceType]; found {
+		return policy
+	}
+	return DefaultRetryPolicy
+}
+
+// isRetryable returns true if the deletion failed because it was throttled, or because of a dependency
+func isRetryable(err error) bool {
+	return awsresources.IsDependencyViolation(err) || awsresources.IsThrottlingError(err)
+}
+
+// retryDelay returns how long to wait before attempting again a deletion of the resource type that failed with err,
+// after the given number of attempts in this pass. It returns false if the deletion isn't retried until the next pass.
+func retryDelay(resourceType string, attempts int, err error) (time.Duration, bool) {
+	policy := retryPolicyFor(resourceType)
+	if attempts >= policy.Attempts || !isRetryable(err) {
+		return 0, false
+	}
+
+	delay := policy.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if policy.MaxDelay != 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+			break
+		}
+	}
+	return delay, true
+}
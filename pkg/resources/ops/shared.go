@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"sort"
+
+	"k8s.io/kops/pkg/resources"
+)
+
+// SummarizeShared returns the ids of the shared resources, which are not deleted with the cluster, by resource type.
+// The ids of each type are sorted.
+func SummarizeShared(trackers []*resources.Resource) map[string][]string {
+	shared := make(map[string][]string)
+	for _, t := range trackers {
+		if !t.Shared {
+			continue
+		}
+		shared[t.Type] = append(shared[t.Type], t.ID)
+	}
+	for _, ids := range shared {
+		sort.Strings(ids)
+	}
+	return shared
+}
+
+// CountByType returns the number of resources of each type, including the shared resources.
+// It doesn't modify the trackers, so it can be used to summarize what was found before anything is deleted.
+func CountByType(trackers []*resources.Resource) map[string]int {
+	counts := make(map[string]int)
+	for _, t := range trackers {
+		counts[t.Type]++
+	}
+	return counts
+}
@@ -36,6 +36,9 @@ type Resource struct {
 	GroupKey     string
 	GroupDeleter func(cloud fi.Cloud, trackers []*Resource) error
 
+	// Actions are the cloud API actions (e.g. "ec2:DeleteRouteTable") that Deleter or GroupDeleter may invoke
+	Actions []string
+
 	// Dumper populates the dump with any information from the resource
 	Dumper func(op *DumpOperation, r *Resource) error
 
@@ -41,10 +41,13 @@ import (
 	"k8s.io/klog/v2"
 	kopsroot "k8s.io/kops"
 	"k8s.io/kops/cloudmock/aws/mockautoscaling"
+	"k8s.io/kops/cloudmock/aws/mockcloudwatchlogs"
 	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/cloudmock/aws/mockefs"
 	"k8s.io/kops/cloudmock/aws/mockelb"
 	"k8s.io/kops/cloudmock/aws/mockelbv2"
 	"k8s.io/kops/cloudmock/aws/mockiam"
+	"k8s.io/kops/cloudmock/aws/mockkms"
 	"k8s.io/kops/cloudmock/aws/mockroute53"
 	gcemock "k8s.io/kops/cloudmock/gce"
 	"k8s.io/kops/cloudmock/openstack/mockblockstorage"
@@ -151,6 +154,12 @@ func (h *IntegrationTestHarness) SetupMockAWS() *awsup.MockAWSCloud {
 	cloud.MockSQS = mockSQS
 	mockEventBridge := &mockeventbridge.MockEventBridge{}
 	cloud.MockEventBridge = mockEventBridge
+	mockKMS := &mockkms.MockKMS{}
+	cloud.MockKMS = mockKMS
+	mockLogs := &mockcloudwatchlogs.MockCloudWatchLogs{}
+	cloud.MockLogs = mockLogs
+	mockEFS := &mockefs.MockEFS{}
+	cloud.MockEFS = mockEFS
 
 	mockRoute53.MockCreateZone(&route53types.HostedZone{
 		Id:   aws.String("/hostedzone/Z1AFAKE1ZON3YO"),
@@ -36,7 +36,8 @@ type RouteTable struct {
 	ID  *string
 	VPC *VPC
 
-	// Shared is set if this is a shared RouteTable
+	// Shared is set if this is a shared RouteTable.
+	// A shared RouteTable that already exists is adopted as it is: it is neither created nor retagged.
 	Shared *bool
 	// Tags is a map of aws tags that are added to the RouteTable
 	Tags map[string]string
@@ -97,6 +98,15 @@ func (e *RouteTable) Find(c *fi.CloudupContext) (*RouteTable, error) {
 		Name: e.Name,
 		Tags: intersectTags(rt.Tags, e.Tags),
 	}
+	if !fi.ValueOf(e.Shared) {
+		// Report the kops tags we no longer want, so that they are removed from the owned RouteTable
+		for k, v := range staleKopsTags(rt.Tags, e.Tags) {
+			if actual.Tags == nil {
+				actual.Tags = make(map[string]string)
+			}
+			actual.Tags[k] = v
+		}
+	}
 	klog.V(2).Infof("found matching RouteTable %q", *actual.ID)
 	e.ID = actual.ID
 
@@ -104,6 +114,11 @@ func (e *RouteTable) Find(c *fi.CloudupContext) (*RouteTable, error) {
 	actual.Lifecycle = e.Lifecycle
 	actual.Shared = e.Shared
 
+	// We don't set the tags for a shared RouteTable
+	if fi.ValueOf(e.Shared) {
+		actual.Tags = e.Tags
+	}
+
 	return actual, nil
 }
 
@@ -165,6 +180,11 @@ func (s *RouteTable) CheckChanges(a, e, changes *RouteTable) error {
 }
 
 func (_ *RouteTable) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *RouteTable) error {
+	if a != nil && fi.ValueOf(e.Shared) {
+		klog.V(4).Infof("reusing existing RouteTable with id %q", aws.ToString(e.ID))
+		return nil
+	}
+
 	if a == nil {
 		vpcID := e.VPC.ID
 		if vpcID == nil {
@@ -185,6 +205,20 @@ func (_ *RouteTable) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *RouteTable)
 
 		rt := response.RouteTable
 		e.ID = rt.RouteTableId
+	} else {
+		// Actual only holds our tags, so anything not desired is a stale kops tag
+		stale := make(map[string]string)
+		for k, v := range a.Tags {
+			if _, found := e.Tags[k]; !found {
+				stale[k] = v
+			}
+		}
+		if len(stale) != 0 {
+			klog.V(2).Infof("Removing tags from RouteTable %q: %v", *e.ID, stale)
+			if err := t.DeleteTags(*e.ID, stale); err != nil {
+				return fmt.Errorf("error removing tags from RouteTable: %v", err)
+			}
+		}
 	}
 
 	return t.AddAWSTags(*e.ID, e.Tags)
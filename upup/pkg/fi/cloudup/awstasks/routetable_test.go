@@ -0,0 +1,268 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// tagRecordingEC2 is a MockEC2 that records the tags that are added to and removed from a resource
+type tagRecordingEC2 struct {
+	*mockec2.MockEC2
+
+	resourceID string
+	created    map[string]string
+	deleted    map[string]string
+}
+
+func (m *tagRecordingEC2) CreateTags(request *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	if aws.ToString(request.Resources[0]) != m.resourceID {
+		return m.MockEC2.CreateTags(request)
+	}
+	for _, tag := range request.Tags {
+		m.created[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return m.MockEC2.CreateTags(request)
+}
+
+func (m *tagRecordingEC2) DeleteTags(request *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+	if aws.ToString(request.Resources[0]) != m.resourceID {
+		return m.MockEC2.DeleteTags(request)
+	}
+	for _, tag := range request.Tags {
+		m.deleted[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return m.MockEC2.DeleteTags(request)
+}
+
+func TestRouteTableReconcilesTags(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &tagRecordingEC2{
+		MockEC2:    &mockec2.MockEC2{},
+		resourceID: "rtb-1234",
+		created:    make(map[string]string),
+		deleted:    make(map[string]string),
+	}
+	cloud.MockEC2 = c
+
+	vpc, err := c.CreateVpc(&ec2.CreateVpcInput{
+		CidrBlock: aws.String("172.20.0.0/16"),
+	})
+	if err != nil {
+		t.Fatalf("error creating test VPC: %v", err)
+	}
+
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        vpc.Vpc.VpcId,
+		RouteTableId: aws.String("rtb-1234"),
+		Tags: buildTags(map[string]string{
+			"KubernetesCluster":                         "cluster.example.com",
+			"kubernetes.io/cluster/cluster.example.com": "owned",
+			"kubernetes.io/kops/role":                   "private-us-east-1a",
+			"kubernetes.io/cluster/other.example.com":   "shared",
+			"team": "network",
+		}),
+	})
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		vpc1 := &VPC{
+			Name:      s("vpc1"),
+			Lifecycle: fi.LifecycleSync,
+			CIDR:      s("172.20.0.0/16"),
+			Tags:      map[string]string{"kubernetes.io/cluster/cluster.example.com": "shared"},
+			Shared:    fi.PtrTo(true),
+			ID:        vpc.Vpc.VpcId,
+		}
+		rt1 := &RouteTable{
+			Name:      s("rt1"),
+			Lifecycle: fi.LifecycleSync,
+			VPC:       vpc1,
+			ID:        aws.String("rtb-1234"),
+			Tags: map[string]string{
+				"Name":              "rt1",
+				"KubernetesCluster": "cluster.example.com",
+				"kubernetes.io/cluster/cluster.example.com": "owned",
+			},
+		}
+
+		return map[string]fi.CloudupTask{
+			"rt1":  rt1,
+			"vpc1": vpc1,
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		runTasks(t, cloud, allTasks)
+
+		if expected := map[string]string{"Name": "rt1"}; !reflect.DeepEqual(c.created, expected) {
+			t.Errorf("unexpected tags added: expected=%v, actual=%v", expected, c.created)
+		}
+		if expected := map[string]string{"kubernetes.io/kops/role": "private-us-east-1a"}; !reflect.DeepEqual(c.deleted, expected) {
+			t.Errorf("unexpected tags removed: expected=%v, actual=%v", expected, c.deleted)
+		}
+
+		actual, err := cloud.GetTags("rtb-1234")
+		if err != nil {
+			t.Fatalf("error getting route table tags: %v", err)
+		}
+		expected := map[string]string{
+			"Name":              "rt1",
+			"KubernetesCluster": "cluster.example.com",
+			"kubernetes.io/cluster/cluster.example.com": "owned",
+			"kubernetes.io/cluster/other.example.com":   "shared",
+			"team": "network",
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("unexpected route table tags: expected=%v, actual=%v", expected, actual)
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestSharedRouteTableIsAdopted(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &tagRecordingEC2{
+		MockEC2:    &mockec2.MockEC2{},
+		resourceID: "rtb-shared",
+		created:    make(map[string]string),
+		deleted:    make(map[string]string),
+	}
+	cloud.MockEC2 = c
+
+	vpc, err := c.CreateVpc(&ec2.CreateVpcInput{
+		CidrBlock: aws.String("172.20.0.0/16"),
+	})
+	if err != nil {
+		t.Fatalf("error creating test VPC: %v", err)
+	}
+
+	existingTags := map[string]string{
+		"Name": "rt1",
+		"kubernetes.io/cluster/cluster.example.com": "shared",
+		"team": "network",
+	}
+	c.AddRouteTable(&ec2.RouteTable{
+		VpcId:        vpc.Vpc.VpcId,
+		RouteTableId: aws.String("rtb-shared"),
+		Tags:         buildTags(existingTags),
+	})
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		vpc1 := &VPC{
+			Name:      s("vpc1"),
+			Lifecycle: fi.LifecycleSync,
+			CIDR:      s("172.20.0.0/16"),
+			Tags:      map[string]string{"kubernetes.io/cluster/cluster.example.com": "shared"},
+			Shared:    fi.PtrTo(true),
+			ID:        vpc.Vpc.VpcId,
+		}
+		rt1 := &RouteTable{
+			Name:      s("rt1"),
+			Lifecycle: fi.LifecycleSync,
+			VPC:       vpc1,
+			Shared:    fi.PtrTo(true),
+			Tags: map[string]string{
+				"Name":              "rt1",
+				"KubernetesCluster": "cluster.example.com",
+				"kubernetes.io/cluster/cluster.example.com": "shared",
+				"kubernetes.io/kops/role":                   "private-us-east-1a",
+			},
+		}
+
+		return map[string]fi.CloudupTask{
+			"rt1":  rt1,
+			"vpc1": vpc1,
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		runTasks(t, cloud, allTasks)
+
+		rt1 := allTasks["rt1"].(*RouteTable)
+		if fi.ValueOf(rt1.ID) != "rtb-shared" {
+			t.Errorf("expected the existing route table to be adopted, got ID %q", fi.ValueOf(rt1.ID))
+		}
+		if len(c.RouteTables) != 1 {
+			t.Errorf("expected no route table to be created, got %d route tables", len(c.RouteTables))
+		}
+		if len(c.created) != 0 || len(c.deleted) != 0 {
+			t.Errorf("expected the shared route table not to be retagged: added=%v, removed=%v", c.created, c.deleted)
+		}
+
+		actual, err := cloud.GetTags("rtb-shared")
+		if err != nil {
+			t.Fatalf("error getting route table tags: %v", err)
+		}
+		if !reflect.DeepEqual(actual, existingTags) {
+			t.Errorf("unexpected route table tags: expected=%v, actual=%v", existingTags, actual)
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNoChanges(t, ctx, cloud, allTasks)
+	}
+}
+
+func TestSharedRouteTableBuildChanges(t *testing.T) {
+	// Find reports the tags we want for a shared route table, whatever it is tagged with
+	a := &RouteTable{
+		Name:   s("rt1"),
+		ID:     s("rtb-shared"),
+		Shared: fi.PtrTo(true),
+		Tags:   map[string]string{"Name": "rt1"},
+	}
+
+	e := &RouteTable{
+		Name:   s("rt1"),
+		ID:     s("rtb-shared"),
+		Shared: fi.PtrTo(true),
+		Tags:   map[string]string{"Name": "rt1"},
+	}
+
+	changes := &RouteTable{}
+	changed := fi.BuildChanges(a, e, changes)
+
+	if changed {
+		t.Errorf("expected changed=false")
+	}
+
+	expectedChanges := &RouteTable{}
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("unexpected changes: +%v", changes)
+	}
+}
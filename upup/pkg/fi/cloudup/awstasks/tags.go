@@ -23,6 +23,7 @@ import (
 	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
 	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 )
 
 func mapEC2TagsToMap(tags []*ec2.Tag) map[string]string {
@@ -90,6 +91,16 @@ func findNameTag(tags []*ec2.Tag) *string {
 	return nil
 }
 
+// isUnmanaged returns true if the resource is tagged as one that kops must not reconcile
+func isUnmanaged(tags []*ec2.Tag) bool {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == awsup.TagNameUnmanaged {
+			return aws.ToString(tag.Value) == "true"
+		}
+	}
+	return false
+}
+
 // intersectTags returns the tags of interest from a specified list of AWS tags;
 // because we only add tags, this set of tags of interest is the tags that occur in the desired set.
 func intersectTags(tags []*ec2.Tag, desired map[string]string) map[string]string {
@@ -111,3 +122,28 @@ func intersectTags(tags []*ec2.Tag, desired map[string]string) map[string]string
 	}
 	return actual
 }
+
+// staleKopsTags returns the tags from a specified list of AWS tags that kops manages, but that are no longer in the desired set.
+// Tags that kops doesn't manage (including the ownership tags of other clusters) are never returned.
+func staleKopsTags(tags []*ec2.Tag, desired map[string]string) map[string]string {
+	clusterName := desired[awsup.TagClusterName]
+	if clusterName == "" {
+		// We can't tell which ownership tags are ours
+		return nil
+	}
+
+	stale := make(map[string]string)
+	for _, t := range tags {
+		k := aws.ToString(t.Key)
+		if _, found := desired[k]; found {
+			continue
+		}
+		if k == awsup.TagClusterName ||
+			k == "kubernetes.io/cluster/"+clusterName ||
+			strings.HasPrefix(k, "kubernetes.io/kops/") ||
+			strings.HasPrefix(k, awsup.TagNameRolePrefix) {
+			stale[k] = aws.ToString(t.Value)
+		}
+	}
+	return stale
+}
@@ -18,6 +18,9 @@ package awstasks
 
 import (
 	"fmt"
+	"net"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -30,6 +33,12 @@ import (
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraformWriter"
 )
 
+// vpcTagsVerifyAttempts is the number of times the tags of a newly created VPC are checked, re-applying the missing ones
+var vpcTagsVerifyAttempts = 5
+
+// vpcTagsVerifyInterval is the time to wait after re-applying the missing tags of a newly created VPC, before checking again
+var vpcTagsVerifyInterval = 2 * time.Second
+
 // +kops:fitask
 type VPC struct {
 	Name      *string
@@ -89,6 +98,17 @@ func (e *VPC) Find(c *fi.CloudupContext) (*VPC, error) {
 		return nil, fmt.Errorf("found multiple VPCs matching tags")
 	}
 	vpc := response.Vpcs[0]
+
+	if isUnmanaged(vpc.Tags) {
+		// Report the VPC as we expect it to be, so that we don't change anything
+		klog.V(2).Infof("VPC %q is tagged %s=true; will not reconcile it", aws.ToString(vpc.VpcId), awsup.TagNameUnmanaged)
+		if e.ID == nil {
+			e.ID = vpc.VpcId
+		}
+		actual := *e
+		return &actual, nil
+	}
+
 	actual := &VPC{
 		ID:         vpc.VpcId,
 		CIDR:       vpc.CidrBlock,
@@ -99,6 +119,14 @@ func (e *VPC) Find(c *fi.CloudupContext) (*VPC, error) {
 
 	klog.V(4).Infof("found matching VPC %v", actual)
 
+	if fi.ValueOf(e.Shared) && e.CIDR != nil {
+		if err := checkSharedVPCCIDR(vpc, fi.ValueOf(e.CIDR)); err != nil {
+			return nil, err
+		}
+		// The cluster may use any range within the VPC's CIDR blocks; we never change a shared VPC's CIDR
+		actual.CIDR = e.CIDR
+	}
+
 	for _, association := range vpc.Ipv6CidrBlockAssociationSet {
 		if association == nil || association.Ipv6CidrBlockState == nil {
 			continue
@@ -229,7 +257,52 @@ func (_ *VPC) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *VPC) error {
 		}
 	}
 
-	return t.AddAWSTags(*e.ID, e.Tags)
+	if err := t.AddAWSTags(*e.ID, e.Tags); err != nil {
+		return err
+	}
+
+	if a == nil {
+		return verifyVPCTags(t, *e.ID, e.Tags)
+	}
+	return nil
+}
+
+// verifyVPCTags describes the newly created VPC again, and re-applies any of the expected tags that are missing.
+// The tags of a new VPC can partially fail to apply, and the VPC can't be found by its tags without them.
+func verifyVPCTags(t *awsup.AWSAPITarget, id string, expected map[string]string) error {
+	for attempt := 1; ; attempt++ {
+		request := &ec2.DescribeVpcsInput{
+			VpcIds: []*string{aws.String(id)},
+		}
+		response, err := t.Cloud.EC2().DescribeVpcs(request)
+		if err != nil {
+			return fmt.Errorf("error describing VPC %q: %v", id, err)
+		}
+
+		missing := make(map[string]string)
+		if len(response.Vpcs) == 1 {
+			actual := mapEC2TagsToMap(response.Vpcs[0].Tags)
+			for k, v := range expected {
+				if actualValue, found := actual[k]; !found || actualValue != v {
+					missing[k] = v
+				}
+			}
+			if len(missing) == 0 {
+				return nil
+			}
+		}
+
+		if attempt >= vpcTagsVerifyAttempts {
+			return fmt.Errorf("VPC %q is still missing tags %v after %d attempts to apply them", id, missing, attempt)
+		}
+		if len(missing) != 0 {
+			klog.Warningf("VPC %q is missing tags %v; applying them again", id, missing)
+			if err := t.Cloud.CreateTags(id, missing); err != nil {
+				return fmt.Errorf("error adding tags to VPC %q: %v", id, err)
+			}
+		}
+		time.Sleep(vpcTagsVerifyInterval)
+	}
 }
 
 func (e *VPC) FindDeletions(c *fi.CloudupContext) ([]fi.CloudupDeletion, error) {
@@ -254,6 +327,9 @@ func (e *VPC) FindDeletions(c *fi.CloudupContext) ([]fi.CloudupDeletion, error)
 		return nil, fmt.Errorf("found multiple VPCs matching tags")
 	}
 	vpc := response.Vpcs[0]
+	if isUnmanaged(vpc.Tags) {
+		return nil, nil
+	}
 	for _, association := range vpc.CidrBlockAssociationSet {
 		// We'll only delete CIDR associations that are not the primary association
 		// and that have a state of "associated"
@@ -279,6 +355,31 @@ func (e *VPC) FindDeletions(c *fi.CloudupContext) ([]fi.CloudupDeletion, error)
 	return removals, nil
 }
 
+// checkSharedVPCCIDR returns an error if the CIDR that the cluster expects isn't within the CIDR blocks of the shared VPC.
+// Otherwise the mismatch only shows up later, as subnets that can't be allocated.
+func checkSharedVPCCIDR(vpc *ec2.Vpc, cidr string) error {
+	_, expected, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("error parsing CIDR %q: %v", cidr, err)
+	}
+	expectedOnes, expectedBits := expected.Mask.Size()
+
+	blocks := vpcCIDRBlocks(vpc)
+	for _, block := range blocks {
+		_, actual, err := net.ParseCIDR(block)
+		if err != nil {
+			return fmt.Errorf("error parsing CIDR block %q of VPC %q: %v", block, aws.ToString(vpc.VpcId), err)
+		}
+		actualOnes, actualBits := actual.Mask.Size()
+		if actualBits == expectedBits && actualOnes <= expectedOnes && actual.Contains(expected.IP) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the CIDR %q expected for shared VPC %q is not within any of its CIDR blocks %s; set the cluster's networkCIDR to the VPC's CIDR %q",
+		cidr, aws.ToString(vpc.VpcId), strings.Join(blocks, ", "), aws.ToString(vpc.CidrBlock))
+}
+
 type terraformVPCData struct {
 	ID *string `cty:"id"`
 }
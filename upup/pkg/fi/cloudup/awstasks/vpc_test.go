@@ -19,7 +19,9 @@ package awstasks
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -86,6 +88,62 @@ func TestVPCCreate(t *testing.T) {
 	}
 }
 
+// tagDroppingEC2 is a MockEC2 where the tags of a new VPC partially fail to apply:
+// only the Name tag is applied on create, and the first CreateTags call is silently dropped.
+type tagDroppingEC2 struct {
+	*mockec2.MockEC2
+	createTagsCalls int
+}
+
+func (m *tagDroppingEC2) CreateVpc(request *ec2.CreateVpcInput) (*ec2.CreateVpcOutput, error) {
+	for _, spec := range request.TagSpecifications {
+		var tags []*ec2.Tag
+		for _, tag := range spec.Tags {
+			if aws.ToString(tag.Key) == "Name" {
+				tags = append(tags, tag)
+			}
+		}
+		spec.Tags = tags
+	}
+	return m.MockEC2.CreateVpc(request)
+}
+
+func (m *tagDroppingEC2) CreateTags(request *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	m.createTagsCalls++
+	if m.createTagsCalls == 1 {
+		return &ec2.CreateTagsOutput{}, nil
+	}
+	return m.MockEC2.CreateTags(request)
+}
+
+func TestVPCCreateReappliesMissingTags(t *testing.T) {
+	vpcTagsVerifyInterval = 0
+	defer func() { vpcTagsVerifyInterval = 2 * time.Second }()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &tagDroppingEC2{MockEC2: &mockec2.MockEC2{}}
+	cloud.MockEC2 = c
+
+	vpc1 := &VPC{
+		Name:      s("vpc1"),
+		Lifecycle: fi.LifecycleSync,
+		CIDR:      s("172.21.0.0/16"),
+		Tags:      map[string]string{"Name": "vpc1", "kubernetes.io/cluster/cluster.example.com": "owned"},
+	}
+	runTasks(t, cloud, map[string]fi.CloudupTask{"vpc1": vpc1})
+
+	actual := c.FindVpc(fi.ValueOf(vpc1.ID))
+	if actual == nil {
+		t.Fatalf("VPC created but then not found")
+	}
+	if tags := mapEC2TagsToMap(actual.Tags); !reflect.DeepEqual(tags, vpc1.Tags) {
+		t.Errorf("unexpected tags: expected=%v actual=%v", vpc1.Tags, tags)
+	}
+	if c.createTagsCalls != 2 {
+		t.Errorf("expected the dropped tags to be applied again, got %d CreateTags calls", c.createTagsCalls)
+	}
+}
+
 func buildTags(tags map[string]string) []*ec2.Tag {
 	var t []*ec2.Tag
 	for k, v := range tags {
@@ -200,3 +258,236 @@ func TestSharedVPCAdditionalCIDR(t *testing.T) {
 		}
 	}
 }
+
+func TestVPCAdditionalCIDROverlap(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockec2.MockEC2{}
+	c.CreateVpcWithId(&ec2.CreateVpcInput{
+		CidrBlock: s("172.21.0.0/16"),
+	}, "vpc-1")
+	c.AssociateVpcCidrBlock(&ec2.AssociateVpcCidrBlockInput{
+		VpcId:     s("vpc-1"),
+		CidrBlock: s("172.22.0.0/16"),
+	})
+
+	cloud.MockEC2 = c
+
+	vpc1 := &VPC{
+		Name:      s("vpc-1"),
+		Lifecycle: fi.LifecycleSync,
+		ID:        s("vpc-1"),
+		CIDR:      s("172.21.0.0/16"),
+		Tags:      map[string]string{"Name": "vpc-1"},
+		Shared:    fi.PtrTo(true),
+	}
+	cidr1 := &VPCCIDRBlock{
+		Name:      s("172.22.128.0/17"),
+		Lifecycle: fi.LifecycleSync,
+		VPC:       vpc1,
+		CIDRBlock: s("172.22.128.0/17"),
+	}
+	allTasks := map[string]fi.CloudupTask{
+		"vpc-1":           vpc1,
+		"172.22.128.0/17": cidr1,
+	}
+
+	target := &awsup.AWSAPITarget{
+		Cloud: cloud,
+	}
+	context, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, nil, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	err = context.RunTasks(testRunTasksOptions)
+	if err == nil {
+		t.Fatalf("expected an error associating an overlapping CIDR block")
+	}
+	if !strings.Contains(err.Error(), `overlaps the VPC's existing CIDR blocks 172.22.0.0/16`) {
+		t.Errorf("expected the error to name the overlapping CIDR block, got: %v", err)
+	}
+
+	if associations := c.FindVpc("vpc-1").CidrBlockAssociationSet; len(associations) != 1 {
+		t.Errorf("expected the overlapping CIDR block not to be associated, got: %v", associations)
+	}
+}
+
+func TestSharedVPCCIDRMismatch(t *testing.T) {
+	grid := []struct {
+		name          string
+		cidr          string
+		expectedError string
+	}{
+		{
+			name: "primary CIDR block",
+			cidr: "172.21.0.0/16",
+		},
+		{
+			name: "within an associated CIDR block",
+			cidr: "172.22.128.0/17",
+		},
+		{
+			name:          "outside the CIDR blocks",
+			cidr:          "10.0.0.0/16",
+			expectedError: `the CIDR "10.0.0.0/16" expected for shared VPC "vpc-1" is not within any of its CIDR blocks 172.21.0.0/16, 172.22.0.0/16`,
+		},
+		{
+			name:          "larger than the CIDR block",
+			cidr:          "172.20.0.0/14",
+			expectedError: `the CIDR "172.20.0.0/14" expected for shared VPC "vpc-1" is not within any of its CIDR blocks`,
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			ctx := context.TODO()
+
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			c := &mockec2.MockEC2{}
+			c.CreateVpcWithId(&ec2.CreateVpcInput{
+				CidrBlock: s("172.21.0.0/16"),
+			}, "vpc-1")
+			c.AssociateVpcCidrBlock(&ec2.AssociateVpcCidrBlockInput{
+				VpcId:     s("vpc-1"),
+				CidrBlock: s("172.22.0.0/16"),
+			})
+
+			cloud.MockEC2 = c
+
+			vpc1 := &VPC{
+				Name:      s("vpc-1"),
+				Lifecycle: fi.LifecycleSync,
+				ID:        s("vpc-1"),
+				CIDR:      s(g.cidr),
+				Tags:      map[string]string{"Name": "vpc-1"},
+				Shared:    fi.PtrTo(true),
+			}
+			allTasks := map[string]fi.CloudupTask{
+				"vpc-1": vpc1,
+			}
+
+			target := &awsup.AWSAPITarget{
+				Cloud: cloud,
+			}
+			context, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, nil, cloud, nil, nil, nil, allTasks)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+
+			err = context.RunTasks(testRunTasksOptions)
+			if g.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("expected an error for a CIDR outside the shared VPC")
+				}
+				if !strings.Contains(err.Error(), g.expectedError) {
+					t.Errorf("expected error containing %q, got: %v", g.expectedError, err)
+				}
+			}
+
+			if cidr := aws.ToString(c.FindVpc("vpc-1").CidrBlock); cidr != "172.21.0.0/16" {
+				t.Errorf("expected the shared VPC CIDR to be unchanged, got %q", cidr)
+			}
+		})
+	}
+}
+
+func TestUnmanagedVPCIsNotReconciled(t *testing.T) {
+	ctx := context.TODO()
+
+	cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	c := &mockec2.MockEC2{}
+	c.CreateVpcWithId(&ec2.CreateVpcInput{
+		CidrBlock: s("172.21.0.0/16"),
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: s(ec2.ResourceTypeVpc),
+				Tags: []*ec2.Tag{
+					{
+						Key:   s("Name"),
+						Value: s("vpc-1"),
+					},
+					{
+						Key:   s(awsup.TagNameUnmanaged),
+						Value: s("true"),
+					},
+				},
+			},
+		},
+	}, "vpc-1")
+	c.AssociateVpcCidrBlock(&ec2.AssociateVpcCidrBlockInput{
+		VpcId:     s("vpc-1"),
+		CidrBlock: s("172.22.0.0/16"),
+	})
+
+	cloud.MockEC2 = c
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		vpc1 := &VPC{
+			Name:               s("vpc-1"),
+			Lifecycle:          fi.LifecycleSync,
+			CIDR:               s("172.21.0.0/16"),
+			EnableDNSHostnames: fi.PtrTo(true),
+			EnableDNSSupport:   fi.PtrTo(true),
+			Tags: map[string]string{
+				"Name": "vpc-1",
+				"kubernetes.io/cluster/cluster.example.com": "owned",
+			},
+		}
+		return map[string]fi.CloudupTask{
+			"vpc-1": vpc1,
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		vpc1 := allTasks["vpc-1"].(*VPC)
+
+		runTasks(t, cloud, allTasks)
+
+		if fi.ValueOf(vpc1.ID) != "vpc-1" {
+			t.Fatalf("expected the unmanaged VPC to be found, got ID %q", fi.ValueOf(vpc1.ID))
+		}
+
+		expected := &ec2.Vpc{
+			CidrBlock: s("172.21.0.0/16"),
+			IsDefault: fi.PtrTo(false),
+			VpcId:     s("vpc-1"),
+			Tags: buildTags(map[string]string{
+				"Name":                 "vpc-1",
+				awsup.TagNameUnmanaged: "true",
+			}),
+			CidrBlockAssociationSet: []*ec2.VpcCidrBlockAssociation{
+				{
+					AssociationId: s("vpc-1-0"),
+					CidrBlock:     s("172.22.0.0/16"),
+					CidrBlockState: &ec2.VpcCidrBlockState{
+						State: s(ec2.VpcCidrBlockStateCodeAssociated),
+					},
+				},
+			},
+		}
+		actual := c.FindVpc("vpc-1")
+		if actual == nil {
+			t.Fatalf("VPC no longer exists")
+		}
+		mockec2.SortTags(expected.Tags)
+		mockec2.SortTags(actual.Tags)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("Unexpected VPC: expected=%v actual=%v", expected, actual)
+		}
+		if len(c.Vpcs) != 1 {
+			t.Fatalf("Expected exactly one Vpc; found %v", c.Vpcs)
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNoChanges(t, ctx, cloud, allTasks)
+	}
+}
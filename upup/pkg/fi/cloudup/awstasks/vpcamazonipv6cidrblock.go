@@ -45,11 +45,13 @@ func (e *VPCAmazonIPv6CIDRBlock) Find(c *fi.CloudupContext) (*VPCAmazonIPv6CIDRB
 		return nil, nil
 	}
 
-	vpcIPv6CIDR, err := findVPCIPv6CIDR(cloud, e.VPC.ID)
+	vpc, err := cloud.DescribeVPC(aws.ToString(e.VPC.ID))
 	if err != nil {
 		return nil, err
 	}
-	if vpcIPv6CIDR == nil {
+	// An association that is still in progress is enough, we mustn't request another block
+	association := findVPCIPv6CIDRBlockAssociation(vpc)
+	if association == nil {
 		return nil, nil
 	}
 
@@ -96,12 +98,16 @@ func (_ *VPCAmazonIPv6CIDRBlock) RenderAWS(t *awsup.AWSAPITarget, a, e, changes
 		AmazonProvidedIpv6CidrBlock: aws.Bool(true),
 	}
 
-	// Response doesn't contain the new CIDR block
-	_, err := t.Cloud.EC2().AssociateVpcCidrBlock(request)
+	response, err := t.Cloud.EC2().AssociateVpcCidrBlock(request)
 	if err != nil {
 		return fmt.Errorf("error associating Amazon IPv6 provided CIDR block to VPC: %v", err)
 	}
 
+	// The response usually doesn't contain the new CIDR block yet, in which case the subnets look it up
+	if association := response.Ipv6CidrBlockAssociation; association != nil && aws.ToString(association.Ipv6CidrBlock) != "" {
+		e.VPC.IPv6CIDR = association.Ipv6CidrBlock
+	}
+
 	return nil // no tags
 }
 
@@ -110,6 +116,34 @@ func (_ *VPCAmazonIPv6CIDRBlock) RenderTerraform(t *terraform.TerraformTarget, a
 	return nil
 }
 
+// findVPCIPv6CIDRBlockAssociation returns the association of an IPv6 CIDR block with the VPC,
+// preferring one provided by Amazon, that is associated or still being associated
+func findVPCIPv6CIDRBlockAssociation(vpc *ec2.Vpc) *ec2.VpcIpv6CidrBlockAssociation {
+	if vpc == nil {
+		return nil
+	}
+
+	var found *ec2.VpcIpv6CidrBlockAssociation
+	for _, association := range vpc.Ipv6CidrBlockAssociationSet {
+		if association == nil || association.Ipv6CidrBlockState == nil {
+			continue
+		}
+
+		state := aws.ToString(association.Ipv6CidrBlockState.State)
+		if state != ec2.VpcCidrBlockStateCodeAssociated && state != ec2.VpcCidrBlockStateCodeAssociating {
+			continue
+		}
+
+		if aws.ToString(association.Ipv6Pool) == "Amazon" {
+			return association
+		}
+		if found == nil {
+			found = association
+		}
+	}
+	return found
+}
+
 func findVPCIPv6CIDR(cloud awsup.AWSCloud, vpcID *string) (*string, error) {
 	vpc, err := cloud.DescribeVPC(aws.ToString(vpcID))
 	if err != nil {
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// associatingEC2 is a MockEC2 where the IPv6 CIDR blocks of the VPCs are still being associated
+type associatingEC2 struct {
+	*mockec2.MockEC2
+}
+
+func (m *associatingEC2) DescribeVpcs(request *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	response, err := m.MockEC2.DescribeVpcs(request)
+	if err != nil {
+		return nil, err
+	}
+	for _, vpc := range response.Vpcs {
+		var associations []*ec2.VpcIpv6CidrBlockAssociation
+		for _, association := range vpc.Ipv6CidrBlockAssociationSet {
+			associations = append(associations, &ec2.VpcIpv6CidrBlockAssociation{
+				AssociationId:      association.AssociationId,
+				Ipv6Pool:           association.Ipv6Pool,
+				Ipv6CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeAssociating)},
+			})
+		}
+		vpc.Ipv6CidrBlockAssociationSet = associations
+	}
+	return response, nil
+}
+
+func TestVPCAmazonIPv6CIDRBlock(t *testing.T) {
+	ctx := context.TODO()
+
+	grid := []struct {
+		name        string
+		associating bool
+	}{
+		{name: "associated"},
+		{name: "associating", associating: true},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			cloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+			c := &mockec2.MockEC2{}
+			cloud.MockEC2 = c
+
+			c.CreateVpcWithId(&ec2.CreateVpcInput{
+				CidrBlock: s("172.21.0.0/16"),
+			}, "vpc-1")
+
+			// We define a function so we can rebuild the tasks, because we modify in-place when running
+			buildTasks := func() map[string]fi.CloudupTask {
+				vpc1 := &VPC{
+					Name:      s("vpc-1"),
+					Lifecycle: fi.LifecycleSync,
+					ID:        s("vpc-1"),
+					CIDR:      s("172.21.0.0/16"),
+					Tags:      map[string]string{"Name": "vpc-1"},
+				}
+				ipv6 := &VPCAmazonIPv6CIDRBlock{
+					Name:      s("AmazonIPv6"),
+					Lifecycle: fi.LifecycleSync,
+					VPC:       vpc1,
+				}
+				return map[string]fi.CloudupTask{
+					"vpc-1":      vpc1,
+					"AmazonIPv6": ipv6,
+				}
+			}
+
+			{
+				allTasks := buildTasks()
+				runTasks(t, cloud, allTasks)
+
+				if actual := fi.ValueOf(allTasks["vpc-1"].(*VPC).IPv6CIDR); actual != "2001:db8::/56" {
+					t.Errorf("expected the assigned IPv6 CIDR block to be recorded, got %q", actual)
+				}
+			}
+
+			if g.associating {
+				cloud.MockEC2 = &associatingEC2{MockEC2: c}
+			}
+
+			{
+				allTasks := buildTasks()
+				runTasks(t, cloud, allTasks)
+			}
+
+			if associations := c.FindVpc("vpc-1").Ipv6CidrBlockAssociationSet; len(associations) != 1 {
+				t.Errorf("expected exactly one IPv6 CIDR block association, got: %v", associations)
+			}
+
+			if !g.associating {
+				allTasks := buildTasks()
+				checkNoChanges(t, ctx, cloud, allTasks)
+			}
+		})
+	}
+}
@@ -18,9 +18,13 @@ package awstasks
 
 import (
 	"fmt"
+	"net"
+	"slices"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/pkg/util/subnet"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
@@ -124,12 +128,28 @@ func (_ *VPCCIDRBlock) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *VPCCIDRBl
 	}
 
 	if changes.CIDRBlock != nil {
+		// AWS rejects an overlapping block with an InvalidVpc.Range error that doesn't say what it overlaps
+		vpc, err := t.Cloud.DescribeVPC(aws.ToString(e.VPC.ID))
+		if err != nil {
+			return err
+		}
+		if vpc != nil {
+			overlapping, err := findOverlappingCIDRBlocks(vpc, aws.ToString(e.CIDRBlock))
+			if err != nil {
+				return err
+			}
+			if len(overlapping) != 0 {
+				return fmt.Errorf("cannot associate CIDR block %q with VPC %q, as it overlaps the VPC's existing CIDR blocks %s; choose a range that doesn't overlap them",
+					aws.ToString(e.CIDRBlock), aws.ToString(e.VPC.ID), strings.Join(overlapping, ", "))
+			}
+		}
+
 		request := &ec2.AssociateVpcCidrBlockInput{
 			VpcId:     e.VPC.ID,
 			CidrBlock: e.CIDRBlock,
 		}
 
-		_, err := t.Cloud.EC2().AssociateVpcCidrBlock(request)
+		_, err = t.Cloud.EC2().AssociateVpcCidrBlock(request)
 		if err != nil {
 			return fmt.Errorf("error associating AdditionalCIDR to VPC: %v", err)
 		}
@@ -138,6 +158,50 @@ func (_ *VPCCIDRBlock) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *VPCCIDRBl
 	return nil // no tags
 }
 
+// findOverlappingCIDRBlocks returns the IPv4 CIDR blocks of the VPC that overlap cidr
+func findOverlappingCIDRBlocks(vpc *ec2.Vpc, cidr string) ([]string, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CIDR block %q: %v", cidr, err)
+	}
+
+	var overlapping []string
+	for _, block := range vpcCIDRBlocks(vpc) {
+		if slices.Contains(overlapping, block) {
+			continue
+		}
+		_, existingNet, err := net.ParseCIDR(block)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing CIDR block %q of VPC %q: %v", block, aws.ToString(vpc.VpcId), err)
+		}
+		if subnet.Overlap(ipNet, existingNet) {
+			overlapping = append(overlapping, block)
+		}
+	}
+	return overlapping, nil
+}
+
+// vpcCIDRBlocks returns the primary CIDR block of the VPC, followed by its associated (or associating) CIDR blocks
+func vpcCIDRBlocks(vpc *ec2.Vpc) []string {
+	var blocks []string
+	if cidr := aws.ToString(vpc.CidrBlock); cidr != "" {
+		blocks = append(blocks, cidr)
+	}
+	for _, cba := range vpc.CidrBlockAssociationSet {
+		if cba == nil || cba.CidrBlockState == nil {
+			continue
+		}
+		state := aws.ToString(cba.CidrBlockState.State)
+		if state != ec2.VpcCidrBlockStateCodeAssociated && state != ec2.VpcCidrBlockStateCodeAssociating {
+			continue
+		}
+		if cidr := aws.ToString(cba.CidrBlock); cidr != "" {
+			blocks = append(blocks, cidr)
+		}
+	}
+	return blocks
+}
+
 type terraformVPCCIDRBlock struct {
 	VPCID     *terraformWriter.Literal `cty:"vpc_id"`
 	CIDRBlock *string                  `cty:"cidr_block"`
@@ -27,7 +27,10 @@ import (
 
 	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
 	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"golang.org/x/sync/errgroup"
@@ -51,6 +54,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/smithy-go/middleware"
 	"k8s.io/klog/v2"
 
 	v1 "k8s.io/api/core/v1"
@@ -105,6 +109,9 @@ const TagRoleMaster = "master"
 // TagNameKopsRole is the AWS tag used to identify the role an object plays for a cluster
 const TagNameKopsRole = "kubernetes.io/kops/role"
 
+// TagNameUnmanaged is the AWS tag that marks an object that kops must not reconcile, when set to "true"
+const TagNameUnmanaged = "kops.k8s.io/unmanaged"
+
 // TagNameClusterOwnershipPrefix is the AWS tag used for ownership
 const TagNameClusterOwnershipPrefix = "kubernetes.io/cluster/"
 
@@ -136,6 +143,9 @@ type AWSCloud interface {
 	SQS() awsinterfaces.SQSAPI
 	EventBridge() awsinterfaces.EventBridgeAPI
 	SSM() awsinterfaces.SSMAPI
+	KMS() awsinterfaces.KMSAPI
+	CloudWatchLogs() awsinterfaces.CloudWatchLogsAPI
+	EFS() awsinterfaces.EFSAPI
 
 	// TODO: Document and rationalize these tags/filters methods
 	AddTags(name *string, tags map[string]string)
@@ -206,6 +216,9 @@ type awsCloudImplementation struct {
 	sqs         *sqs.Client
 	eventbridge *eventbridge.Client
 	ssm         *ssm.Client
+	kms         *kms.Client
+	logs        *cloudwatchlogs.Client
+	efs         *efs.Client
 
 	region string
 
@@ -280,9 +293,54 @@ func getCloudInstancesFromRegion(region string) AWSCloud {
 	return cloud
 }
 
+// AssumeRoleOptions configure the IAM role that the cloud assumes, e.g. in the account of the cluster,
+// before it calls AWS.
+type AssumeRoleOptions struct {
+	// RoleARN is the ARN of the role to assume; no role is assumed if it is empty
+	RoleARN string
+	// ExternalID, if set, is passed to STS when assuming the role, as the trust policy of the role may require
+	ExternalID string
+}
+
+// assumeRoleOptionsFromEnv returns the role to assume set in the KOPS_AWS_ROLE_ARN
+// and KOPS_AWS_ROLE_EXTERNAL_ID environment variables
+func assumeRoleOptionsFromEnv() AssumeRoleOptions {
+	return AssumeRoleOptions{
+		RoleARN:    os.Getenv("KOPS_AWS_ROLE_ARN"),
+		ExternalID: os.Getenv("KOPS_AWS_ROLE_EXTERNAL_ID"),
+	}
+}
+
+// cloudInstanceKey returns the key under which the cloud is cached, as clouds that assume different roles can't be shared
+func cloudInstanceKey(region string, assumeRole AssumeRoleOptions) string {
+	if assumeRole.RoleARN == "" {
+		return region
+	}
+	return region + "/" + assumeRole.RoleARN + "/" + assumeRole.ExternalID
+}
+
+// newAssumeRoleProvider returns the provider of the credentials of the role to assume, or nil if there is no role to assume
+func newAssumeRoleProvider(client stscredsv2.AssumeRoleAPIClient, assumeRole AssumeRoleOptions) awsv2.CredentialsProvider {
+	if assumeRole.RoleARN == "" {
+		return nil
+	}
+	return stscredsv2.NewAssumeRoleProvider(client, assumeRole.RoleARN, func(o *stscredsv2.AssumeRoleOptions) {
+		if assumeRole.ExternalID != "" {
+			o.ExternalID = awsv2.String(assumeRole.ExternalID)
+		}
+	})
+}
+
+// NewAWSCloud returns the cloud for the region, assuming the role set in the KOPS_AWS_ROLE_ARN environment variable, if any
 func NewAWSCloud(region string, tags map[string]string) (AWSCloud, error) {
+	return NewAWSCloudWithAssumeRole(region, tags, assumeRoleOptionsFromEnv())
+}
+
+// NewAWSCloudWithAssumeRole returns the cloud for the region, whose calls to AWS are made as the role to assume, if any
+func NewAWSCloudWithAssumeRole(region string, tags map[string]string, assumeRole AssumeRoleOptions) (AWSCloud, error) {
 	ctx := context.TODO()
-	raw := getCloudInstancesFromRegion(region)
+	key := cloudInstanceKey(region, assumeRole)
+	raw := getCloudInstancesFromRegion(key)
 
 	if raw == nil {
 		c := &awsCloudImplementation{
@@ -304,6 +362,12 @@ func NewAWSCloud(region string, tags map[string]string) (AWSCloud, error) {
 			}),
 		}
 
+		if featureflag.AWSRequestTracing.Enabled() {
+			loadOptions = append(loadOptions, awsconfig.WithAPIOptions([]func(*middleware.Stack) error{
+				requestTracingMiddleware(logRequestTrace),
+			}))
+		}
+
 		config := aws.NewConfig().WithRegion(region)
 		config = setConfig(config)
 
@@ -318,18 +382,21 @@ func NewAWSCloud(region string, tags map[string]string) (AWSCloud, error) {
 		}
 
 		// assumes the role before executing commands
-		roleARN := os.Getenv("KOPS_AWS_ROLE_ARN")
-		if roleARN != "" {
+		if assumeRole.RoleARN != "" {
 			cfgV2, err := awsconfig.LoadDefaultConfig(ctx, loadOptions...)
 			if err != nil {
 				return c, fmt.Errorf("failed to load default aws config: %w", err)
 			}
 			stsClient := sts.NewFromConfig(cfgV2)
-			assumeRoleProvider := stscredsv2.NewAssumeRoleProvider(stsClient, roleARN)
+			assumeRoleProvider := newAssumeRoleProvider(stsClient, assumeRole)
 
 			loadOptions = append(loadOptions, awsconfig.WithCredentialsProvider(assumeRoleProvider))
 
-			creds := stscreds.NewCredentials(sess, roleARN)
+			creds := stscreds.NewCredentials(sess, assumeRole.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+				if assumeRole.ExternalID != "" {
+					p.ExternalID = aws.String(assumeRole.ExternalID)
+				}
+			})
 			config = &aws.Config{Credentials: creds}
 			config = setConfig(config).WithRegion(region)
 		}
@@ -337,6 +404,9 @@ func NewAWSCloud(region string, tags map[string]string) (AWSCloud, error) {
 		c.ec2 = ec2.New(sess, config)
 		c.ec2.Handlers.Send.PushFront(requestLogger)
 		c.addHandlers(region, &c.ec2.Handlers)
+		if featureflag.AWSRequestTracing.Enabled() {
+			c.ec2.Handlers.Complete.PushBackNamed(requestTracingHandler(logRequestTrace))
+		}
 
 		cfgV2, err := awsconfig.LoadDefaultConfig(ctx, loadOptions...)
 		if err != nil {
@@ -360,8 +430,11 @@ func NewAWSCloud(region string, tags map[string]string) (AWSCloud, error) {
 		c.sqs = sqs.NewFromConfig(cfgV2)
 		c.eventbridge = eventbridge.NewFromConfig(cfgV2)
 		c.ssm = ssm.NewFromConfig(cfgV2)
+		c.kms = kms.NewFromConfig(cfgV2)
+		c.logs = cloudwatchlogs.NewFromConfig(cfgV2)
+		c.efs = efs.NewFromConfig(cfgV2)
 
-		updateAwsCloudInstances(region, c)
+		updateAwsCloudInstances(key, c)
 
 		raw = c
 	}
@@ -2179,6 +2252,18 @@ func (c *awsCloudImplementation) SSM() awsinterfaces.SSMAPI {
 	return c.ssm
 }
 
+func (c *awsCloudImplementation) KMS() awsinterfaces.KMSAPI {
+	return c.kms
+}
+
+func (c *awsCloudImplementation) CloudWatchLogs() awsinterfaces.CloudWatchLogsAPI {
+	return c.logs
+}
+
+func (c *awsCloudImplementation) EFS() awsinterfaces.EFSAPI {
+	return c.efs
+}
+
 func (c *awsCloudImplementation) FindVPCInfo(vpcID string) (*fi.VPCInfo, error) {
 	return findVPCInfo(c, vpcID)
 }
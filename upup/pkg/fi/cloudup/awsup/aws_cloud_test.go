@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+// recordingSTS is an STS client that records the AssumeRole requests
+type recordingSTS struct {
+	requests []*sts.AssumeRoleInput
+}
+
+func (m *recordingSTS) AssumeRole(ctx context.Context, request *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	m.requests = append(m.requests, request)
+	return &sts.AssumeRoleOutput{
+		Credentials: &ststypes.Credentials{
+			AccessKeyId:     awsv2.String("AKIDASSUMED"),
+			SecretAccessKey: awsv2.String("secret"),
+			SessionToken:    awsv2.String("token"),
+			Expiration:      awsv2.Time(time.Now().Add(time.Hour)),
+		},
+	}, nil
+}
+
+func TestNewAssumeRoleProvider(t *testing.T) {
+	ctx := context.TODO()
+
+	grid := []struct {
+		name       string
+		assumeRole AssumeRoleOptions
+	}{
+		{
+			name:       "role",
+			assumeRole: AssumeRoleOptions{RoleARN: "arn:aws:iam::123456789012:role/kops"},
+		},
+		{
+			name:       "role with external id",
+			assumeRole: AssumeRoleOptions{RoleARN: "arn:aws:iam::123456789012:role/kops", ExternalID: "external-id"},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			client := &recordingSTS{}
+			provider := newAssumeRoleProvider(client, g.assumeRole)
+			if provider == nil {
+				t.Fatalf("expected a credentials provider for role %q", g.assumeRole.RoleARN)
+			}
+
+			credentials, err := provider.Retrieve(ctx)
+			if err != nil {
+				t.Fatalf("error retrieving credentials: %v", err)
+			}
+			if credentials.AccessKeyID != "AKIDASSUMED" {
+				t.Errorf("expected the credentials of the assumed role, got access key %q", credentials.AccessKeyID)
+			}
+
+			if len(client.requests) != 1 {
+				t.Fatalf("expected one AssumeRole request, got %d", len(client.requests))
+			}
+			request := client.requests[0]
+			if actual := awsv2.ToString(request.RoleArn); actual != g.assumeRole.RoleARN {
+				t.Errorf("unexpected role: actual=%q, expected=%q", actual, g.assumeRole.RoleARN)
+			}
+			if g.assumeRole.ExternalID == "" {
+				if request.ExternalId != nil {
+					t.Errorf("expected no external id, got %q", awsv2.ToString(request.ExternalId))
+				}
+			} else if actual := awsv2.ToString(request.ExternalId); actual != g.assumeRole.ExternalID {
+				t.Errorf("unexpected external id: actual=%q, expected=%q", actual, g.assumeRole.ExternalID)
+			}
+		})
+	}
+}
+
+func TestNewAssumeRoleProviderWithoutRole(t *testing.T) {
+	client := &recordingSTS{}
+	if provider := newAssumeRoleProvider(client, AssumeRoleOptions{}); provider != nil {
+		t.Errorf("expected no credentials provider without a role, got %T", provider)
+	}
+}
+
+func TestCloudInstanceKey(t *testing.T) {
+	withoutRole := cloudInstanceKey("us-east-1", AssumeRoleOptions{})
+	if withoutRole != "us-east-1" {
+		t.Errorf("expected a cloud without a role to be cached by region, got %q", withoutRole)
+	}
+
+	withRole := cloudInstanceKey("us-east-1", AssumeRoleOptions{RoleARN: "arn:aws:iam::123456789012:role/kops"})
+	withExternalID := cloudInstanceKey("us-east-1", AssumeRoleOptions{RoleARN: "arn:aws:iam::123456789012:role/kops", ExternalID: "external-id"})
+	if withRole == withoutRole || withRole == withExternalID {
+		t.Errorf("expected clouds assuming different roles to be cached separately, got %q, %q and %q", withoutRole, withRole, withExternalID)
+	}
+}
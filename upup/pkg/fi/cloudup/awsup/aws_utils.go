@@ -35,6 +35,7 @@ import (
 	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
 	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/smithy-go"
 	"k8s.io/klog/v2"
@@ -115,6 +116,15 @@ func FindRegion(cluster *kops.Cluster) (string, error) {
 	return region, nil
 }
 
+// PartitionForRegion returns the AWS partition (e.g. "aws", "aws-us-gov", "aws-cn") that the region belongs to.
+// Regions that are not recognized are assumed to be in the standard partition.
+func PartitionForRegion(region string) string {
+	if p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return p.ID()
+	}
+	return endpoints.AwsPartitionID
+}
+
 // FindEC2Tag find the value of the tag with the specified key
 func FindEC2Tag(tags []*ec2.Tag, key string) (string, bool) {
 	for _, tag := range tags {
@@ -157,7 +167,8 @@ func FindELBV2Tag(tags []elbv2types.Tag, key string) (string, bool) {
 
 // AWSErrorCode returns the aws error code, if it is an awserr.Error or smithy.APIError, otherwise ""
 func AWSErrorCode(err error) string {
-	if awsError, ok := err.(awserr.Error); ok {
+	var awsError awserr.Error
+	if errors.As(err, &awsError) {
 		return awsError.Code()
 	}
 	var apiErr smithy.APIError
@@ -169,7 +180,8 @@ func AWSErrorCode(err error) string {
 
 // AWSErrorMessage returns the aws error message, if it is an awserr.Error or smithy.APIError, otherwise ""
 func AWSErrorMessage(err error) string {
-	if awsError, ok := err.(awserr.Error); ok {
+	var awsError awserr.Error
+	if errors.As(err, &awsError) {
 		return awsError.Message()
 	}
 	var apiErr smithy.APIError
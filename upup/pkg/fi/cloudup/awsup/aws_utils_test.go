@@ -69,6 +69,24 @@ func TestFindRegion(t *testing.T) {
 	}
 }
 
+func TestPartitionForRegion(t *testing.T) {
+	grid := map[string]string{
+		"us-east-1":      "aws",
+		"eu-west-2":      "aws",
+		"us-gov-west-1":  "aws-us-gov",
+		"us-gov-east-1":  "aws-us-gov",
+		"cn-north-1":     "aws-cn",
+		"cn-northwest-1": "aws-cn",
+		"not-a-region":   "aws",
+	}
+	for region, expected := range grid {
+		actual := PartitionForRegion(region)
+		if actual != expected {
+			t.Errorf("unexpected partition for region %q: actual=%q, expected=%q", region, actual, expected)
+		}
+	}
+}
+
 func TestEC2TagSpecification(t *testing.T) {
 	cases := []struct {
 		Name          string
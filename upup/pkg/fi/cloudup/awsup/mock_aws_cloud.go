@@ -29,6 +29,9 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
+	"k8s.io/kops/cloudmock/aws/mockcloudwatchlogs"
+	"k8s.io/kops/cloudmock/aws/mockefs"
+	"k8s.io/kops/cloudmock/aws/mockkms"
 	"k8s.io/kops/dnsprovider/pkg/dnsprovider"
 	dnsproviderroute53 "k8s.io/kops/dnsprovider/pkg/dnsprovider/providers/aws/route53"
 	"k8s.io/kops/pkg/apis/kops"
@@ -59,6 +62,10 @@ func InstallMockAWSCloud(region string, zoneLetters string) *MockAWSCloud {
 
 func BuildMockAWSCloud(region string, zoneLetters string) *MockAWSCloud {
 	i := &MockAWSCloud{region: region}
+	// The KMS, CloudWatch Logs and EFS resources are always listed with the cluster's resources, so they default to empty mocks
+	i.MockKMS = &mockkms.MockKMS{}
+	i.MockLogs = &mockcloudwatchlogs.MockCloudWatchLogs{}
+	i.MockEFS = &mockefs.MockEFS{}
 	for _, c := range zoneLetters {
 		azName := fmt.Sprintf("%s%c", region, c)
 		az := &ec2.AvailabilityZone{
@@ -82,6 +89,9 @@ type MockCloud struct {
 	MockSQS         awsinterfaces.SQSAPI
 	MockEventBridge awsinterfaces.EventBridgeAPI
 	MockSSM         awsinterfaces.SSMAPI
+	MockKMS         awsinterfaces.KMSAPI
+	MockLogs        awsinterfaces.CloudWatchLogsAPI
+	MockEFS         awsinterfaces.EFSAPI
 }
 
 func (c *MockAWSCloud) DeleteGroup(g *cloudinstances.CloudInstanceGroup) error {
@@ -302,6 +312,27 @@ func (c *MockAWSCloud) SSM() awsinterfaces.SSMAPI {
 	return c.MockSSM
 }
 
+func (c *MockAWSCloud) KMS() awsinterfaces.KMSAPI {
+	if c.MockKMS == nil {
+		klog.Fatalf("MockKMS not set")
+	}
+	return c.MockKMS
+}
+
+func (c *MockAWSCloud) CloudWatchLogs() awsinterfaces.CloudWatchLogsAPI {
+	if c.MockLogs == nil {
+		klog.Fatalf("MockLogs not set")
+	}
+	return c.MockLogs
+}
+
+func (c *MockAWSCloud) EFS() awsinterfaces.EFSAPI {
+	if c.MockEFS == nil {
+		klog.Fatalf("MockEFS not set")
+	}
+	return c.MockEFS
+}
+
 func (c *MockAWSCloud) FindVPCInfo(id string) (*fi.VPCInfo, error) {
 	return findVPCInfo(c, id)
 }
@@ -386,6 +417,10 @@ func (c *MockAWSCloud) DescribeInstanceType(instanceType string) (*ec2.InstanceT
 
 // AccountInfo returns the AWS account ID and AWS partition that we are deploying into
 func (c *MockAWSCloud) AccountInfo(ctx context.Context) (string, string, error) {
+	// Regions outside the standard partition report their real partition, so that ARNs can be checked
+	if partition := PartitionForRegion(c.region); partition != "aws" {
+		return "123456789012", partition, nil
+	}
 	return "123456789012", "aws-test", nil
 }
 
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/smithy-go/middleware"
+	"k8s.io/klog/v2"
+)
+
+// RequestTrace records a single AWS API call, including any retries
+type RequestTrace struct {
+	Service   string
+	Operation string
+	RequestID string
+	Latency   time.Duration
+	Err       error
+}
+
+// RequestTracer is called with the RequestTrace of every AWS API call
+type RequestTracer func(trace RequestTrace)
+
+// logRequestTrace is the RequestTracer used when the AWSRequestTracing feature flag is enabled
+func logRequestTrace(trace RequestTrace) {
+	if trace.Err != nil {
+		klog.Infof("AWS request %s/%s (request id %q) failed after %v: %v", trace.Service, trace.Operation, trace.RequestID, trace.Latency, trace.Err)
+		return
+	}
+	klog.Infof("AWS request %s/%s (request id %q) took %v", trace.Service, trace.Operation, trace.RequestID, trace.Latency)
+}
+
+// requestTracingHandler returns an aws-sdk-go handler, to be added to the Complete handlers,
+// that passes a RequestTrace for every request to tracer
+func requestTracingHandler(tracer RequestTracer) request.NamedHandler {
+	return request.NamedHandler{
+		Name: "kops/request-tracing",
+		Fn: func(r *request.Request) {
+			operation := "?"
+			if r.Operation != nil {
+				operation = r.Operation.Name
+			}
+			tracer(RequestTrace{
+				Service:   r.ClientInfo.ServiceName,
+				Operation: operation,
+				RequestID: r.RequestID,
+				Latency:   time.Since(r.Time),
+				Err:       r.Error,
+			})
+		},
+	}
+}
+
+// requestTracingMiddleware returns an aws-sdk-go-v2 API option that passes a RequestTrace for every request to tracer
+func requestTracingMiddleware(tracer RequestTracer) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		// Added after the other initialize middleware, so that the service metadata is available
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("kops/request-tracing", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			start := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			requestID, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+			tracer(RequestTrace{
+				Service:   awsmiddleware.GetServiceID(ctx),
+				Operation: awsmiddleware.GetOperationName(ctx),
+				RequestID: requestID,
+				Latency:   time.Since(start),
+				Err:       err,
+			})
+			return out, metadata, err
+		}), middleware.After)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	ec2v2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go/aws"
+	credentialsv1 "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/smithy-go/middleware"
+)
+
+const describeRouteTablesResponse = `<DescribeRouteTablesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>req-1234</requestId>
+  <routeTableSet/>
+</DescribeRouteTablesResponse>`
+
+// newMockEC2Server returns a server that answers every EC2 request with an empty DescribeRouteTables response
+func newMockEC2Server() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Header().Set("X-Amzn-Requestid", "req-1234")
+		w.Write([]byte(describeRouteTablesResponse))
+	}))
+}
+
+func assertSingleDescribeRouteTablesTrace(t *testing.T, traces []RequestTrace) {
+	if len(traces) != 1 {
+		t.Fatalf("expected 1 trace, got %d: %v", len(traces), traces)
+	}
+	trace := traces[0]
+	if trace.Operation != "DescribeRouteTables" {
+		t.Errorf("unexpected operation: %q", trace.Operation)
+	}
+	if trace.RequestID != "req-1234" {
+		t.Errorf("unexpected request id: %q", trace.RequestID)
+	}
+	if trace.Err != nil {
+		t.Errorf("unexpected error: %v", trace.Err)
+	}
+	if trace.Latency <= 0 {
+		t.Errorf("expected latency to be recorded, got %v", trace.Latency)
+	}
+}
+
+func TestRequestTracingHandler(t *testing.T) {
+	server := newMockEC2Server()
+	defer server.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-test-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentialsv1.NewStaticCredentials("id", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("error building session: %v", err)
+	}
+
+	var traces []RequestTrace
+	client := ec2.New(sess)
+	client.Handlers.Complete.PushBackNamed(requestTracingHandler(func(trace RequestTrace) {
+		traces = append(traces, trace)
+	}))
+
+	if _, err := client.DescribeRouteTables(&ec2.DescribeRouteTablesInput{}); err != nil {
+		t.Fatalf("error describing route tables: %v", err)
+	}
+
+	assertSingleDescribeRouteTablesTrace(t, traces)
+	if traces[0].Service != "ec2" {
+		t.Errorf("unexpected service: %q", traces[0].Service)
+	}
+}
+
+func TestRequestTracingMiddleware(t *testing.T) {
+	server := newMockEC2Server()
+	defer server.Close()
+
+	var traces []RequestTrace
+	client := ec2v2.New(ec2v2.Options{
+		Region:       "us-test-1",
+		BaseEndpoint: awsv2.String(server.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("id", "secret", ""),
+		APIOptions: []func(*middleware.Stack) error{
+			requestTracingMiddleware(func(trace RequestTrace) {
+				traces = append(traces, trace)
+			}),
+		},
+	})
+
+	if _, err := client.DescribeRouteTables(context.Background(), &ec2v2.DescribeRouteTablesInput{}); err != nil {
+		t.Fatalf("error describing route tables: %v", err)
+	}
+
+	assertSingleDescribeRouteTablesTrace(t, traces)
+	if traces[0].Service != "EC2" {
+		t.Errorf("unexpected service: %q", traces[0].Service)
+	}
+}